@@ -0,0 +1,51 @@
+package opentracing_helpers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WithAccessLog writes one structured access log line per traced request
+// to logger, with the method, path, status, response size, latency and the
+// trace and span IDs of the server span. It reuses the middleware's
+// ResponseWriter wrapper, so no separate logging middleware is needed.
+// Requests skipped by WithFilter are not logged.
+func WithAccessLog(logger *slog.Logger) HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.accessLog = logger
+	})
+}
+
+// AccessLogMiddleware is NewMiddleware with WithAccessLog(logger):
+//
+//	r.Use(opentracing_helpers.AccessLogMiddleware(slog.Default()))
+func AccessLogMiddleware(logger *slog.Logger, opts ...HandlerOption) func(http.Handler) http.Handler {
+	return NewMiddleware(append(opts[:len(opts):len(opts)], WithAccessLog(logger))...)
+}
+
+// logAccess writes the access log line of sr.
+func (c *handlerConfig) logAccess(sr *serverRequest, duration time.Duration) {
+	if c.accessLog == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("operation", sr.operationName),
+		slog.String("method", sr.r.Method),
+		slog.String("path", sr.r.URL.Path),
+		slog.String("remote_addr", sr.r.RemoteAddr),
+		slog.Int("status", sr.rr.status),
+		slog.Int64("bytes", sr.rr.size),
+		slog.Duration("duration", duration),
+	}
+	if ids, ok := SpanIdentifiersFromContext(sr.r.Context(), c.codec); ok {
+		attrs = append(attrs,
+			slog.String("trace_id", ids.TraceID),
+			slog.String("span_id", ids.SpanID))
+	}
+	level := slog.LevelInfo
+	if sr.failed {
+		level = slog.LevelError
+	}
+	c.accessLog.LogAttrs(sr.r.Context(), level, "http request", attrs...)
+}
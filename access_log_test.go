@@ -0,0 +1,92 @@
+package opentracing_helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// accessLogs returns the JSON lines written to buf.
+func accessLogs(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]interface{}
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := mocktracer.New()
+	mw := AccessLogMiddleware(slog.New(slog.NewJSONHandler(&buf, nil)), WithTracer(tracer), WithSpanContextCodec(mockCodec{}))
+	r := httptest.NewRequest(http.MethodGet, "/users?page=2", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	span := finishedSpan(t, tracer)
+	lines := accessLogs(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want 1", len(lines))
+	}
+	line := lines[0]
+	for key, want := range map[string]interface{}{
+		"level":       "INFO",
+		"msg":         "http request",
+		"operation":   "GET /users",
+		"method":      "GET",
+		"path":        "/users",
+		"remote_addr": "192.0.2.1:1234",
+		"status":      float64(200),
+		"bytes":       float64(2),
+		"trace_id":    fmt.Sprintf("%016x", span.SpanContext.TraceID),
+		"span_id":     fmt.Sprintf("%016x", span.SpanContext.SpanID),
+	} {
+		if line[key] != want {
+			t.Errorf("%s = %v, want %v", key, line[key], want)
+		}
+	}
+	if _, ok := line["duration"]; !ok {
+		t.Error("no duration logged")
+	}
+}
+
+func TestWithAccessLogErrors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	_, h := TraceHandler("/", panicking, WithTracer(mocktracer.New()), WithAccessLog(logger), WithPanicRecovery(false))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	lines := accessLogs(t, &buf)
+	if len(lines) != 1 || lines[0]["level"] != "ERROR" || lines[0]["status"] != float64(500) {
+		t.Errorf("logged %v, want a single error line with status 500", lines)
+	}
+	if _, ok := lines[0]["trace_id"]; ok {
+		t.Error("trace_id logged although mocktracer's IDs are unknown without a codec")
+	}
+}
+
+func TestWithAccessLogFiltered(t *testing.T) {
+	var buf bytes.Buffer
+	_, h := TraceHandler("/healthz", okHandler, WithTracer(mocktracer.New()),
+		WithAccessLog(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithFilter(func(*http.Request) bool { return false }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if buf.Len() != 0 {
+		t.Errorf("logged %q for a filtered request", buf.String())
+	}
+}
@@ -0,0 +1,139 @@
+// Package amqp propagates spans through AMQP message headers, for use with
+// rabbitmq/amqp091-go. Publishers inject the span context into the
+// amqp.Publishing headers and consumers extract it from the delivery:
+//
+//	msg := amqp.Publishing{Body: body}
+//	span, ctx := otamqp.TracePublish(ctx, "orders", "orders.created", &msg)
+//	err := ch.PublishWithContext(ctx, "orders", "orders.created", false, false, msg)
+//	span.Finish()
+//
+//	for d := range deliveries {
+//	    span, ctx := otamqp.TraceDelivery(ctx, "orders-queue", d)
+//	    handle(ctx, d)
+//	    span.Finish()
+//	}
+package amqp
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "amqp"}
+
+// Option customizes the spans started by this package.
+type Option func(*config)
+
+type config struct {
+	tracer opentracing.Tracer
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) activeTracer() opentracing.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return opentracing.GlobalTracer()
+}
+
+// WithTracer uses tracer instead of opentracing.GlobalTracer().
+func WithTracer(tracer opentracing.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// TableCarrier adapts amqp.Table message headers to the opentracing
+// TextMap interfaces.
+type TableCarrier amqp.Table
+
+// Set implements opentracing.TextMapWriter.
+func (c TableCarrier) Set(key, val string) {
+	c[key] = val
+}
+
+// ForeachKey implements opentracing.TextMapReader. Header values that
+// aren't strings are ignored.
+func (c TableCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range c {
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		default:
+			continue
+		}
+		if err := handler(k, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TracePublish starts a producer span as a child of the span in ctx and
+// injects its context into the headers of msg. The caller finishes the
+// span once the message has been published.
+func TracePublish(ctx context.Context, exchange, routingKey string, msg *amqp.Publishing, opts ...Option) (opentracing.Span, context.Context) {
+	c := newConfig(opts)
+	tracer := c.activeTracer()
+	var parent opentracing.SpanContext
+	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+		parent = parentSpan.Context()
+	}
+	span := tracer.StartSpan(
+		"amqp publish "+destination(exchange, routingKey),
+		opentracing.ChildOf(parent),
+		ext.SpanKindProducer,
+		componentTag,
+		opentracing.Tag{Key: "amqp.exchange", Value: exchange},
+		opentracing.Tag{Key: "amqp.routing_key", Value: routingKey},
+	)
+	ext.MessageBusDestination.Set(span, destination(exchange, routingKey))
+
+	if msg.Headers == nil {
+		msg.Headers = amqp.Table{}
+	}
+	tracer.Inject(span.Context(), opentracing.TextMap, TableCarrier(msg.Headers))
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+// TraceDelivery starts a consumer span for a delivery received from queue.
+// The span follows from the publisher's span. The caller finishes the span
+// once the delivery has been handled.
+func TraceDelivery(ctx context.Context, queue string, d amqp.Delivery, opts ...Option) (opentracing.Span, context.Context) {
+	c := newConfig(opts)
+	tracer := c.activeTracer()
+	producer, _ := tracer.Extract(opentracing.TextMap, TableCarrier(d.Headers))
+	span := tracer.StartSpan(
+		"amqp consume "+queue,
+		opentracing.FollowsFrom(producer),
+		ext.SpanKindConsumer,
+		componentTag,
+		opentracing.Tag{Key: "amqp.exchange", Value: d.Exchange},
+		opentracing.Tag{Key: "amqp.routing_key", Value: d.RoutingKey},
+		opentracing.Tag{Key: "amqp.queue", Value: queue},
+	)
+	ext.MessageBusDestination.Set(span, queue)
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+// destination names where a message is published. The default exchange
+// routes messages directly to the queue named by the routing key.
+func destination(exchange, routingKey string) string {
+	if exchange == "" {
+		return routingKey
+	}
+	return exchange
+}
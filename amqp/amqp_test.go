@@ -0,0 +1,71 @@
+package amqp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestPublishAndDelivery(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	var msg amqp.Publishing
+	publish, _ := TracePublish(ctx, "orders", "orders.created", &msg, WithTracer(tracer))
+	publish.Finish()
+
+	d := amqp.Delivery{Exchange: "orders", RoutingKey: "orders.created", Headers: msg.Headers}
+	consume, _ := TraceDelivery(context.Background(), "orders-queue", d, WithTracer(tracer))
+	consume.Finish()
+
+	p, c := publish.(*mocktracer.MockSpan), consume.(*mocktracer.MockSpan)
+	if p.OperationName != "amqp publish orders" || c.OperationName != "amqp consume orders-queue" {
+		t.Errorf("operation names = %q, %q", p.OperationName, c.OperationName)
+	}
+	if p.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the publisher span is not a child of the context span")
+	}
+	if c.ParentID != p.SpanContext.SpanID {
+		t.Error("the consumer span doesn't follow from the publisher span")
+	}
+	for key, want := range map[string]interface{}{
+		"amqp.exchange":           "orders",
+		"amqp.routing_key":        "orders.created",
+		"amqp.queue":              "orders-queue",
+		"message_bus.destination": "orders-queue",
+	} {
+		if got := c.Tag(key); got != want {
+			t.Errorf("consumer span: %s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestPublishToDefaultExchange(t *testing.T) {
+	tracer := mocktracer.New()
+	msg := amqp.Publishing{Headers: amqp.Table{"x-retry": int32(1)}}
+	span, _ := TracePublish(context.Background(), "", "jobs", &msg, WithTracer(tracer))
+	span.Finish()
+
+	if got := span.(*mocktracer.MockSpan).OperationName; got != "amqp publish jobs" {
+		t.Errorf("operation name = %q, want it named after the routing key", got)
+	}
+	if msg.Headers["x-retry"] != int32(1) {
+		t.Error("existing headers were lost")
+	}
+}
+
+func TestTableCarrierSkipsNonStrings(t *testing.T) {
+	carrier := TableCarrier{"a": "1", "b": []byte("2"), "c": int64(3)}
+	got := map[string]string{}
+	carrier.ForeachKey(func(key, val string) error {
+		got[key] = val
+		return nil
+	})
+	if len(got) != 2 || got["a"] != "1" || got["b"] != "2" {
+		t.Errorf("ForeachKey visited %v, want a=1 and b=2 only", got)
+	}
+}
@@ -0,0 +1,46 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// URLTemplateFunc returns the route template of a URL, such as
+// "/users/{id}" for "/users/42", or "" if there is none. Naming client
+// spans after templates rather than raw paths keeps the number of
+// distinct operations low.
+type URLTemplateFunc func(u *url.URL) string
+
+// WithURLTemplate names client spans "METHOD host template" using f,
+// falling back to "METHOD host" when f returns "". Unless
+// WithOperationNameFunc is given, it applies to TracedTransport and
+// TraceRequestAuto, and overrides the operationName argument of
+// TraceRequestContext.
+func WithURLTemplate(f URLTemplateFunc) TransportOption {
+	return transportOption(func(c *transportConfig) {
+		c.urlTemplate = f
+	})
+}
+
+// TraceRequestAuto is like TraceRequestContext, deriving the operation
+// name from r as "METHOD host", or "METHOD host template" with
+// WithURLTemplate, so that call sites don't have to name their spans:
+//
+//	tracedReq, span := opentracing_helpers.TraceRequestAuto(ctx, req)
+func TraceRequestAuto(ctx context.Context, r *http.Request, opts ...TransportOption) (*http.Request, opentracing.Span) {
+	return TraceRequestContext(ctx, newTransportConfig(opts).autoName(r), r, opts...)
+}
+
+// autoName derives a client span name from r.
+func (c *transportConfig) autoName(r *http.Request) string {
+	name := r.Method + " " + r.URL.Host
+	if c.urlTemplate != nil {
+		if template := c.urlTemplate(r.URL); template != "" {
+			name += " " + template
+		}
+	}
+	return name
+}
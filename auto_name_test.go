@@ -0,0 +1,72 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// usersTemplate templates the /users/{id} route.
+func usersTemplate(u *url.URL) string {
+	if strings.HasPrefix(u.Path, "/users/") {
+		return "/users/{id}"
+	}
+	return ""
+}
+
+func TestTraceRequestAuto(t *testing.T) {
+	tests := []struct {
+		url  string
+		opts []TransportOption
+		want string
+	}{
+		{"http://api.example.com/users/42", nil, "GET api.example.com"},
+		{"http://api.example.com/users/42", []TransportOption{WithURLTemplate(usersTemplate)}, "GET api.example.com /users/{id}"},
+		{"http://api.example.com/health", []TransportOption{WithURLTemplate(usersTemplate)}, "GET api.example.com"},
+	}
+	for _, tt := range tests {
+		tracer := mocktracer.New()
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		_, span := TraceRequestAuto(context.Background(), req, append(tt.opts, WithTracer(tracer))...)
+		span.Finish()
+
+		if got := finishedSpan(t, tracer).OperationName; got != tt.want {
+			t.Errorf("%s: operation name = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestWithURLTemplateTransport(t *testing.T) {
+	tracer := mocktracer.New()
+	transport := NewTracedTransport(respond(http.StatusOK, "", nil), WithTracer(tracer), WithURLTemplate(usersTemplate))
+	resp, err := transport.RoundTrip(httptest.NewRequest(http.MethodDelete, "http://api.example.com/users/42", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got, want := finishedSpan(t, tracer).OperationName, "DELETE api.example.com /users/{id}"; got != want {
+		t.Errorf("operation name = %q, want %q", got, want)
+	}
+}
+
+func TestWithURLTemplateOperationNameFuncWins(t *testing.T) {
+	tracer := mocktracer.New()
+	transport := NewTracedTransport(respond(http.StatusOK, "", nil), WithTracer(tracer),
+		WithURLTemplate(usersTemplate),
+		WithOperationNameFunc(func(r *http.Request) string { return "users" }))
+	resp, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://api.example.com/users/42", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := finishedSpan(t, tracer).OperationName; got != "users" {
+		t.Errorf("operation name = %q, want %q", got, "users")
+	}
+}
@@ -0,0 +1,145 @@
+// Package awsv2 traces AWS SDK for Go v2 API calls with smithy
+// middlewares. Each call gets a span named "Service.Operation", a child of
+// the span carried by the context passed to the call:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	otawsv2.AppendMiddlewares(&cfg.APIOptions)
+//	client := s3.NewFromConfig(cfg)
+package awsv2
+
+import (
+	"context"
+	"errors"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "aws-sdk-go-v2"}
+
+// Option customizes the middlewares.
+type Option func(*config)
+
+type config struct {
+	tracer opentracing.Tracer
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) activeTracer() opentracing.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return opentracing.GlobalTracer()
+}
+
+// WithTracer uses tracer instead of opentracing.GlobalTracer().
+func WithTracer(tracer opentracing.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// AppendMiddlewares registers the tracing middlewares in apiOptions,
+// usually &cfg.APIOptions of an aws.Config or the APIOptions of a service
+// client's options. Spans are tagged with aws.service, aws.operation,
+// aws.region, aws.request_id, aws.retry.attempts and the HTTP status code.
+func AppendMiddlewares(apiOptions *[]func(*middleware.Stack) error, opts ...Option) {
+	c := newConfig(opts)
+	*apiOptions = append(*apiOptions, func(stack *middleware.Stack) error {
+		// Running last in the initialize step, the service metadata is
+		// available.
+		if err := stack.Initialize.Add(c.initialize(), middleware.After); err != nil {
+			return err
+		}
+		// Inside the retry loop, the middleware sees every attempt.
+		if err := stack.Finalize.Insert(attempt(), "Retry", middleware.After); err != nil {
+			return stack.Finalize.Add(attempt(), middleware.After)
+		}
+		return nil
+	})
+}
+
+type attemptsKey struct{}
+
+// initialize starts and finishes the span of an API call.
+func (c *config) initialize() middleware.InitializeMiddleware {
+	return middleware.InitializeMiddlewareFunc("OpenTracingSpan", func(
+		ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+		var parent opentracing.SpanContext
+		if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+			parent = parentSpan.Context()
+		}
+		span := c.activeTracer().StartSpan(
+			service+"."+operation,
+			opentracing.ChildOf(parent),
+			ext.SpanKindRPCClient,
+			componentTag,
+		)
+		defer span.Finish()
+		span.SetTag("aws.service", service)
+		span.SetTag("aws.operation", operation)
+		if region := awsmiddleware.GetRegion(ctx); region != "" {
+			span.SetTag("aws.region", region)
+		}
+
+		attempts := new(int)
+		ctx = opentracing.ContextWithSpan(ctx, span)
+		ctx = context.WithValue(ctx, attemptsKey{}, attempts)
+		out, metadata, err := next.HandleInitialize(ctx, in)
+
+		span.SetTag("aws.retry.attempts", *attempts)
+		if requestID, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+			span.SetTag("aws.request_id", requestID)
+		}
+		if err != nil {
+			var respErr *awshttp.ResponseError
+			if errors.As(err, &respErr) {
+				ext.HTTPStatusCode.Set(span, uint16(respErr.HTTPStatusCode()))
+				if requestID := respErr.ServiceRequestID(); requestID != "" {
+					span.SetTag("aws.request_id", requestID)
+				}
+			}
+			ext.Error.Set(span, true)
+			span.LogFields(log.String("event", "error"), log.Error(err))
+		} else if resp, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok {
+			ext.HTTPStatusCode.Set(span, uint16(resp.StatusCode))
+		}
+		return out, metadata, err
+	})
+}
+
+// attempt counts and logs the attempts of an API call.
+func attempt() middleware.FinalizeMiddleware {
+	return middleware.FinalizeMiddlewareFunc("OpenTracingAttempt", func(
+		ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+	) (middleware.FinalizeOutput, middleware.Metadata, error) {
+		attempts, _ := ctx.Value(attemptsKey{}).(*int)
+		span := opentracing.SpanFromContext(ctx)
+		if attempts == nil || span == nil {
+			return next.HandleFinalize(ctx, in)
+		}
+		*attempts++
+		if *attempts > 1 {
+			span.LogFields(log.String("event", "retry"), log.Int("aws.retry.attempt", *attempts))
+		}
+		out, metadata, err := next.HandleFinalize(ctx, in)
+		if err != nil {
+			span.LogFields(log.String("event", "attempt failed"), log.Int("aws.retry.attempt", *attempts), log.Error(err))
+		}
+		return out, metadata, err
+	})
+}
@@ -0,0 +1,124 @@
+package awsv2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// retry stands for the SDK's retry middleware, making up to n attempts.
+func retry(n int) middleware.FinalizeMiddleware {
+	return middleware.FinalizeMiddlewareFunc("Retry", func(
+		ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+	) (out middleware.FinalizeOutput, metadata middleware.Metadata, err error) {
+		for i := 0; i < n; i++ {
+			if out, metadata, err = next.HandleFinalize(ctx, in); err == nil {
+				break
+			}
+		}
+		return out, metadata, err
+	})
+}
+
+// invoke runs an S3 GetObject call through a stack like the SDK's, with
+// the tracing middlewares, sending requests to send.
+func invoke(ctx context.Context, tracer opentracing.Tracer, attempts int, send middleware.HandlerFunc) error {
+	stack := middleware.NewStack("GetObject", smithyhttp.NewStackRequest)
+	stack.Initialize.Add(&awsmiddleware.RegisterServiceMetadata{
+		ServiceID:     "S3",
+		Region:        "eu-west-1",
+		OperationName: "GetObject",
+	}, middleware.Before)
+	stack.Finalize.Add(retry(attempts), middleware.After)
+	awsmiddleware.AddRawResponseToMetadata(stack)
+
+	var apiOptions []func(*middleware.Stack) error
+	AppendMiddlewares(&apiOptions, WithTracer(tracer))
+	for _, apply := range apiOptions {
+		if err := apply(stack); err != nil {
+			return err
+		}
+	}
+	_, _, err := middleware.DecorateHandler(send, stack).Handle(ctx, struct{}{})
+	return err
+}
+
+func TestAppendMiddlewares(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	calls := 0
+	err := invoke(ctx, tracer, 3, func(ctx context.Context, in interface{}) (interface{}, middleware.Metadata, error) {
+		var metadata middleware.Metadata
+		if calls++; calls == 1 {
+			return nil, metadata, errors.New("throttled")
+		}
+		awsmiddleware.SetRequestIDMetadata(&metadata, "req-1")
+		return &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusOK}}, metadata, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("%d finished spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.OperationName != "S3.GetObject" || span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Errorf("span %q with parent %d, want S3.GetObject under the context span", span.OperationName, span.ParentID)
+	}
+	for key, want := range map[string]interface{}{
+		"span.kind":          ext.SpanKindRPCClientEnum,
+		"component":          "aws-sdk-go-v2",
+		"aws.service":        "S3",
+		"aws.operation":      "GetObject",
+		"aws.region":         "eu-west-1",
+		"aws.request_id":     "req-1",
+		"aws.retry.attempts": 2,
+		"http.status_code":   uint16(http.StatusOK),
+	} {
+		if got := span.Tag(key); got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+	var events []string
+	for _, l := range span.Logs() {
+		events = append(events, l.Fields[0].ValueString)
+	}
+	if want := []string{"attempt failed", "retry"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("logged events %q, want %q", events, want)
+	}
+}
+
+func TestAppendMiddlewaresResponseError(t *testing.T) {
+	tracer := mocktracer.New()
+	err := invoke(context.Background(), tracer, 1, func(ctx context.Context, in interface{}) (interface{}, middleware.Metadata, error) {
+		return nil, middleware.Metadata{}, &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+				Err:      errors.New("NoSuchKey"),
+			},
+			RequestID: "req-2",
+		}
+	})
+	if err == nil {
+		t.Fatal("the call succeeded, want the response error")
+	}
+
+	span := tracer.FinishedSpans()[0]
+	if span.Tag("error") != true || span.Tag("http.status_code") != uint16(http.StatusNotFound) || span.Tag("aws.request_id") != "req-2" {
+		t.Errorf("tags = %v, want the error, its status and request ID", span.Tags())
+	}
+}
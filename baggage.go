@@ -0,0 +1,38 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// SetBaggage sets a baggage item on the span in ctx, so that it is
+// propagated to every downstream span. It does nothing if ctx carries no
+// span.
+func SetBaggage(ctx context.Context, key, value string) {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.SetBaggageItem(key, value)
+	}
+}
+
+// Baggage returns the baggage item key of the span in ctx, or an empty
+// string if ctx carries no span or the item isn't set.
+func Baggage(ctx context.Context, key string) string {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		return span.BaggageItem(key)
+	}
+	return ""
+}
+
+// WithBaggageTags copies the listed baggage items, when received from the
+// caller, into tags of the server span so they can be searched for.
+func WithBaggageTags(keys ...string) HandlerOption {
+	return WithSpanObserver(func(span opentracing.Span, _ *http.Request) {
+		for _, key := range keys {
+			if value := span.BaggageItem(key); value != "" {
+				span.SetTag(key, value)
+			}
+		}
+	})
+}
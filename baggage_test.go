@@ -0,0 +1,52 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestBaggage(t *testing.T) {
+	tracer := mocktracer.New()
+	ctx := opentracing.ContextWithSpan(context.Background(), tracer.StartSpan("parent"))
+
+	SetBaggage(ctx, "tenant", "acme")
+	if got := Baggage(ctx, "tenant"); got != "acme" {
+		t.Errorf("Baggage = %q, want %q", got, "acme")
+	}
+	if got := Baggage(ctx, "missing"); got != "" {
+		t.Errorf("Baggage of a missing item = %q, want empty", got)
+	}
+
+	// Without a span both are no-ops.
+	SetBaggage(context.Background(), "tenant", "acme")
+	if got := Baggage(context.Background(), "tenant"); got != "" {
+		t.Errorf("Baggage without a span = %q, want empty", got)
+	}
+}
+
+func TestWithBaggageTags(t *testing.T) {
+	tracer := mocktracer.New()
+	client := tracer.StartSpan("client")
+	client.SetBaggageItem("tenant", "acme")
+	client.SetBaggageItem("secret", "hunter2")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	tracer.Inject(client.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+
+	_, h := TraceHandler("/", okHandler, WithTracer(tracer), WithBaggageTags("tenant", "region"))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	span := finishedSpan(t, tracer)
+	if got := span.Tag("tenant"); got != "acme" {
+		t.Errorf("tenant tag = %v, want %q", got, "acme")
+	}
+	for _, key := range []string{"secret", "region"} {
+		if got := span.Tag(key); got != nil {
+			t.Errorf("%s tag = %v, want none", key, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// maxLoggedBatchErrors bounds the item errors logged on a batch span.
+const maxLoggedBatchErrors = 10
+
+// BatchOption customizes TraceBatch.
+type BatchOption func(*batchConfig)
+
+type batchConfig struct {
+	itemSpans int
+}
+
+// WithItemSpans starts a child span for each of the first limit items, so
+// large batches can't produce an explosion of spans. By default only the
+// batch span is created.
+func WithItemSpans(limit int) BatchOption {
+	return func(c *batchConfig) {
+		c.itemSpans = limit
+	}
+}
+
+// TraceBatch calls fn for each of n items, in order, within a span named
+// name that is a child of the span in ctx. The batch span is tagged with
+// batch.size and batch.errors, the number of failed items, and the errors
+// of the first items that failed are logged. It returns the item errors
+// joined with errors.Join:
+//
+//	err := opentracing_helpers.TraceBatch(ctx, "index documents", len(docs),
+//		func(ctx context.Context, i int) error { return index(ctx, docs[i]) },
+//		opentracing_helpers.WithItemSpans(50))
+func TraceBatch(ctx context.Context, name string, n int, fn func(ctx context.Context, i int) error, opts ...BatchOption) error {
+	c := &batchConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, name)
+	defer span.Finish()
+	span.SetTag("batch.size", n)
+
+	var errs []error
+	for i := 0; i < n; i++ {
+		var err error
+		if i < c.itemSpans {
+			err = traceBatchItem(ctx, name, i, fn)
+		} else {
+			err = fn(ctx, i)
+		}
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		if len(errs) <= maxLoggedBatchErrors {
+			span.LogFields(log.String("event", "error"), log.Int("batch.item", i), log.Error(err))
+		}
+	}
+
+	span.SetTag("batch.errors", len(errs))
+	if len(errs) > 0 {
+		ext.Error.Set(span, true)
+	}
+	return errors.Join(errs...)
+}
+
+// traceBatchItem calls fn for item i within a child span.
+func traceBatchItem(ctx context.Context, name string, i int, fn func(ctx context.Context, i int) error) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, name+" item")
+	defer span.Finish()
+	span.SetTag("batch.item", i)
+	err := fn(ctx, i)
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+	return err
+}
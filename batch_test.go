@@ -0,0 +1,74 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestTraceBatch(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	var processed []int
+	err := TraceBatch(context.Background(), "index", 5, func(ctx context.Context, i int) error {
+		processed = append(processed, i)
+		if i%2 == 1 {
+			return fmt.Errorf("item %d failed", i)
+		}
+		return nil
+	}, WithItemSpans(2))
+	if err == nil || err.Error() != "item 1 failed\nitem 3 failed" {
+		t.Errorf("err = %v, want the joined item errors", err)
+	}
+	if len(processed) != 5 {
+		t.Errorf("processed items %v, want all 5 in order", processed)
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 3 {
+		t.Fatalf("%d finished spans, want 2 item spans and the batch span", len(spans))
+	}
+	batch := spans[2]
+	if batch.OperationName != "index" || batch.Tag("batch.size") != 5 || batch.Tag("batch.errors") != 2 || batch.Tag("error") != true {
+		t.Errorf("batch span %q tagged %v", batch.OperationName, batch.Tags())
+	}
+	if n := len(batch.Logs()); n != 2 {
+		t.Errorf("batch span has %d logs, want 2 errors", n)
+	}
+	for i, item := range spans[:2] {
+		if item.OperationName != "index item" || item.Tag("batch.item") != i || item.ParentID != batch.SpanContext.SpanID {
+			t.Errorf("item span %d: %q tagged %v with parent %d", i, item.OperationName, item.Tags(), item.ParentID)
+		}
+	}
+	if spans[0].Tag("error") != nil || spans[1].Tag("error") != true {
+		t.Errorf("item errors = %v, %v; want only item 1 failed", spans[0].Tag("error"), spans[1].Tag("error"))
+	}
+}
+
+func TestTraceBatchBoundsLoggedErrors(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	failure := errors.New("failed")
+	err := TraceBatch(context.Background(), "index", 3*maxLoggedBatchErrors, func(ctx context.Context, i int) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("err = %v, want it to wrap the item errors", err)
+	}
+
+	batch := finishedSpan(t, tracer)
+	if n := len(batch.Logs()); n != maxLoggedBatchErrors {
+		t.Errorf("batch span has %d logs, want %d", n, maxLoggedBatchErrors)
+	}
+	if got := batch.Tag("batch.errors"); got != 3*maxLoggedBatchErrors {
+		t.Errorf("batch.errors = %v, want %d", got, 3*maxLoggedBatchErrors)
+	}
+}
@@ -0,0 +1,233 @@
+// Package blob traces object storage calls made by the Amazon S3, Google
+// Cloud Storage and Azure Blob Storage SDKs. It provides a TracedTransport
+// that recognizes their endpoints and names spans after the storage
+// operation, tagged with the bucket, the key and the bytes sent, to plug
+// into the SDKs' HTTP client hooks:
+//
+//	client := &http.Client{Transport: otblob.NewTransport(nil)}
+//
+//	// AWS SDK v2
+//	s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) { o.HTTPClient = client })
+//
+//	// Azure SDK
+//	azClient, err := azblob.NewClient(url, cred, &azblob.ClientOptions{
+//		ClientOptions: azcore.ClientOptions{Transport: client},
+//	})
+//
+//	// Google Cloud, keeping authentication
+//	transport, err := htransport.NewTransport(ctx, otblob.NewTransport(nil), option.WithScopes(storage.ScopeReadWrite))
+//	gcsClient, err := storage.NewClient(ctx, option.WithHTTPClient(&http.Client{Transport: transport}))
+//
+// Requests to other hosts are traced like with a plain TracedTransport.
+package blob
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+)
+
+// Provider names, used as the blob.provider tag.
+const (
+	S3    = "s3"
+	GCS   = "gcs"
+	Azure = "azblob"
+)
+
+// Object describes an object storage request.
+type Object struct {
+	Provider  string
+	Bucket    string
+	Key       string
+	Operation string
+}
+
+// NewTransport returns a TracedTransport wrapping base that names and tags
+// the spans of object storage requests. opts are applied after the blob
+// options, so WithOperationNameFunc can still override span names.
+func NewTransport(base http.RoundTripper, opts ...opentracing_helpers.TransportOption) *opentracing_helpers.TracedTransport {
+	blobOpts := []opentracing_helpers.TransportOption{
+		opentracing_helpers.WithOperationNameFunc(operationName),
+		opentracing_helpers.WithSpanDecorator(decorate),
+	}
+	return opentracing_helpers.NewTracedTransport(base, append(blobOpts, opts...)...)
+}
+
+func operationName(r *http.Request) string {
+	if o, ok := ParseRequest(r); ok {
+		return o.Provider + " " + o.Operation
+	}
+	return "HTTP " + r.Method
+}
+
+func decorate(span opentracing.Span, r *http.Request, _ int) {
+	o, ok := ParseRequest(r)
+	if !ok {
+		return
+	}
+	span.SetTag("blob.provider", o.Provider)
+	span.SetTag("blob.operation", o.Operation)
+	if o.Bucket != "" {
+		span.SetTag("blob.bucket", o.Bucket)
+	}
+	if o.Key != "" {
+		span.SetTag("blob.key", o.Key)
+	}
+	if r.ContentLength > 0 {
+		span.SetTag("blob.bytes_sent", r.ContentLength)
+	}
+}
+
+// ParseRequest recognizes requests to S3, GCS and Azure Blob Storage
+// endpoints and describes them.
+func ParseRequest(r *http.Request) (Object, bool) {
+	host := r.URL.Hostname()
+	switch {
+	case strings.HasSuffix(host, ".amazonaws.com") && strings.Contains(host, "s3"):
+		return parseS3(r, host), true
+	case host == "storage.googleapis.com" || strings.HasSuffix(host, ".storage.googleapis.com"):
+		return parseGCS(r, host), true
+	case strings.HasSuffix(host, ".blob.core.windows.net"):
+		return parseAzure(r), true
+	}
+	return Object{}, false
+}
+
+// parseS3 handles virtual-hosted style (bucket.s3.region.amazonaws.com)
+// and path style (s3.region.amazonaws.com/bucket) URLs.
+func parseS3(r *http.Request, host string) Object {
+	o := Object{Provider: S3}
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if i := strings.Index(host, ".s3"); i > 0 {
+		o.Bucket, o.Key = host[:i], path
+	} else {
+		o.Bucket, o.Key, _ = strings.Cut(path, "/")
+	}
+	q := r.URL.Query()
+	switch {
+	case o.Bucket == "":
+		o.Operation = "ListBuckets"
+	case has(q, "uploads"):
+		o.Operation = "CreateMultipartUpload"
+	case has(q, "uploadId") && r.Method == http.MethodPut:
+		o.Operation = "UploadPart"
+	case has(q, "uploadId") && r.Method == http.MethodPost:
+		o.Operation = "CompleteMultipartUpload"
+	case has(q, "uploadId") && r.Method == http.MethodDelete:
+		o.Operation = "AbortMultipartUpload"
+	case has(q, "delete") && r.Method == http.MethodPost:
+		o.Operation = "DeleteObjects"
+	case o.Key == "" && r.Method == http.MethodGet:
+		o.Operation = "ListObjects"
+	case o.Key == "":
+		o.Operation = methodOperation(r.Method, "Bucket")
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		o.Operation = "CopyObject"
+	default:
+		o.Operation = methodOperation(r.Method, "Object")
+	}
+	return o
+}
+
+// parseGCS handles the JSON API (storage.googleapis.com/storage/v1/b/...),
+// its upload endpoint and the XML API.
+func parseGCS(r *http.Request, host string) Object {
+	o := Object{Provider: GCS}
+	path := r.URL.EscapedPath()
+	upload := strings.HasPrefix(path, "/upload/")
+	if rest, ok := strings.CutPrefix(strings.TrimPrefix(path, "/upload"), "/storage/v1/b/"); ok {
+		bucket, tail, _ := strings.Cut(rest, "/")
+		o.Bucket = unescape(bucket)
+		if upload {
+			o.Key = r.URL.Query().Get("name")
+			o.Operation = "UploadObject"
+			return o
+		}
+		if tail != "o" && !strings.HasPrefix(tail, "o/") {
+			o.Operation = methodOperation(r.Method, "Bucket")
+			return o
+		}
+		o.Key = unescape(strings.TrimPrefix(tail[1:], "/"))
+		if o.Key == "" {
+			o.Operation = "ListObjects"
+			return o
+		}
+		if r.URL.Query().Get("alt") == "media" {
+			o.Operation = "GetObject"
+			return o
+		}
+		o.Operation = methodOperation(r.Method, "ObjectMetadata")
+		return o
+	}
+	// XML API, virtual-hosted or path style.
+	if bucket, ok := strings.CutSuffix(host, ".storage.googleapis.com"); ok {
+		o.Bucket, o.Key = bucket, strings.TrimPrefix(r.URL.Path, "/")
+	} else {
+		o.Bucket, o.Key, _ = strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	}
+	if o.Key == "" {
+		o.Operation = methodOperation(r.Method, "Bucket")
+	} else {
+		o.Operation = methodOperation(r.Method, "Object")
+	}
+	return o
+}
+
+// parseAzure handles account.blob.core.windows.net/container/blob URLs.
+func parseAzure(r *http.Request) Object {
+	o := Object{Provider: Azure}
+	o.Bucket, o.Key, _ = strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	q := r.URL.Query()
+	switch comp := q.Get("comp"); {
+	case comp == "list":
+		o.Operation = "ListBlobs"
+		if o.Bucket == "" {
+			o.Operation = "ListContainers"
+		}
+	case comp == "block":
+		o.Operation = "PutBlock"
+	case comp == "blocklist":
+		o.Operation = methodOperation(r.Method, "BlockList")
+	case comp != "":
+		o.Operation = methodOperation(r.Method, "Blob") + ":" + comp
+	case o.Key == "":
+		o.Operation = methodOperation(r.Method, "Container")
+	default:
+		o.Operation = methodOperation(r.Method, "Blob")
+	}
+	return o
+}
+
+// methodOperation names a CRUD operation on resource after the method.
+func methodOperation(method, resource string) string {
+	switch method {
+	case http.MethodGet:
+		return "Get" + resource
+	case http.MethodHead:
+		return "Head" + resource
+	case http.MethodPut:
+		return "Put" + resource
+	case http.MethodPost:
+		return "Post" + resource
+	case http.MethodDelete:
+		return "Delete" + resource
+	case http.MethodPatch:
+		return "Update" + resource
+	}
+	return method + " " + resource
+}
+
+func has(q url.Values, key string) bool {
+	_, ok := q[key]
+	return ok
+}
+
+func unescape(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
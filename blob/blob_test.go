@@ -0,0 +1,103 @@
+package blob
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		method, url string
+		header      http.Header
+		want        Object
+	}{
+		{"GET", "https://photos.s3.eu-west-1.amazonaws.com/2024/cat.jpg", nil, Object{S3, "photos", "2024/cat.jpg", "GetObject"}},
+		{"PUT", "https://s3.us-east-1.amazonaws.com/photos/cat.jpg", nil, Object{S3, "photos", "cat.jpg", "PutObject"}},
+		{"PUT", "https://photos.s3.amazonaws.com/copy.jpg", http.Header{"X-Amz-Copy-Source": {"/photos/cat.jpg"}}, Object{S3, "photos", "copy.jpg", "CopyObject"}},
+		{"GET", "https://s3.amazonaws.com/", nil, Object{S3, "", "", "ListBuckets"}},
+		{"GET", "https://photos.s3.amazonaws.com/?list-type=2", nil, Object{S3, "photos", "", "ListObjects"}},
+		{"POST", "https://photos.s3.amazonaws.com/big.bin?uploads", nil, Object{S3, "photos", "big.bin", "CreateMultipartUpload"}},
+		{"PUT", "https://photos.s3.amazonaws.com/big.bin?partNumber=1&uploadId=x", nil, Object{S3, "photos", "big.bin", "UploadPart"}},
+		{"POST", "https://photos.s3.amazonaws.com/?delete", nil, Object{S3, "photos", "", "DeleteObjects"}},
+		{"GET", "https://storage.googleapis.com/storage/v1/b/photos/o/2024%2Fcat.jpg?alt=media", nil, Object{GCS, "photos", "2024/cat.jpg", "GetObject"}},
+		{"GET", "https://storage.googleapis.com/storage/v1/b/photos/o/cat.jpg", nil, Object{GCS, "photos", "cat.jpg", "GetObjectMetadata"}},
+		{"GET", "https://storage.googleapis.com/storage/v1/b/photos/o?prefix=2024", nil, Object{GCS, "photos", "", "ListObjects"}},
+		{"POST", "https://storage.googleapis.com/upload/storage/v1/b/photos/o?name=dog.jpg", nil, Object{GCS, "photos", "dog.jpg", "UploadObject"}},
+		{"GET", "https://storage.googleapis.com/storage/v1/b/photos", nil, Object{GCS, "photos", "", "GetBucket"}},
+		{"DELETE", "https://photos.storage.googleapis.com/cat.jpg", nil, Object{GCS, "photos", "cat.jpg", "DeleteObject"}},
+		{"GET", "https://acct.blob.core.windows.net/photos/cat.jpg", nil, Object{Azure, "photos", "cat.jpg", "GetBlob"}},
+		{"GET", "https://acct.blob.core.windows.net/?comp=list", nil, Object{Azure, "", "", "ListContainers"}},
+		{"GET", "https://acct.blob.core.windows.net/photos?restype=container&comp=list", nil, Object{Azure, "photos", "", "ListBlobs"}},
+		{"PUT", "https://acct.blob.core.windows.net/photos/big.bin?comp=block&blockid=AA", nil, Object{Azure, "photos", "big.bin", "PutBlock"}},
+		{"PUT", "https://acct.blob.core.windows.net/photos/big.bin?comp=blocklist", nil, Object{Azure, "photos", "big.bin", "PutBlockList"}},
+		{"PUT", "https://acct.blob.core.windows.net/photos/cat.jpg?comp=metadata", nil, Object{Azure, "photos", "cat.jpg", "PutBlob:metadata"}},
+		{"PUT", "https://acct.blob.core.windows.net/photos?restype=container", nil, Object{Azure, "photos", "", "PutContainer"}},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.url, nil)
+		for k, v := range tt.header {
+			r.Header[k] = v
+		}
+		got, ok := ParseRequest(r)
+		if !ok || got != tt.want {
+			t.Errorf("ParseRequest(%s %s) = %+v, %v; want %+v", tt.method, tt.url, got, ok, tt.want)
+		}
+	}
+}
+
+func TestParseRequestOtherHost(t *testing.T) {
+	if o, ok := ParseRequest(httptest.NewRequest("GET", "https://example.com/photos/cat.jpg", nil)); ok {
+		t.Errorf("ParseRequest of a non-storage host = %+v", o)
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	tracer := mocktracer.New()
+	base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: r}, nil
+	})
+	client := &http.Client{Transport: NewTransport(base, opentracing_helpers.WithTracer(tracer))}
+
+	for _, url := range []string{"https://photos.s3.amazonaws.com/cat.jpg", "https://example.com/upload"} {
+		resp, err := client.Post(url, "image/jpeg", strings.NewReader("meow"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d finished spans, want 2", len(spans))
+	}
+	s3 := spans[0]
+	if s3.OperationName != "s3 PostObject" {
+		t.Errorf("operation name = %q, want %q", s3.OperationName, "s3 PostObject")
+	}
+	for key, want := range map[string]interface{}{
+		"blob.provider":   S3,
+		"blob.operation":  "PostObject",
+		"blob.bucket":     "photos",
+		"blob.key":        "cat.jpg",
+		"blob.bytes_sent": int64(4),
+	} {
+		if got := s3.Tag(key); got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+	other := spans[1]
+	if other.OperationName != "HTTP POST" || other.Tag("blob.provider") != nil {
+		t.Errorf("span %q tagged %v, want a plain client span", other.OperationName, other.Tags())
+	}
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
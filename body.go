@@ -0,0 +1,90 @@
+package opentracing_helpers
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// WrapResponseBody ties the lifetime of span to resp. The status code is
+// tagged right away, and the span is finished once the body has been read
+// to EOF or closed, whichever comes first, with the number of body bytes
+// read tagged as http.response_size. It is meant to be used with
+// TraceRequestContext:
+//
+//	tracedReq, span := opentracing_helpers.TraceRequestContext(ctx, "GET example.com", req)
+//	resp, err := http.DefaultClient.Do(tracedReq)
+//	if err != nil {
+//	    span.SetTag("error", true)
+//	    span.Finish()
+//	    return err
+//	}
+//	opentracing_helpers.WrapResponseBody(resp, span)
+//	defer resp.Body.Close()
+func WrapResponseBody(resp *http.Response, span opentracing.Span) {
+	wrapResponseBody(resp, span, nil)
+}
+
+// wrapResponseBody is WrapResponseBody calling beforeFinish, if not nil,
+// right before the span is finished.
+func wrapResponseBody(resp *http.Response, span opentracing.Span, beforeFinish func()) {
+	ext.HTTPStatusCode.Set(span, uint16(resp.StatusCode))
+	resp.Body = newSpanBody(resp.Body, span, beforeFinish)
+}
+
+// spanBody wraps a response body and finishes the client span when the
+// body is fully read or closed.
+type spanBody struct {
+	io.ReadCloser
+	span opentracing.Span
+	size int64
+	once sync.Once
+
+	beforeFinish func()
+}
+
+// newSpanBody wraps body. Bodies of 101 Switching Protocols responses are
+// also writable, so that capability is preserved.
+func newSpanBody(body io.ReadCloser, span opentracing.Span, beforeFinish func()) io.ReadCloser {
+	if body == nil {
+		body = http.NoBody
+	}
+	sb := &spanBody{ReadCloser: body, span: span, beforeFinish: beforeFinish}
+	if w, ok := body.(io.Writer); ok {
+		return &writableSpanBody{spanBody: sb, Writer: w}
+	}
+	return sb
+}
+
+func (b *spanBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+
+func (b *spanBody) finish() {
+	b.once.Do(func() {
+		b.span.SetTag("http.response_size", b.size)
+		if b.beforeFinish != nil {
+			b.beforeFinish()
+		}
+		b.span.Finish()
+	})
+}
+
+type writableSpanBody struct {
+	*spanBody
+	io.Writer
+}
@@ -0,0 +1,81 @@
+package opentracing_helpers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// WithBodyCapture logs up to maxBytes of the request body on server and
+// client spans, which helps debugging. The body is copied as it is read by
+// the handler or the transport, so at most maxBytes are buffered and
+// nothing is logged for bytes that were never read. Bodies longer than the
+// limit are truncated and flagged with http.request.body_truncated.
+func WithBodyCapture(maxBytes int) Option {
+	return commonOption(func(c *commonConfig) {
+		c.bodyCaptureLimit = maxBytes
+	})
+}
+
+// captureRequestBody replaces r.Body with a reader that captures its
+// content for span. It returns nil when body capture is disabled or r has
+// no body. r must be a shallow copy owned by the caller.
+func (c *commonConfig) captureRequestBody(span opentracing.Span, r *http.Request) *bodyCapture {
+	if c.bodyCaptureLimit <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	bc := &bodyCapture{ReadCloser: r.Body, span: span, limit: c.bodyCaptureLimit}
+	r.Body = bc
+	return bc
+}
+
+// bodyCapture tees what is read from a body into a bounded buffer.
+type bodyCapture struct {
+	io.ReadCloser
+	span      opentracing.Span
+	limit     int
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	truncated bool
+	logOnce   sync.Once
+}
+
+func (bc *bodyCapture) Read(p []byte) (int, error) {
+	n, err := bc.ReadCloser.Read(p)
+	if n > 0 {
+		bc.mu.Lock()
+		if room := bc.limit - bc.buf.Len(); room >= n {
+			bc.buf.Write(p[:n])
+		} else {
+			bc.buf.Write(p[:room])
+			bc.truncated = true
+		}
+		bc.mu.Unlock()
+	}
+	return n, err
+}
+
+// Close closes the body and logs what was captured. Transports close the
+// request body once it has been sent.
+func (bc *bodyCapture) Close() error {
+	err := bc.ReadCloser.Close()
+	bc.log()
+	return err
+}
+
+// log logs the captured body on the span, at most once.
+func (bc *bodyCapture) log() {
+	bc.logOnce.Do(func() {
+		bc.mu.Lock()
+		defer bc.mu.Unlock()
+		bc.span.LogFields(
+			log.String("event", "request body"),
+			log.String("http.request.body", bc.buf.String()),
+			log.Bool("http.request.body_truncated", bc.truncated),
+		)
+	})
+}
@@ -0,0 +1,79 @@
+package opentracing_helpers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestWithBodyCaptureServer(t *testing.T) {
+	tests := []struct {
+		body          string
+		wantBody      string
+		wantTruncated string
+	}{
+		{"short", "short", "false"},
+		{"a body longer than the limit", "a body lon", "true"},
+	}
+	for _, tt := range tests {
+		tracer := mocktracer.New()
+		_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.ReadAll(r.Body)
+		}), WithTracer(tracer), WithBodyCapture(10))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+
+		fields := loggedFields(finishedSpan(t, tracer))
+		if got := fields["http.request.body"]; got != tt.wantBody {
+			t.Errorf("%q: http.request.body = %q, want %q", tt.body, got, tt.wantBody)
+		}
+		if got := fields["http.request.body_truncated"]; got != tt.wantTruncated {
+			t.Errorf("%q: http.request.body_truncated = %q, want %s", tt.body, got, tt.wantTruncated)
+		}
+	}
+}
+
+func TestWithBodyCaptureOnlyLogsWhatWasRead(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadFull(r.Body, make([]byte, 4))
+	}), WithTracer(tracer), WithBodyCapture(100))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("partially read")))
+
+	if got := loggedFields(finishedSpan(t, tracer))["http.request.body"]; got != "part" {
+		t.Errorf("http.request.body = %q, want %q", got, "part")
+	}
+}
+
+func TestWithBodyCaptureClient(t *testing.T) {
+	tracer := mocktracer.New()
+	transport := NewTracedTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		io.ReadAll(req.Body)
+		req.Body.Close()
+		return respond(http.StatusOK, "", nil).RoundTrip(req)
+	}), WithTracer(tracer), WithBodyCapture(100))
+	resp, err := transport.RoundTrip(httptest.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader(`{"id":1}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := loggedFields(finishedSpan(t, tracer))["http.request.body"]; got != `{"id":1}` {
+		t.Errorf("http.request.body = %q, want %q", got, `{"id":1}`)
+	}
+}
+
+func TestWithoutBodyCapture(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+	}), WithTracer(tracer))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("body")))
+
+	if _, ok := loggedFields(finishedSpan(t, tracer))["http.request.body"]; ok {
+		t.Error("the body was logged without WithBodyCapture")
+	}
+}
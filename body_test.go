@@ -0,0 +1,81 @@
+package opentracing_helpers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestWrapResponseBodyFinishesAtEOF(t *testing.T) {
+	tracer := mocktracer.New()
+	resp := &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader("hello"))}
+	WrapResponseBody(resp, tracer.StartSpan("client"))
+
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	span := finishedSpan(t, tracer)
+	if got := span.Tag("http.status_code"); got != uint16(http.StatusCreated) {
+		t.Errorf("http.status_code = %v, want 201", got)
+	}
+	if got := span.Tag("http.response_size"); got != int64(5) {
+		t.Errorf("http.response_size = %v, want 5", got)
+	}
+
+	resp.Body.Close()
+	if n := len(tracer.FinishedSpans()); n != 1 {
+		t.Errorf("closing after EOF finished the span again, %d finished spans", n)
+	}
+}
+
+func TestWrapResponseBodyFinishesOnClose(t *testing.T) {
+	tracer := mocktracer.New()
+	resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("hello"))}
+	WrapResponseBody(resp, tracer.StartSpan("client"))
+
+	buf := make([]byte, 2)
+	resp.Body.Read(buf)
+	if n := len(tracer.FinishedSpans()); n != 0 {
+		t.Fatalf("%d spans finished before EOF or Close", n)
+	}
+	resp.Body.Close()
+	if got := finishedSpan(t, tracer).Tag("http.response_size"); got != int64(2) {
+		t.Errorf("http.response_size = %v, want 2", got)
+	}
+}
+
+func TestWrapResponseBodyNilBody(t *testing.T) {
+	tracer := mocktracer.New()
+	resp := &http.Response{StatusCode: http.StatusNoContent}
+	WrapResponseBody(resp, tracer.StartSpan("client"))
+	resp.Body.Close()
+	finishedSpan(t, tracer)
+}
+
+type readWriteCloser struct {
+	io.Reader
+	io.Writer
+}
+
+func (readWriteCloser) Close() error { return nil }
+
+func TestWrapResponseBodyKeepsWriter(t *testing.T) {
+	var sent strings.Builder
+	resp := &http.Response{
+		StatusCode: http.StatusSwitchingProtocols,
+		Body:       readWriteCloser{strings.NewReader(""), &sent},
+	}
+	WrapResponseBody(resp, mocktracer.New().StartSpan("client"))
+
+	w, ok := resp.Body.(io.Writer)
+	if !ok {
+		t.Fatal("the body of a 101 response is no longer writable")
+	}
+	io.WriteString(w, "ping")
+	if sent.String() != "ping" {
+		t.Errorf("wrote %q, want %q", sent.String(), "ping")
+	}
+}
@@ -0,0 +1,163 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// maxCarrierValueLen bounds the values read from query parameters and
+// cookies, which are attacker controlled. It comfortably fits traceparent,
+// b3 and uber-trace-id values.
+const maxCarrierValueLen = 256
+
+// QueryCarrier is an opentracing.TextMapReader over the query parameters
+// of a URL, for clients such as browsers' EventSource that can't set
+// headers, e.g. "?traceparent=00-...". Only the parameters named in
+// Allowed are read, and overly long values are ignored.
+//
+// Query strings end up in access logs, proxies and Referer headers, and
+// anyone can craft them: only use this for span context keys, never for
+// secrets, and don't trust the extracted baggage.
+type QueryCarrier struct {
+	Values  url.Values
+	Allowed []string
+}
+
+// ForeachKey implements opentracing.TextMapReader.
+func (c QueryCarrier) ForeachKey(handler func(key, val string) error) error {
+	for _, key := range c.Allowed {
+		if v := c.Values.Get(key); v != "" && len(v) <= maxCarrierValueLen {
+			if err := handler(key, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CookieCarrier is an opentracing.TextMapReader over the cookies of a
+// request. Only the cookies named in Allowed are read, and overly long
+// values are ignored.
+//
+// Cookies are sent by browsers on cross-site requests, so a third party
+// can make a client join an arbitrary trace: don't trust the extracted
+// baggage.
+type CookieCarrier struct {
+	Request *http.Request
+	Allowed []string
+}
+
+// ForeachKey implements opentracing.TextMapReader.
+func (c CookieCarrier) ForeachKey(handler func(key, val string) error) error {
+	for _, name := range c.Allowed {
+		cookie, err := c.Request.Cookie(name)
+		if err != nil || cookie.Value == "" || len(cookie.Value) > maxCarrierValueLen {
+			continue
+		}
+		if err := handler(name, cookie.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithQueryParamContext makes TraceHandler fall back to the query
+// parameters named by allowed, such as "traceparent", when the request
+// headers carry no span context. The parameters are fed to the configured
+// propagators as if they were headers. See QueryCarrier for the security
+// implications.
+func WithQueryParamContext(allowed ...string) HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.queryParamContext = allowed
+	})
+}
+
+// WithCookieContext makes TraceHandler fall back to the cookies named by
+// allowed when neither the request headers nor the query parameters carry
+// a span context. See CookieCarrier for the security implications.
+func WithCookieContext(allowed ...string) HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.cookieContext = allowed
+	})
+}
+
+// extractRequest returns the span context of r, looking at the headers and
+// then at the allowed query parameters and cookies.
+func (c *handlerConfig) extractRequest(r *http.Request) (opentracing.SpanContext, error) {
+	sc, err := c.extract(r.Header)
+	if err == nil && sc != nil {
+		return sc, nil
+	}
+	if len(c.queryParamContext) > 0 {
+		if sc, qerr := c.extractCarrier(QueryCarrier{Values: r.URL.Query(), Allowed: c.queryParamContext}); qerr == nil && sc != nil {
+			return sc, nil
+		}
+	}
+	if len(c.cookieContext) > 0 {
+		if sc, cerr := c.extractCarrier(CookieCarrier{Request: r, Allowed: c.cookieContext}); cerr == nil && sc != nil {
+			return sc, nil
+		}
+	}
+	return nil, err
+}
+
+// extractCarrier copies the keys of carrier into a header to run the
+// propagators on them.
+func (c *commonConfig) extractCarrier(carrier opentracing.TextMapReader) (opentracing.SpanContext, error) {
+	h := http.Header{}
+	carrier.ForeachKey(func(key, val string) error {
+		h.Set(key, val)
+		return nil
+	})
+	if len(h) == 0 {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	return c.extract(h)
+}
+
+// injectCarrier runs the propagators on a header and copies it into
+// carrier.
+func (c *commonConfig) injectCarrier(sc opentracing.SpanContext, carrier opentracing.TextMapWriter) error {
+	h := http.Header{}
+	if err := c.inject(sc, h); err != nil {
+		return err
+	}
+	for key, vals := range h {
+		for _, val := range vals {
+			carrier.Set(key, val)
+		}
+	}
+	return nil
+}
+
+// ProtoCarrier satisfies both opentracing.TextMapWriter and
+// opentracing.TextMapReader for a map<string, string> field of a protobuf
+// message, for services sending protobuf messages over custom framing
+// rather than HTTP or gRPC. The map is allocated on the first Set, so
+// Metadata can point to the field of a new message:
+//
+//	tracer.Inject(span.Context(), opentracing.TextMap, opentracing_helpers.ProtoCarrier{&msg.Metadata})
+//	sc, err := tracer.Extract(opentracing.TextMap, opentracing_helpers.ProtoCarrier{&msg.Metadata})
+type ProtoCarrier struct {
+	Metadata *map[string]string
+}
+
+// Set implements opentracing.TextMapWriter.
+func (c ProtoCarrier) Set(key, val string) {
+	if *c.Metadata == nil {
+		*c.Metadata = make(map[string]string)
+	}
+	(*c.Metadata)[key] = val
+}
+
+// ForeachKey implements opentracing.TextMapReader.
+func (c ProtoCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range *c.Metadata {
+		if err := handler(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
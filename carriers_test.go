@@ -0,0 +1,103 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// carrierKeys returns the keys and values read from carrier.
+func carrierKeys(carrier opentracing.TextMapReader) []string {
+	var got []string
+	carrier.ForeachKey(func(key, val string) error {
+		got = append(got, key+"="+val)
+		return nil
+	})
+	return got
+}
+
+func TestQueryCarrier(t *testing.T) {
+	values := url.Values{
+		"traceparent": {"00-abc"},
+		"secret":      {"hunter2"},
+		"b3":          {strings.Repeat("x", maxCarrierValueLen+1)},
+	}
+	got := carrierKeys(QueryCarrier{Values: values, Allowed: []string{"traceparent", "b3", "missing"}})
+	if want := []string{"traceparent=00-abc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("read %q, want %q", got, want)
+	}
+}
+
+func TestCookieCarrier(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "traceparent", Value: "00-abc"})
+	r.AddCookie(&http.Cookie{Name: "session", Value: "hunter2"})
+	got := carrierKeys(CookieCarrier{Request: r, Allowed: []string{"traceparent", "missing"}})
+	if want := []string{"traceparent=00-abc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("read %q, want %q", got, want)
+	}
+}
+
+func TestWithQueryParamAndCookieContext(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("browser").Context().(mocktracer.MockSpanContext)
+	h := http.Header{}
+	tracer.Inject(parent, opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h))
+	traceID, spanID := h.Get("mockpfx-Ids-Traceid"), h.Get("mockpfx-Ids-Spanid")
+
+	query := httptest.NewRequest(http.MethodGet, "/events?mockpfx-ids-traceid="+traceID+"&mockpfx-ids-spanid="+spanID, nil)
+	cookie := httptest.NewRequest(http.MethodGet, "/events", nil)
+	cookie.AddCookie(&http.Cookie{Name: "mockpfx-ids-traceid", Value: traceID})
+	cookie.AddCookie(&http.Cookie{Name: "mockpfx-ids-spanid", Value: spanID})
+
+	for name, r := range map[string]*http.Request{"query": query, "cookie": cookie} {
+		tracer.Reset()
+		_, handler := TraceHandler("/events", okHandler, WithTracer(tracer),
+			WithQueryParamContext("mockpfx-ids-traceid", "mockpfx-ids-spanid"),
+			WithCookieContext("mockpfx-ids-traceid", "mockpfx-ids-spanid"))
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		span := finishedSpan(t, tracer)
+		if span.SpanContext.TraceID != parent.TraceID || span.ParentID != parent.SpanID {
+			t.Errorf("%s: span has parent %d in trace %d, want %d in trace %d",
+				name, span.ParentID, span.SpanContext.TraceID, parent.SpanID, parent.TraceID)
+		}
+	}
+}
+
+func TestQueryParamContextNotAllowed(t *testing.T) {
+	tracer := mocktracer.New()
+	r := httptest.NewRequest(http.MethodGet, "/events?mockpfx-ids-traceid=1&mockpfx-ids-spanid=2", nil)
+	_, handler := TraceHandler("/events", okHandler, WithTracer(tracer))
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if span := finishedSpan(t, tracer); span.ParentID != 0 {
+		t.Errorf("span has parent %d, want the query ignored without WithQueryParamContext", span.ParentID)
+	}
+}
+
+func TestProtoCarrier(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("send")
+	var metadata map[string]string
+	if err := tracer.Inject(span.Context(), opentracing.TextMap, ProtoCarrier{&metadata}); err != nil {
+		t.Fatal(err)
+	}
+	if len(metadata) == 0 {
+		t.Fatal("nothing injected into the nil map")
+	}
+
+	sc, err := tracer.Extract(opentracing.TextMap, ProtoCarrier{&metadata})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.(mocktracer.MockSpanContext).SpanID != span.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("extracted a different span context")
+	}
+}
@@ -0,0 +1,42 @@
+// Package chi traces requests routed by go-chi/chi. Spans are named after
+// the matched route pattern, for example "GET /users/{id}", including the
+// patterns of nested and mounted routers:
+//
+//	r := chi.NewRouter()
+//	r.Use(otchi.Middleware())
+//	r.Mount("/admin", adminRouter)
+package chi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jfernandez/opentracing-helpers"
+)
+
+// Middleware returns chi middleware that traces requests. opts are passed
+// to the parent package.
+//
+// chi only knows the full route pattern once routing has completed, which
+// for mounted routers happens below this middleware. The route is
+// therefore recorded with opentracing_helpers.SetRoute after the wrapped
+// handler returns, which renames the span.
+func Middleware(opts ...opentracing_helpers.HandlerOption) func(http.Handler) http.Handler {
+	traced := opentracing_helpers.NewMiddleware(opts...)
+	return func(next http.Handler) http.Handler {
+		return traced(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			opentracing_helpers.SetRoute(r, RoutePattern(r))
+		}))
+	}
+}
+
+// RoutePattern returns the route pattern matched for r, or an empty string
+// if r wasn't routed by chi.
+func RoutePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+	return rctx.RoutePattern()
+}
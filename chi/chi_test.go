@@ -0,0 +1,82 @@
+package chi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestMiddleware(t *testing.T) {
+	tracer := mocktracer.New()
+	admin := chi.NewRouter()
+	admin.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r := chi.NewRouter()
+	r.Use(Middleware(opentracing_helpers.WithTracer(tracer)))
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Mount("/admin", admin)
+
+	for path, want := range map[string]string{
+		"/items/42":       "/items/{id}",
+		"/admin/users/42": "/admin/users/{id}",
+	} {
+		tracer.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+
+		spans := tracer.FinishedSpans()
+		if len(spans) != 1 {
+			t.Fatalf("%s: got %d spans, want 1", path, len(spans))
+		}
+		if got := spans[0].OperationName; got != "GET "+want {
+			t.Errorf("%s: operation name = %q, want %q", path, got, "GET "+want)
+		}
+		if got := spans[0].Tag("http.route"); got != want {
+			t.Errorf("%s: http.route = %v, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMiddlewareUnmatched(t *testing.T) {
+	tracer := mocktracer.New()
+	r := chi.NewRouter()
+	r.Use(Middleware(opentracing_helpers.WithTracer(tracer)))
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	span := tracer.FinishedSpans()[0]
+	if span.OperationName != "GET /missing" {
+		t.Errorf("operation name = %q, want the request path", span.OperationName)
+	}
+	if got := span.Tag("http.route"); got != nil {
+		t.Errorf("http.route = %v, want no tag", got)
+	}
+}
+
+type metricsRecorder []opentracing_helpers.RequestMetrics
+
+func (m *metricsRecorder) ObserveRequest(rm opentracing_helpers.RequestMetrics) {
+	*m = append(*m, rm)
+}
+
+func TestMiddlewareMetricsOperation(t *testing.T) {
+	var metrics metricsRecorder
+	r := chi.NewRouter()
+	r.Use(Middleware(opentracing_helpers.WithTracer(mocktracer.New()), opentracing_helpers.WithMetricsObserver(&metrics)))
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if len(metrics) != 2 || metrics[0].Operation != "GET /items/{id}" || metrics[1].Operation != http.MethodGet {
+		t.Errorf("observed %+v, want the route, then the method of the unmatched request", metrics)
+	}
+}
+
+func TestRoutePatternWithoutChi(t *testing.T) {
+	if got := RoutePattern(httptest.NewRequest(http.MethodGet, "/", nil)); got != "" {
+		t.Errorf("RoutePattern = %q, want empty", got)
+	}
+}
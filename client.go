@@ -0,0 +1,69 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client mirrors the convenience methods of http.Client, taking a context
+// so that requests are traced as children of the span it carries. Call
+// sites can adopt tracing without building requests by hand:
+//
+//	client := opentracing_helpers.NewClient(nil)
+//	resp, err := client.Get(ctx, "http://example.com/")
+type Client struct {
+	client *http.Client
+}
+
+// NewClient returns a Client sending requests with a copy of client,
+// wrapped by TracedClient. A nil client is treated as http.DefaultClient.
+func NewClient(client *http.Client, opts ...TransportOption) *Client {
+	return &Client{client: TracedClient(client, opts...)}
+}
+
+// HTTPClient returns the traced http.Client used by c.
+func (c *Client) HTTPClient() *http.Client {
+	return c.client
+}
+
+// Do sends req like http.Client.Do. The span is a child of the span in the
+// request's context.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.client.Do(req)
+}
+
+// Get issues a GET to url.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Head issues a HEAD to url.
+func (c *Client) Head(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Post issues a POST to url with the given content type and body.
+func (c *Client) Post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// PostForm issues a POST to url with data URL-encoded as the body.
+func (c *Client) PostForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.Post(ctx, url, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+}
@@ -0,0 +1,153 @@
+package opentracing_helpers
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http/httptrace"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// WithConnectionPhaseSpans models the DNS lookup, TCP connect, TLS
+// handshake and the wait for the first response byte as child spans of the
+// client span, instead of logging httptrace events. The spans have real
+// durations, so the latency breakdown shows up in trace timelines.
+func WithConnectionPhaseSpans() TransportOption {
+	return transportOption(func(c *transportConfig) {
+		c.phaseSpans = true
+	})
+}
+
+// phaseTracer starts and finishes the connection phase spans. httptrace
+// hooks may be called from different goroutines, hence the mutex.
+type phaseTracer struct {
+	parent opentracing.Span
+
+	mu sync.Mutex
+	// closed is set once the parent span is finishing, after which no
+	// phase span is started.
+	closed  bool
+	dns     opentracing.Span
+	connect map[string]opentracing.Span
+	tls     opentracing.Span
+	wait    opentracing.Span
+}
+
+func newPhaseClientTrace(parent opentracing.Span) *httptrace.ClientTrace {
+	pt := &phaseTracer{parent: parent, connect: make(map[string]opentracing.Span)}
+	// The hook ending a phase may never be called, for example when the
+	// request fails or is canceled while waiting for the response.
+	if s, ok := parent.(*SafeSpan); ok {
+		s.whenFinishing(pt.close)
+	}
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			pt.mu.Lock()
+			defer pt.mu.Unlock()
+			tagConn(pt.parent, info)
+		},
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			pt.mu.Lock()
+			defer pt.mu.Unlock()
+			pt.dns = pt.start("DNS lookup", opentracing.Tag{Key: "dns.host", Value: info.Host})
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			pt.mu.Lock()
+			defer pt.mu.Unlock()
+			pt.finish(pt.dns, info.Err)
+			pt.dns = nil
+		},
+		// Dialers may race several addresses, each gets its own span.
+		ConnectStart: func(network, addr string) {
+			pt.mu.Lock()
+			defer pt.mu.Unlock()
+			pt.connect[network+" "+addr] = pt.start("TCP connect",
+				opentracing.Tag{Key: string(ext.PeerAddress), Value: addr})
+		},
+		ConnectDone: func(network, addr string, err error) {
+			pt.mu.Lock()
+			defer pt.mu.Unlock()
+			key := network + " " + addr
+			pt.finish(pt.connect[key], err)
+			delete(pt.connect, key)
+		},
+		TLSHandshakeStart: func() {
+			pt.mu.Lock()
+			defer pt.mu.Unlock()
+			pt.tls = pt.start("TLS handshake")
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			pt.mu.Lock()
+			defer pt.mu.Unlock()
+			if pt.tls != nil {
+				if fields := tlsFields(state); fields != nil {
+					pt.tls.LogFields(fields...)
+				}
+			}
+			pt.finish(pt.tls, err)
+			pt.tls = nil
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			pt.mu.Lock()
+			defer pt.mu.Unlock()
+			if info.Err != nil {
+				pt.parent.LogFields(log.String("event", "write request failed"), log.Error(info.Err))
+				return
+			}
+			pt.wait = pt.start("time to first byte")
+		},
+		GotFirstResponseByte: func() {
+			pt.mu.Lock()
+			defer pt.mu.Unlock()
+			pt.finish(pt.wait, nil)
+			pt.wait = nil
+		},
+	}
+}
+
+// close finishes the phase spans still open when the parent span is
+// finished, tagging them as failed.
+func (pt *phaseTracer) close() {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.closed = true
+	err := errPhaseUnfinished
+	pt.finish(pt.dns, err)
+	for key, span := range pt.connect {
+		pt.finish(span, err)
+		delete(pt.connect, key)
+	}
+	pt.finish(pt.tls, err)
+	pt.finish(pt.wait, err)
+	pt.dns, pt.tls, pt.wait = nil, nil, nil
+}
+
+// errPhaseUnfinished is logged on the phase spans finished by close.
+var errPhaseUnfinished = errors.New("client span finished before the phase completed")
+
+// start starts a phase span, or returns nil once pt is closed.
+func (pt *phaseTracer) start(operationName string, tags ...opentracing.Tag) opentracing.Span {
+	if pt.closed {
+		return nil
+	}
+	opts := []opentracing.StartSpanOption{opentracing.ChildOf(pt.parent.Context())}
+	for _, tag := range tags {
+		opts = append(opts, tag)
+	}
+	return pt.parent.Tracer().StartSpan(operationName, opts...)
+}
+
+// finish finishes span, which may be nil if its start hook didn't fire.
+func (pt *phaseTracer) finish(span opentracing.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+	span.Finish()
+}
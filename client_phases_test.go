@@ -0,0 +1,85 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestWithConnectionPhaseSpans(t *testing.T) {
+	server := httptest.NewTLSServer(okHandler)
+	defer server.Close()
+
+	tracer := mocktracer.New()
+	base := server.Client().Transport
+	client := &http.Client{Transport: NewTracedTransport(base, WithTracer(tracer), WithConnectionPhaseSpans())}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	spans := map[string]*mocktracer.MockSpan{}
+	var parent *mocktracer.MockSpan
+	for _, span := range tracer.FinishedSpans() {
+		spans[span.OperationName] = span
+		if span.ParentID == 0 {
+			parent = span
+		}
+	}
+	if parent == nil {
+		t.Fatalf("no client span in %v", tracer.FinishedSpans())
+	}
+	for _, name := range []string{"TCP connect", "TLS handshake", "time to first byte"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("no %q span in %v", name, tracer.FinishedSpans())
+			continue
+		}
+		if span.ParentID != parent.SpanContext.SpanID {
+			t.Errorf("%q is not a child of the client span", name)
+		}
+	}
+	if got := spans["TCP connect"].Tag("peer.address"); got != server.Listener.Addr().String() {
+		t.Errorf("TCP connect: peer.address = %v, want %s", got, server.Listener.Addr())
+	}
+	if got := loggedFields(spans["TLS handshake"])["tls.version"]; got != "TLS 1.3" {
+		t.Errorf("TLS handshake: tls.version = %q, want TLS 1.3", got)
+	}
+	if got := parent.Tag("http.protocol"); got != "http/1.1" {
+		t.Errorf("client span: http.protocol = %v, want http/1.1", got)
+	}
+	if n := len(parent.Logs()); n != 0 {
+		t.Errorf("the client span has %d logs, want the events replaced by spans", n)
+	}
+}
+
+func TestConnectionPhaseSpansFinishedWithParent(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := NewSafeSpan(tracer.StartSpan("HTTP GET"))
+	trace := newPhaseClientTrace(parent)
+	trace.DNSStart(httptrace.DNSStartInfo{Host: "example.com"})
+	trace.ConnectStart("tcp", "192.0.2.1:443")
+	parent.Finish()
+	// Hooks called after the client span finished start no phase span.
+	trace.TLSHandshakeStart()
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d finished spans, want the DNS, connect and client spans", len(spans))
+	}
+	for _, span := range spans[:2] {
+		if span.Tag("error") != true || loggedFields(span)["error.object"] != errPhaseUnfinished.Error() {
+			t.Errorf("%q span not failed with errPhaseUnfinished", span.OperationName)
+		}
+		if span.ParentID != spans[2].SpanContext.SpanID {
+			t.Errorf("%q span isn't a child of the client span", span.OperationName)
+		}
+	}
+	if spans[2].OperationName != "HTTP GET" {
+		t.Errorf("the client span %q finished before its phases", spans[2].OperationName)
+	}
+}
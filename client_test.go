@@ -0,0 +1,64 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestClient(t *testing.T) {
+	tracer := mocktracer.New()
+	var sent *http.Request
+	client := NewClient(&http.Client{Transport: respond(http.StatusOK, "", &sent)}, WithTracer(tracer))
+	parent := tracer.StartSpan("request")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	calls := []struct {
+		method, contentType string
+		do                  func() (*http.Response, error)
+	}{
+		{http.MethodGet, "", func() (*http.Response, error) { return client.Get(ctx, "http://example.com/a") }},
+		{http.MethodHead, "", func() (*http.Response, error) { return client.Head(ctx, "http://example.com/a") }},
+		{http.MethodPost, "text/plain", func() (*http.Response, error) {
+			return client.Post(ctx, "http://example.com/a", "text/plain", strings.NewReader("hi"))
+		}},
+		{http.MethodPost, "application/x-www-form-urlencoded", func() (*http.Response, error) {
+			return client.PostForm(ctx, "http://example.com/a", url.Values{"q": {"1"}})
+		}},
+	}
+	for _, call := range calls {
+		tracer.Reset()
+		resp, err := call.do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if sent.Method != call.method || sent.Header.Get("Content-Type") != call.contentType {
+			t.Errorf("sent %s with Content-Type %q, want %s with %q", sent.Method, sent.Header.Get("Content-Type"), call.method, call.contentType)
+		}
+		span := finishedSpan(t, tracer)
+		if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+			t.Errorf("%s: the client span is not a child of the context span", call.method)
+		}
+	}
+}
+
+func TestClientInvalidURL(t *testing.T) {
+	tracer := mocktracer.New()
+	client := NewClient(nil, WithTracer(tracer))
+	if _, err := client.Get(context.Background(), "http://[::1"); err == nil {
+		t.Error("Get succeeded with an invalid URL")
+	}
+	if n := len(tracer.FinishedSpans()); n != 0 {
+		t.Errorf("%d spans for a request that couldn't be built", n)
+	}
+	if client.HTTPClient() == http.DefaultClient {
+		t.Error("HTTPClient() is http.DefaultClient, want a traced copy")
+	}
+}
@@ -0,0 +1,192 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// ClientTraceEvents selects the httptrace events logged on client spans.
+// Values can be combined with |.
+type ClientTraceEvents uint
+
+const (
+	// ClientTraceConnection logs when a connection is requested and
+	// obtained from the pool or dialed, and tags the span with
+	// http.connection_reused, http.was_idle, http.idle_time, http.protocol
+	// and peer.address.
+	ClientTraceConnection ClientTraceEvents = 1 << iota
+	// ClientTraceDNS logs the start and result of DNS lookups.
+	ClientTraceDNS
+	// ClientTraceConnect logs the result of dialing new connections.
+	ClientTraceConnect
+	// ClientTraceWroteRequest logs when the request has been written.
+	ClientTraceWroteRequest
+	// ClientTraceFirstByte logs when the first response byte is read.
+	ClientTraceFirstByte
+	// ClientTraceTLS logs the TLS handshake along with the negotiated
+	// version, cipher suite and application protocol.
+	ClientTraceTLS
+
+	// ClientTraceAllEvents logs every supported event. It is the default.
+	ClientTraceAllEvents = ClientTraceConnection | ClientTraceDNS | ClientTraceConnect |
+		ClientTraceWroteRequest | ClientTraceFirstByte | ClientTraceTLS
+)
+
+// WithClientTraceEvents limits the httptrace events logged on client spans,
+// which keeps spans small:
+//
+//	opentracing_helpers.WithClientTraceEvents(
+//	    opentracing_helpers.ClientTraceDNS | opentracing_helpers.ClientTraceFirstByte)
+func WithClientTraceEvents(events ClientTraceEvents) TransportOption {
+	return transportOption(func(c *transportConfig) {
+		c.clientTraceEvents = events
+	})
+}
+
+// WithoutClientTrace disables httptrace event logging on client spans.
+func WithoutClientTrace() TransportOption {
+	return WithClientTraceEvents(0)
+}
+
+// WithObjectLogFields logs the details of httptrace events, such as the
+// connection and DNS info structs, as log.Object payloads like earlier
+// versions did, instead of typed fields.
+func WithObjectLogFields() TransportOption {
+	return transportOption(func(c *transportConfig) {
+		c.objectLogFields = true
+	})
+}
+
+// WithLazyLogFields implies WithObjectLogFields, deferring formatting of
+// the payloads to the moment the tracer encodes the span, using log.Lazy.
+// Tracers that drop unsampled spans then never pay for it. The payloads are
+// reported as strings.
+func WithLazyLogFields() TransportOption {
+	return transportOption(func(c *transportConfig) {
+		c.objectLogFields = true
+		c.lazyLogFields = true
+	})
+}
+
+// WithLogBudget limits client spans to max log records, so that chatty
+// connections can't blow up span sizes. Records beyond the budget are
+// dropped and counted in the log.dropped_records tag. A max of 0, the
+// default, means no limit.
+func WithLogBudget(max int) TransportOption {
+	return transportOption(func(c *transportConfig) {
+		c.logBudget = max
+	})
+}
+
+// lazyObject is like log.Object but formats value only when the field is
+// encoded.
+func lazyObject(key string, value interface{}) log.Field {
+	return log.Lazy(func(enc log.Encoder) {
+		enc.EmitString(key, fmt.Sprintf("%+v", value))
+	})
+}
+
+// withClientTrace returns ctx with a ClientTrace logging the configured
+// events on span, or starting connection phase spans.
+func (c *transportConfig) withClientTrace(ctx context.Context, span opentracing.Span) context.Context {
+	if c.phaseSpans {
+		return httptrace.WithClientTrace(ctx, newPhaseClientTrace(span))
+	}
+	if c.clientTraceEvents == 0 {
+		return ctx
+	}
+	trace := newClientTrace(span, c)
+	if c.clientTraceEvents&ClientTraceConnection == 0 {
+		trace.GetConn, trace.GotConn = nil, nil
+	}
+	if c.clientTraceEvents&ClientTraceDNS == 0 {
+		trace.DNSStart, trace.DNSDone = nil, nil
+	}
+	if c.clientTraceEvents&ClientTraceConnect == 0 {
+		trace.ConnectDone = nil
+	}
+	if c.clientTraceEvents&ClientTraceWroteRequest == 0 {
+		trace.WroteRequest = nil
+	}
+	if c.clientTraceEvents&ClientTraceFirstByte == 0 {
+		trace.GotFirstResponseByte = nil
+	}
+	if c.clientTraceEvents&ClientTraceTLS == 0 {
+		trace.TLSHandshakeStart, trace.TLSHandshakeDone = nil, nil
+	}
+	return httptrace.WithClientTrace(ctx, trace)
+}
+
+// tagConn tags span with how the connection of the request was obtained
+// and the protocol spoken on it.
+func tagConn(span opentracing.Span, info httptrace.GotConnInfo) {
+	span.SetTag("http.connection_reused", info.Reused)
+	span.SetTag("http.was_idle", info.WasIdle)
+	if info.WasIdle {
+		span.SetTag("http.idle_time", info.IdleTime.String())
+	}
+	if info.Conn == nil {
+		return
+	}
+	protocol := "http/1.1"
+	if tc, ok := info.Conn.(*tls.Conn); ok {
+		if p := tc.ConnectionState().NegotiatedProtocol; p != "" {
+			protocol = p
+		}
+	}
+	span.SetTag("http.protocol", protocol)
+	ext.PeerAddress.Set(span, info.Conn.RemoteAddr().String())
+}
+
+// gotConnFields describes how the connection of a request was obtained.
+func gotConnFields(info httptrace.GotConnInfo) []log.Field {
+	fields := []log.Field{
+		log.Bool("reused", info.Reused),
+		log.Bool("was_idle", info.WasIdle),
+	}
+	if info.WasIdle {
+		fields = append(fields, log.String("idle_time", info.IdleTime.String()))
+	}
+	if info.Conn != nil {
+		fields = append(fields,
+			log.String("local_address", info.Conn.LocalAddr().String()),
+			log.String("remote_address", info.Conn.RemoteAddr().String()))
+	}
+	return fields
+}
+
+// dnsDoneFields describes the result of a DNS lookup.
+func dnsDoneFields(info httptrace.DNSDoneInfo) []log.Field {
+	addrs := make([]string, len(info.Addrs))
+	for i, addr := range info.Addrs {
+		addrs[i] = addr.String()
+	}
+	fields := []log.Field{
+		log.String("dns.addresses", strings.Join(addrs, ",")),
+		log.Bool("dns.coalesced", info.Coalesced),
+	}
+	if info.Err != nil {
+		fields = append(fields, log.Error(info.Err))
+	}
+	return fields
+}
+
+// tlsFields describes the outcome of a TLS handshake.
+func tlsFields(state tls.ConnectionState) []log.Field {
+	if !state.HandshakeComplete {
+		return nil
+	}
+	return []log.Field{
+		log.String("tls.version", tls.VersionName(state.Version)),
+		log.String("tls.cipher_suite", tls.CipherSuiteName(state.CipherSuite)),
+		log.String("tls.negotiated_protocol", state.NegotiatedProtocol),
+		log.Bool("tls.resumed", state.DidResume),
+	}
+}
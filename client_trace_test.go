@@ -0,0 +1,222 @@
+package opentracing_helpers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestWithClientTraceEvents(t *testing.T) {
+	server := httptest.NewServer(okHandler)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		opts []TransportOption
+		want []string
+	}{
+		{"default", nil, []string{"Get Connection ", "Connect Done", "Got Connection", "Wrote Request", "Got First Response Byte"}},
+		{"selected", []TransportOption{WithClientTraceEvents(ClientTraceConnect | ClientTraceFirstByte)}, []string{"Connect Done", "Got First Response Byte"}},
+		{"disabled", []TransportOption{WithoutClientTrace()}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			// A new transport per case so that every request dials.
+			base := &http.Transport{}
+			defer base.CloseIdleConnections()
+			client := &http.Client{Transport: NewTracedTransport(base, append(tt.opts, WithTracer(tracer))...)}
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if got := loggedEvents(finishedSpan(t, tracer)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logged events %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientTraceTLS(t *testing.T) {
+	server := httptest.NewTLSServer(okHandler)
+	defer server.Close()
+
+	tracer := mocktracer.New()
+	client := &http.Client{Transport: NewTracedTransport(server.Client().Transport,
+		WithTracer(tracer), WithClientTraceEvents(ClientTraceTLS))}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	span := finishedSpan(t, tracer)
+	if got, want := loggedEvents(span), []string{"TLS Handshake Start", "TLS Handshake Done"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logged events %q, want %q", got, want)
+	}
+	fields := loggedFields(span)
+	if fields["tls.version"] != "TLS 1.3" || fields["tls.cipher_suite"] == "" || fields["tls.resumed"] != "false" {
+		t.Errorf("logged fields %v, want the negotiated parameters", fields)
+	}
+}
+
+func TestWithLazyLogFields(t *testing.T) {
+	server := httptest.NewServer(okHandler)
+	defer server.Close()
+
+	tracer := mocktracer.New()
+	base := &http.Transport{}
+	defer base.CloseIdleConnections()
+	client := &http.Client{Transport: NewTracedTransport(base,
+		WithTracer(tracer), WithClientTraceEvents(ClientTraceConnection), WithLazyLogFields())}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	info := loggedFields(finishedSpan(t, tracer))["connection info"]
+	if !strings.HasPrefix(info, "{Conn:") {
+		t.Errorf("connection info = %q, want the formatted GotConnInfo", info)
+	}
+}
+
+type countingStringer struct{ calls *int }
+
+func (s countingStringer) String() string {
+	*s.calls++
+	return "formatted"
+}
+
+func TestLazyObject(t *testing.T) {
+	var calls int
+	field := lazyObject("key", countingStringer{&calls})
+	if calls != 0 {
+		t.Fatalf("value formatted %d times before encoding", calls)
+	}
+
+	var kv mocktracer.MockKeyValue
+	field.Marshal(&kv)
+	if kv.Key != "key" || kv.ValueString != "formatted" || calls != 1 {
+		t.Errorf("encoded %s=%q after %d calls, want key=%q after 1", kv.Key, kv.ValueString, calls, "formatted")
+	}
+}
+
+func TestWithLogBudget(t *testing.T) {
+	server := httptest.NewServer(okHandler)
+	defer server.Close()
+
+	tracer := mocktracer.New()
+	base := &http.Transport{}
+	defer base.CloseIdleConnections()
+	client := &http.Client{Transport: NewTracedTransport(base, WithTracer(tracer), WithLogBudget(2))}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	span := finishedSpan(t, tracer)
+	if got, want := loggedEvents(span), []string{"Get Connection ", "Connect Done"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logged events %q, want %q", got, want)
+	}
+	if got := span.Tag("log.dropped_records"); got != 3 {
+		t.Errorf("log.dropped_records = %v, want 3", got)
+	}
+}
+
+func TestClientTraceTagsConnection(t *testing.T) {
+	server := httptest.NewServer(okHandler)
+	defer server.Close()
+
+	tracer := mocktracer.New()
+	base := &http.Transport{}
+	defer base.CloseIdleConnections()
+	client := &http.Client{Transport: NewTracedTransport(base, WithTracer(tracer))}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d finished spans, want 2", len(spans))
+	}
+	for i, span := range spans {
+		reused := i == 1
+		if span.Tag("http.connection_reused") != reused || span.Tag("http.was_idle") != reused {
+			t.Errorf("request %d: connection_reused = %v, was_idle = %v; want %v",
+				i, span.Tag("http.connection_reused"), span.Tag("http.was_idle"), reused)
+		}
+		if span.Tag("http.protocol") != "http/1.1" || span.Tag(string(ext.PeerAddress)) != server.Listener.Addr().String() {
+			t.Errorf("request %d: protocol = %v, peer.address = %v", i, span.Tag("http.protocol"), span.Tag(string(ext.PeerAddress)))
+		}
+	}
+	if _, ok := spans[1].Tag("http.idle_time").(string); !ok {
+		t.Errorf("http.idle_time = %v, want the idle duration", spans[1].Tag("http.idle_time"))
+	}
+}
+
+func TestClientTraceTypedFields(t *testing.T) {
+	server := httptest.NewServer(okHandler)
+	defer server.Close()
+
+	for _, object := range []bool{false, true} {
+		tracer := mocktracer.New()
+		base := &http.Transport{}
+		opts := []TransportOption{WithTracer(tracer), WithClientTraceEvents(ClientTraceConnection)}
+		if object {
+			opts = append(opts, WithObjectLogFields())
+		}
+		client := &http.Client{Transport: NewTracedTransport(base, opts...)}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		base.CloseIdleConnections()
+
+		fields := loggedFields(finishedSpan(t, tracer))
+		if object {
+			if _, ok := fields["connection info"]; !ok || fields["remote_address"] != "" {
+				t.Errorf("WithObjectLogFields: logged %v, want the connection info payload", fields)
+			}
+			continue
+		}
+		if fields["reused"] != "false" || fields["remote_address"] != server.Listener.Addr().String() {
+			t.Errorf("logged %v, want typed connection fields", fields)
+		}
+		if _, ok := fields["connection info"]; ok {
+			t.Error("the connection info payload is logged without WithObjectLogFields")
+		}
+	}
+}
+
+func TestDNSDoneFields(t *testing.T) {
+	info := httptrace.DNSDoneInfo{
+		Addrs: []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}, {IP: net.ParseIP("2001:db8::1")}},
+		Err:   errors.New("partial failure"),
+	}
+	var got []string
+	for _, f := range dnsDoneFields(info) {
+		got = append(got, fmt.Sprintf("%s=%v", f.Key(), f.Value()))
+	}
+	want := []string{"dns.addresses=192.0.2.1,2001:db8::1", "dns.coalesced=false", "error.object=partial failure"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %q, want %q", got, want)
+	}
+}
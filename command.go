@@ -0,0 +1,101 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// stderrTailSize is the number of trailing stderr bytes logged for failed
+// commands.
+const stderrTailSize = 1024
+
+// TraceCommand runs cmd within a child of the span in ctx, for services
+// shelling out to tools like ffmpeg or git. The span is tagged with the
+// binary name and the exit code, and the tail of stderr is logged if the
+// command fails. Arguments aren't recorded since they may carry secrets.
+// cmd.Stderr, if set, still receives the whole output. If cmd.Stdout is
+// the same writer, both streams keep sharing it and the logged tail
+// includes stdout:
+//
+//	err := opentracing_helpers.TraceCommand(ctx, exec.CommandContext(ctx, "git", "fetch"))
+func TraceCommand(ctx context.Context, cmd *exec.Cmd) error {
+	binary := filepath.Base(cmd.Path)
+	span, _ := opentracing.StartSpanFromContext(ctx, "exec "+binary)
+	defer span.Finish()
+	span.SetTag("process.executable.name", binary)
+	span.SetTag("process.executable.path", cmd.Path)
+
+	tail := &tailWriter{w: cmd.Stderr}
+	if cmd.Stderr != nil && interfaceEqual(cmd.Stdout, cmd.Stderr) {
+		// os/exec only lets both streams share a single pipe if they are
+		// the same writer, otherwise it writes to it from two goroutines.
+		cmd.Stdout = tail
+	}
+	cmd.Stderr = tail
+
+	err := cmd.Run()
+	if cmd.ProcessState != nil {
+		span.SetTag("process.exit_code", cmd.ProcessState.ExitCode())
+	}
+	if err != nil {
+		ext.Error.Set(span, true)
+		fields := []log.Field{log.String("event", "error"), log.Error(err)}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if stderr := tail.String(); stderr != "" {
+				fields = append(fields, log.String("stderr", stderr))
+			}
+		}
+		span.LogFields(fields...)
+	}
+	return err
+}
+
+// tailWriter writes to w, unless it is nil, and keeps the last
+// stderrTailSize bytes written. Writes are serialized so that it can be
+// shared by stdout and stderr.
+type tailWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf []byte
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := len(p)
+	var err error
+	if t.w != nil {
+		n, err = t.w.Write(p)
+	}
+	t.buf = append(t.buf, p[:n]...)
+	if len(t.buf) > stderrTailSize {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-stderrTailSize:]...)
+	}
+	return n, err
+}
+
+// String returns the bytes kept.
+func (t *tailWriter) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+// interfaceEqual reports whether a and b are equal, like os/exec does to
+// detect a shared stdout and stderr, without panicking on writers of
+// incomparable types.
+func interfaceEqual(a, b interface{}) (equal bool) {
+	defer func() {
+		recover()
+	}()
+	return a == b
+}
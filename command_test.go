@@ -0,0 +1,123 @@
+package opentracing_helpers
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestTraceCommand(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	if err := TraceCommand(context.Background(), exec.Command("sh", "-c", "echo secret-arg >/dev/null")); err != nil {
+		t.Fatal(err)
+	}
+
+	span := finishedSpan(t, tracer)
+	if span.OperationName != "exec sh" || span.Tag("process.executable.name") != "sh" {
+		t.Errorf("span %q tagged %v, want exec sh", span.OperationName, span.Tags())
+	}
+	if span.Tag("process.exit_code") != 0 || span.Tag("error") != nil {
+		t.Errorf("exit code = %v, error = %v; want a successful run", span.Tag("process.exit_code"), span.Tag("error"))
+	}
+	for _, v := range span.Tags() {
+		if s, ok := v.(string); ok && strings.Contains(s, "secret-arg") {
+			t.Errorf("the arguments are recorded: %v", span.Tags())
+		}
+	}
+}
+
+func TestTraceCommandFailure(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", "echo oops >&2; exit 3")
+	cmd.Stderr = &stderr
+	if err := TraceCommand(context.Background(), cmd); err == nil {
+		t.Fatal("TraceCommand succeeded, want the exit error")
+	}
+	if got := stderr.String(); got != "oops\n" {
+		t.Errorf("cmd.Stderr got %q, want the whole output", got)
+	}
+
+	span := finishedSpan(t, tracer)
+	if span.Tag("process.exit_code") != 3 || span.Tag("error") != true {
+		t.Errorf("exit code = %v, error = %v; want 3 and true", span.Tag("process.exit_code"), span.Tag("error"))
+	}
+	if got := loggedFields(span)["stderr"]; got != "oops\n" {
+		t.Errorf("logged stderr %q, want %q", got, "oops\n")
+	}
+}
+
+func TestTraceCommandNotFound(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	if err := TraceCommand(context.Background(), exec.Command("/nonexistent/tool")); err == nil {
+		t.Fatal("TraceCommand succeeded, want the start error")
+	}
+
+	span := finishedSpan(t, tracer)
+	if span.OperationName != "exec tool" || span.Tag("error") != true || span.Tag("process.exit_code") != nil {
+		t.Errorf("span %q tagged %v, want an error without exit code", span.OperationName, span.Tags())
+	}
+}
+
+func TestTraceCommandSharedOutput(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	var out bytes.Buffer
+	cmd := exec.Command("sh", "-c", "echo out; echo err >&2; echo out2; exit 1")
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := TraceCommand(context.Background(), cmd); err == nil {
+		t.Fatal("TraceCommand succeeded, want the exit error")
+	}
+	if cmd.Stdout != cmd.Stderr {
+		t.Error("stdout and stderr no longer share a writer")
+	}
+	const want = "out\nerr\nout2\n"
+	if got := out.String(); got != want {
+		t.Errorf("the shared writer got %q, want %q", got, want)
+	}
+	if got := loggedFields(finishedSpan(t, tracer))["stderr"]; got != want {
+		t.Errorf("logged stderr %q, want both streams %q", got, want)
+	}
+}
+
+func TestTailWriter(t *testing.T) {
+	var w bytes.Buffer
+	tail := &tailWriter{w: &w}
+	tail.Write(bytes.Repeat([]byte("a"), stderrTailSize))
+	tail.Write([]byte("end"))
+	got := tail.String()
+	if len(got) != stderrTailSize || !strings.HasSuffix(got, "aend") {
+		t.Errorf("kept %d bytes ending in %q, want the last %d", len(got), got[len(got)-4:], stderrTailSize)
+	}
+	if w.Len() != stderrTailSize+3 {
+		t.Errorf("the wrapped writer got %d bytes, want all %d", w.Len(), stderrTailSize+3)
+	}
+}
+
+func TestInterfaceEqual(t *testing.T) {
+	var b bytes.Buffer
+	type funcWriter struct{ f func() }
+	if !interfaceEqual(&b, &b) || interfaceEqual(&b, &bytes.Buffer{}) {
+		t.Error("pointers not compared by identity")
+	}
+	if interfaceEqual(funcWriter{}, funcWriter{}) {
+		t.Error("incomparable values reported as equal")
+	}
+}
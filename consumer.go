@@ -0,0 +1,47 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// TraceConsumer wraps handle, a message handler for any queue or message
+// system, so that every message is processed within a consumer span named
+// operationName. The span follows from the span context extract finds in
+// the message, if any, since producers don't wait for consumers. Errors
+// returned by handle are tagged and logged, and the processing time is
+// logged when handle returns:
+//
+//	handle := opentracing_helpers.TraceConsumer("process order",
+//		func(m *sqs.Message) opentracing.SpanContext { return extractFromAttributes(m) },
+//		processOrder)
+//	for _, m := range messages {
+//		handle(ctx, m)
+//	}
+func TraceConsumer[T any](operationName string, extract func(msg T) opentracing.SpanContext, handle func(ctx context.Context, msg T) error) func(ctx context.Context, msg T) error {
+	return func(ctx context.Context, msg T) error {
+		opts := []opentracing.StartSpanOption{ext.SpanKindConsumer}
+		if sc := extract(msg); sc != nil {
+			opts = append(opts, opentracing.FollowsFrom(sc))
+		}
+		start := time.Now()
+		span := opentracing.GlobalTracer().StartSpan(operationName, append(opts, opentracing.StartTime(start))...)
+		defer span.Finish()
+
+		err := handle(opentracing.ContextWithSpan(ctx, span), msg)
+		fields := []log.Field{
+			log.String("event", "processed"),
+			log.String("processing_time", time.Since(start).String()),
+		}
+		if err != nil {
+			ext.Error.Set(span, true)
+			fields = append(fields, log.Error(err))
+		}
+		span.LogFields(fields...)
+		return err
+	}
+}
@@ -0,0 +1,70 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+type message struct {
+	headers opentracing.TextMapCarrier
+	body    string
+}
+
+func TestTraceConsumer(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	producer := tracer.StartSpan("publish")
+	traced := message{headers: opentracing.TextMapCarrier{}, body: "ok"}
+	tracer.Inject(producer.Context(), opentracing.TextMap, traced.headers)
+	untraced := message{body: "bad"}
+
+	extract := func(m message) opentracing.SpanContext {
+		if m.headers == nil {
+			return nil
+		}
+		sc, _ := tracer.Extract(opentracing.TextMap, m.headers)
+		return sc
+	}
+	handle := TraceConsumer("process", extract, func(ctx context.Context, m message) error {
+		if opentracing.SpanFromContext(ctx) == nil {
+			t.Error("the handler context carries no span")
+		}
+		if m.body == "bad" {
+			return errors.New("invalid message")
+		}
+		return nil
+	})
+	if err := handle(context.Background(), traced); err != nil {
+		t.Fatal(err)
+	}
+	if err := handle(context.Background(), untraced); err == nil {
+		t.Error("the handler error was not returned")
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d finished spans, want 2", len(spans))
+	}
+	span := spans[0]
+	if span.OperationName != "process" || span.Tag("span.kind") != ext.SpanKindConsumerEnum {
+		t.Errorf("span %q has kind %v, want a consumer span", span.OperationName, span.Tag("span.kind"))
+	}
+	if span.ParentID != producer.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the span doesn't follow from the producer span")
+	}
+	if _, ok := loggedFields(span)["processing_time"]; !ok || span.Tag("error") != nil {
+		t.Errorf("successful message logged %v, tagged %v", loggedFields(span), span.Tags())
+	}
+
+	failed := spans[1]
+	if failed.ParentID != 0 || failed.Tag("error") != true || loggedFields(failed)["error.object"] != "invalid message" {
+		t.Errorf("failed message span has parent %d, tags %v, logs %v", failed.ParentID, failed.Tags(), loggedFields(failed))
+	}
+}
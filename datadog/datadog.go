@@ -0,0 +1,37 @@
+// Package datadog bootstraps a Datadog tracer through its OpenTracing
+// bridge. Importing it registers the "datadog" backend for
+// opentracing_helpers.InitTracer:
+//
+//	import _ "github.com/jfernandez/opentracing-helpers/datadog"
+//
+//	// TRACER_BACKEND=datadog DD_AGENT_HOST=...
+//	tracer, closer, err := opentracing_helpers.InitTracer("users")
+package datadog
+
+import (
+	"io"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/opentracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func init() {
+	opentracing_helpers.RegisterTracerFactory("datadog", NewTracer)
+}
+
+// NewTracer creates a Datadog tracer named serviceName. The agent address,
+// environment and version are read by the Datadog tracer from the
+// standard DD_* environment variables. Closing the closer stops the tracer
+// and flushes buffered spans.
+func NewTracer(serviceName string) (opentracing.Tracer, io.Closer, error) {
+	return opentracer.New(tracer.WithService(serviceName)), closer{}, nil
+}
+
+type closer struct{}
+
+func (closer) Close() error {
+	tracer.Stop()
+	return nil
+}
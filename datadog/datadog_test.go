@@ -0,0 +1,32 @@
+package datadog
+
+import (
+	"testing"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+)
+
+func TestInitTracer(t *testing.T) {
+	global := opentracing.GlobalTracer()
+	defer opentracing.SetGlobalTracer(global)
+	t.Setenv(opentracing_helpers.TracerBackendEnv, "datadog")
+	t.Setenv("DD_AGENT_HOST", "127.0.0.1")
+	t.Setenv("DD_TRACE_AGENT_PORT", "1")
+
+	tracer, closer, err := opentracing_helpers.InitTracer("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opentracing.GlobalTracer() != tracer {
+		t.Error("the datadog tracer was not set as the global tracer")
+	}
+	span := tracer.StartSpan("op")
+	if span.Context() == nil {
+		t.Error("the span has no context")
+	}
+	span.Finish()
+	if err := closer.Close(); err != nil {
+		t.Error(err)
+	}
+}
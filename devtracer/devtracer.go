@@ -0,0 +1,227 @@
+// Package devtracer provides a tracer for local development that keeps
+// the most recent traces in memory and shows them as waterfalls on a
+// debug page, so that instrumentation can be checked without running
+// Jaeger:
+//
+//	tracer := devtracer.New()
+//	opentracing.SetGlobalTracer(tracer)
+//	http.Handle("/debug/traces", tracer.Handler())
+//
+// Importing the package also registers it as the "dev" backend of
+// opentracing_helpers.InitTracer, which serves the page on
+// DEVTRACER_ADDR, localhost:7070 by default. Spans are propagated with
+// the mocktracer headers, so every service must use this tracer.
+package devtracer
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// DefaultAddr is the address the "dev" backend serves the debug page on
+// unless DEVTRACER_ADDR is set.
+const DefaultAddr = "localhost:7070"
+
+func init() {
+	opentracing_helpers.RegisterTracerFactory("dev", NewTracer)
+}
+
+// NewTracer is an opentracing_helpers.TracerFactory creating a Tracer and
+// serving its debug page on DEVTRACER_ADDR at /debug/traces. The closer
+// stops the server.
+func NewTracer(serviceName string) (opentracing.Tracer, io.Closer, error) {
+	addr := os.Getenv("DEVTRACER_ADDR")
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracer := New(WithServiceName(serviceName))
+	mux := http.NewServeMux()
+	mux.Handle("/debug/traces", tracer.Handler())
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	return tracer, closerFunc(func() error { return srv.Shutdown(context.Background()) }), nil
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+// Option customizes New.
+type Option func(*Tracer)
+
+// WithServiceName shows name in the title of the debug page.
+func WithServiceName(name string) Option {
+	return func(t *Tracer) {
+		t.serviceName = name
+	}
+}
+
+// WithMaxTraces sets how many traces are kept, 100 by default. Older
+// traces are forgotten first.
+func WithMaxTraces(n int) Option {
+	return func(t *Tracer) {
+		t.maxTraces = n
+	}
+}
+
+// WithMaxSpans sets how many spans are kept per trace, 1000 by default.
+// Further spans of the trace are counted but not kept.
+func WithMaxSpans(n int) Option {
+	return func(t *Tracer) {
+		t.maxSpans = n
+	}
+}
+
+// Tracer is an opentracing.Tracer recording the finished spans of the
+// most recent traces in memory.
+type Tracer struct {
+	mock        *mocktracer.MockTracer
+	serviceName string
+	maxTraces   int
+	maxSpans    int
+
+	mu     sync.Mutex
+	traces map[int]*trace
+	// order holds trace IDs from the oldest to the most recent.
+	order []int
+}
+
+type trace struct {
+	id      int
+	spans   []*mocktracer.MockSpan
+	dropped int
+}
+
+// New returns a Tracer.
+func New(opts ...Option) *Tracer {
+	t := &Tracer{
+		mock:      mocktracer.New(),
+		maxTraces: 100,
+		maxSpans:  1000,
+		traces:    make(map[int]*trace),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// StartSpan implements opentracing.Tracer.
+func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	return &span{MockSpan: t.mock.StartSpan(operationName, opts...).(*mocktracer.MockSpan), tracer: t}
+}
+
+// Inject implements opentracing.Tracer.
+func (t *Tracer) Inject(sc opentracing.SpanContext, format any, carrier any) error {
+	return t.mock.Inject(sc, format, carrier)
+}
+
+// Extract implements opentracing.Tracer.
+func (t *Tracer) Extract(format any, carrier any) (opentracing.SpanContext, error) {
+	return t.mock.Extract(format, carrier)
+}
+
+// Reset forgets all recorded traces.
+func (t *Tracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.traces = make(map[int]*trace)
+	t.order = nil
+}
+
+// record keeps the finished span s.
+func (t *Tracer) record(s *mocktracer.MockSpan) {
+	// The spans are kept here, not by the mock tracer.
+	t.mock.Reset()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id := s.SpanContext.TraceID
+	tr, ok := t.traces[id]
+	if !ok {
+		tr = &trace{id: id}
+		t.traces[id] = tr
+		t.order = append(t.order, id)
+		if len(t.order) > t.maxTraces {
+			delete(t.traces, t.order[0])
+			t.order = t.order[1:]
+		}
+	}
+	if len(tr.spans) >= t.maxSpans {
+		tr.dropped++
+		return
+	}
+	tr.spans = append(tr.spans, s)
+}
+
+// snapshot returns copies of the recorded traces, the most recent first.
+func (t *Tracer) snapshot() []trace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	traces := make([]trace, 0, len(t.order))
+	for i := len(t.order) - 1; i >= 0; i-- {
+		tr := t.traces[t.order[i]]
+		traces = append(traces, trace{
+			id:      tr.id,
+			spans:   append([]*mocktracer.MockSpan(nil), tr.spans...),
+			dropped: tr.dropped,
+		})
+	}
+	return traces
+}
+
+// span records the wrapped span in its Tracer when finished.
+type span struct {
+	*mocktracer.MockSpan
+	tracer   *Tracer
+	finished atomic.Bool
+}
+
+func (s *span) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+func (s *span) FinishWithOptions(opts opentracing.FinishOptions) {
+	if s.finished.Swap(true) {
+		return
+	}
+	if opts.FinishTime.IsZero() {
+		// Unlike Finish, the mock span doesn't default the time.
+		opts.FinishTime = time.Now()
+	}
+	s.MockSpan.FinishWithOptions(opts)
+	s.tracer.record(s.MockSpan)
+}
+
+func (s *span) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+func (s *span) SetOperationName(operationName string) opentracing.Span {
+	s.MockSpan.SetOperationName(operationName)
+	return s
+}
+
+func (s *span) SetTag(key string, value any) opentracing.Span {
+	s.MockSpan.SetTag(key, value)
+	return s
+}
+
+func (s *span) SetBaggageItem(key, val string) opentracing.Span {
+	s.MockSpan.SetBaggageItem(key, val)
+	return s
+}
@@ -0,0 +1,139 @@
+package devtracer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// startTrace finishes a root span with n-1 children and returns its
+// trace ID.
+func startTrace(tracer *Tracer, name string, n int) int {
+	root := tracer.StartSpan(name)
+	for i := 1; i < n; i++ {
+		tracer.StartSpan("child", opentracing.ChildOf(root.Context())).Finish()
+	}
+	root.Finish()
+	return root.Context().(mocktracer.MockSpanContext).TraceID
+}
+
+func TestTracerRecordsTraces(t *testing.T) {
+	tracer := New(WithMaxTraces(2), WithMaxSpans(2))
+	first := startTrace(tracer, "first", 1)
+	second := startTrace(tracer, "second", 3)
+	third := startTrace(tracer, "third", 1)
+
+	traces := tracer.snapshot()
+	if len(traces) != 2 || traces[0].id != third || traces[1].id != second {
+		t.Fatalf("recorded traces %v, want third then second; first (%d) evicted", traces, first)
+	}
+	if len(traces[1].spans) != 2 || traces[1].dropped != 1 {
+		t.Errorf("second trace kept %d spans and dropped %d, want 2 and 1", len(traces[1].spans), traces[1].dropped)
+	}
+
+	tracer.Reset()
+	if traces := tracer.snapshot(); len(traces) != 0 {
+		t.Errorf("%d traces after Reset", len(traces))
+	}
+}
+
+func TestSpanFinishedOnce(t *testing.T) {
+	tracer := New()
+	span := tracer.StartSpan("once")
+	span.Finish()
+	span.Finish()
+	if traces := tracer.snapshot(); len(traces) != 1 || len(traces[0].spans) != 1 {
+		t.Errorf("recorded %v, want a single span", traces)
+	}
+	if span.Tracer() != tracer {
+		t.Error("the span isn't bound to the dev tracer")
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	tracer := New()
+	span := tracer.StartSpan("client")
+	header := http.Header{}
+	if err := tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header)); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.(mocktracer.MockSpanContext).SpanID != span.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("extracted a different span context")
+	}
+}
+
+func TestNewTraceView(t *testing.T) {
+	tracer := New()
+	root := tracer.StartSpan("GET /users")
+	child := tracer.StartSpan("db.query", opentracing.ChildOf(root.Context()))
+	grandchild := tracer.StartSpan("dial", opentracing.ChildOf(child.Context()))
+	ext.Error.Set(grandchild, true)
+	grandchild.LogKV("event", "error")
+	grandchild.Finish()
+	child.Finish()
+	root.Finish()
+
+	v := newTraceView(tracer.snapshot()[0])
+	if v.Root != "GET /users" || !v.Error {
+		t.Errorf("trace rooted at %q with error %t, want GET /users failed", v.Root, v.Error)
+	}
+	depths := map[string]int{}
+	for _, s := range v.Spans {
+		depths[s.Name] = s.Depth
+	}
+	if depths["GET /users"] != 0 || depths["db.query"] != 1 || depths["dial"] != 2 {
+		t.Errorf("span depths %v", depths)
+	}
+	dial := v.Spans[2]
+	if !dial.Error || len(dial.Logs) != 1 || !strings.HasSuffix(dial.Logs[0], "event=error") {
+		t.Errorf("dial span view %+v", dial)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tracer := New(WithServiceName("checkout"))
+	id := startTrace(tracer, "GET /cart", 2)
+	h := tracer.Handler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/traces", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "checkout") || !strings.Contains(w.Body.String(), "GET /cart") {
+		t.Errorf("list page %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/traces?trace="+strconv.Itoa(id), nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "child") {
+		t.Errorf("trace page %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/traces?trace=1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing trace served %d, want 404", w.Code)
+	}
+}
+
+func TestNewTracer(t *testing.T) {
+	t.Setenv("DEVTRACER_ADDR", "127.0.0.1:0")
+	tracer, closer, err := NewTracer("checkout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tracer.(*Tracer).serviceName != "checkout" {
+		t.Error("the service name wasn't set")
+	}
+	if err := closer.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,176 @@
+package devtracer
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// Handler returns the debug page, listing the recorded traces, or showing
+// the waterfall of one with a trace query parameter.
+func (t *Tracer) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traces := t.snapshot()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if id := r.URL.Query().Get("trace"); id != "" {
+			for _, tr := range traces {
+				if strconv.Itoa(tr.id) == id {
+					executeTemplate(w, traceTemplate, t.serviceName, newTraceView(tr))
+					return
+				}
+			}
+			http.Error(w, "trace not found, it may have been evicted", http.StatusNotFound)
+			return
+		}
+		views := make([]traceView, len(traces))
+		for i, tr := range traces {
+			views[i] = newTraceView(tr)
+		}
+		executeTemplate(w, listTemplate, t.serviceName, views)
+	})
+}
+
+func executeTemplate(w http.ResponseWriter, tmpl *template.Template, serviceName string, data any) {
+	err := tmpl.Execute(w, struct {
+		ServiceName string
+		Data        any
+	}{serviceName, data})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type traceView struct {
+	ID       int
+	Root     string
+	Start    time.Time
+	Duration time.Duration
+	Error    bool
+	Dropped  int
+	Spans    []spanView
+}
+
+type spanView struct {
+	Name     string
+	Depth    int
+	Offset   float64
+	Width    float64
+	Duration time.Duration
+	Error    bool
+	Tags     []string
+	Logs     []string
+}
+
+func newTraceView(tr trace) traceView {
+	v := traceView{ID: tr.id, Dropped: tr.dropped}
+	if len(tr.spans) == 0 {
+		return v
+	}
+	spans := tr.spans
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].StartTime.Before(spans[j].StartTime) })
+
+	start, end := spans[0].StartTime, spans[0].FinishTime
+	byID := make(map[int]*mocktracer.MockSpan, len(spans))
+	for _, s := range spans {
+		byID[s.SpanContext.SpanID] = s
+		if s.FinishTime.After(end) {
+			end = s.FinishTime
+		}
+	}
+	v.Root = spans[0].OperationName
+	v.Start = start
+	v.Duration = end.Sub(start)
+	total := float64(v.Duration)
+	if total == 0 {
+		total = 1
+	}
+
+	for _, s := range spans {
+		sv := spanView{
+			Name:     s.OperationName,
+			Depth:    depth(s, byID),
+			Offset:   float64(s.StartTime.Sub(start)) / total * 100,
+			Width:    max(float64(s.FinishTime.Sub(s.StartTime))/total*100, 0.2),
+			Duration: s.FinishTime.Sub(s.StartTime),
+		}
+		if s.ParentID == 0 {
+			v.Root = s.OperationName
+		}
+		for k, val := range s.Tags() {
+			sv.Tags = append(sv.Tags, fmt.Sprintf("%s=%v", k, val))
+			if k == "error" && val == true {
+				sv.Error = true
+				v.Error = true
+			}
+		}
+		sort.Strings(sv.Tags)
+		for _, record := range s.Logs() {
+			fields := make([]string, len(record.Fields))
+			for i, f := range record.Fields {
+				fields[i] = f.Key + "=" + f.ValueString
+			}
+			sv.Logs = append(sv.Logs, fmt.Sprintf("+%v %s", record.Timestamp.Sub(s.StartTime), strings.Join(fields, " ")))
+		}
+		v.Spans = append(v.Spans, sv)
+	}
+	return v
+}
+
+// depth returns the number of recorded ancestors of s.
+func depth(s *mocktracer.MockSpan, byID map[int]*mocktracer.MockSpan) int {
+	d := 0
+	for p, ok := byID[s.ParentID]; ok && d < len(byID); p, ok = byID[p.ParentID] {
+		d++
+	}
+	return d
+}
+
+const style = `<style>
+body { font: 13px sans-serif; margin: 1em 2em; }
+table { border-collapse: collapse; width: 100%; }
+td, th { text-align: left; padding: 2px 8px; border-bottom: 1px solid #eee; vertical-align: top; }
+.error { color: #c00; }
+.row { display: flex; align-items: center; border-bottom: 1px solid #eee; }
+.name { width: 30%; white-space: nowrap; overflow: hidden; text-overflow: ellipsis; }
+.lane { position: relative; flex: 1; height: 14px; }
+.bar { position: absolute; height: 100%; background: #4a90d9; }
+.error .bar { background: #d94a4a; }
+details { width: 100%; }
+pre { margin: 0 0 0 2em; color: #555; }
+</style>`
+
+var listTemplate = template.Must(template.New("list").Parse(`<!DOCTYPE html>
+<title>Traces{{with .ServiceName}} - {{.}}{{end}}</title>` + style + `
+<h1>Recent traces{{with .ServiceName}} of {{.}}{{end}}</h1>
+<table>
+<tr><th>Root span</th><th>Start</th><th>Duration</th><th>Spans</th></tr>
+{{range .Data}}<tr{{if .Error}} class="error"{{end}}>
+<td><a href="?trace={{.ID}}">{{.Root}}</a></td>
+<td>{{.Start.Format "15:04:05.000"}}</td>
+<td>{{.Duration}}</td>
+<td>{{len .Spans}}{{if .Dropped}} (+{{.Dropped}} dropped){{end}}</td>
+</tr>
+{{else}}<tr><td colspan="4">No traces yet.</td></tr>
+{{end}}</table>
+`))
+
+var traceTemplate = template.Must(template.New("trace").Parse(`<!DOCTYPE html>
+<title>{{.Data.Root}}{{with .ServiceName}} - {{.}}{{end}}</title>` + style + `
+{{with .Data}}<p><a href="?">All traces</a></p>
+<h1>{{.Root}}</h1>
+<p>Trace {{.ID}}, started {{.Start.Format "15:04:05.000"}}, took {{.Duration}}{{if .Dropped}}, {{.Dropped}} spans dropped{{end}}.</p>
+{{range .Spans}}<details{{if .Error}} class="error"{{end}}>
+<summary class="row">
+<span class="name" style="padding-left: {{.Depth}}em">{{.Name}} ({{.Duration}})</span>
+<span class="lane"><span class="bar" style="left: {{printf "%.2f" .Offset}}%; width: {{printf "%.2f" .Width}}%"></span></span>
+</summary>
+{{range .Tags}}<pre>{{.}}</pre>{{end}}{{range .Logs}}<pre>{{.}}</pre>{{end}}
+</details>
+{{end}}{{end}}
+`))
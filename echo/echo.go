@@ -0,0 +1,113 @@
+// Package echo traces requests served by labstack/echo. Spans are named
+// after the matched route path, for example "GET /users/:id":
+//
+//	e := echo.New()
+//	e.Use(otecho.Middleware())
+package echo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// contextKey is the request context key the call state is stored under
+// while the parent package's middleware runs.
+type contextKey struct{}
+
+// call is the state of a request passed through the parent package's
+// middleware.
+type call struct {
+	c    echo.Context
+	next echo.HandlerFunc
+	err  error
+}
+
+// Middleware returns an echo.MiddlewareFunc that traces requests. opts are
+// passed to the parent package and may override the span name. Errors
+// returned by the handler are passed to echo's HTTP error handler before
+// the response status is recorded, so the span reflects what the client
+// received: only errors answered with a 5xx status mark the span as
+// failed.
+func Middleware(opts ...opentracing_helpers.HandlerOption) echo.MiddlewareFunc {
+	opts = append([]opentracing_helpers.HandlerOption{
+		opentracing_helpers.WithOperationNameFunc(operationName),
+		opentracing_helpers.WithSpanObserver(tagComponent),
+	}, opts...)
+	traced := opentracing_helpers.NewMiddleware(opts...)(http.HandlerFunc(serve))
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			cl := &call{c: c, next: next}
+			req := c.Request()
+			traced.ServeHTTP(c.Response().Writer, req.WithContext(context.WithValue(req.Context(), contextKey{}, cl)))
+			return cl.err
+		}
+	}
+}
+
+// serve runs the next handler behind the parent package's middleware,
+// writing the response through w so that it is recorded.
+func serve(w http.ResponseWriter, r *http.Request) {
+	cl := r.Context().Value(contextKey{}).(*call)
+	c, resp := cl.c, cl.c.Response()
+	c.SetRequest(r)
+	opentracing_helpers.SetRoute(r, c.Path())
+	orig := resp.Writer
+	resp.Writer = w
+	defer func() { resp.Writer = orig }()
+
+	err := cl.next(c)
+	if err == nil {
+		return
+	}
+	cl.err = err
+	// Let the error handler write the response now so that its status
+	// can be recorded.
+	c.Error(err)
+	if errorStatus(resp, err) < http.StatusInternalServerError {
+		return
+	}
+	if span := opentracing.SpanFromContext(r.Context()); span != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+}
+
+// errorStatus returns the status the client received for err: the status
+// written by the error handler, or the code of an *echo.HTTPError if no
+// response was written.
+func errorStatus(resp *echo.Response, err error) int {
+	if resp.Committed {
+		return resp.Status
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
+
+func echoContext(r *http.Request) echo.Context {
+	if cl, ok := r.Context().Value(contextKey{}).(*call); ok {
+		return cl.c
+	}
+	return nil
+}
+
+func operationName(r *http.Request) string {
+	if c := echoContext(r); c != nil && c.Path() != "" {
+		return r.Method + " " + c.Path()
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+// tagComponent tags span with the echo component.
+func tagComponent(span opentracing.Span, r *http.Request) {
+	ext.Component.Set(span, "echo")
+}
@@ -0,0 +1,127 @@
+package echo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// serveEcho sends a GET request for path to an echo server traced with
+// tracer and routing pattern to handler.
+func serveEcho(t *testing.T, tracer *mocktracer.MockTracer, pattern, path string, handler echo.HandlerFunc) (*httptest.ResponseRecorder, *mocktracer.MockSpan) {
+	t.Helper()
+	e := echo.New()
+	e.Use(Middleware(opentracing_helpers.WithTracer(tracer)))
+	e.GET(pattern, handler)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	return w, spans[0]
+}
+
+func TestMiddleware(t *testing.T) {
+	tracer := mocktracer.New()
+	var inHandler opentracing.Span
+	_, span := serveEcho(t, tracer, "/users/:id", "/users/42", func(c echo.Context) error {
+		inHandler = opentracing.SpanFromContext(c.Request().Context())
+		return c.String(http.StatusOK, "hello")
+	})
+
+	if span.OperationName != "GET /users/:id" {
+		t.Errorf("operation name = %q, want %q", span.OperationName, "GET /users/:id")
+	}
+	for key, want := range map[string]interface{}{
+		"component":          "echo",
+		"http.route":         "/users/:id",
+		"http.status_code":   uint16(http.StatusOK),
+		"http.response_size": int64(5),
+	} {
+		if got := span.Tag(key); got != want {
+			t.Errorf("%s = %v (%T), want %v (%T)", key, got, got, want, want)
+		}
+	}
+	if inHandler == nil || inHandler.Context().(mocktracer.MockSpanContext).SpanID != span.SpanContext.SpanID {
+		t.Error("the request context doesn't carry the server span")
+	}
+	if span.Tag("error") != nil {
+		t.Error("a successful request is tagged as an error")
+	}
+}
+
+func TestMiddlewareRecordsErrorHandlerStatus(t *testing.T) {
+	tracer := mocktracer.New()
+	w, span := serveEcho(t, tracer, "/", "/", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "no such user")
+	})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	if got := span.Tag("http.status_code"); got != uint16(http.StatusNotFound) {
+		t.Errorf("http.status_code = %v, want 404", got)
+	}
+	if span.Tag("error") != nil {
+		t.Error("a 404 response is tagged as an error")
+	}
+}
+
+func TestMiddlewareServerError(t *testing.T) {
+	tracer := mocktracer.New()
+	w, span := serveEcho(t, tracer, "/", "/", func(c echo.Context) error {
+		return errors.New("database unavailable")
+	})
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	if got := span.Tag("http.status_code"); got != uint16(http.StatusInternalServerError) {
+		t.Errorf("http.status_code = %v, want 500", got)
+	}
+	if span.Tag("error") != true {
+		t.Error("the span is not tagged as an error")
+	}
+	if len(span.Logs()) == 0 {
+		t.Error("the handler error was not logged")
+	}
+}
+
+func TestMiddlewareOptions(t *testing.T) {
+	tracer := mocktracer.New()
+	e := echo.New()
+	e.Use(Middleware(
+		opentracing_helpers.WithTracer(tracer),
+		opentracing_helpers.WithOperationNameFunc(func(r *http.Request) string { return "custom" }),
+	))
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) })
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if spans := tracer.FinishedSpans(); len(spans) != 1 || spans[0].OperationName != "custom" {
+		t.Errorf("finished spans %v, want one named custom", spans)
+	}
+}
+
+type metricsRecorder []opentracing_helpers.RequestMetrics
+
+func (m *metricsRecorder) ObserveRequest(rm opentracing_helpers.RequestMetrics) {
+	*m = append(*m, rm)
+}
+
+func TestMiddlewareMetricsOperation(t *testing.T) {
+	var metrics metricsRecorder
+	e := echo.New()
+	e.Use(Middleware(opentracing_helpers.WithTracer(mocktracer.New()), opentracing_helpers.WithMetricsObserver(&metrics)))
+	e.GET("/users/:id", func(c echo.Context) error { return nil })
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if len(metrics) != 2 || metrics[0].Operation != "GET /users/:id" || metrics[1].Operation != http.MethodGet {
+		t.Errorf("observed %+v, want the route, then the method of the unmatched request", metrics)
+	}
+}
@@ -0,0 +1,201 @@
+package opentracing_helpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// Environment variables read by NewConfigFromEnv.
+const (
+	EnvDisabled         = "OT_HELPERS_DISABLED"
+	EnvExcludePaths     = "OT_HELPERS_EXCLUDE_PATHS"
+	EnvExcludeProbes    = "OT_HELPERS_EXCLUDE_PROBES"
+	EnvCaptureHeaders   = "OT_HELPERS_CAPTURE_HEADERS"
+	EnvPropagation      = "OT_HELPERS_PROPAGATION"
+	EnvBodyCaptureLimit = "OT_HELPERS_BODY_CAPTURE_LIMIT"
+	EnvSlowThreshold    = "OT_HELPERS_SLOW_THRESHOLD"
+)
+
+// NewConfigFromEnv returns an Option built from environment variables, so
+// that tracing can be tuned per environment without code changes:
+//
+//   - OT_HELPERS_DISABLED=true for WithDisabled
+//   - OT_HELPERS_EXCLUDE_PATHS=/ping,/static/* for WithFilter, where a
+//     trailing * matches any suffix
+//   - OT_HELPERS_EXCLUDE_PROBES=true for ExcludeDefaultProbes
+//   - OT_HELPERS_CAPTURE_HEADERS=X-Request-Id,User-Agent for
+//     WithCapturedHeaders
+//   - OT_HELPERS_PROPAGATION=b3,w3c for WithPropagators
+//   - OT_HELPERS_BODY_CAPTURE_LIMIT=4096 for WithBodyCapture
+//   - OT_HELPERS_SLOW_THRESHOLD=500ms for WithSlowThreshold
+//
+// Unset variables leave the defaults alone. The propagation formats are
+// native (the tracer's own), b3, b3single and w3c, all injected and tried
+// in order on extraction; for all but native the span contexts are
+// converted with the codec of WithSpanContextCodec. The
+// returned Option can be passed along with others, which take precedence
+// when given after it:
+//
+//	envOpt, err := opentracing_helpers.NewConfigFromEnv()
+//	handler := opentracing_helpers.NewMiddleware(envOpt, opentracing_helpers.WithSpanContextCodec(codec))(mux)
+func NewConfigFromEnv() (Option, error) {
+	var opts options
+
+	if v := os.Getenv(EnvDisabled); v != "" {
+		disabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, envError(EnvDisabled, err)
+		}
+		if disabled {
+			opts = append(opts, WithDisabled(func() bool { return true }))
+		}
+	}
+
+	var excludeProbes bool
+	if v := os.Getenv(EnvExcludeProbes); v != "" {
+		var err error
+		if excludeProbes, err = strconv.ParseBool(v); err != nil {
+			return nil, envError(EnvExcludeProbes, err)
+		}
+	}
+	excluded := splitList(os.Getenv(EnvExcludePaths))
+	if excludeProbes || len(excluded) > 0 {
+		// A single filter, since filters replace each other.
+		opts = append(opts, WithFilter(func(r *http.Request) bool {
+			return !(excludeProbes && IsDefaultProbe(r)) && !matchPaths(excluded, r.URL.Path)
+		}))
+	}
+
+	if headers := splitList(os.Getenv(EnvCaptureHeaders)); len(headers) > 0 {
+		opts = append(opts, WithCapturedHeaders(headers))
+	}
+
+	if formats := splitList(os.Getenv(EnvPropagation)); len(formats) > 0 {
+		opt, err := propagationOption(formats)
+		if err != nil {
+			return nil, envError(EnvPropagation, err)
+		}
+		opts = append(opts, opt)
+	}
+
+	if v := os.Getenv(EnvBodyCaptureLimit); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return nil, envError(EnvBodyCaptureLimit, fmt.Errorf("invalid size %q", v))
+		}
+		opts = append(opts, WithBodyCapture(limit))
+	}
+
+	if v := os.Getenv(EnvSlowThreshold); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, envError(EnvSlowThreshold, err)
+		}
+		opts = append(opts, WithSlowThreshold(d))
+	}
+
+	return opts, nil
+}
+
+func envError(name string, err error) error {
+	return fmt.Errorf("opentracing_helpers: %s: %w", name, err)
+}
+
+// splitList splits a comma-separated list, ignoring empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// matchPaths reports whether path is one of paths, where a trailing *
+// matches any suffix.
+func matchPaths(paths []string, path string) bool {
+	for _, p := range paths {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// propagationOption returns a WithPropagators option for the named
+// formats, whose codec is resolved from the configuration on use.
+func propagationOption(formats []string) (Option, error) {
+	for _, f := range formats {
+		switch f {
+		case "native", "b3", "b3single", "w3c":
+		default:
+			return nil, fmt.Errorf("unknown propagation format %q", f)
+		}
+	}
+	return commonOption(func(c *commonConfig) {
+		codec := configCodec{c}
+		chain := make(PropagatorChain, len(formats))
+		for i, f := range formats {
+			switch f {
+			case "native":
+				chain[i] = NativePropagator{}
+			case "b3":
+				chain[i] = B3Propagator{Codec: codec}
+			case "b3single":
+				chain[i] = B3Propagator{Codec: codec, SingleHeader: true}
+			case "w3c":
+				chain[i] = W3CPropagator{Codec: codec}
+			}
+		}
+		c.propagators = []Propagator{chain}
+	}), nil
+}
+
+// configCodec uses the SpanContextCodec of a configuration, which may be
+// set by an option applied later.
+type configCodec struct {
+	c *commonConfig
+}
+
+var errNoCodec = errors.New("opentracing_helpers: no SpanContextCodec configured")
+
+func (cc configCodec) Identifiers(sc opentracing.SpanContext) (SpanIdentifiers, bool) {
+	if cc.c.codec == nil {
+		return SpanIdentifiers{}, false
+	}
+	return cc.c.codec.Identifiers(sc)
+}
+
+func (cc configCodec) SpanContext(ids SpanIdentifiers) (opentracing.SpanContext, error) {
+	if cc.c.codec == nil {
+		return nil, errNoCodec
+	}
+	return cc.c.codec.SpanContext(ids)
+}
+
+// options applies several options in order.
+type options []Option
+
+func (o options) applyHandler(c *handlerConfig) {
+	for _, opt := range o {
+		opt.applyHandler(c)
+	}
+}
+
+func (o options) applyTransport(c *transportConfig) {
+	for _, opt := range o {
+		opt.applyTransport(c)
+	}
+}
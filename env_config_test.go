@@ -0,0 +1,93 @@
+package opentracing_helpers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNewConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvDisabled, "true")
+	t.Setenv(EnvExcludePaths, "/ping, /static/*,")
+	t.Setenv(EnvExcludeProbes, "1")
+	t.Setenv(EnvCaptureHeaders, "x-request-id,User-Agent")
+	t.Setenv(EnvPropagation, "w3c,native")
+	t.Setenv(EnvBodyCaptureLimit, "4096")
+	t.Setenv(EnvSlowThreshold, "500ms")
+	opt, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newHandlerConfig([]HandlerOption{opt})
+
+	if c.disabled == nil || !c.disabled() {
+		t.Error("tracing not disabled")
+	}
+	for path, traced := range map[string]bool{
+		"/ping":         false,
+		"/static/a.css": false,
+		"/healthz":      false,
+		"/users":        true,
+	} {
+		if got := c.filter(httptest.NewRequest("GET", path, nil)); got != traced {
+			t.Errorf("filter(%q) = %t, want %t", path, got, traced)
+		}
+	}
+	if want := []string{"X-Request-Id", "User-Agent"}; !reflect.DeepEqual(c.capturedHeaders, want) {
+		t.Errorf("captured headers %q, want %q", c.capturedHeaders, want)
+	}
+	if len(c.propagators) != 1 {
+		t.Fatalf("%d propagators, want a single chain", len(c.propagators))
+	}
+	chain := c.propagators[0].(PropagatorChain)
+	if _, ok := chain[0].(W3CPropagator); !ok || len(chain) != 2 {
+		t.Errorf("propagators %#v, want w3c then native", chain)
+	}
+	if c.bodyCaptureLimit != 4096 {
+		t.Errorf("body capture limit %d, want 4096", c.bodyCaptureLimit)
+	}
+	if len(c.finishHooks) != 1 {
+		t.Errorf("%d finish hooks, want the slow threshold's", len(c.finishHooks))
+	}
+}
+
+func TestNewConfigFromEnvUnset(t *testing.T) {
+	opt, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newHandlerConfig([]HandlerOption{opt})
+	if c.disabled != nil || c.filter != nil || c.propagators != nil || len(c.finishHooks) != 0 {
+		t.Error("unset variables changed the defaults")
+	}
+}
+
+func TestNewConfigFromEnvErrors(t *testing.T) {
+	for name, value := range map[string]string{
+		EnvDisabled:         "sometimes",
+		EnvExcludeProbes:    "yes please",
+		EnvPropagation:      "b3,jaeger",
+		EnvBodyCaptureLimit: "-1",
+		EnvSlowThreshold:    "500",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := NewConfigFromEnv(); err == nil {
+				t.Errorf("%s=%s accepted", name, value)
+			}
+		})
+	}
+}
+
+func TestEnvPropagationUsesLaterCodec(t *testing.T) {
+	t.Setenv(EnvPropagation, "b3")
+	opt, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newHandlerConfig([]HandlerOption{opt, WithSpanContextCodec(testCodec{})})
+	b3 := c.propagators[0].(PropagatorChain)[0].(B3Propagator)
+	if _, err := b3.Codec.SpanContext(SpanIdentifiers{}); err == errNoCodec {
+		t.Error("the codec given after the environment option wasn't used")
+	}
+}
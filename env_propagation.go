@@ -0,0 +1,61 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/url"
+	"os"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// SpanContextEnv is the environment variable used by InjectIntoEnv and
+// ExtractFromEnv. It holds the TextMap carrier of the span context as a
+// URL-encoded query string, since carrier keys such as uber-trace-id
+// aren't valid variable names.
+const SpanContextEnv = "OT_SPAN_CONTEXT"
+
+// InjectIntoEnv returns the environment variables carrying the context of
+// the span in ctx, to be added to the environment of a subprocess so that
+// it continues the trace with ExtractFromEnv. It returns nil if ctx
+// carries no span or the tracer fails to inject it:
+//
+//	cmd := exec.CommandContext(ctx, "worker")
+//	cmd.Env = append(os.Environ(), opentracing_helpers.InjectIntoEnv(ctx)...)
+//	err := opentracing_helpers.TraceCommand(ctx, cmd)
+func InjectIntoEnv(ctx context.Context) []string {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+	carrier := opentracing.TextMapCarrier{}
+	if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, carrier); err != nil || len(carrier) == 0 {
+		return nil
+	}
+	values := url.Values{}
+	for k, v := range carrier {
+		values.Set(k, v)
+	}
+	return []string{SpanContextEnv + "=" + values.Encode()}
+}
+
+// ExtractFromEnv extracts the span context passed by a parent process
+// with InjectIntoEnv, with the global tracer, or fails with
+// opentracing.ErrSpanContextNotFound if there is none:
+//
+//	sc, _ := opentracing_helpers.ExtractFromEnv()
+//	span := opentracing.StartSpan("worker", opentracing.ChildOf(sc))
+func ExtractFromEnv() (opentracing.SpanContext, error) {
+	encoded := os.Getenv(SpanContextEnv)
+	if encoded == "" {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	carrier := opentracing.TextMapCarrier{}
+	for k := range values {
+		carrier[k] = values.Get(k)
+	}
+	return opentracing.GlobalTracer().Extract(opentracing.TextMap, carrier)
+}
@@ -0,0 +1,50 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestInjectIntoEnv(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	span := tracer.StartSpan("spawn")
+	span.SetBaggageItem("tenant", "a b&c")
+
+	env := InjectIntoEnv(opentracing.ContextWithSpan(context.Background(), span))
+	if len(env) != 1 || !strings.HasPrefix(env[0], SpanContextEnv+"=") {
+		t.Fatalf("InjectIntoEnv = %q, want a single %s variable", env, SpanContextEnv)
+	}
+	t.Setenv(SpanContextEnv, strings.TrimPrefix(env[0], SpanContextEnv+"="))
+
+	sc, err := ExtractFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := sc.(mocktracer.MockSpanContext)
+	if got.SpanID != span.Context().(mocktracer.MockSpanContext).SpanID || got.Baggage["tenant"] != "a b&c" {
+		t.Errorf("extracted %+v, want the injected span context", got)
+	}
+}
+
+func TestInjectIntoEnvWithoutSpan(t *testing.T) {
+	if env := InjectIntoEnv(context.Background()); env != nil {
+		t.Errorf("InjectIntoEnv without a span = %q, want nil", env)
+	}
+}
+
+func TestExtractFromEnvErrors(t *testing.T) {
+	t.Setenv(SpanContextEnv, "")
+	if _, err := ExtractFromEnv(); err != opentracing.ErrSpanContextNotFound {
+		t.Errorf("unset: %v, want ErrSpanContextNotFound", err)
+	}
+	t.Setenv(SpanContextEnv, "%zz")
+	if _, err := ExtractFromEnv(); err != opentracing.ErrSpanContextCorrupted {
+		t.Errorf("malformed: %v, want ErrSpanContextCorrupted", err)
+	}
+}
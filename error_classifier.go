@@ -0,0 +1,60 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// errorKindTag names the category of a failed request.
+const errorKindTag = "error.kind"
+
+// ErrorClassifier decides whether a request failed, given its response
+// status, or 0 if no response was received, and the error returned by the
+// round trip, which is always nil on the server. A non-empty errorKind is
+// tagged as error.kind.
+type ErrorClassifier func(status int, err error) (isError bool, errorKind string)
+
+// WithErrorClassifier replaces the default policy of tagging failed round
+// trips and 5xx responses with error=true. For example, an API may not
+// consider 404s errors but want 429s flagged:
+//
+//	opentracing_helpers.WithErrorClassifier(func(status int, err error) (bool, string) {
+//		switch {
+//		case err != nil:
+//			return true, "transport"
+//		case status == http.StatusTooManyRequests:
+//			return true, "rate_limited"
+//		}
+//		return status >= 500, ""
+//	})
+//
+// Panics recovered by WithPanicRecovery are always errors.
+func WithErrorClassifier(f func(status int, err error) (isError bool, errorKind string)) Option {
+	return commonOption(func(c *commonConfig) {
+		c.errorClassifier = f
+	})
+}
+
+// defaultErrorClassifier flags failed round trips and 5xx responses.
+func defaultErrorClassifier(status int, err error) (bool, string) {
+	return err != nil || status >= http.StatusInternalServerError, ""
+}
+
+// tagError classifies the outcome of a request and tags span accordingly.
+// It reports whether the request failed.
+func (c *commonConfig) tagError(span opentracing.Span, status int, err error) bool {
+	classify := c.errorClassifier
+	if classify == nil {
+		classify = defaultErrorClassifier
+	}
+	isError, kind := classify(status, err)
+	if isError {
+		ext.Error.Set(span, true)
+		if kind != "" {
+			span.SetTag(errorKindTag, kind)
+		}
+	}
+	return isError
+}
@@ -0,0 +1,99 @@
+package opentracing_helpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func classifyRateLimits(status int, err error) (bool, string) {
+	switch {
+	case err != nil:
+		return true, "transport"
+	case status == http.StatusTooManyRequests:
+		return true, "rate_limited"
+	}
+	return status >= http.StatusInternalServerError, ""
+}
+
+func TestWithErrorClassifierServer(t *testing.T) {
+	tests := []struct {
+		status int
+		err    interface{}
+		kind   interface{}
+	}{
+		{http.StatusNotFound, nil, nil},
+		{http.StatusTooManyRequests, true, "rate_limited"},
+		{http.StatusBadGateway, true, nil},
+	}
+	for _, tt := range tests {
+		tracer := mocktracer.New()
+		_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}), WithTracer(tracer), WithErrorClassifier(classifyRateLimits))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		span := finishedSpan(t, tracer)
+		if got := span.Tag("error"); got != tt.err {
+			t.Errorf("status %d: error = %v, want %v", tt.status, got, tt.err)
+		}
+		if got := span.Tag("error.kind"); got != tt.kind {
+			t.Errorf("status %d: error.kind = %v, want %v", tt.status, got, tt.kind)
+		}
+	}
+}
+
+func TestWithErrorClassifierClient(t *testing.T) {
+	tracer := mocktracer.New()
+	failing := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	transport := NewTracedTransport(failing, WithTracer(tracer), WithErrorClassifier(classifyRateLimits))
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Fatal("RoundTrip succeeded, want the transport error")
+	}
+
+	span := finishedSpan(t, tracer)
+	if span.Tag("error") != true || span.Tag("error.kind") != "transport" {
+		t.Errorf("tags = %v, want error=true error.kind=transport", span.Tags())
+	}
+}
+
+func TestWithErrorClassifierNotAnError(t *testing.T) {
+	tracer := mocktracer.New()
+	transport := NewTracedTransport(respond(http.StatusServiceUnavailable, "", nil),
+		WithTracer(tracer), WithErrorClassifier(func(status int, err error) (bool, string) {
+			return false, ""
+		}))
+	resp, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := finishedSpan(t, tracer).Tag("error"); got != nil {
+		t.Errorf("error = %v, want no error tag", got)
+	}
+}
+
+func TestDefaultErrorClassifier(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		want   bool
+	}{
+		{http.StatusOK, nil, false},
+		{http.StatusNotFound, nil, false},
+		{http.StatusInternalServerError, nil, true},
+		{0, errors.New("reset"), true},
+	}
+	for _, tt := range tests {
+		if got, kind := defaultErrorClassifier(tt.status, tt.err); got != tt.want || kind != "" {
+			t.Errorf("defaultErrorClassifier(%d, %v) = %v, %q; want %v", tt.status, tt.err, got, kind, tt.want)
+		}
+	}
+}
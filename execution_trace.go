@@ -0,0 +1,28 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"runtime/trace"
+)
+
+// WithExecutionTraceTasks wraps every traced request in a runtime/trace
+// task named after the server span, with the handler running in a
+// ServeHTTP region, so that Go execution traces line up with distributed
+// traces when investigating latency in depth. The trace ID is logged in
+// the task under the trace_id category. Tasks are only created while an
+// execution trace is being collected, for example through
+// /debug/pprof/trace.
+func WithExecutionTraceTasks() HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.executionTraceTasks = true
+	})
+}
+
+// startTask starts the execution trace task of sr.
+func (c *handlerConfig) startTask(sr *serverRequest) (context.Context, *trace.Task) {
+	ctx, task := trace.NewTask(sr.r.Context(), sr.operationName)
+	if ids, ok := SpanIdentifiersFromContext(ctx, c.codec); ok {
+		trace.Log(ctx, "trace_id", ids.TraceID)
+	}
+	return ctx, task
+}
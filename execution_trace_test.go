@@ -0,0 +1,72 @@
+package opentracing_helpers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"runtime/trace"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestWithExecutionTraceTasks(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("an execution trace is already being collected")
+	}
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/reports/", okHandler, WithTracer(tracer), WithExecutionTraceTasks())
+
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Fatal(err)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reports/", nil))
+	trace.Stop()
+
+	span := finishedSpan(t, tracer)
+	for _, name := range []string{span.OperationName, "ServeHTTP"} {
+		if !bytes.Contains(buf.Bytes(), []byte(name)) {
+			t.Errorf("no %q task or region in the execution trace", name)
+		}
+	}
+}
+
+func TestExecutionTraceTasksWithProfilerLabels(t *testing.T) {
+	tracer := mocktracer.New()
+	labels := map[string]string{}
+	_, h := TraceHandler("/", labelHandler(labels), WithTracer(tracer), WithExecutionTraceTasks(), WithProfilerLabels())
+
+	if !trace.IsEnabled() {
+		var buf bytes.Buffer
+		if err := trace.Start(&buf); err != nil {
+			t.Fatal(err)
+		}
+		defer trace.Stop()
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if span := finishedSpan(t, tracer); labels["operation"] != span.OperationName {
+		t.Errorf("operation label %q, want %q", labels["operation"], span.OperationName)
+	}
+}
+
+func TestRouteTaggedWithinExecutionTraceTasks(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("an execution trace is already being collected")
+	}
+	tracer := mocktracer.New()
+	mux := http.NewServeMux()
+	mux.Handle("GET /items/{id}", okHandler)
+	h := NewMiddleware(WithTracer(tracer), WithProfilerLabels(), WithExecutionTraceTasks())(mux)
+
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Fatal(err)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/items/42", nil))
+	trace.Stop()
+
+	if got := finishedSpan(t, tracer).Tag("http.route"); got != "GET /items/{id}" {
+		t.Errorf("http.route = %v, want GET /items/{id}", got)
+	}
+}
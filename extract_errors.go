@@ -0,0 +1,42 @@
+package opentracing_helpers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// WithExtractErrorHandler registers a function that is called when a
+// request carries a span context that can't be extracted, for example
+// because of corrupted headers or mismatched propagation formats between
+// services. The server span then starts a new trace. Requests without
+// any span context aren't reported. Failures are also counted in
+// Stats.ExtractionFailures.
+func WithExtractErrorHandler(f func(err error, r *http.Request)) HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.extractErrorHandler = f
+	})
+}
+
+// WithExtractFailedTag sets the trace.extract_failed tag on server spans
+// whose incoming span context couldn't be extracted, so that broken
+// propagation shows up as unexpected root spans that can be searched for.
+func WithExtractFailedTag() HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.extractFailedTag = true
+	})
+}
+
+// extractFailed reports whether err is a failure to extract the span
+// context of r, as opposed to its absence, calling the extract error
+// handler if so.
+func (c *handlerConfig) extractFailed(err error, r *http.Request) bool {
+	if err == nil || errors.Is(err, opentracing.ErrSpanContextNotFound) {
+		return false
+	}
+	if c.extractErrorHandler != nil {
+		c.extractErrorHandler(err, r)
+	}
+	return true
+}
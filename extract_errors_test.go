@@ -0,0 +1,57 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestWithExtractErrorHandler(t *testing.T) {
+	tracer := mocktracer.New()
+	var reported error
+	var reportedPath string
+	_, h := TraceHandler("/", okHandler,
+		WithTracer(corruptTracer{tracer}),
+		WithExtractErrorHandler(func(err error, r *http.Request) {
+			reported, reportedPath = err, r.URL.Path
+		}),
+		WithExtractFailedTag())
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders", nil))
+
+	if reported != opentracing.ErrSpanContextCorrupted || reportedPath != "/orders" {
+		t.Errorf("reported %v for %q, want ErrSpanContextCorrupted for /orders", reported, reportedPath)
+	}
+	span := finishedSpan(t, tracer)
+	if span.Tag("trace.extract_failed") != true || span.ParentID != 0 {
+		t.Errorf("span with parent %d tagged %v, want a tagged root span", span.ParentID, span.Tags())
+	}
+}
+
+func TestExtractErrorHandlerIgnoresMissingContext(t *testing.T) {
+	tracer := mocktracer.New()
+	called := false
+	_, h := TraceHandler("/", okHandler,
+		WithTracer(tracer),
+		WithExtractErrorHandler(func(err error, r *http.Request) { called = true }),
+		WithExtractFailedTag())
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if called {
+		t.Error("the handler was called for a request without a span context")
+	}
+	if span := finishedSpan(t, tracer); span.Tag("trace.extract_failed") != nil {
+		t.Error("a request without a span context was tagged as failed")
+	}
+}
+
+func TestExtractFailedTagNotSetByDefault(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", okHandler, WithTracer(corruptTracer{tracer}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if span := finishedSpan(t, tracer); span.Tag("trace.extract_failed") != nil {
+		t.Error("trace.extract_failed set without WithExtractFailedTag")
+	}
+}
@@ -0,0 +1,278 @@
+// Package fasthttp traces requests served and sent with valyala/fasthttp,
+// which doesn't use net/http types and so can't be instrumented with the
+// root package:
+//
+//	handler := otfasthttp.Middleware(router.Handler)
+//	fasthttp.ListenAndServe(":8080", handler)
+//
+//	client := otfasthttp.NewClient(&fasthttp.Client{})
+//	err := client.Do(ctx, req, resp)
+//
+// Both accept the root package's Options, see opentracing_helpers.Settings
+// for the ones honored. Filters, OperationNameFuncs and URL scrubbers are
+// passed a net/http copy of the request's method, URL and headers, whose
+// context is the *fasthttp.RequestCtx on the server, see RequestCtx, and
+// the context passed to Client.Do on the client.
+package fasthttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/valyala/fasthttp"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "fasthttp"}
+
+// spanKey is the user value under which Middleware stores the server span.
+const spanKey = "opentracing_helpers.span"
+
+// RequestHeaderCarrier satisfies both opentracing.TextMapWriter and
+// opentracing.TextMapReader for fasthttp request headers:
+//
+//	tracer.Inject(span.Context(), opentracing.HTTPHeaders, otfasthttp.RequestHeaderCarrier{&req.Header})
+type RequestHeaderCarrier struct {
+	Header *fasthttp.RequestHeader
+}
+
+// Set conforms to the TextMapWriter interface.
+func (c RequestHeaderCarrier) Set(key, val string) {
+	c.Header.Set(key, val)
+}
+
+// ForeachKey conforms to the TextMapReader interface.
+func (c RequestHeaderCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range c.Header.All() {
+		if err := handler(string(k), string(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResponseHeaderCarrier satisfies both opentracing.TextMapWriter and
+// opentracing.TextMapReader for fasthttp response headers.
+type ResponseHeaderCarrier struct {
+	Header *fasthttp.ResponseHeader
+}
+
+// Set conforms to the TextMapWriter interface.
+func (c ResponseHeaderCarrier) Set(key, val string) {
+	c.Header.Set(key, val)
+}
+
+// ForeachKey conforms to the TextMapReader interface.
+func (c ResponseHeaderCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range c.Header.All() {
+		if err := handler(string(k), string(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Middleware returns a fasthttp.RequestHandler that starts a server span
+// for every request, as a child of the span context extracted from the
+// request headers, before calling next. Spans are named after the request
+// method unless opts include opentracing_helpers.WithOperationNameFunc,
+// since raw paths make for too many distinct operations. The span can be
+// retrieved in next with SpanFromRequestCtx or Context. Panics raised by
+// next are logged on the span, which is finished before they propagate.
+func Middleware(next fasthttp.RequestHandler, opts ...opentracing_helpers.Option) fasthttp.RequestHandler {
+	s := opentracing_helpers.NewSettings(opts...)
+	return func(ctx *fasthttp.RequestCtx) {
+		if s.Noop() {
+			next(ctx)
+			return
+		}
+		r := httpRequest(ctx, &ctx.Request)
+		if !s.Traced(r) {
+			next(ctx)
+			return
+		}
+		parentSpanContext, _ := s.Extract(r.Header)
+
+		span := s.Tracer().StartSpan(
+			s.OperationName(r, r.Method),
+			ext.RPCServerOption(parentSpanContext),
+			componentTag,
+		)
+		defer func() {
+			if p := recover(); p != nil {
+				s.LogPanic(span, p)
+				span.Finish()
+				panic(p)
+			}
+			span.Finish()
+		}()
+		ext.HTTPMethod.Set(span, r.Method)
+		ext.HTTPUrl.Set(span, s.URLTag(r.URL))
+		ext.PeerAddress.Set(span, ctx.RemoteAddr().String())
+
+		ctx.SetUserValue(spanKey, span)
+		next(ctx)
+
+		status := ctx.Response.StatusCode()
+		ext.HTTPStatusCode.Set(span, uint16(status))
+		if size, ok := responseSize(&ctx.Response); ok {
+			span.SetTag("http.response_size", size)
+		}
+		s.TagError(span, status, nil)
+	}
+}
+
+// responseSize returns the size of the body of resp, preferring its
+// Content-Length when set. Streamed bodies are only known that way, since
+// Body would drain the stream before fasthttp writes it, so chunked ones
+// report false.
+func responseSize(resp *fasthttp.Response) (int, bool) {
+	if n := resp.Header.ContentLength(); n > 0 || resp.IsBodyStream() {
+		return n, n >= 0
+	}
+	return len(resp.Body()), true
+}
+
+// RequestCtx returns the *fasthttp.RequestCtx of a request passed to the
+// parent package's options by Middleware, or nil. OperationNameFuncs can
+// use it to read the route matched by a router:
+//
+//	otfasthttp.Middleware(r.Handler, opentracing_helpers.WithOperationNameFunc(func(r *http.Request) string {
+//	    route, _ := otfasthttp.RequestCtx(r).UserValue(router.MatchedRoutePathParam).(string)
+//	    return r.Method + " " + route
+//	}))
+func RequestCtx(r *http.Request) *fasthttp.RequestCtx {
+	ctx, _ := r.Context().(*fasthttp.RequestCtx)
+	return ctx
+}
+
+// httpRequest returns a net/http copy of the method, URL and headers of
+// req, using ctx as its context.
+func httpRequest(ctx context.Context, req *fasthttp.Request) *http.Request {
+	uri := req.URI()
+	h := make(http.Header)
+	for k, v := range req.Header.All() {
+		h.Add(string(k), string(v))
+	}
+	r := &http.Request{
+		Method: string(req.Header.Method()),
+		URL: &url.URL{
+			Scheme:   string(uri.Scheme()),
+			Host:     string(uri.Host()),
+			Path:     string(uri.Path()),
+			RawQuery: string(uri.QueryString()),
+		},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     h,
+		Host:       string(uri.Host()),
+	}
+	return r.WithContext(ctx)
+}
+
+// SpanFromRequestCtx returns the server span started by Middleware, or
+// nil.
+func SpanFromRequestCtx(ctx *fasthttp.RequestCtx) opentracing.Span {
+	span, _ := ctx.UserValue(spanKey).(opentracing.Span)
+	return span
+}
+
+// Context returns a context.Context carrying the server span of ctx, as
+// expected by opentracing.SpanFromContext and the rest of this module.
+// Since the RequestCtx is recycled once the handler returns, the
+// context must not be used afterwards.
+func Context(ctx *fasthttp.RequestCtx) context.Context {
+	if span := SpanFromRequestCtx(ctx); span != nil {
+		return opentracing.ContextWithSpan(ctx, span)
+	}
+	return ctx
+}
+
+// Doer is implemented by fasthttp.Client, fasthttp.HostClient and
+// fasthttp.PipelineClient.
+type Doer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+}
+
+// Client sends fasthttp requests within client spans.
+type Client struct {
+	doer     Doer
+	settings *opentracing_helpers.Settings
+}
+
+// NewClient returns a Client sending requests with doer. Spans are named
+// "HTTP METHOD" unless opts include
+// opentracing_helpers.WithOperationNameFunc.
+func NewClient(doer Doer, opts ...opentracing_helpers.Option) *Client {
+	return &Client{doer: doer, settings: opentracing_helpers.NewSettings(opts...)}
+}
+
+// Do sends req within a client span that is a child of the span in ctx,
+// injecting the span context into the request headers. Unlike
+// net/http, fasthttp reads the whole response body before returning, so
+// the span is finished when Do returns.
+func (c *Client) Do(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response) error {
+	s := c.settings
+	if s.Noop() {
+		return c.doer.Do(req, resp)
+	}
+	r := httpRequest(ctx, req)
+	if !s.Traced(r) {
+		return c.doer.Do(req, resp)
+	}
+	var parent opentracing.SpanContext
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		parent = span.Context()
+	}
+	span := s.Tracer().StartSpan(
+		s.OperationName(r, "HTTP "+r.Method),
+		opentracing.ChildOf(parent),
+		ext.SpanKindRPCClient,
+		componentTag,
+	)
+	defer span.Finish()
+	ext.HTTPMethod.Set(span, r.Method)
+	ext.HTTPUrl.Set(span, s.URLTag(r.URL))
+	host := r.URL.Host
+	if h, p, ok := splitHostPort(host); ok {
+		ext.PeerHostname.Set(span, h)
+		ext.PeerPort.Set(span, p)
+	} else {
+		ext.PeerHostname.Set(span, host)
+	}
+
+	if err := s.InjectTextMap(span.Context(), RequestHeaderCarrier{&req.Header}); err != nil {
+		span.LogFields(log.String("event", "inject failed"), log.Error(err))
+	}
+
+	if err := c.doer.Do(req, resp); err != nil {
+		s.TagError(span, 0, err)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+		return err
+	}
+	status := resp.StatusCode()
+	ext.HTTPStatusCode.Set(span, uint16(status))
+	s.TagError(span, status, nil)
+	return nil
+}
+
+// splitHostPort splits a "host:port" URI host, reporting false if it has
+// no numeric port.
+func splitHostPort(hostport string) (string, uint16, bool) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", 0, false
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return "", 0, false
+	}
+	return host, uint16(p), true
+}
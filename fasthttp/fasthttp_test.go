@@ -0,0 +1,238 @@
+package fasthttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/valyala/fasthttp"
+)
+
+// requestCtx returns a RequestCtx for a request to uri.
+func requestCtx(method, uri string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}, nil)
+	return ctx
+}
+
+func TestMiddleware(t *testing.T) {
+	tracer := mocktracer.New()
+	client := tracer.StartSpan("client")
+	ctx := requestCtx(fasthttp.MethodGet, "http://example.com/items?token=secret")
+	tracer.Inject(client.Context(), opentracing.HTTPHeaders, RequestHeaderCarrier{&ctx.Request.Header})
+
+	var inHandler opentracing.Span
+	h := Middleware(func(ctx *fasthttp.RequestCtx) {
+		inHandler = opentracing.SpanFromContext(Context(ctx))
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString("down")
+	}, opentracing_helpers.WithTracer(tracer))
+	h(ctx)
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("%d finished spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if inHandler == nil || inHandler.Context().(mocktracer.MockSpanContext).SpanID != span.SpanContext.SpanID {
+		t.Error("the handler context doesn't carry the server span")
+	}
+	if span.OperationName != "GET" || span.ParentID != client.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Errorf("span %q with parent %d, want GET under the client span", span.OperationName, span.ParentID)
+	}
+	for key, want := range map[string]interface{}{
+		"span.kind":          ext.SpanKindRPCServerEnum,
+		"component":          "fasthttp",
+		"http.method":        "GET",
+		"http.url":           "http://example.com/items",
+		"peer.address":       "10.0.0.1:1234",
+		"http.status_code":   uint16(fasthttp.StatusServiceUnavailable),
+		"http.response_size": 4,
+		"error":              true,
+	} {
+		if got := span.Tag(key); got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestSpanFromRequestCtxWithoutMiddleware(t *testing.T) {
+	ctx := requestCtx(fasthttp.MethodGet, "/")
+	if span := SpanFromRequestCtx(ctx); span != nil {
+		t.Errorf("SpanFromRequestCtx = %v, want nil", span)
+	}
+	if span := opentracing.SpanFromContext(Context(ctx)); span != nil {
+		t.Errorf("Context carries %v, want no span", span)
+	}
+}
+
+type doerFunc func(req *fasthttp.Request, resp *fasthttp.Response) error
+
+func (f doerFunc) Do(req *fasthttp.Request, resp *fasthttp.Response) error { return f(req, resp) }
+
+func TestClient(t *testing.T) {
+	tracer := mocktracer.New()
+	var injected opentracing.SpanContext
+	client := NewClient(doerFunc(func(req *fasthttp.Request, resp *fasthttp.Response) error {
+		injected, _ = tracer.Extract(opentracing.HTTPHeaders, RequestHeaderCarrier{&req.Header})
+		resp.SetStatusCode(fasthttp.StatusOK)
+		return nil
+	}), opentracing_helpers.WithTracer(tracer), opentracing_helpers.WithOperationNameFunc(func(r *http.Request) string {
+		return "list " + r.URL.Path
+	}))
+
+	parent := tracer.StartSpan("request")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI("http://api.example.com:8080/items")
+	if err := client.Do(ctx, req, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	span := tracer.FinishedSpans()[0]
+	if span.OperationName != "list /items" || span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Errorf("span %q with parent %d, want list /items under the context span", span.OperationName, span.ParentID)
+	}
+	if sc, ok := injected.(mocktracer.MockSpanContext); !ok || sc.SpanID != span.SpanContext.SpanID {
+		t.Errorf("injected span context %v, want the client span's", injected)
+	}
+	for key, want := range map[string]interface{}{
+		"span.kind":        ext.SpanKindRPCClientEnum,
+		"peer.hostname":    "api.example.com",
+		"peer.port":        uint16(8080),
+		"http.status_code": uint16(fasthttp.StatusOK),
+	} {
+		if got := span.Tag(key); got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestClientError(t *testing.T) {
+	tracer := mocktracer.New()
+	client := NewClient(doerFunc(func(req *fasthttp.Request, resp *fasthttp.Response) error {
+		return errors.New("connection refused")
+	}), opentracing_helpers.WithTracer(tracer))
+	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI("http://api.example.com/items")
+	if err := client.Do(context.Background(), req, resp); err == nil {
+		t.Fatal("Do succeeded, want the transport error")
+	}
+
+	span := tracer.FinishedSpans()[0]
+	if span.OperationName != "HTTP GET" {
+		t.Errorf("span named %q, want HTTP GET", span.OperationName)
+	}
+	if span.Tag("error") != true || span.Tag("peer.hostname") != "api.example.com" || span.Tag("peer.port") != nil {
+		t.Errorf("span tagged %v", span.Tags())
+	}
+}
+
+func TestMiddlewareOptions(t *testing.T) {
+	tracer := mocktracer.New()
+	var seen *fasthttp.RequestCtx
+	h := Middleware(func(ctx *fasthttp.RequestCtx) {}, opentracing_helpers.WithTracer(tracer),
+		opentracing_helpers.WithFilter(func(r *http.Request) bool { return r.URL.Path != "/healthz" }),
+		opentracing_helpers.WithOperationNameFunc(func(r *http.Request) string {
+			seen = RequestCtx(r)
+			return r.Method + " /users/{id}"
+		}))
+
+	h(requestCtx(fasthttp.MethodGet, "/healthz"))
+	if spans := tracer.FinishedSpans(); len(spans) != 0 {
+		t.Fatalf("filtered request traced as %v", spans)
+	}
+	ctx := requestCtx(fasthttp.MethodGet, "/users/42")
+	h(ctx)
+	if seen != ctx {
+		t.Error("RequestCtx didn't return the request's RequestCtx")
+	}
+	if spans := tracer.FinishedSpans(); len(spans) != 1 || spans[0].OperationName != "GET /users/{id}" {
+		t.Errorf("finished spans %v, want one GET /users/{id}", spans)
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	tracer := mocktracer.New()
+	h := Middleware(func(ctx *fasthttp.RequestCtx) {
+		panic("boom")
+	}, opentracing_helpers.WithTracer(tracer))
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the handler's panic", p)
+			}
+		}()
+		h(requestCtx(fasthttp.MethodGet, "/"))
+	}()
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("%d finished spans, want 1", len(spans))
+	}
+	if spans[0].Tag("error") != true {
+		t.Error("span not tagged as failed")
+	}
+	logs := spans[0].Logs()
+	if len(logs) != 1 || logs[0].Fields[0].ValueString != "panic" || logs[0].Fields[1].ValueString != "boom" {
+		t.Errorf("span logs %v, want the panic", logs)
+	}
+}
+
+func TestMiddlewareResponseSize(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		handler fasthttp.RequestHandler
+		want    interface{}
+	}{
+		{"body", func(ctx *fasthttp.RequestCtx) { ctx.SetBodyString("hello") }, 5},
+		{"stream", func(ctx *fasthttp.RequestCtx) {
+			ctx.SetBodyStream(strings.NewReader("streamed"), 8)
+		}, 8},
+		{"chunked stream", func(ctx *fasthttp.RequestCtx) {
+			ctx.SetBodyStream(strings.NewReader("streamed"), -1)
+		}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			ctx := requestCtx(fasthttp.MethodGet, "/")
+			Middleware(tt.handler, opentracing_helpers.WithTracer(tracer))(ctx)
+
+			if got := tracer.FinishedSpans()[0].Tag("http.response_size"); got != tt.want {
+				t.Errorf("http.response_size = %v, want %v", got, tt.want)
+			}
+			if ctx.Response.IsBodyStream() != (tt.name != "body") {
+				t.Error("the response body stream was read")
+			}
+		})
+	}
+}
+
+func TestResponseHeaderCarrier(t *testing.T) {
+	var h fasthttp.ResponseHeader
+	carrier := ResponseHeaderCarrier{&h}
+	carrier.Set("Trace-Id", "abc")
+	got := map[string]string{}
+	carrier.ForeachKey(func(key, val string) error {
+		got[key] = val
+		return nil
+	})
+	if got["Trace-Id"] != "abc" {
+		t.Errorf("read headers %v, want Trace-Id=abc", got)
+	}
+}
@@ -0,0 +1,52 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"runtime"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// TraceFunc starts a child of the span in ctx named after the function
+// calling it, such as "orders.(*Service).Create", which removes
+// boilerplate when instrumenting internal call paths:
+//
+//	func (s *Service) Create(ctx context.Context, o Order) error {
+//	    ctx, span := opentracing_helpers.TraceFunc(ctx)
+//	    defer span.Finish()
+//	    ...
+//	}
+//
+// The span is also tagged with the caller's fully qualified name, file and
+// line.
+func TraceFunc(ctx context.Context) (context.Context, opentracing.Span) {
+	name, file, line := "unknown", "", 0
+	// CallersFrames, unlike FuncForPC, reports the caller rather than the
+	// function it was inlined into.
+	pcs := make([]uintptr, 1)
+	if runtime.Callers(2, pcs) == 1 {
+		frame, _ := runtime.CallersFrames(pcs).Next()
+		if frame.Function != "" {
+			name = frame.Function
+		}
+		file, line = frame.File, frame.Line
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, shortFuncName(name))
+	span.SetTag("code.function", name)
+	if file != "" {
+		span.SetTag("code.filepath", file)
+		span.SetTag("code.lineno", line)
+	}
+	return ctx, span
+}
+
+// shortFuncName strips the import path from a fully qualified function
+// name, leaving the package name, for example "orders.(*Service).Create".
+func shortFuncName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
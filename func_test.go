@@ -0,0 +1,71 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+type service struct{}
+
+func (*service) create(ctx context.Context) opentracing.Span {
+	_, span := TraceFunc(ctx)
+	span.Finish()
+	return span
+}
+
+func TestTraceFunc(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+	parent := tracer.StartSpan("request")
+
+	span := new(service).create(opentracing.ContextWithSpan(context.Background(), parent)).(*mocktracer.MockSpan)
+	if want := "opentracing-helpers.(*service).create"; span.OperationName != want {
+		t.Errorf("operation name = %q, want %q", span.OperationName, want)
+	}
+	if want := "github.com/jfernandez/opentracing-helpers.(*service).create"; span.Tag("code.function") != want {
+		t.Errorf("code.function = %v, want %q", span.Tag("code.function"), want)
+	}
+	if file, _ := span.Tag("code.filepath").(string); filepath.Base(file) != "func_test.go" {
+		t.Errorf("code.filepath = %q", file)
+	}
+	if span.Tag("code.lineno") != 15 {
+		t.Errorf("code.lineno = %v, want 15", span.Tag("code.lineno"))
+	}
+	if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the span is not a child of the span in ctx")
+	}
+}
+
+func TestShortFuncName(t *testing.T) {
+	for name, want := range map[string]string{
+		"github.com/acme/orders.(*Service).Create": "orders.(*Service).Create",
+		"main.main":                           "main.main",
+		"github.com/acme/orders.Create.func1": "orders.Create.func1",
+	} {
+		if got := shortFuncName(name); got != want {
+			t.Errorf("shortFuncName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// inlinedCaller is small enough to be inlined into its callers.
+func inlinedCaller(ctx context.Context) opentracing.Span {
+	_, span := TraceFunc(ctx)
+	return span
+}
+
+func TestTraceFuncInlinedCaller(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	span := inlinedCaller(context.Background()).(*mocktracer.MockSpan)
+	if want := "opentracing-helpers.inlinedCaller"; span.OperationName != want {
+		t.Errorf("operation name = %q, want %q", span.OperationName, want)
+	}
+}
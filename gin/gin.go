@@ -0,0 +1,119 @@
+// Package gin traces requests served by gin-gonic/gin. Spans are named
+// after the matched route, for example "GET /users/:id":
+//
+//	r := gin.New()
+//	r.Use(otgin.Middleware())
+package gin
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// spanKey is the gin context key the server span is stored under.
+const spanKey = "opentracing-helpers.span"
+
+// contextKey is the request context key the gin context is stored under
+// while the parent package's middleware runs.
+type contextKey struct{}
+
+// Middleware returns a gin.HandlerFunc that starts a server span for every
+// request. opts are passed to the parent package and may override the span
+// name. The span is stored in the gin context, see SpanFromContext, and in
+// the context of c.Request. Errors attached to the gin context with
+// c.Error are logged on the span.
+func Middleware(opts ...opentracing_helpers.HandlerOption) gin.HandlerFunc {
+	opts = append([]opentracing_helpers.HandlerOption{
+		opentracing_helpers.WithOperationNameFunc(operationName),
+		opentracing_helpers.WithSpanObserver(tagComponent),
+	}, opts...)
+	traced := opentracing_helpers.NewMiddleware(opts...)(http.HandlerFunc(serve))
+	return func(c *gin.Context) {
+		r := c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, c))
+		traced.ServeHTTP(c.Writer, r)
+	}
+}
+
+// serve runs the rest of the gin handler chain behind the parent package's
+// middleware, writing the response through w so that it is recorded.
+func serve(w http.ResponseWriter, r *http.Request) {
+	c := ginContext(r)
+	span := opentracing.SpanFromContext(r.Context())
+	if span != nil {
+		c.Set(spanKey, span)
+	}
+	c.Request = r
+	opentracing_helpers.SetRoute(r, c.FullPath())
+	c.Writer = &responseWriter{ResponseWriter: c.Writer, w: w}
+
+	c.Next()
+
+	if span != nil && len(c.Errors) > 0 {
+		ext.Error.Set(span, true)
+		for _, err := range c.Errors {
+			span.LogFields(log.String("event", "error"), log.Error(err.Err))
+		}
+	}
+}
+
+// responseWriter is a gin.ResponseWriter that writes through the
+// ResponseWriter of the parent package's middleware, which wraps the
+// original gin.ResponseWriter.
+type responseWriter struct {
+	gin.ResponseWriter
+	w http.ResponseWriter
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	rw.w.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	return rw.w.Write(b)
+}
+
+func (rw *responseWriter) WriteString(s string) (int, error) {
+	return io.WriteString(rw.w, s)
+}
+
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// SpanFromContext returns the span started by Middleware, or nil.
+func SpanFromContext(c *gin.Context) opentracing.Span {
+	if v, ok := c.Get(spanKey); ok {
+		if span, ok := v.(opentracing.Span); ok {
+			return span
+		}
+	}
+	return nil
+}
+
+func ginContext(r *http.Request) *gin.Context {
+	c, _ := r.Context().Value(contextKey{}).(*gin.Context)
+	return c
+}
+
+func operationName(r *http.Request) string {
+	if c := ginContext(r); c != nil {
+		if route := c.FullPath(); route != "" {
+			return r.Method + " " + route
+		}
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+// tagComponent tags span with the gin component.
+func tagComponent(span opentracing.Span, r *http.Request) {
+	ext.Component.Set(span, "gin")
+}
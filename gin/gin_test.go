@@ -0,0 +1,141 @@
+package gin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// serveGin sends a GET request for path to a gin engine traced with tracer
+// and routing pattern to handler.
+func serveGin(tracer opentracing.Tracer, pattern, path string, handler gin.HandlerFunc) *mocktracer.MockSpan {
+	r := gin.New()
+	r.Use(Middleware(opentracing_helpers.WithTracer(tracer)))
+	r.GET(pattern, handler)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	spans := tracer.(*mocktracer.MockTracer).FinishedSpans()
+	if len(spans) != 1 {
+		return nil
+	}
+	return spans[0]
+}
+
+func TestMiddleware(t *testing.T) {
+	tracer := mocktracer.New()
+	var fromGin, fromRequest opentracing.Span
+	span := serveGin(tracer, "/users/:id", "/users/42", func(c *gin.Context) {
+		fromGin = SpanFromContext(c)
+		fromRequest = opentracing.SpanFromContext(c.Request.Context())
+		c.String(http.StatusCreated, "created")
+	})
+	if span == nil {
+		t.Fatalf("got %d spans, want 1", len(tracer.FinishedSpans()))
+	}
+
+	if span.OperationName != "GET /users/:id" {
+		t.Errorf("operation name = %q, want %q", span.OperationName, "GET /users/:id")
+	}
+	for key, want := range map[string]interface{}{
+		"component":          "gin",
+		"http.route":         "/users/:id",
+		"http.status_code":   uint16(http.StatusCreated),
+		"http.response_size": int64(7),
+	} {
+		if got := span.Tag(key); got != want {
+			t.Errorf("%s = %v (%T), want %v (%T)", key, got, got, want, want)
+		}
+	}
+	for name, got := range map[string]opentracing.Span{"SpanFromContext": fromGin, "the request context": fromRequest} {
+		if got == nil || got.Context().(mocktracer.MockSpanContext).SpanID != span.SpanContext.SpanID {
+			t.Errorf("%s doesn't carry the server span", name)
+		}
+	}
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	tracer := mocktracer.New()
+	span := serveGin(tracer, "/", "/", func(c *gin.Context) {
+		c.Error(errors.New("validation failed"))
+		c.Status(http.StatusBadRequest)
+	})
+	if span.Tag("error") != true {
+		t.Error("the span is not tagged as an error")
+	}
+	if len(span.Logs()) == 0 {
+		t.Error("the gin error was not logged")
+	}
+}
+
+func TestMiddlewareServerError(t *testing.T) {
+	tracer := mocktracer.New()
+	span := serveGin(tracer, "/", "/", func(c *gin.Context) {
+		c.Status(http.StatusBadGateway)
+	})
+	if span.Tag("error") != true {
+		t.Error("a 502 response is not tagged as an error")
+	}
+}
+
+func TestSpanFromContextWithoutMiddleware(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if span := SpanFromContext(c); span != nil {
+		t.Errorf("SpanFromContext = %v, want nil", span)
+	}
+}
+
+func TestMiddlewareOptions(t *testing.T) {
+	tracer := mocktracer.New()
+	r := gin.New()
+	r.Use(Middleware(
+		opentracing_helpers.WithTracer(tracer),
+		opentracing_helpers.WithOperationNameFunc(func(r *http.Request) string { return "custom" }),
+	))
+	r.GET("/", func(c *gin.Context) {})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if spans := tracer.FinishedSpans(); len(spans) != 1 || spans[0].OperationName != "custom" {
+		t.Errorf("finished spans %v, want one named custom", spans)
+	}
+}
+
+func TestMiddlewareUnmatchedRoute(t *testing.T) {
+	tracer := mocktracer.New()
+	r := gin.New()
+	r.Use(Middleware(opentracing_helpers.WithTracer(tracer)))
+	r.NoRoute(func(c *gin.Context) { c.String(http.StatusNotFound, "no such page") })
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	span := tracer.FinishedSpans()[0]
+	if span.OperationName != "GET /missing" || span.Tag("http.route") != nil || span.Tag("http.status_code") != uint16(http.StatusNotFound) {
+		t.Errorf("unmatched request span %q tagged %v", span.OperationName, span.Tags())
+	}
+}
+
+type metricsRecorder []opentracing_helpers.RequestMetrics
+
+func (m *metricsRecorder) ObserveRequest(rm opentracing_helpers.RequestMetrics) {
+	*m = append(*m, rm)
+}
+
+func TestMiddlewareMetricsOperation(t *testing.T) {
+	var metrics metricsRecorder
+	r := gin.New()
+	r.Use(Middleware(opentracing_helpers.WithTracer(mocktracer.New()), opentracing_helpers.WithMetricsObserver(&metrics)))
+	r.GET("/users/:id", func(c *gin.Context) {})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if len(metrics) != 2 || metrics[0].Operation != "GET /users/:id" || metrics[1].Operation != http.MethodGet {
+		t.Errorf("observed %+v, want the route, then the method of the unmatched request", metrics)
+	}
+}
@@ -0,0 +1,62 @@
+// Package gorillamux traces requests routed by gorilla/mux. Spans are
+// named after the matched route template, for example "GET /users/{id}",
+// instead of the request path, and path variables are tagged on the span:
+//
+//	r := mux.NewRouter()
+//	r.Use(gorillamux.Middleware())
+//	r.HandleFunc("/users/{id}", userHandler)
+package gorillamux
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+)
+
+// Middleware returns a mux.MiddlewareFunc that traces requests. opts are
+// passed to the parent package and may override the span name. The route
+// template is recorded with opentracing_helpers.SetRoute, so that it is
+// also the operation of the request's metrics.
+func Middleware(opts ...opentracing_helpers.HandlerOption) mux.MiddlewareFunc {
+	opts = append([]opentracing_helpers.HandlerOption{
+		opentracing_helpers.WithOperationNameFunc(OperationName),
+		opentracing_helpers.WithSpanObserver(tagPathParams),
+	}, opts...)
+	traced := opentracing_helpers.NewMiddleware(opts...)
+	return func(next http.Handler) http.Handler {
+		return traced(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			opentracing_helpers.SetRoute(r, routeTemplate(r))
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// OperationName names a request after the template of the route it
+// matched. It falls back to the request path when no route matched or the
+// route has no path template.
+func OperationName(r *http.Request) string {
+	if tmpl := routeTemplate(r); tmpl != "" {
+		return r.Method + " " + tmpl
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+// routeTemplate returns the path template of the route r matched, or an
+// empty string.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return ""
+}
+
+// tagPathParams tags span with the path variables.
+func tagPathParams(span opentracing.Span, r *http.Request) {
+	for name, value := range mux.Vars(r) {
+		span.SetTag("http.path_param."+name, value)
+	}
+}
@@ -0,0 +1,78 @@
+package gorillamux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestMiddleware(t *testing.T) {
+	tracer := mocktracer.New()
+	r := mux.NewRouter()
+	r.Use(Middleware(opentracing_helpers.WithTracer(tracer)))
+	r.HandleFunc("/users/{id}/orders/{order}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42/orders/7", nil))
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.OperationName != "GET /users/{id}/orders/{order}" {
+		t.Errorf("operation name = %q", span.OperationName)
+	}
+	for key, want := range map[string]string{
+		"http.route":            "/users/{id}/orders/{order}",
+		"http.path_param.id":    "42",
+		"http.path_param.order": "7",
+	} {
+		if got := span.Tag(key); got != want {
+			t.Errorf("%s = %v, want %q", key, got, want)
+		}
+	}
+}
+
+func TestMiddlewareOptionsOverrideName(t *testing.T) {
+	tracer := mocktracer.New()
+	r := mux.NewRouter()
+	r.Use(Middleware(opentracing_helpers.WithTracer(tracer), opentracing_helpers.WithOperationNameFunc(func(r *http.Request) string {
+		return "custom"
+	})))
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	if got := tracer.FinishedSpans()[0].OperationName; got != "custom" {
+		t.Errorf("operation name = %q, want %q", got, "custom")
+	}
+}
+
+type metricsRecorder []opentracing_helpers.RequestMetrics
+
+func (m *metricsRecorder) ObserveRequest(rm opentracing_helpers.RequestMetrics) {
+	*m = append(*m, rm)
+}
+
+func TestMiddlewareMetricsOperation(t *testing.T) {
+	var metrics metricsRecorder
+	r := mux.NewRouter()
+	r.Use(Middleware(opentracing_helpers.WithTracer(mocktracer.New()), opentracing_helpers.WithMetricsObserver(&metrics)))
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	if len(metrics) != 1 || metrics[0].Operation != "GET /users/{id}" {
+		t.Errorf("observed %+v, want the route template as the operation", metrics)
+	}
+}
+
+func TestOperationNameWithoutRoute(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/unrouted", nil)
+	if got := OperationName(r); got != "GET /unrouted" {
+		t.Errorf("OperationName = %q, want %q", got, "GET /unrouted")
+	}
+}
@@ -0,0 +1,50 @@
+package opentracing_helpers
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// Go runs fn in a new goroutine within a span that follows from the span in
+// ctx, making fire-and-forget work visible in the trace. The span is
+// finished when fn returns. The context passed to fn carries the new span
+// and the values of ctx, but isn't canceled when ctx is, since the work
+// usually outlives the request that started it. For example:
+//
+//	opentracing_helpers.Go(r.Context(), "send welcome email", func(ctx context.Context) {
+//	    mailer.Send(ctx, user)
+//	})
+//
+// A panic in fn is recorded on the span before it is propagated.
+func Go(ctx context.Context, operationName string, fn func(ctx context.Context)) {
+	GoWithSpan(ctx, operationName, func(ctx context.Context, _ opentracing.Span) {
+		fn(ctx)
+	})
+}
+
+// GoWithSpan is like Go but also passes the span to fn, so it can be tagged
+// directly.
+func GoWithSpan(ctx context.Context, operationName string, fn func(ctx context.Context, span opentracing.Span)) {
+	span := startFollowsFromSpan(ctx, operationName)
+	ctx = opentracing.ContextWithSpan(context.WithoutCancel(ctx), span)
+	go func() {
+		defer span.Finish()
+		defer func() {
+			if p := recover(); p != nil {
+				logPanic(span, p)
+				panic(p)
+			}
+		}()
+		fn(ctx, span)
+	}()
+}
+
+// startFollowsFromSpan starts a span that follows from the span in ctx,
+// using the tracer of that span, or the global tracer if ctx has no span.
+func startFollowsFromSpan(ctx context.Context, operationName string) opentracing.Span {
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		return parent.Tracer().StartSpan(operationName, opentracing.FollowsFrom(parent.Context()))
+	}
+	return opentracing.GlobalTracer().StartSpan(operationName)
+}
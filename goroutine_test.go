@@ -0,0 +1,66 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// waitForSpan returns the first span finished by tracer, which Go finishes
+// after fn has returned.
+func waitForSpan(t *testing.T, tracer *mocktracer.MockTracer) *mocktracer.MockSpan {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if spans := tracer.FinishedSpans(); len(spans) > 0 {
+			return spans[0]
+		}
+	}
+	t.Fatal("the span was not finished")
+	return nil
+}
+
+func TestGo(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("request")
+	ctx, cancel := context.WithCancel(opentracing.ContextWithSpan(context.Background(), parent))
+	cancel()
+
+	done := make(chan error)
+	Go(ctx, "send email", func(ctx context.Context) {
+		done <- ctx.Err()
+	})
+	if err := <-done; err != nil {
+		t.Errorf("ctx.Err() = %v, want the context to outlive its parent", err)
+	}
+
+	span := waitForSpan(t, tracer)
+	if span.OperationName != "send email" {
+		t.Errorf("operation name = %q", span.OperationName)
+	}
+	if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the span doesn't follow from the span in ctx")
+	}
+}
+
+func TestGoWithSpanUsesGlobalTracer(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	done := make(chan bool)
+	GoWithSpan(context.Background(), "cleanup", func(ctx context.Context, span opentracing.Span) {
+		span.SetTag("files", 3)
+		done <- opentracing.SpanFromContext(ctx) == span
+	})
+	if !<-done {
+		t.Error("the context passed to fn doesn't carry the span")
+	}
+
+	span := waitForSpan(t, tracer)
+	if span.ParentID != 0 || span.Tag("files") != 3 {
+		t.Errorf("span parent = %d, tags %v; want a tagged root span", span.ParentID, span.Tags())
+	}
+}
@@ -0,0 +1,86 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Extension is a gqlgen handler extension that traces operations and
+// resolvers.
+type Extension struct {
+	config *config
+}
+
+var (
+	_ graphql.HandlerExtension     = (*Extension)(nil)
+	_ graphql.OperationInterceptor = (*Extension)(nil)
+	_ graphql.FieldInterceptor     = (*Extension)(nil)
+)
+
+// NewExtension returns an extension to register with the gqlgen server's
+// Use method.
+func NewExtension(opts ...Option) *Extension {
+	return &Extension{config: newConfig(opts)}
+}
+
+// ExtensionName implements graphql.HandlerExtension.
+func (e *Extension) ExtensionName() string {
+	return "OpenTracing"
+}
+
+// Validate implements graphql.HandlerExtension.
+func (e *Extension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptOperation starts the operation span. For queries and mutations
+// the span is finished with the response; subscriptions keep it open until
+// the stream of responses ends.
+func (e *Extension) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	operationType := "query"
+	if oc.Operation != nil {
+		operationType = string(oc.Operation.Operation)
+	}
+	span, ctx := e.config.startSpan(ctx, operationSpanName(operationType, oc.OperationName))
+	span.SetTag("graphql.operation.type", operationType)
+	span.SetTag("graphql.operation.name", oc.OperationName)
+
+	responses := next(ctx)
+	return func(ctx context.Context) *graphql.Response {
+		resp := responses(ctx)
+		if resp != nil {
+			errs := make([]error, len(resp.Errors))
+			for i, err := range resp.Errors {
+				errs[i] = err
+			}
+			logErrors(span, errs)
+		}
+		if resp == nil || operationType != string(ast.Subscription) {
+			span.Finish()
+		}
+		return resp
+	}
+}
+
+// InterceptField traces fields backed by a resolver method. Fields that
+// are plain struct fields are not traced to keep the number of spans low.
+func (e *Extension) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || (!fc.IsMethod && !fc.IsResolver) {
+		return next(ctx)
+	}
+
+	span, ctx := e.config.startSpan(ctx, "graphql resolve "+fc.Object+"."+fc.Field.Name)
+	defer span.Finish()
+	span.SetTag("graphql.field.path", fc.Path().String())
+	e.config.logArguments(span, fc.Field.Name, fc.Args)
+
+	res, err := next(ctx)
+	if err != nil {
+		logErrors(span, []error{err})
+	}
+	return res, err
+}
@@ -0,0 +1,113 @@
+// Package graphql traces GraphQL operations and resolvers. It provides an
+// extension for 99designs/gqlgen and a tracer for graph-gophers/graphql-go.
+// Each operation gets a span, and each resolver a child span tagged with
+// the field path and arguments:
+//
+//	srv := handler.NewDefaultServer(schema)
+//	srv.Use(otgraphql.NewExtension())
+//
+//	schema := graphql.MustParseSchema(sdl, resolver, graphql.Tracer(otgraphql.NewTracer()))
+package graphql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "graphql"}
+
+// Option customizes the spans started by the extension and the tracer.
+type Option func(*config)
+
+type config struct {
+	tracer        opentracing.Tracer
+	argRedactor   func(field, arg string, value interface{}) interface{}
+	omitArguments bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) activeTracer() opentracing.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return opentracing.GlobalTracer()
+}
+
+// WithTracer uses tracer instead of opentracing.GlobalTracer().
+func WithTracer(tracer opentracing.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// WithArgumentRedactor transforms resolver arguments before they are
+// logged, for example to hide passwords. field is the name of the field
+// being resolved.
+func WithArgumentRedactor(f func(field, arg string, value interface{}) interface{}) Option {
+	return func(c *config) {
+		c.argRedactor = f
+	}
+}
+
+// WithoutArguments disables logging of resolver arguments.
+func WithoutArguments() Option {
+	return func(c *config) {
+		c.omitArguments = true
+	}
+}
+
+// startSpan starts a span as a child of the span in ctx.
+func (c *config) startSpan(ctx context.Context, operationName string) (opentracing.Span, context.Context) {
+	var parent opentracing.SpanContext
+	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+		parent = parentSpan.Context()
+	}
+	span := c.activeTracer().StartSpan(operationName, opentracing.ChildOf(parent), componentTag)
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+// logArguments logs the arguments of field on span.
+func (c *config) logArguments(span opentracing.Span, field string, args map[string]interface{}) {
+	if c.omitArguments || len(args) == 0 {
+		return
+	}
+	fields := make([]log.Field, 0, len(args)+1)
+	fields = append(fields, log.String("event", "arguments"))
+	for name, value := range args {
+		if c.argRedactor != nil {
+			value = c.argRedactor(field, name, value)
+		}
+		fields = append(fields, log.String("graphql.argument."+name, fmt.Sprint(value)))
+	}
+	span.LogFields(fields...)
+}
+
+// operationSpanName names the span of an operation, for example
+// "graphql query GetUser".
+func operationSpanName(operationType, operationName string) string {
+	if operationName == "" {
+		return "graphql " + operationType
+	}
+	return "graphql " + operationType + " " + operationName
+}
+
+func logErrors(span opentracing.Span, errs []error) {
+	if len(errs) == 0 {
+		return
+	}
+	ext.Error.Set(span, true)
+	for _, err := range errs {
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+}
@@ -0,0 +1,156 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func operationContext(ctx context.Context, operation ast.Operation, name string) context.Context {
+	return graphql.WithOperationContext(ctx, &graphql.OperationContext{
+		Operation:     &ast.OperationDefinition{Operation: operation, Name: name},
+		OperationName: name,
+	})
+}
+
+func TestExtensionOperation(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	e := NewExtension(WithTracer(tracer))
+
+	var inner opentracing.Span
+	responses := e.InterceptOperation(operationContext(ctx, ast.Mutation, "CreateUser"), func(ctx context.Context) graphql.ResponseHandler {
+		inner = opentracing.SpanFromContext(ctx)
+		return func(context.Context) *graphql.Response {
+			return &graphql.Response{Errors: gqlerror.List{gqlerror.Errorf("email taken")}}
+		}
+	})
+	responses(ctx)
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.OperationName != "graphql mutation CreateUser" {
+		t.Errorf("operation name = %q", span.OperationName)
+	}
+	if inner != opentracing.Span(span) {
+		t.Error("the operation span is not in the context of next")
+	}
+	if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the operation span is not a child of the context span")
+	}
+	if span.Tag("graphql.operation.type") != "mutation" || span.Tag("graphql.operation.name") != "CreateUser" {
+		t.Errorf("tags = %v", span.Tags())
+	}
+	if span.Tag(string(ext.Error)) != true {
+		t.Error("response errors were not recorded")
+	}
+}
+
+func TestExtensionSubscription(t *testing.T) {
+	tracer := mocktracer.New()
+	e := NewExtension(WithTracer(tracer))
+
+	remaining := 2
+	responses := e.InterceptOperation(operationContext(context.Background(), ast.Subscription, ""), func(context.Context) graphql.ResponseHandler {
+		return func(context.Context) *graphql.Response {
+			if remaining == 0 {
+				return nil
+			}
+			remaining--
+			return &graphql.Response{}
+		}
+	})
+	for i := 0; i < 2; i++ {
+		responses(context.Background())
+		if n := len(tracer.FinishedSpans()); n != 0 {
+			t.Fatalf("the subscription span finished after %d responses", i+1)
+		}
+	}
+	responses(context.Background())
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 || spans[0].OperationName != "graphql subscription" {
+		t.Fatalf("got %v, want a single graphql subscription span", spans)
+	}
+}
+
+func TestExtensionField(t *testing.T) {
+	tracer := mocktracer.New()
+	e := NewExtension(WithTracer(tracer), WithArgumentRedactor(func(field, arg string, value interface{}) interface{} {
+		if arg == "password" {
+			return "[redacted]"
+		}
+		return value
+	}))
+
+	field := func(isResolver bool) context.Context {
+		return graphql.WithFieldContext(context.Background(), &graphql.FieldContext{
+			Object:     "Mutation",
+			Field:      graphql.CollectedField{Field: &ast.Field{Name: "login", Alias: "login"}},
+			Args:       map[string]interface{}{"user": "ada", "password": "hunter2"},
+			IsResolver: isResolver,
+		})
+	}
+	resolveErr := errors.New("invalid credentials")
+	resolve := func(context.Context) (interface{}, error) { return nil, resolveErr }
+
+	if _, err := e.InterceptField(field(false), resolve); err != resolveErr {
+		t.Fatalf("err = %v", err)
+	}
+	if n := len(tracer.FinishedSpans()); n != 0 {
+		t.Fatalf("a plain field started %d spans", n)
+	}
+	if _, err := e.InterceptField(field(true), resolve); err != resolveErr {
+		t.Fatalf("err = %v", err)
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.OperationName != "graphql resolve Mutation.login" {
+		t.Errorf("operation name = %q", span.OperationName)
+	}
+	if got := span.Tag("graphql.field.path"); got != "login" {
+		t.Errorf("graphql.field.path = %v", got)
+	}
+	if span.Tag(string(ext.Error)) != true {
+		t.Error("the resolver error was not recorded")
+	}
+	args := map[string]string{}
+	for _, f := range span.Logs()[0].Fields {
+		args[f.Key] = f.ValueString
+	}
+	if args["graphql.argument.user"] != "ada" || args["graphql.argument.password"] != "[redacted]" {
+		t.Errorf("logged arguments = %v", args)
+	}
+}
+
+func TestExtensionWithoutArguments(t *testing.T) {
+	tracer := mocktracer.New()
+	e := NewExtension(WithTracer(tracer), WithoutArguments())
+	ctx := graphql.WithFieldContext(context.Background(), &graphql.FieldContext{
+		Object:   "Query",
+		Field:    graphql.CollectedField{Field: &ast.Field{Name: "user", Alias: "user"}},
+		Args:     map[string]interface{}{"id": "1"},
+		IsMethod: true,
+	})
+	e.InterceptField(ctx, func(context.Context) (interface{}, error) { return nil, nil })
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 || len(spans[0].Logs()) != 0 {
+		t.Fatalf("got %v, want a single span without logs", spans)
+	}
+}
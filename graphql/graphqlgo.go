@@ -0,0 +1,76 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/introspection"
+	"github.com/graph-gophers/graphql-go/trace/tracer"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// Tracer is a graphql-go tracer with the same behavior as Extension.
+type Tracer struct {
+	config *config
+}
+
+var _ tracer.Tracer = (*Tracer)(nil)
+
+// NewTracer returns a tracer to pass to graphql.Tracer when parsing the
+// schema.
+func NewTracer(opts ...Option) *Tracer {
+	return &Tracer{config: newConfig(opts)}
+}
+
+// TraceQuery implements tracer.Tracer.
+func (t *Tracer) TraceQuery(ctx context.Context, queryString, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, tracer.QueryFinishFunc) {
+	operationType := parseOperationType(queryString, operationName)
+	span, ctx := t.config.startSpan(ctx, operationSpanName(operationType, operationName))
+	span.SetTag("graphql.operation.type", operationType)
+	span.SetTag("graphql.operation.name", operationName)
+	return ctx, func(errs []*errors.QueryError) {
+		logErrors(span, queryErrors(errs))
+		span.Finish()
+	}
+}
+
+// TraceField implements tracer.Tracer. Trivial fields, those without a
+// resolver method, are not traced.
+func (t *Tracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, tracer.FieldFinishFunc) {
+	if trivial {
+		return ctx, func(*errors.QueryError) {}
+	}
+	span, ctx := t.config.startSpan(ctx, "graphql resolve "+typeName+"."+fieldName)
+	span.SetTag("graphql.field.path", label)
+	t.config.logArguments(span, fieldName, args)
+	return ctx, func(err *errors.QueryError) {
+		if err != nil {
+			logErrors(span, []error{err})
+		}
+		span.Finish()
+	}
+}
+
+// parseOperationType returns the type of the operation named operationName
+// in queryString, or "query" if it can't be found. graphql-go doesn't pass
+// the document it parsed to tracers, so the query is parsed again.
+func parseOperationType(queryString, operationName string) string {
+	doc, err := parser.ParseQuery(&ast.Source{Input: queryString})
+	if err != nil {
+		return string(ast.Query)
+	}
+	op := doc.Operations.ForName(operationName)
+	if op == nil {
+		return string(ast.Query)
+	}
+	return string(op.Operation)
+}
+
+func queryErrors(errs []*errors.QueryError) []error {
+	result := make([]error, len(errs))
+	for i, err := range errs {
+		result[i] = err
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+const schema = `
+	type Query {
+		greeting(name: String!): String!
+		version: String!
+		fail: String!
+	}
+
+	type Mutation {
+		rename(name: String!): String!
+	}
+`
+
+type resolver struct{}
+
+func (*resolver) Greeting(args struct{ Name string }) string {
+	return "hello " + args.Name
+}
+
+func (*resolver) Version() string {
+	return "1"
+}
+
+func (*resolver) Fail() (string, error) {
+	return "", errors.New("unavailable")
+}
+
+func (*resolver) Rename(args struct{ Name string }) string {
+	return args.Name
+}
+
+func TestTracer(t *testing.T) {
+	tracer := mocktracer.New()
+	s := graphqlgo.MustParseSchema(schema, &resolver{}, graphqlgo.Tracer(NewTracer(WithTracer(tracer))))
+
+	resp := s.Exec(context.Background(), `query Greet { greeting(name: "ada") }`, "Greet", nil)
+	if len(resp.Errors) != 0 {
+		t.Fatal(resp.Errors)
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	field, query := spans[0], spans[1]
+	if query.OperationName != "graphql query Greet" || query.Tag("graphql.operation.name") != "Greet" {
+		t.Errorf("query span = %q, tags %v", query.OperationName, query.Tags())
+	}
+	if field.OperationName != "graphql resolve Query.greeting" {
+		t.Errorf("field span = %q", field.OperationName)
+	}
+	if field.ParentID != query.SpanContext.SpanID {
+		t.Error("the field span is not a child of the query span")
+	}
+	if len(field.Logs()) != 1 {
+		t.Errorf("logged %d events, want the arguments", len(field.Logs()))
+	}
+}
+
+func TestTracerErrors(t *testing.T) {
+	tracer := mocktracer.New()
+	s := graphqlgo.MustParseSchema(schema, &resolver{}, graphqlgo.Tracer(NewTracer(WithTracer(tracer))))
+
+	resp := s.Exec(context.Background(), `{ version fail }`, "", nil)
+	if len(resp.Errors) == 0 {
+		t.Fatal("expected a resolver error")
+	}
+	spans := tracer.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2: the trivial version field is not traced", len(spans))
+	}
+	field, query := spans[0], spans[1]
+	if field.OperationName != "graphql resolve Query.fail" || field.Tag(string(ext.Error)) != true {
+		t.Errorf("field span = %q, tags %v", field.OperationName, field.Tags())
+	}
+	if query.OperationName != "graphql query" || query.Tag(string(ext.Error)) != true {
+		t.Errorf("query span = %q, tags %v", query.OperationName, query.Tags())
+	}
+}
+
+func TestTracerOperationType(t *testing.T) {
+	tracer := mocktracer.New()
+	s := graphqlgo.MustParseSchema(schema, &resolver{}, graphqlgo.Tracer(NewTracer(WithTracer(tracer))))
+
+	query := `query Version { version } mutation Rename { rename(name: "ada") }`
+	if resp := s.Exec(context.Background(), query, "Rename", nil); len(resp.Errors) != 0 {
+		t.Fatal(resp.Errors)
+	}
+	spans := tracer.FinishedSpans()
+	op := spans[len(spans)-1]
+	if op.OperationName != "graphql mutation Rename" || op.Tag("graphql.operation.type") != "mutation" {
+		t.Errorf("operation span = %q, tags %v", op.OperationName, op.Tags())
+	}
+}
+
+func TestParseOperationType(t *testing.T) {
+	tests := []struct {
+		query, operationName, want string
+	}{
+		{`{ version }`, "", "query"},
+		{`mutation { rename(name: "ada") }`, "", "mutation"},
+		{`query A { version } subscription B { version }`, "B", "subscription"},
+		{`query A { version } query B { version }`, "", "query"},
+		{`not graphql`, "", "query"},
+	}
+	for _, tt := range tests {
+		if got := parseOperationType(tt.query, tt.operationName); got != tt.want {
+			t.Errorf("parseOperationType(%q, %q) = %q, want %q", tt.query, tt.operationName, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+// Package grpc provides gRPC interceptors that trace unary and streaming
+// calls the same way the parent package traces HTTP requests. The span
+// context is propagated through gRPC metadata:
+//
+//	server := grpc.NewServer(
+//	    grpc.UnaryInterceptor(otgrpc.UnaryServerInterceptor()),
+//	    grpc.StreamInterceptor(otgrpc.StreamServerInterceptor()),
+//	)
+//	conn, err := grpc.Dial(addr,
+//	    grpc.WithUnaryInterceptor(otgrpc.UnaryClientInterceptor()),
+//	    grpc.WithStreamInterceptor(otgrpc.StreamClientInterceptor()),
+//	)
+package grpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "gRPC"}
+
+// Option customizes the interceptors.
+type Option func(*config)
+
+type config struct {
+	tracer opentracing.Tracer
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) activeTracer() opentracing.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return opentracing.GlobalTracer()
+}
+
+// WithTracer uses tracer instead of opentracing.GlobalTracer().
+func WithTracer(tracer opentracing.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// metadataCarrier adapts metadata.MD to the opentracing TextMap interfaces.
+type metadataCarrier metadata.MD
+
+func (mc metadataCarrier) Set(key, val string) {
+	// gRPC metadata keys are always lower case.
+	key = strings.ToLower(key)
+	mc[key] = []string{val}
+}
+
+func (mc metadataCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, vals := range mc {
+		for _, v := range vals {
+			if err := handler(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UnaryServerInterceptor traces unary calls handled by a grpc.Server.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	c := newConfig(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		span, ctx := startServerSpan(ctx, c.activeTracer(), info.FullMethod)
+		defer span.Finish()
+
+		resp, err := handler(ctx, req)
+		setStatus(span, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor traces streaming calls handled by a grpc.Server.
+// Every message sent or received on the stream is logged on the span.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	c := newConfig(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		span, ctx := startServerSpan(ss.Context(), c.activeTracer(), info.FullMethod)
+		defer span.Finish()
+
+		err := handler(srv, &tracedServerStream{ServerStream: ss, ctx: ctx, span: span})
+		setStatus(span, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor traces unary calls made through a grpc.ClientConn.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	c := newConfig(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		span, ctx := startClientSpan(ctx, c.activeTracer(), method)
+		defer span.Finish()
+
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		setStatus(span, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor traces streaming calls made through a
+// grpc.ClientConn. The span is finished once the stream ends: when the
+// server closes it, when an error occurs, when ctx is done, or, for calls
+// whose server sends a single response, once that response is received.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	c := newConfig(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		span, ctx := startClientSpan(ctx, c.activeTracer(), method)
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			setStatus(span, err)
+			span.Finish()
+			return cs, err
+		}
+		s := &tracedClientStream{
+			ClientStream:  cs,
+			span:          span,
+			serverStreams: desc.ServerStreams,
+			done:          make(chan struct{}),
+		}
+		go s.watch(ctx)
+		return s, nil
+	}
+}
+
+func startServerSpan(ctx context.Context, tracer opentracing.Tracer, method string) (opentracing.Span, context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	parentSpanContext, _ := tracer.Extract(opentracing.TextMap, metadataCarrier(md))
+	span := tracer.StartSpan(
+		method,
+		ext.RPCServerOption(parentSpanContext),
+		componentTag,
+		opentracing.Tag{Key: "grpc.method", Value: method},
+	)
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+func startClientSpan(ctx context.Context, tracer opentracing.Tracer, method string) (opentracing.Span, context.Context) {
+	var parent opentracing.SpanContext
+	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+		parent = parentSpan.Context()
+	}
+	span := tracer.StartSpan(
+		method,
+		opentracing.ChildOf(parent),
+		ext.SpanKindRPCClient,
+		componentTag,
+		opentracing.Tag{Key: "grpc.method", Value: method},
+	)
+
+	// Copy the outgoing metadata since it must not be modified in place.
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	tracer.Inject(span.Context(), opentracing.TextMap, metadataCarrier(md))
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+// setStatus tags span with the gRPC status code of err.
+func setStatus(span opentracing.Span, err error) {
+	code := status.Code(err)
+	span.SetTag("grpc.status_code", code.String())
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+}
+
+func logMessage(span opentracing.Span, event string, err error) {
+	if err != nil {
+		span.LogFields(log.String("event", event), log.Error(err))
+		return
+	}
+	span.LogFields(log.String("event", event))
+}
+
+type tracedServerStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	span opentracing.Span
+}
+
+func (s *tracedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *tracedServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	logMessage(s.span, "message sent", err)
+	return err
+}
+
+func (s *tracedServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err != io.EOF {
+		logMessage(s.span, "message received", err)
+	}
+	return err
+}
+
+type tracedClientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	done          chan struct{}
+
+	// mu serializes the use of span by the stream methods and by watch,
+	// which may finish it at any time.
+	mu       sync.Mutex
+	span     opentracing.Span
+	finished bool
+}
+
+// watch finishes the span when ctx is done, since callers that abandon a
+// stream are not required to drain it.
+func (s *tracedClientStream) watch(ctx context.Context) {
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		s.finish(status.FromContextError(ctx.Err()).Err())
+	}
+}
+
+func (s *tracedClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	switch err {
+	case nil:
+		s.logMessage("message sent", nil)
+	case io.EOF:
+		// The stream was aborted; RecvMsg returns the actual status.
+	default:
+		s.logMessage("message sent", err)
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch err {
+	case nil:
+		s.logMessage("message received", nil)
+		if !s.serverStreams {
+			s.finish(nil)
+		}
+	case io.EOF:
+		s.finish(nil)
+	default:
+		s.finish(err)
+	}
+	return err
+}
+
+// logMessage logs event unless the span is finished.
+func (s *tracedClientStream) logMessage(event string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.finished {
+		logMessage(s.span, event, err)
+	}
+}
+
+func (s *tracedClientStream) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished {
+		return
+	}
+	s.finished = true
+	close(s.done)
+	setStatus(s.span, err)
+	s.span.Finish()
+}
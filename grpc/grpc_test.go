@@ -0,0 +1,304 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial starts a health server traced with tracer and returns a client
+// connection to it, also traced with tracer.
+func dial(t *testing.T, tracer opentracing.Tracer) (*grpc.ClientConn, *health.Server) {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(WithTracer(tracer))),
+		grpc.StreamInterceptor(StreamServerInterceptor(WithTracer(tracer))),
+	)
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(server, hs)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(WithTracer(tracer))),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(WithTracer(tracer))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, hs
+}
+
+// spansByKind splits the finished spans of tracer into client and server
+// spans.
+func spansByKind(tracer *mocktracer.MockTracer) (client, server []*mocktracer.MockSpan) {
+	for _, span := range tracer.FinishedSpans() {
+		switch span.Tag(string(ext.SpanKind)) {
+		case ext.SpanKindRPCClientEnum:
+			client = append(client, span)
+		case ext.SpanKindRPCServerEnum:
+			server = append(server, span)
+		}
+	}
+	return client, server
+}
+
+// waitForSpans waits until tracer has finished n spans.
+func waitForSpans(t *testing.T, tracer *mocktracer.MockTracer, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(tracer.FinishedSpans()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d finished spans, want %d", len(tracer.FinishedSpans()), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	tracer := mocktracer.New()
+	conn, _ := dial(t, tracer)
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForSpans(t, tracer, 2)
+
+	client, server := spansByKind(tracer)
+	if len(client) != 1 || len(server) != 1 {
+		t.Fatalf("got %d client and %d server spans, want 1 of each", len(client), len(server))
+	}
+	const method = "/grpc.health.v1.Health/Check"
+	for _, span := range []*mocktracer.MockSpan{client[0], server[0]} {
+		if span.OperationName != method {
+			t.Errorf("operation name = %q, want %q", span.OperationName, method)
+		}
+		if got := span.Tag("grpc.status_code"); got != codes.OK.String() {
+			t.Errorf("grpc.status_code = %v, want OK", got)
+		}
+	}
+	if client[0].ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the client span is not a child of the context span")
+	}
+	if server[0].ParentID != client[0].SpanContext.SpanID {
+		t.Error("the server span is not a child of the client span")
+	}
+}
+
+func TestUnaryInterceptorsError(t *testing.T) {
+	tracer := mocktracer.New()
+	conn, _ := dial(t, tracer)
+
+	_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("err = %v, want NotFound", err)
+	}
+	waitForSpans(t, tracer, 2)
+
+	for _, span := range tracer.FinishedSpans() {
+		if got := span.Tag("grpc.status_code"); got != codes.NotFound.String() {
+			t.Errorf("%s span: grpc.status_code = %v, want NotFound", span.Tag("span.kind"), got)
+		}
+		if span.Tag("error") != true {
+			t.Errorf("%s span is not tagged as an error", span.Tag("span.kind"))
+		}
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	tracer := mocktracer.New()
+	conn, _ := dial(t, tracer)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Fatalf("err = %v, want Canceled", err)
+	}
+	waitForSpans(t, tracer, 2)
+
+	client, server := spansByKind(tracer)
+	if len(client) != 1 || len(server) != 1 {
+		t.Fatalf("got %d client and %d server spans, want 1 of each", len(client), len(server))
+	}
+	if server[0].ParentID != client[0].SpanContext.SpanID {
+		t.Error("the server span is not a child of the client span")
+	}
+	if got := countEvents(client[0], "message received"); got != 1 {
+		t.Errorf("the client span logged %d received messages, want 1", got)
+	}
+	if got := countEvents(server[0], "message sent"); got != 1 {
+		t.Errorf("the server span logged %d sent messages, want 1", got)
+	}
+}
+
+func countEvents(span *mocktracer.MockSpan, event string) int {
+	n := 0
+	for _, l := range span.Logs() {
+		for _, f := range l.Fields {
+			if f.Key == "event" && f.ValueString == event {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestClientKeepsOutgoingMetadata(t *testing.T) {
+	tracer := mocktracer.New()
+	md := metadata.Pairs("x-request-id", "42")
+	ctx := metadata.NewOutgoingContext(context.Background(), md)
+
+	var sent metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	if err := UnaryClientInterceptor(WithTracer(tracer))(ctx, "/svc/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent.Get("x-request-id"); len(got) != 1 || got[0] != "42" {
+		t.Errorf("x-request-id = %v, want [42]", got)
+	}
+	if len(md) != 1 {
+		t.Errorf("the caller's metadata was modified: %v", md)
+	}
+	if _, err := tracer.Extract(opentracing.TextMap, metadataCarrier(sent)); err != nil {
+		t.Errorf("no span context in the sent metadata: %v", err)
+	}
+}
+
+// fakeClientStream returns the errors of sendErr and recvErr.
+type fakeClientStream struct {
+	grpc.ClientStream
+	sendErr, recvErr error
+}
+
+func (s *fakeClientStream) SendMsg(m interface{}) error { return s.sendErr }
+func (s *fakeClientStream) RecvMsg(m interface{}) error { return s.recvErr }
+
+// newStream calls the stream client interceptor with a streamer returning
+// cs.
+func newStream(t *testing.T, ctx context.Context, tracer opentracing.Tracer, desc *grpc.StreamDesc, cs grpc.ClientStream) grpc.ClientStream {
+	t.Helper()
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return cs, nil
+	}
+	stream, err := StreamClientInterceptor(WithTracer(tracer))(ctx, desc, nil, "/svc/Upload", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stream
+}
+
+func TestClientStreamFinishesOnSingleResponse(t *testing.T) {
+	tracer := mocktracer.New()
+	stream := newStream(t, context.Background(), tracer, &grpc.StreamDesc{ClientStreams: true}, &fakeClientStream{})
+	if err := stream.SendMsg(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.RecvMsg(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d finished spans after the single response, want 1", len(spans))
+	}
+	if got := spans[0].Tag("grpc.status_code"); got != codes.OK.String() {
+		t.Errorf("grpc.status_code = %v, want OK", got)
+	}
+}
+
+func TestClientStreamFinishesOnContextDone(t *testing.T) {
+	tracer := mocktracer.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	newStream(t, ctx, tracer, &grpc.StreamDesc{ServerStreams: true}, &fakeClientStream{})
+	cancel()
+	waitForSpans(t, tracer, 1)
+
+	if got := tracer.FinishedSpans()[0].Tag("grpc.status_code"); got != codes.Canceled.String() {
+		t.Errorf("grpc.status_code = %v, want Canceled", got)
+	}
+}
+
+func TestClientStreamIgnoresSendEOF(t *testing.T) {
+	tracer := mocktracer.New()
+	cs := &fakeClientStream{sendErr: io.EOF}
+	stream := newStream(t, context.Background(), tracer, &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, cs)
+	if err := stream.SendMsg(nil); err != io.EOF {
+		t.Fatalf("SendMsg = %v, want io.EOF", err)
+	}
+	if n := len(tracer.FinishedSpans()); n != 0 {
+		t.Fatalf("io.EOF from SendMsg finished %d spans, want 0", n)
+	}
+
+	cs.recvErr = status.Error(codes.ResourceExhausted, "quota")
+	stream.RecvMsg(nil)
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 || spans[0].Tag("grpc.status_code") != codes.ResourceExhausted.String() {
+		t.Errorf("finished spans %v, want one with the status returned by RecvMsg", spans)
+	}
+	if got := countEvents(spans[0], "message sent"); got != 0 {
+		t.Errorf("logged %d sent messages, want 0", got)
+	}
+}
+
+func TestClientStreamIgnoresMessagesAfterFinish(t *testing.T) {
+	tracer := mocktracer.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := newStream(t, ctx, tracer, &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, &fakeClientStream{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			stream.SendMsg(nil)
+		}
+	}()
+	cancel()
+	<-done
+
+	waitForSpans(t, tracer, 1)
+	span := tracer.FinishedSpans()[0]
+	n := len(span.Logs())
+	stream.SendMsg(nil)
+	if got := len(span.Logs()); got != n {
+		t.Errorf("logged %d records after the span was finished", got-n)
+	}
+}
+
+func TestMetadataCarrierSetReplaces(t *testing.T) {
+	md := metadata.Pairs("trace-id", "stale")
+	metadataCarrier(md).Set("Trace-Id", "fresh")
+	if got := md.Get("trace-id"); len(got) != 1 || got[0] != "fresh" {
+		t.Errorf("trace-id = %v, want only the value set last", got)
+	}
+}
@@ -0,0 +1,66 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// redactedValue replaces the value of sensitive headers.
+const redactedValue = "[REDACTED]"
+
+// sensitiveHeaders are always redacted, regardless of the configured
+// redactor, since they carry credentials.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// WithCapturedHeaders logs the values of the listed request and response
+// headers on server and client spans. Credentials carried by headers such
+// as Authorization and Cookie are redacted.
+func WithCapturedHeaders(headers []string) Option {
+	return commonOption(func(c *commonConfig) {
+		for _, h := range headers {
+			c.capturedHeaders = append(c.capturedHeaders, http.CanonicalHeaderKey(h))
+		}
+	})
+}
+
+// WithHeaderRedactor transforms captured header values before they are
+// logged, for example to mask API keys in custom headers. It is applied in
+// addition to the built-in redaction of credentials.
+func WithHeaderRedactor(f func(key, value string) string) Option {
+	return commonOption(func(c *commonConfig) {
+		c.headerRedactor = f
+	})
+}
+
+// logHeaders logs the captured headers found in h on span. Field keys are
+// prefix followed by the lower case header name.
+func (c *commonConfig) logHeaders(span opentracing.Span, event, prefix string, h http.Header) {
+	if len(c.capturedHeaders) == 0 {
+		return
+	}
+	fields := []log.Field{log.String("event", event)}
+	for _, key := range c.capturedHeaders {
+		values, ok := h[key]
+		if !ok {
+			continue
+		}
+		value := strings.Join(values, ", ")
+		if sensitiveHeaders[key] {
+			value = redactedValue
+		} else if c.headerRedactor != nil {
+			value = c.headerRedactor(key, value)
+		}
+		fields = append(fields, log.String(prefix+strings.ToLower(key), value))
+	}
+	if len(fields) > 1 {
+		span.LogFields(fields...)
+	}
+}
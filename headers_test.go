@@ -0,0 +1,78 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestWithCapturedHeadersServer(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("Content-Type", "text/plain")
+	}), WithTracer(tracer), WithCapturedHeaders([]string{"user-agent", "authorization", "x-missing", "content-type", "set-cookie"}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("User-Agent", "test")
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("X-Other", "not captured")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	fields := loggedFields(finishedSpan(t, tracer))
+	for key, want := range map[string]string{
+		"http.request.header.user-agent":    "test",
+		"http.request.header.authorization": redactedValue,
+		"http.response.header.content-type": "text/plain",
+		"http.response.header.set-cookie":   redactedValue,
+	} {
+		if got := fields[key]; got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	for key := range fields {
+		if strings.Contains(key, "x-missing") || strings.Contains(key, "x-other") {
+			t.Errorf("logged %s, which wasn't sent or captured", key)
+		}
+	}
+}
+
+func TestWithHeaderRedactor(t *testing.T) {
+	tracer := mocktracer.New()
+	transport := NewTracedTransport(respond(http.StatusOK, "", nil),
+		WithTracer(tracer),
+		WithCapturedHeaders([]string{"X-Api-Key", "Cookie"}),
+		WithHeaderRedactor(func(key, value string) string {
+			return value[:2] + "***"
+		}))
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.Header.Set("X-Api-Key", "abcdef")
+	req.Header.Set("Cookie", "session=secret")
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	fields := loggedFields(finishedSpan(t, tracer))
+	if got := fields["http.request.header.x-api-key"]; got != "ab***" {
+		t.Errorf("x-api-key = %q, want %q", got, "ab***")
+	}
+	if got := fields["http.request.header.cookie"]; got != redactedValue {
+		t.Errorf("cookie = %q, want it redacted by the built-in rules", got)
+	}
+}
+
+func TestNoCapturedHeaders(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", okHandler, WithTracer(tracer))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("User-Agent", "test")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if logs := finishedSpan(t, tracer).Logs(); len(logs) != 0 {
+		t.Errorf("logged %v without WithCapturedHeaders", logs)
+	}
+}
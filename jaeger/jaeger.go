@@ -0,0 +1,55 @@
+// Package jaeger bootstraps a Jaeger tracer for services instrumented with
+// opentracing_helpers:
+//
+//	tracer, closer, err := otjaeger.InitJaeger("users")
+//	if err != nil {
+//		return err
+//	}
+//	defer closer.Close()
+//
+// Importing the package also registers the "jaeger" backend for
+// opentracing_helpers.InitTracer.
+package jaeger
+
+import (
+	"io"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go/config"
+)
+
+func init() {
+	opentracing_helpers.RegisterTracerFactory("jaeger", NewTracer)
+}
+
+// NewTracer is an opentracing_helpers.TracerFactory creating a Jaeger
+// tracer configured from the environment, like InitJaeger but without
+// setting the global tracer.
+func NewTracer(serviceName string) (opentracing.Tracer, io.Closer, error) {
+	return newTracer(serviceName)
+}
+
+// InitJaeger creates a Jaeger tracer named serviceName, configured from the
+// standard JAEGER_* environment variables such as JAEGER_AGENT_HOST,
+// JAEGER_SAMPLER_TYPE and JAEGER_TAGS, and sets it as the global tracer.
+// opts are passed to the Jaeger configuration, for example
+// config.Logger or config.Metrics. The returned closer flushes buffered
+// spans and must be closed on shutdown.
+func InitJaeger(serviceName string, opts ...config.Option) (opentracing.Tracer, io.Closer, error) {
+	tracer, closer, err := newTracer(serviceName, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	opentracing.SetGlobalTracer(tracer)
+	return tracer, closer, nil
+}
+
+func newTracer(serviceName string, opts ...config.Option) (opentracing.Tracer, io.Closer, error) {
+	cfg, err := config.FromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.ServiceName = serviceName
+	return cfg.NewTracer(opts...)
+}
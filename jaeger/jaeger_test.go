@@ -0,0 +1,87 @@
+package jaeger
+
+import (
+	"context"
+	"testing"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+	"github.com/uber/jaeger-client-go/config"
+)
+
+func TestInitJaeger(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	t.Setenv("JAEGER_SAMPLER_TYPE", "const")
+	t.Setenv("JAEGER_SAMPLER_PARAM", "1")
+	t.Setenv("JAEGER_TAGS", "region=eu")
+	reporter := jaeger.NewInMemoryReporter()
+
+	tracer, closer, err := InitJaeger("users", config.Reporter(reporter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	if opentracing.GlobalTracer() != tracer {
+		t.Error("the tracer was not set as the global tracer")
+	}
+
+	span := tracer.StartSpan("op")
+	ids, ok := opentracing_helpers.SpanIdentifiersFromContext(opentracing.ContextWithSpan(context.Background(), span), nil)
+	span.Finish()
+
+	spans := reporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("reported %d spans, want 1", len(spans))
+	}
+	reported := spans[0].(*jaeger.Span)
+	if got := tracer.(*jaeger.Tracer).Tags(); !hasTag(got, "region", "eu") {
+		t.Errorf("process tags %v, want region=eu from JAEGER_TAGS", got)
+	}
+	sc := reported.SpanContext()
+	if !ok || ids.TraceID != sc.TraceID().String() || !sc.IsSampled() {
+		t.Errorf("SpanIdentifiersFromContext = %+v, %v; want trace %s", ids, ok, sc.TraceID())
+	}
+}
+
+func hasTag(tags []opentracing.Tag, key string, value interface{}) bool {
+	for _, tag := range tags {
+		if tag.Key == key && tag.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInitJaegerInvalidEnvironment(t *testing.T) {
+	t.Setenv("JAEGER_SAMPLER_PARAM", "not a number")
+	if _, _, err := InitJaeger("users"); err == nil {
+		t.Error("expected an error for an invalid JAEGER_SAMPLER_PARAM")
+	}
+}
+
+func TestNewTracerRegistered(t *testing.T) {
+	global := opentracing.GlobalTracer()
+	t.Setenv(opentracing_helpers.TracerBackendEnv, "jaeger")
+	t.Setenv("JAEGER_DISABLED", "true")
+
+	tracer, closer, err := opentracing_helpers.InitTracer("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	defer opentracing.SetGlobalTracer(global)
+	if opentracing.GlobalTracer() != tracer {
+		t.Error("InitTracer didn't set the jaeger tracer as the global tracer")
+	}
+
+	opentracing.SetGlobalTracer(global)
+	_, closer, err = NewTracer("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	if opentracing.GlobalTracer() != global {
+		t.Error("NewTracer replaced the global tracer")
+	}
+}
@@ -0,0 +1,48 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// TraceJob returns a function running fn within a new root span named
+// name on every call, for scheduled jobs and ticker loops. It matches
+// robfig/cron's FuncJob:
+//
+//	c.AddFunc("@hourly", opentracing_helpers.TraceJob("cleanup", cleanup))
+//
+// Each span is tagged with job.name, job.run, a counter incremented on
+// every run, job.duration and job.outcome, "success", "error" or "panic".
+// Failed runs are tagged with error=true and their error is logged.
+// Panics are recorded and propagated.
+func TraceJob(name string, fn func(ctx context.Context) error) func() {
+	var runs atomic.Int64
+	return func() {
+		start := time.Now()
+		span := opentracing.GlobalTracer().StartSpan(name, opentracing.StartTime(start))
+		defer span.Finish()
+		span.SetTag("job.name", name)
+		span.SetTag("job.run", runs.Add(1))
+		defer func() {
+			span.SetTag("job.duration", time.Since(start).String())
+			if p := recover(); p != nil {
+				span.SetTag("job.outcome", "panic")
+				logPanic(span, p)
+				panic(p)
+			}
+		}()
+
+		if err := fn(opentracing.ContextWithSpan(context.Background(), span)); err != nil {
+			span.SetTag("job.outcome", "error")
+			ext.Error.Set(span, true)
+			span.LogFields(log.String("event", "error"), log.Error(err))
+			return
+		}
+		span.SetTag("job.outcome", "success")
+	}
+}
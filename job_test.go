@@ -0,0 +1,71 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestTraceJob(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	fail := false
+	job := TraceJob("cleanup", func(ctx context.Context) error {
+		if opentracing.SpanFromContext(ctx) == nil {
+			t.Error("the job context carries no span")
+		}
+		if fail {
+			return errors.New("disk full")
+		}
+		return nil
+	})
+	job()
+	fail = true
+	job()
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d finished spans, want 2", len(spans))
+	}
+	for i, want := range []string{"success", "error"} {
+		span := spans[i]
+		if span.OperationName != "cleanup" || span.ParentID != 0 || span.Tag("job.name") != "cleanup" {
+			t.Errorf("run %d: root span %q tagged %v", i+1, span.OperationName, span.Tags())
+		}
+		if span.Tag("job.run") != int64(i+1) || span.Tag("job.outcome") != want {
+			t.Errorf("run %d: job.run = %v, job.outcome = %v; want %d, %s", i+1, span.Tag("job.run"), span.Tag("job.outcome"), i+1, want)
+		}
+		if _, ok := span.Tag("job.duration").(string); !ok {
+			t.Errorf("run %d: job.duration = %v", i+1, span.Tag("job.duration"))
+		}
+	}
+	if spans[1].Tag("error") != true || loggedFields(spans[1])["error.object"] != "disk full" {
+		t.Errorf("failed run tagged %v, want the error logged", spans[1].Tags())
+	}
+}
+
+func TestTraceJobPanic(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	job := TraceJob("cleanup", func(ctx context.Context) error { panic("boom") })
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the job's panic", p)
+			}
+		}()
+		job()
+	}()
+
+	span := finishedSpan(t, tracer)
+	if span.Tag("job.outcome") != "panic" || span.Tag("error") != true || loggedFields(span)["message"] != "boom" {
+		t.Errorf("span tagged %v, want the panic recorded", span.Tags())
+	}
+}
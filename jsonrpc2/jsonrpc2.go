@@ -0,0 +1,140 @@
+// Package jsonrpc2 traces JSON-RPC 2.0 calls made and served with
+// sourcegraph/jsonrpc2. Spans are named after the called method, and the
+// span context is propagated in the reserved "meta" field of requests:
+//
+//	handler := otjsonrpc2.HandlerWithError(handle)
+//	conn := jsonrpc2.NewConn(ctx, stream, handler)
+//
+//	client := otjsonrpc2.NewClient(conn)
+//	err := client.Call(ctx, "textDocument/hover", params, &result)
+//
+// Both accept the root package's Options, of which WithTracer,
+// WithDisabled and WithPropagators apply.
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "jsonrpc2"}
+
+// HandlerWithError is like jsonrpc2.HandlerWithError, but every request is
+// handled within a server span, a child of the span context in the
+// request's meta field. The span is stored in the context passed to
+// handleFunc, and tagged with the code of returned *jsonrpc2.Error values.
+func HandlerWithError(handleFunc func(context.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error), opts ...opentracing_helpers.Option) *jsonrpc2.HandlerWithErrorConfigurer {
+	s := opentracing_helpers.NewSettings(opts...)
+	return jsonrpc2.HandlerWithError(func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+		if s.Noop() {
+			return handleFunc(ctx, conn, req)
+		}
+		var parent opentracing.SpanContext
+		if req.Meta != nil {
+			var carrier opentracing.TextMapCarrier
+			if json.Unmarshal(*req.Meta, &carrier) == nil {
+				parent, _ = s.ExtractTextMap(carrier)
+			}
+		}
+		span := s.Tracer().StartSpan(
+			req.Method,
+			ext.RPCServerOption(parent),
+			componentTag,
+		)
+		defer span.Finish()
+		span.SetTag("rpc.method", req.Method)
+		if req.Notif {
+			span.SetTag("rpc.jsonrpc.notification", true)
+		}
+
+		result, err := handleFunc(opentracing.ContextWithSpan(ctx, span), conn, req)
+		if err != nil {
+			tagError(span, err)
+		}
+		return result, err
+	})
+}
+
+// Client sends JSON-RPC requests within client spans.
+type Client struct {
+	conn     *jsonrpc2.Conn
+	settings *opentracing_helpers.Settings
+}
+
+// NewClient returns a Client sending requests on conn.
+func NewClient(conn *jsonrpc2.Conn, opts ...opentracing_helpers.Option) *Client {
+	return &Client{conn: conn, settings: opentracing_helpers.NewSettings(opts...)}
+}
+
+// Call is like conn.Call, but the call is made within a client span that
+// is a child of the span in ctx. The span context is sent in the meta
+// field, so callOpts must not include jsonrpc2.Meta.
+func (c *Client) Call(ctx context.Context, method string, params, result any, callOpts ...jsonrpc2.CallOption) error {
+	if c.settings.Noop() {
+		return c.conn.Call(ctx, method, params, result, callOpts...)
+	}
+	span, callOpts := c.startSpan(ctx, method, callOpts)
+	defer span.Finish()
+	err := c.conn.Call(ctx, method, params, result, callOpts...)
+	if err != nil {
+		tagError(span, err)
+	}
+	return err
+}
+
+// Notify is like conn.Notify, but the notification is sent within a
+// client span. See Call.
+func (c *Client) Notify(ctx context.Context, method string, params any, callOpts ...jsonrpc2.CallOption) error {
+	if c.settings.Noop() {
+		return c.conn.Notify(ctx, method, params, callOpts...)
+	}
+	span, callOpts := c.startSpan(ctx, method, callOpts)
+	defer span.Finish()
+	span.SetTag("rpc.jsonrpc.notification", true)
+	err := c.conn.Notify(ctx, method, params, callOpts...)
+	if err != nil {
+		tagError(span, err)
+	}
+	return err
+}
+
+// startSpan starts the client span of a call to method and returns
+// callOpts with the span context added as meta.
+func (c *Client) startSpan(ctx context.Context, method string, callOpts []jsonrpc2.CallOption) (opentracing.Span, []jsonrpc2.CallOption) {
+	var parent opentracing.SpanContext
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		parent = span.Context()
+	}
+	span := c.settings.Tracer().StartSpan(
+		method,
+		opentracing.ChildOf(parent),
+		ext.SpanKindRPCClient,
+		componentTag,
+	)
+	span.SetTag("rpc.method", method)
+
+	carrier := opentracing.TextMapCarrier{}
+	if err := c.settings.InjectTextMap(span.Context(), carrier); err != nil {
+		span.LogFields(log.String("event", "inject failed"), log.Error(err))
+		return span, callOpts
+	}
+	return span, append(callOpts[:len(callOpts):len(callOpts)], jsonrpc2.Meta(carrier))
+}
+
+// tagError marks span as failed, tagging the JSON-RPC error code if err
+// is a *jsonrpc2.Error.
+func tagError(span opentracing.Span, err error) {
+	ext.Error.Set(span, true)
+	var rpcErr *jsonrpc2.Error
+	if errors.As(err, &rpcErr) {
+		span.SetTag("rpc.jsonrpc.error_code", rpcErr.Code)
+	}
+	span.LogFields(log.String("event", "error"), log.Error(err))
+}
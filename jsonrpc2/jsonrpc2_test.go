@@ -0,0 +1,132 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// connect serves handle over a pipe and returns a client of it, both
+// traced by tracer.
+func connect(t *testing.T, tracer opentracing.Tracer, handle func(context.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error)) *Client {
+	ctx := context.Background()
+	cli, srv := net.Pipe()
+	server := jsonrpc2.NewConn(ctx, jsonrpc2.NewPlainObjectStream(srv), HandlerWithError(handle, opentracing_helpers.WithTracer(tracer)))
+	conn := jsonrpc2.NewConn(ctx, jsonrpc2.NewPlainObjectStream(cli), nil)
+	t.Cleanup(func() {
+		conn.Close()
+		server.Close()
+	})
+	return NewClient(conn, opentracing_helpers.WithTracer(tracer))
+}
+
+// waitForSpans waits until tracer has finished n spans.
+func waitForSpans(t *testing.T, tracer *mocktracer.MockTracer, n int) []*mocktracer.MockSpan {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		spans := tracer.FinishedSpans()
+		if len(spans) >= n {
+			return spans
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d finished spans, want %d", len(spans), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// spansByKind returns the client and server spans of a call.
+func spansByKind(spans []*mocktracer.MockSpan) (client, server *mocktracer.MockSpan) {
+	for _, span := range spans {
+		switch span.Tag(string(ext.SpanKind)) {
+		case ext.SpanKindRPCClientEnum:
+			client = span
+		case ext.SpanKindRPCServerEnum:
+			server = span
+		}
+	}
+	return client, server
+}
+
+func TestCall(t *testing.T) {
+	tracer := mocktracer.New()
+	var handlerSpan opentracing.Span
+	client := connect(t, tracer, func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+		handlerSpan = opentracing.SpanFromContext(ctx)
+		return "pong", nil
+	})
+	parent := tracer.StartSpan("request")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	var result string
+	if err := client.Call(ctx, "ping", nil, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result != "pong" {
+		t.Errorf("result = %q, want pong", result)
+	}
+
+	clientSpan, serverSpan := spansByKind(waitForSpans(t, tracer, 2))
+	if clientSpan == nil || serverSpan == nil {
+		t.Fatalf("missing client or server span in %v", tracer.FinishedSpans())
+	}
+	if clientSpan.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the client span isn't a child of the context span")
+	}
+	if serverSpan.ParentID != clientSpan.SpanContext.SpanID {
+		t.Error("the server span isn't a child of the client span")
+	}
+	if handlerSpan == nil || handlerSpan.Context().(mocktracer.MockSpanContext).SpanID != serverSpan.SpanContext.SpanID {
+		t.Error("the handler context doesn't carry the server span")
+	}
+	for _, span := range []*mocktracer.MockSpan{clientSpan, serverSpan} {
+		if span.OperationName != "ping" || span.Tag("rpc.method") != "ping" || span.Tag("component") != "jsonrpc2" {
+			t.Errorf("span %q tagged %v", span.OperationName, span.Tags())
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	tracer := mocktracer.New()
+	client := connect(t, tracer, func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+		return nil, nil
+	})
+	if err := client.Notify(context.Background(), "exit", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	clientSpan, serverSpan := spansByKind(waitForSpans(t, tracer, 2))
+	for _, span := range []*mocktracer.MockSpan{clientSpan, serverSpan} {
+		if span.Tag("rpc.jsonrpc.notification") != true {
+			t.Errorf("%s span not tagged as a notification", span.Tag(string(ext.SpanKind)))
+		}
+	}
+}
+
+func TestCallError(t *testing.T) {
+	tracer := mocktracer.New()
+	client := connect(t, tracer, func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "method not found"}
+	})
+	err := client.Call(context.Background(), "missing", nil, nil)
+	var rpcErr *jsonrpc2.Error
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("Call returned %v, want a *jsonrpc2.Error", err)
+	}
+
+	clientSpan, serverSpan := spansByKind(waitForSpans(t, tracer, 2))
+	for _, span := range []*mocktracer.MockSpan{clientSpan, serverSpan} {
+		if span.Tag("error") != true || span.Tag("rpc.jsonrpc.error_code") != int64(jsonrpc2.CodeMethodNotFound) {
+			t.Errorf("%s span tagged %v", span.Tag(string(ext.SpanKind)), span.Tags())
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package kafka
+
+import (
+	"github.com/IBM/sarama"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// SaramaProducerCarrier reads and writes the headers of a sarama producer
+// message.
+type SaramaProducerCarrier struct {
+	msg *sarama.ProducerMessage
+}
+
+// NewSaramaProducerCarrier returns a carrier for the headers of msg.
+func NewSaramaProducerCarrier(msg *sarama.ProducerMessage) SaramaProducerCarrier {
+	return SaramaProducerCarrier{msg: msg}
+}
+
+// Set implements opentracing.TextMapWriter, replacing any existing header
+// with the same key.
+func (c SaramaProducerCarrier) Set(key, val string) {
+	for i, h := range c.msg.Headers {
+		if string(h.Key) == key {
+			c.msg.Headers[i].Value = []byte(val)
+			return
+		}
+	}
+	c.msg.Headers = append(c.msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(val)})
+}
+
+// ForeachKey implements opentracing.TextMapReader.
+func (c SaramaProducerCarrier) ForeachKey(handler func(key, val string) error) error {
+	for _, h := range c.msg.Headers {
+		if err := handler(string(h.Key), string(h.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaramaConsumerCarrier reads the headers of a sarama consumer message.
+type SaramaConsumerCarrier struct {
+	msg *sarama.ConsumerMessage
+}
+
+// NewSaramaConsumerCarrier returns a carrier for the headers of msg.
+func NewSaramaConsumerCarrier(msg *sarama.ConsumerMessage) SaramaConsumerCarrier {
+	return SaramaConsumerCarrier{msg: msg}
+}
+
+// ForeachKey implements opentracing.TextMapReader.
+func (c SaramaConsumerCarrier) ForeachKey(handler func(key, val string) error) error {
+	for _, h := range c.msg.Headers {
+		if h == nil {
+			continue
+		}
+		if err := handler(string(h.Key), string(h.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KafkaGoCarrier reads and writes the headers of a kafka-go message, for
+// both producing and consuming.
+type KafkaGoCarrier struct {
+	msg *kafkago.Message
+}
+
+// NewKafkaGoCarrier returns a carrier for the headers of msg.
+func NewKafkaGoCarrier(msg *kafkago.Message) KafkaGoCarrier {
+	return KafkaGoCarrier{msg: msg}
+}
+
+// Set implements opentracing.TextMapWriter, replacing any existing header
+// with the same key.
+func (c KafkaGoCarrier) Set(key, val string) {
+	for i, h := range c.msg.Headers {
+		if h.Key == key {
+			c.msg.Headers[i].Value = []byte(val)
+			return
+		}
+	}
+	c.msg.Headers = append(c.msg.Headers, kafkago.Header{Key: key, Value: []byte(val)})
+}
+
+// ForeachKey implements opentracing.TextMapReader.
+func (c KafkaGoCarrier) ForeachKey(handler func(key, val string) error) error {
+	for _, h := range c.msg.Headers {
+		if err := handler(h.Key, string(h.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package kafka propagates spans through Kafka record headers so a
+// message can be followed from its producer to its consumers. It provides
+// carriers for IBM/sarama and segmentio/kafka-go messages:
+//
+//	msg := &sarama.ProducerMessage{Topic: "orders", Value: value}
+//	span, _ := otkafka.TraceProduce(ctx, msg.Topic, otkafka.NewSaramaProducerCarrier(msg))
+//	_, _, err := producer.SendMessage(msg)
+//	span.Finish()
+//
+// and on the consumer side:
+//
+//	span, ctx := otkafka.TraceConsume(ctx, msg.Topic, otkafka.NewSaramaConsumerCarrier(msg))
+//	defer span.Finish()
+package kafka
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "kafka"}
+
+// Option customizes the spans started by TraceProduce and TraceConsume.
+type Option func(*config)
+
+type config struct {
+	tracer opentracing.Tracer
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) activeTracer() opentracing.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return opentracing.GlobalTracer()
+}
+
+// WithTracer uses tracer instead of opentracing.GlobalTracer().
+func WithTracer(tracer opentracing.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// TraceProduce starts a producer span as a child of the span in ctx and
+// injects its context into carrier. The caller finishes the span once the
+// message has been sent.
+func TraceProduce(ctx context.Context, topic string, carrier opentracing.TextMapWriter, opts ...Option) (opentracing.Span, context.Context) {
+	c := newConfig(opts)
+	tracer := c.activeTracer()
+	var parent opentracing.SpanContext
+	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+		parent = parentSpan.Context()
+	}
+	span := tracer.StartSpan(
+		"kafka produce "+topic,
+		opentracing.ChildOf(parent),
+		ext.SpanKindProducer,
+		componentTag,
+	)
+	ext.MessageBusDestination.Set(span, topic)
+	tracer.Inject(span.Context(), opentracing.TextMap, carrier)
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+// TraceConsume starts a consumer span for a message read from topic. The
+// span follows from the producer span extracted from carrier, since the
+// producer doesn't wait for the message to be processed. The caller
+// finishes the span once the message has been handled.
+func TraceConsume(ctx context.Context, topic string, carrier opentracing.TextMapReader, opts ...Option) (opentracing.Span, context.Context) {
+	c := newConfig(opts)
+	tracer := c.activeTracer()
+	producer, _ := tracer.Extract(opentracing.TextMap, carrier)
+	span := tracer.StartSpan(
+		"kafka consume "+topic,
+		opentracing.FollowsFrom(producer),
+		ext.SpanKindConsumer,
+		componentTag,
+	)
+	ext.MessageBusDestination.Set(span, topic)
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+func TestSaramaProduceConsume(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	msg := &sarama.ProducerMessage{Topic: "orders"}
+	produce, _ := TraceProduce(ctx, msg.Topic, NewSaramaProducerCarrier(msg), WithTracer(tracer))
+	produce.Finish()
+
+	// What the broker hands to the consumer.
+	received := &sarama.ConsumerMessage{Topic: msg.Topic}
+	for i := range msg.Headers {
+		received.Headers = append(received.Headers, &msg.Headers[i])
+	}
+	received.Headers = append(received.Headers, nil)
+	consume, ctx := TraceConsume(context.Background(), received.Topic, NewSaramaConsumerCarrier(received), WithTracer(tracer))
+	consume.Finish()
+
+	p, c := produce.(*mocktracer.MockSpan), consume.(*mocktracer.MockSpan)
+	if p.OperationName != "kafka produce orders" || c.OperationName != "kafka consume orders" {
+		t.Errorf("operation names = %q, %q", p.OperationName, c.OperationName)
+	}
+	if p.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the producer span is not a child of the context span")
+	}
+	if c.ParentID != p.SpanContext.SpanID || c.SpanContext.TraceID != p.SpanContext.TraceID {
+		t.Error("the consumer span doesn't follow from the producer span")
+	}
+	for _, span := range []*mocktracer.MockSpan{p, c} {
+		if got := span.Tag("message_bus.destination"); got != "orders" {
+			t.Errorf("%s: message_bus.destination = %v", span.OperationName, got)
+		}
+	}
+	if opentracing.SpanFromContext(ctx) != consume {
+		t.Error("TraceConsume didn't return a context carrying the consumer span")
+	}
+}
+
+func TestKafkaGoProduceConsume(t *testing.T) {
+	tracer := mocktracer.New()
+	msg := &kafkago.Message{Topic: "orders"}
+	produce, _ := TraceProduce(context.Background(), msg.Topic, NewKafkaGoCarrier(msg), WithTracer(tracer))
+	produce.Finish()
+
+	consume, _ := TraceConsume(context.Background(), msg.Topic, NewKafkaGoCarrier(msg), WithTracer(tracer))
+	consume.Finish()
+
+	if consume.(*mocktracer.MockSpan).ParentID != produce.(*mocktracer.MockSpan).SpanContext.SpanID {
+		t.Error("the consumer span doesn't follow from the producer span")
+	}
+}
+
+func TestCarriersReplaceHeaders(t *testing.T) {
+	sm := &sarama.ProducerMessage{}
+	sc := NewSaramaProducerCarrier(sm)
+	sc.Set("trace", "1")
+	sc.Set("trace", "2")
+	if len(sm.Headers) != 1 || string(sm.Headers[0].Value) != "2" {
+		t.Errorf("sarama headers = %v, want a single trace=2 header", sm.Headers)
+	}
+
+	km := &kafkago.Message{}
+	kc := NewKafkaGoCarrier(km)
+	kc.Set("trace", "1")
+	kc.Set("trace", "2")
+	if len(km.Headers) != 1 || string(km.Headers[0].Value) != "2" {
+		t.Errorf("kafka-go headers = %v, want a single trace=2 header", km.Headers)
+	}
+}
@@ -0,0 +1,66 @@
+// Package lightstep bootstraps a Lightstep tracer. Importing it registers
+// the "lightstep" backend for opentracing_helpers.InitTracer:
+//
+//	import _ "github.com/jfernandez/opentracing-helpers/lightstep"
+//
+//	// TRACER_BACKEND=lightstep LIGHTSTEP_ACCESS_TOKEN=...
+//	tracer, closer, err := opentracing_helpers.InitTracer("users")
+package lightstep
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/lightstep/lightstep-tracer-go"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	opentracing_helpers.RegisterTracerFactory("lightstep", NewTracer)
+}
+
+// NewTracer creates a Lightstep tracer named serviceName, configured from
+// the LIGHTSTEP_ACCESS_TOKEN, LIGHTSTEP_COLLECTOR_HOST,
+// LIGHTSTEP_COLLECTOR_PORT and LIGHTSTEP_COLLECTOR_PLAINTEXT environment
+// variables. The collector defaults to Lightstep's public satellites.
+// Closing the closer flushes buffered spans.
+func NewTracer(serviceName string) (opentracing.Tracer, io.Closer, error) {
+	opts := lightstep.Options{
+		AccessToken: os.Getenv("LIGHTSTEP_ACCESS_TOKEN"),
+		Collector: lightstep.Endpoint{
+			Host: os.Getenv("LIGHTSTEP_COLLECTOR_HOST"),
+		},
+		Tags: opentracing.Tags{lightstep.ComponentNameKey: serviceName},
+	}
+	if port := os.Getenv("LIGHTSTEP_COLLECTOR_PORT"); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.Collector.Port = p
+	}
+	if plaintext := os.Getenv("LIGHTSTEP_COLLECTOR_PLAINTEXT"); plaintext != "" {
+		p, err := strconv.ParseBool(plaintext)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.Collector.Plaintext = p
+	}
+	tracer, err := lightstep.CreateTracer(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tracer, closer{tracer}, nil
+}
+
+type closer struct {
+	tracer lightstep.Tracer
+}
+
+func (c closer) Close() error {
+	lightstep.Close(context.Background(), c.tracer)
+	return nil
+}
@@ -0,0 +1,41 @@
+package lightstep
+
+import (
+	"testing"
+
+	"github.com/lightstep/lightstep-tracer-go"
+)
+
+func TestNewTracerInvalidEnvironment(t *testing.T) {
+	for key, value := range map[string]string{
+		"LIGHTSTEP_COLLECTOR_PORT":      "http",
+		"LIGHTSTEP_COLLECTOR_PLAINTEXT": "maybe",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv("LIGHTSTEP_ACCESS_TOKEN", "token")
+			t.Setenv(key, value)
+			if _, _, err := NewTracer("users"); err == nil {
+				t.Errorf("expected an error for %s=%s", key, value)
+			}
+		})
+	}
+}
+
+func TestNewTracer(t *testing.T) {
+	t.Setenv("LIGHTSTEP_ACCESS_TOKEN", "token")
+	t.Setenv("LIGHTSTEP_COLLECTOR_HOST", "127.0.0.1")
+	t.Setenv("LIGHTSTEP_COLLECTOR_PORT", "1")
+	t.Setenv("LIGHTSTEP_COLLECTOR_PLAINTEXT", "true")
+	// The collector address is unreachable, don't log the report errors.
+	lightstep.SetGlobalEventHandler(func(lightstep.Event) {})
+
+	tracer, closer, err := NewTracer("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := tracer.StartSpan("op")
+	span.Finish()
+	if err := closer.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,52 @@
+// Package logrus adds the trace and span IDs of the span in a context to
+// logrus entries, so that logs and traces can be joined:
+//
+//	otlogrus.LoggerWithTrace(r.Context(), logger).Info("user created")
+package logrus
+
+import (
+	"context"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/sirupsen/logrus"
+)
+
+// Option customizes LoggerWithTrace.
+type Option func(*config)
+
+type config struct {
+	codec opentracing_helpers.SpanContextCodec
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithCodec reads the IDs with codec instead of from the tracer's native
+// headers. See opentracing_helpers.SpanIdentifiersFromContext.
+func WithCodec(codec opentracing_helpers.SpanContextCodec) Option {
+	return func(c *config) {
+		c.codec = codec
+	}
+}
+
+// LoggerWithTrace returns an entry of logger, a *logrus.Logger or
+// *logrus.Entry, with the trace_id, span_id and
+// sampled fields of the span in ctx. The entry has no such fields if ctx
+// carries no span.
+func LoggerWithTrace(ctx context.Context, logger logrus.FieldLogger, opts ...Option) *logrus.Entry {
+	c := newConfig(opts)
+	ids, ok := opentracing_helpers.SpanIdentifiersFromContext(ctx, c.codec)
+	if !ok {
+		return logger.WithFields(nil)
+	}
+	return logger.WithFields(logrus.Fields{
+		"trace_id": ids.TraceID,
+		"span_id":  ids.SpanID,
+		"sampled":  ids.Sampled,
+	})
+}
@@ -0,0 +1,48 @@
+package logrus
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/sirupsen/logrus"
+)
+
+// codec reports fixed IDs for mocktracer span contexts.
+type codec struct{}
+
+func (codec) Identifiers(sc opentracing.SpanContext) (opentracing_helpers.SpanIdentifiers, bool) {
+	_, ok := sc.(mocktracer.MockSpanContext)
+	return opentracing_helpers.SpanIdentifiers{TraceID: "463ac35c9f6413ad", SpanID: "a2fb4a1d1a96d312", Sampled: true}, ok
+}
+
+func (codec) SpanContext(opentracing_helpers.SpanIdentifiers) (opentracing.SpanContext, error) {
+	return nil, opentracing.ErrUnsupportedFormat
+}
+
+func TestLoggerWithTrace(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = io.Discard
+	span := mocktracer.New().StartSpan("op")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	for _, fl := range []logrus.FieldLogger{logger, logger.WithField("component", "users")} {
+		entry := LoggerWithTrace(ctx, fl, WithCodec(codec{}))
+		if entry.Data["trace_id"] != "463ac35c9f6413ad" || entry.Data["span_id"] != "a2fb4a1d1a96d312" || entry.Data["sampled"] != true {
+			t.Errorf("entry fields %v", entry.Data)
+		}
+	}
+	if got := LoggerWithTrace(ctx, logger.WithField("component", "users"), WithCodec(codec{})).Data["component"]; got != "users" {
+		t.Errorf("component = %v, want the fields of the entry to be kept", got)
+	}
+}
+
+func TestLoggerWithTraceWithoutSpan(t *testing.T) {
+	entry := LoggerWithTrace(context.Background(), logrus.New())
+	if len(entry.Data) != 0 {
+		t.Errorf("entry fields %v, want none", entry.Data)
+	}
+}
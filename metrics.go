@@ -0,0 +1,54 @@
+package opentracing_helpers
+
+import (
+	"time"
+)
+
+// RequestMetrics describes a completed request, for MetricsObserver.
+type RequestMetrics struct {
+	// Operation is the span operation name. For servers it is only used
+	// once the route of the request is known, from the pattern of
+	// TraceHandler or of an http.ServeMux behind NewMiddleware, and is else
+	// the request method, so that raw paths never make for unbounded
+	// metric labels.
+	Operation string
+	// Kind is "server" or "client".
+	Kind   string
+	Method string
+	// StatusCode is 0 if no response was received.
+	StatusCode int
+	Error      bool
+	// Duration is the time spent in the handler, or for clients until the
+	// response headers were received.
+	Duration time.Duration
+}
+
+// MetricsObserver receives RED (rate, errors, duration) metrics for every
+// traced request, so the same instrumentation can feed a metrics system.
+// The prometheus sub-package provides an implementation.
+type MetricsObserver interface {
+	ObserveRequest(m RequestMetrics)
+}
+
+// WithMetricsObserver reports metrics for every request traced by
+// TraceHandler, NewMiddleware or TracedTransport to m.
+func WithMetricsObserver(m MetricsObserver) Option {
+	return commonOption(func(c *commonConfig) {
+		c.metrics = m
+	})
+}
+
+func (c *commonConfig) observeMetrics(m RequestMetrics) {
+	if c.metrics != nil {
+		c.metrics.ObserveRequest(m)
+	}
+}
+
+// boundedOperationName returns the name of the server span of sr if it
+// derives from the route, and else the request method.
+func (sr *serverRequest) boundedOperationName() string {
+	if sr.route == "" {
+		return sr.r.Method
+	}
+	return sr.operationName
+}
@@ -0,0 +1,119 @@
+package opentracing_helpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// metricsRecorder is a MetricsObserver keeping what it observed.
+type metricsRecorder struct {
+	mu       sync.Mutex
+	observed []RequestMetrics
+}
+
+func (m *metricsRecorder) ObserveRequest(rm RequestMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observed = append(m.observed, rm)
+}
+
+// only returns the single observation, failing the test otherwise.
+func (m *metricsRecorder) only(t *testing.T) RequestMetrics {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.observed) != 1 {
+		t.Fatalf("observed %d requests, want 1", len(m.observed))
+	}
+	return m.observed[0]
+}
+
+func TestWithMetricsObserverServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.Handler
+		status  int
+		failed  bool
+	}{
+		{"ok", okHandler, http.StatusOK, false},
+		{"server error", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}), http.StatusBadGateway, true},
+		{"panic", panicking, http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := &metricsRecorder{}
+			_, h := TraceHandler("/users/{id}", tt.handler, WithTracer(mocktracer.New()),
+				WithMetricsObserver(metrics), WithPanicRecovery(false))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+
+			m := metrics.only(t)
+			if m.Operation != "DELETE /users/{id}" || m.Kind != "server" || m.Method != http.MethodDelete {
+				t.Errorf("observed %+v", m)
+			}
+			if m.StatusCode != tt.status || m.Error != tt.failed {
+				t.Errorf("status %d, error %v; want %d, %v", m.StatusCode, m.Error, tt.status, tt.failed)
+			}
+			if m.Duration <= 0 {
+				t.Errorf("duration = %v", m.Duration)
+			}
+		})
+	}
+}
+
+func TestWithMetricsObserverRoute(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /users/{id}", okHandler)
+	tests := []struct {
+		path, operation string
+	}{
+		{"/users/1", "GET /users/{id}"},
+		{"/unknown/1", http.MethodGet},
+	}
+	for _, tt := range tests {
+		metrics := &metricsRecorder{}
+		h := NewMiddleware(WithTracer(mocktracer.New()), WithMetricsObserver(metrics))(mux)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if m := metrics.only(t); m.Operation != tt.operation {
+			t.Errorf("%s: operation %q, want %q", tt.path, m.Operation, tt.operation)
+		}
+	}
+}
+
+func TestWithMetricsObserverClient(t *testing.T) {
+	metrics := &metricsRecorder{}
+	client := &http.Client{Transport: NewTracedTransport(respond(http.StatusServiceUnavailable, "", nil),
+		WithTracer(mocktracer.New()), WithMetricsObserver(metrics))}
+	resp, err := client.Get("http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	m := metrics.only(t)
+	if m.Operation != "HTTP GET" || m.Kind != "client" || m.StatusCode != 503 || !m.Error {
+		t.Errorf("observed %+v", m)
+	}
+}
+
+func TestWithMetricsObserverClientError(t *testing.T) {
+	metrics := &metricsRecorder{}
+	failing := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	client := &http.Client{Transport: NewTracedTransport(failing,
+		WithTracer(mocktracer.New()), WithMetricsObserver(metrics))}
+	if _, err := client.Get("http://example.com/"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if m := metrics.only(t); m.StatusCode != 0 || !m.Error {
+		t.Errorf("observed %+v, want an error without status", m)
+	}
+}
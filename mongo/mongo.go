@@ -0,0 +1,116 @@
+// Package mongo traces commands sent by the official MongoDB Go driver.
+// Spans are children of the span carried by the context passed to the
+// driver, for example the request context of a traced handler:
+//
+//	opts := options.Client().ApplyURI(uri).SetMonitor(otmongo.NewMonitor())
+//	client, err := mongo.Connect(opts)
+package mongo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "mongo-go-driver"}
+
+// Option customizes the monitor.
+type Option func(*config)
+
+type config struct {
+	tracer opentracing.Tracer
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) activeTracer() opentracing.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return opentracing.GlobalTracer()
+}
+
+// WithTracer uses tracer instead of opentracing.GlobalTracer().
+func WithTracer(tracer opentracing.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// monitor tracks the spans of in-flight commands by request ID, since the
+// driver reports the start and the end of a command in separate events.
+type monitor struct {
+	config *config
+	mu     sync.Mutex
+	spans  map[int64]opentracing.Span
+}
+
+// NewMonitor returns a command monitor to pass to
+// options.ClientOptions.SetMonitor.
+func NewMonitor(opts ...Option) *event.CommandMonitor {
+	m := &monitor{config: newConfig(opts), spans: make(map[int64]opentracing.Span)}
+	return &event.CommandMonitor{
+		Started:   m.started,
+		Succeeded: m.succeeded,
+		Failed:    m.failed,
+	}
+}
+
+func (m *monitor) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	var parent opentracing.SpanContext
+	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+		parent = parentSpan.Context()
+	}
+	span := m.config.activeTracer().StartSpan(
+		"mongo "+evt.CommandName,
+		opentracing.ChildOf(parent),
+		ext.SpanKindRPCClient,
+		componentTag,
+	)
+	ext.DBType.Set(span, "mongo")
+	ext.DBInstance.Set(span, evt.DatabaseName)
+	span.SetTag("mongo.command", evt.CommandName)
+	span.SetTag("mongo.connection_id", evt.ConnectionID)
+	// Most commands name their collection as the value of the command key,
+	// as in {"find": "users", ...}.
+	if collection, ok := evt.Command.Lookup(evt.CommandName).StringValueOK(); ok {
+		span.SetTag("mongo.collection", collection)
+	}
+
+	m.mu.Lock()
+	m.spans[evt.RequestID] = span
+	m.mu.Unlock()
+}
+
+func (m *monitor) succeeded(_ context.Context, evt *event.CommandSucceededEvent) {
+	m.finish(evt.RequestID, nil)
+}
+
+func (m *monitor) failed(_ context.Context, evt *event.CommandFailedEvent) {
+	m.finish(evt.RequestID, evt.Failure)
+}
+
+func (m *monitor) finish(requestID int64, err error) {
+	m.mu.Lock()
+	span, ok := m.spans[requestID]
+	delete(m.spans, requestID)
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+	span.Finish()
+}
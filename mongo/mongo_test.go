@@ -0,0 +1,95 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+func started(t *testing.T, requestID int64, name string, command bson.D) *event.CommandStartedEvent {
+	t.Helper()
+	raw, err := bson.Marshal(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &event.CommandStartedEvent{
+		Command:      raw,
+		DatabaseName: "shop",
+		CommandName:  name,
+		RequestID:    requestID,
+		ConnectionID: "localhost:27017[-1]",
+	}
+}
+
+func finished(requestID int64, name string) event.CommandFinishedEvent {
+	return event.CommandFinishedEvent{CommandName: name, DatabaseName: "shop", RequestID: requestID}
+}
+
+func TestMonitor(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	m := NewMonitor(WithTracer(tracer))
+
+	m.Started(ctx, started(t, 1, "find", bson.D{{Key: "find", Value: "users"}}))
+	m.Started(ctx, started(t, 2, "ping", bson.D{{Key: "ping", Value: 1}}))
+	m.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished(2, "ping")})
+	m.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished(1, "find")})
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	ping, find := spans[0], spans[1]
+	if find.OperationName != "mongo find" || ping.OperationName != "mongo ping" {
+		t.Errorf("operation names = %q, %q", find.OperationName, ping.OperationName)
+	}
+	if find.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the command span is not a child of the context span")
+	}
+	for key, want := range map[string]interface{}{
+		string(ext.DBType):     "mongo",
+		string(ext.DBInstance): "shop",
+		string(ext.SpanKind):   ext.SpanKindRPCClientEnum,
+		"mongo.command":        "find",
+		"mongo.collection":     "users",
+		"mongo.connection_id":  "localhost:27017[-1]",
+	} {
+		if got := find.Tag(key); got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+	if got := ping.Tag("mongo.collection"); got != nil {
+		t.Errorf("ping: mongo.collection = %v, want none", got)
+	}
+}
+
+func TestMonitorFailed(t *testing.T) {
+	tracer := mocktracer.New()
+	m := NewMonitor(WithTracer(tracer))
+
+	m.Started(context.Background(), started(t, 1, "insert", bson.D{{Key: "insert", Value: "orders"}}))
+	m.Failed(context.Background(), &event.CommandFailedEvent{
+		CommandFinishedEvent: finished(1, "insert"),
+		Failure:              errors.New("duplicate key"),
+	})
+	// An event for an unknown request is ignored.
+	m.Failed(context.Background(), &event.CommandFailedEvent{CommandFinishedEvent: finished(7, "insert")})
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Tag(string(ext.Error)) != true {
+		t.Error("the failed command is not tagged as an error")
+	}
+	if len(spans[0].Logs()) == 0 {
+		t.Error("the failure was not logged")
+	}
+}
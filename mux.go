@@ -0,0 +1,52 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// TraceMux is an http.ServeMux whose routes are traced, each with its own
+// settings on top of those shared by the mux:
+//
+//	mux := opentracing_helpers.NewTraceMux(opentracing_helpers.WithTracer(tracer))
+//	mux.Handle("/checkout", checkout,
+//		opentracing_helpers.WithSamplingPriority(1),
+//		opentracing_helpers.WithCapturedHeaders([]string{"X-Cart-Id"}))
+//	mux.Handle("/healthz", healthz, opentracing_helpers.WithFilter(func(*http.Request) bool { return false }))
+//	http.ListenAndServe(":8080", mux)
+type TraceMux struct {
+	mux  *http.ServeMux
+	opts []HandlerOption
+}
+
+// NewTraceMux returns a TraceMux applying opts to every route.
+func NewTraceMux(opts ...HandlerOption) *TraceMux {
+	return &TraceMux{mux: http.NewServeMux(), opts: opts}
+}
+
+// Handle registers handler for pattern, traced with the options of the mux
+// followed by opts.
+func (m *TraceMux) Handle(pattern string, handler http.Handler, opts ...HandlerOption) {
+	all := append(m.opts[:len(m.opts):len(m.opts)], opts...)
+	m.mux.Handle(TraceHandler(pattern, handler, all...))
+}
+
+// HandleFunc registers handler for pattern like Handle.
+func (m *TraceMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), opts ...HandlerOption) {
+	m.Handle(pattern, http.HandlerFunc(handler), opts...)
+}
+
+// ServeHTTP implements http.Handler.
+func (m *TraceMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// WithSamplingPriority sets the sampling.priority tag on server spans,
+// asking the tracer to keep (priority > 0) or drop (0) their traces.
+func WithSamplingPriority(priority uint16) HandlerOption {
+	return WithSpanObserver(func(span opentracing.Span, _ *http.Request) {
+		ext.SamplingPriority.Set(span, priority)
+	})
+}
@@ -0,0 +1,56 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// sampledRequest returns a request continuing a sampled trace of tracer.
+func sampledRequest(tracer *mocktracer.MockTracer, path string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	parent := tracer.StartSpan("client")
+	tracer.Inject(parent.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+	return r
+}
+
+func TestTraceMux(t *testing.T) {
+	tracer := mocktracer.New()
+	mux := NewTraceMux(WithTracer(tracer))
+	mux.Handle("/checkout", okHandler, WithSamplingPriority(0))
+	mux.HandleFunc("/healthz", okHandler, WithFilter(func(*http.Request) bool { return false }))
+	mux.Handle("/items/", okHandler)
+
+	for _, path := range []string{"/checkout", "/healthz", "/items/42"} {
+		mux.ServeHTTP(httptest.NewRecorder(), sampledRequest(tracer, path))
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d finished spans, want 2 with /healthz filtered", len(spans))
+	}
+	// The mock tracer turns sampling.priority into the sampled flag.
+	if spans[0].OperationName != "GET /checkout" || spans[0].SpanContext.Sampled {
+		t.Errorf("checkout span %q is sampled, want it dropped", spans[0].OperationName)
+	}
+	if spans[1].OperationName != "GET /items/" || !spans[1].SpanContext.Sampled {
+		t.Errorf("items span %q is not sampled", spans[1].OperationName)
+	}
+}
+
+func TestTraceMuxDoesNotShareRouteOptions(t *testing.T) {
+	tracer := mocktracer.New()
+	opts := make([]HandlerOption, 1, 4)
+	opts[0] = WithTracer(tracer)
+	mux := NewTraceMux(opts...)
+	mux.Handle("/a", okHandler, WithSamplingPriority(0))
+	mux.Handle("/b", okHandler)
+
+	mux.ServeHTTP(httptest.NewRecorder(), sampledRequest(tracer, "/b"))
+	if !finishedSpan(t, tracer).SpanContext.Sampled {
+		t.Error("/b got the sampling priority of the /a route")
+	}
+}
@@ -0,0 +1,146 @@
+// Package nats propagates spans through NATS message headers, for use with
+// nats-io/nats.go:
+//
+//	err := otnats.Publish(ctx, nc, &nats.Msg{Subject: "orders.created", Data: data})
+//
+//	sub, err := otnats.Subscribe(nc, "orders.*", func(ctx context.Context, msg *nats.Msg) {
+//	    // ctx carries the consumer span.
+//	})
+package nats
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "nats"}
+
+// Option customizes the spans started by this package.
+type Option func(*config)
+
+type config struct {
+	tracer opentracing.Tracer
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) activeTracer() opentracing.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return opentracing.GlobalTracer()
+}
+
+// WithTracer uses tracer instead of opentracing.GlobalTracer().
+func WithTracer(tracer opentracing.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// headerCarrier adapts nats.Header to the opentracing TextMap interfaces.
+// Unlike HTTP headers, NATS header keys are kept as is.
+type headerCarrier nats.Header
+
+func (c headerCarrier) Set(key, val string) {
+	c[key] = []string{val}
+}
+
+func (c headerCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, vals := range c {
+		for _, v := range vals {
+			if err := handler(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// InjectNATSHeaders injects the context of the span in ctx into the
+// headers of msg. It does nothing if ctx carries no span.
+func InjectNATSHeaders(ctx context.Context, msg *nats.Msg, opts ...Option) error {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+	return inject(newConfig(opts).activeTracer(), span.Context(), msg)
+}
+
+// ExtractNATSHeaders extracts a span context from the headers of msg.
+func ExtractNATSHeaders(msg *nats.Msg, opts ...Option) (opentracing.SpanContext, error) {
+	return newConfig(opts).activeTracer().Extract(opentracing.TextMap, headerCarrier(msg.Header))
+}
+
+func inject(tracer opentracing.Tracer, sc opentracing.SpanContext, msg *nats.Msg) error {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	return tracer.Inject(sc, opentracing.TextMap, headerCarrier(msg.Header))
+}
+
+// Publish publishes msg within a producer span that is a child of the span
+// in ctx.
+func Publish(ctx context.Context, nc *nats.Conn, msg *nats.Msg, opts ...Option) error {
+	tracer := newConfig(opts).activeTracer()
+	var parent opentracing.SpanContext
+	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+		parent = parentSpan.Context()
+	}
+	span := tracer.StartSpan(
+		"nats publish "+msg.Subject,
+		opentracing.ChildOf(parent),
+		ext.SpanKindProducer,
+		componentTag,
+	)
+	defer span.Finish()
+	tagMessage(span, msg)
+
+	if err := inject(tracer, span.Context(), msg); err != nil {
+		span.LogFields(log.String("event", "inject failed"), log.Error(err))
+	}
+	err := nc.PublishMsg(msg)
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+	return err
+}
+
+// Subscribe subscribes to subject and calls handler for every message
+// within a consumer span that follows from the publisher's span.
+func Subscribe(nc *nats.Conn, subject string, handler func(ctx context.Context, msg *nats.Msg), opts ...Option) (*nats.Subscription, error) {
+	c := newConfig(opts)
+	return nc.Subscribe(subject, func(msg *nats.Msg) {
+		tracer := c.activeTracer()
+		producer, _ := tracer.Extract(opentracing.TextMap, headerCarrier(msg.Header))
+		span := tracer.StartSpan(
+			"nats receive "+msg.Subject,
+			opentracing.FollowsFrom(producer),
+			ext.SpanKindConsumer,
+			componentTag,
+		)
+		defer span.Finish()
+		tagMessage(span, msg)
+
+		handler(opentracing.ContextWithSpan(context.Background(), span), msg)
+	})
+}
+
+func tagMessage(span opentracing.Span, msg *nats.Msg) {
+	ext.MessageBusDestination.Set(span, msg.Subject)
+	span.SetTag("nats.subject", msg.Subject)
+	if msg.Reply != "" {
+		span.SetTag("nats.reply_subject", msg.Reply)
+	}
+}
@@ -0,0 +1,168 @@
+package nats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// serve runs a minimal single-connection NATS server that delivers every
+// HPUB back to the matching SUB, which is all Publish and Subscribe need.
+func serve(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var mu sync.Mutex
+		send := func(format string, args ...interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintf(conn, format, args...)
+		}
+		send("INFO {\"server_id\":\"test\",\"headers\":true,\"max_payload\":1048576,\"proto\":1}\r\n")
+
+		subs := map[string]string{}
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "PING":
+				send("PONG\r\n")
+			case "SUB":
+				subs[fields[1]] = fields[len(fields)-1]
+			case "HPUB":
+				subject, reply := fields[1], ""
+				if len(fields) == 5 {
+					reply = fields[2]
+				}
+				hdrLen, _ := strconv.Atoi(fields[len(fields)-2])
+				total, _ := strconv.Atoi(fields[len(fields)-1])
+				data := make([]byte, total+2)
+				if _, err := io.ReadFull(r, data); err != nil {
+					return
+				}
+				if sid, ok := subs[subject]; ok {
+					if reply != "" {
+						reply += " "
+					}
+					send("HMSG %s %s %s%d %d\r\n%s", subject, sid, reply, hdrLen, total, data)
+				}
+			}
+		}
+	}()
+	return "nats://" + l.Addr().String()
+}
+
+func TestPublishAndSubscribe(t *testing.T) {
+	tracer := mocktracer.New()
+	nc, err := nats.Connect(serve(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	received := make(chan opentracing.Span, 1)
+	_, err = Subscribe(nc, "orders.created", func(ctx context.Context, msg *nats.Msg) {
+		received <- opentracing.SpanFromContext(ctx)
+	}, WithTracer(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	msg := &nats.Msg{Subject: "orders.created", Reply: "inbox", Data: []byte("{}")}
+	if err := Publish(ctx, nc, msg, WithTracer(tracer)); err != nil {
+		t.Fatal(err)
+	}
+
+	var consumer *mocktracer.MockSpan
+	select {
+	case span := <-received:
+		consumer = span.(*mocktracer.MockSpan)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the message was not delivered")
+	}
+
+	var producer *mocktracer.MockSpan
+	for _, span := range tracer.FinishedSpans() {
+		if span.OperationName == "nats publish orders.created" {
+			producer = span
+		}
+	}
+	if producer == nil {
+		t.Fatalf("no publish span in %v", tracer.FinishedSpans())
+	}
+	if producer.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the publish span is not a child of the context span")
+	}
+	if consumer.OperationName != "nats receive orders.created" {
+		t.Errorf("consumer operation name = %q", consumer.OperationName)
+	}
+	if consumer.ParentID != producer.SpanContext.SpanID {
+		t.Error("the receive span doesn't follow from the publish span")
+	}
+	if got := producer.Tag(string(ext.SpanKind)); got != ext.SpanKindProducerEnum {
+		t.Errorf("producer span.kind = %v", got)
+	}
+	if got := consumer.Tag(string(ext.SpanKind)); got != ext.SpanKindConsumerEnum {
+		t.Errorf("consumer span.kind = %v", got)
+	}
+	for key, want := range map[string]interface{}{
+		"nats.subject":       "orders.created",
+		"nats.reply_subject": "inbox",
+	} {
+		if got := consumer.Tag(key); got != want {
+			t.Errorf("consumer span: %s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestInjectAndExtract(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("producer")
+	msg := &nats.Msg{Subject: "orders"}
+
+	if err := InjectNATSHeaders(context.Background(), msg, WithTracer(tracer)); err != nil || msg.Header != nil {
+		t.Fatalf("InjectNATSHeaders without a span = %v, headers %v", err, msg.Header)
+	}
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	if err := InjectNATSHeaders(ctx, msg, WithTracer(tracer)); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := ExtractNATSHeaders(msg, WithTracer(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.(mocktracer.MockSpanContext).SpanID != span.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the extracted context doesn't match the injected span")
+	}
+}
@@ -0,0 +1,58 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// WithDisabled skips all tracing work while disabled returns true, as if
+// the active tracer were an opentracing.NoopTracer. It allows turning
+// tracing off at runtime.
+func WithDisabled(disabled func() bool) Option {
+	return commonOption(func(c *commonConfig) {
+		c.disabled = disabled
+	})
+}
+
+// isNoopTracer reports whether tracer is known to record nothing.
+func isNoopTracer(tracer opentracing.Tracer) bool {
+	switch tracer.(type) {
+	case opentracing.NoopTracer, *opentracing.NoopTracer:
+		return true
+	}
+	return false
+}
+
+// noop reports whether tracing can be skipped altogether: Toggle is off,
+// or the tracer is a NoopTracer or tracing was disabled, and no metrics
+// are collected.
+func (c *commonConfig) noop() bool {
+	if !Toggle.Enabled() {
+		return true
+	}
+	if c.metrics != nil {
+		return false
+	}
+	return (c.disabled != nil && c.disabled()) || isNoopTracer(c.activeTracer())
+}
+
+// noop is like commonConfig.noop but also takes handler-only features
+// that work without a span into account.
+func (c *handlerConfig) noop() bool {
+	if !Toggle.Enabled() {
+		return true
+	}
+	return c.accessLog == nil && c.commonConfig.noop()
+}
+
+// skipIfNoop returns handler itself if c is configured with a NoopTracer,
+// so that wrapping it costs nothing. Neither the global tracer nor Toggle
+// is considered since they may still change. Handlers recovering panics
+// are always wrapped since recovery doesn't need a span.
+func (c *handlerConfig) skipIfNoop(handler, traced http.Handler) http.Handler {
+	if c.tracer != nil && isNoopTracer(c.tracer) && c.disabled == nil && c.metrics == nil && c.accessLog == nil && !c.recoverPanics {
+		return handler
+	}
+	return traced
+}
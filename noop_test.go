@@ -0,0 +1,64 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// countingHandler is a comparable handler counting its requests.
+type countingHandler struct{ requests int }
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { h.requests++ }
+
+func TestTraceHandlerNoopTracer(t *testing.T) {
+	for _, tracer := range []opentracing.Tracer{opentracing.NoopTracer{}, &opentracing.NoopTracer{}} {
+		handler := &countingHandler{}
+		if _, h := TraceHandler("/", handler, WithTracer(tracer)); h != http.Handler(handler) {
+			t.Errorf("%T: TraceHandler wrapped the handler", tracer)
+		}
+	}
+
+	// The global tracer may still change, so the handler stays wrapped.
+	handler := &countingHandler{}
+	if _, h := TraceHandler("/", handler); h == http.Handler(handler) {
+		t.Error("TraceHandler without WithTracer didn't wrap the handler")
+	}
+	// Metrics are collected without spans.
+	if _, h := TraceHandler("/", handler, WithTracer(opentracing.NoopTracer{}), WithMetricsObserver(&metricsRecorder{})); h == http.Handler(handler) {
+		t.Error("TraceHandler with a metrics observer didn't wrap the handler")
+	}
+}
+
+func TestWithDisabled(t *testing.T) {
+	tracer := mocktracer.New()
+	disabled := true
+	handler := &countingHandler{}
+	_, h := TraceHandler("/", handler, WithTracer(tracer), WithDisabled(func() bool { return disabled }))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if n := len(tracer.FinishedSpans()); n != 0 || handler.requests != 1 {
+		t.Fatalf("%d spans and %d requests while disabled, want 0 and 1", n, handler.requests)
+	}
+	disabled = false
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	finishedSpan(t, tracer)
+}
+
+func TestTracedTransportNoopTracer(t *testing.T) {
+	var sent *http.Request
+	client := &http.Client{Transport: NewTracedTransport(respond(http.StatusOK, "", &sent), WithTracer(opentracing.NoopTracer{}))}
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if sent != req {
+		t.Error("the request was cloned although nothing is traced")
+	}
+}
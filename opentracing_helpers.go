@@ -4,10 +4,15 @@ import (
 	"net/http"
 	"github.com/opentracing/opentracing-go"
 	"net/http/httptrace"
+	"time"
 	"context"
+	"crypto/tls"
 	"github.com/opentracing/opentracing-go/log"
+	"github.com/opentracing/opentracing-go/ext"
 )
 
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "net/http"}
+
 // TraceHandler facilitates tracing of handlers registered with an
 // http.ServeMux.  For example, to trace this code:
 //
@@ -17,26 +22,203 @@ import (
 //
 //    http.Handle(opentracing_helpers.TraceHandler("/foo", fooHandler))
 //
-func TraceHandler(pattern string, handler http.Handler) (string, http.Handler) {
-	return pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Look for the request caller's SpanContext in the headers
-		// If not found create a new SpanContext
-		carrier := opentracing.HTTPHeadersCarrier(r.Header)
-		tracer := opentracing.GlobalTracer()
-		parentSpanContext, _ := tracer.Extract(opentracing.HTTPHeaders, carrier)
-
-		spanName := r.Method + " " + pattern
-		var span opentracing.Span
-		if parentSpanContext == nil {
-			span = opentracing.StartSpan(spanName)
-		} else {
-			span = opentracing.StartSpan(spanName, opentracing.ChildOf(parentSpanContext))
+// The span can be customized with HandlerOptions:
+//
+//    http.Handle(opentracing_helpers.TraceHandler("/foo", fooHandler,
+//        opentracing_helpers.WithTracer(tracer)))
+//
+func TraceHandler(pattern string, handler http.Handler, opts ...HandlerOption) (string, http.Handler) {
+	return pattern, traceHandler(pattern, handler, newHandlerConfig(opts))
+}
+
+// TraceHandlerWithTracer is like TraceHandler but starts spans with tracer
+// instead of the global tracer, for services running several tracers.
+func TraceHandlerWithTracer(tracer opentracing.Tracer, pattern string, handler http.Handler, opts ...HandlerOption) (string, http.Handler) {
+	return TraceHandler(pattern, handler, append(opts[:len(opts):len(opts)], WithTracer(tracer))...)
+}
+
+// Middleware traces every request served by next. It has the standard
+// middleware signature so it can be used with chaining libraries, for
+// example chi's router.Use(opentracing_helpers.Middleware). Since the
+// registration pattern is unknown, spans are named after the request path,
+// or after the pattern matched by an http.ServeMux behind the middleware
+// such as "GET /items/{id}".
+func Middleware(next http.Handler) http.Handler {
+	return traceHandler("", next, newHandlerConfig(nil))
+}
+
+// NewMiddleware is like Middleware but accepts HandlerOptions:
+//
+//    r.Use(opentracing_helpers.NewMiddleware(opentracing_helpers.WithTracer(tracer)))
+//
+func NewMiddleware(opts ...HandlerOption) func(http.Handler) http.Handler {
+	c := newHandlerConfig(opts)
+	return func(next http.Handler) http.Handler {
+		return traceHandler("", next, c)
+	}
+}
+
+// Handler traces the requests served by a wrapped handler. It is what
+// TraceHandler and NewMiddleware return, unless tracing is disabled
+// altogether. Creating a Handler once and reusing it avoids work on every
+// request: for handlers registered with a pattern, the default span names
+// are computed up front.
+type Handler struct {
+	pattern string
+	next    http.Handler
+	config  *handlerConfig
+	names   map[string]string
+}
+
+// NewHandler returns a Handler tracing handler, registered with pattern,
+// which may be empty.
+func NewHandler(pattern string, handler http.Handler, opts ...HandlerOption) *Handler {
+	return newHandler(pattern, handler, newHandlerConfig(opts))
+}
+
+// standardMethods are the methods whose span names are precomputed.
+var standardMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+func newHandler(pattern string, handler http.Handler, c *handlerConfig) *Handler {
+	h := &Handler{pattern: pattern, next: handler, config: c}
+	if pattern != "" && !c.customOperationName {
+		h.names = make(map[string]string, len(standardMethods))
+		for _, method := range standardMethods {
+			h.names[method] = patternName(pattern, method)
 		}
-		defer span.Finish()
-		r = r.WithContext(opentracing.ContextWithSpan(r.Context(), span))
+	}
+	return h
+}
+
+func traceHandler(pattern string, handler http.Handler, c *handlerConfig) http.Handler {
+	return c.skipIfNoop(handler, newHandler(pattern, handler, c))
+}
+
+// operationName returns the name of the server span for r.
+func (h *Handler) operationName(r *http.Request) string {
+	if name, ok := h.names[r.Method]; ok {
+		return name
+	}
+	return h.config.operationName(h.pattern, r)
+}
 
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, pattern, handler := h.config, h.pattern, h.next
+	if c.noop() || !c.traced(r) {
+		if c.recoverPanics {
+			c.serveRecovered(w, r, handler)
+			return
+		}
 		handler.ServeHTTP(w, r)
+		return
+	}
+
+	// A span already in the request context, inserted by an upstream
+	// middleware, is the closest parent. Otherwise look for the
+	// request caller's SpanContext in the headers.
+	// If not found create a new SpanContext
+	tracer := c.activeTracer()
+	var parentRef opentracing.StartSpanOption
+	extractFailed := false
+	if ctxSpan := opentracing.SpanFromContext(r.Context()); ctxSpan != nil {
+		parentRef = opentracing.SpanReference{Type: c.contextSpanRef, ReferencedContext: ctxSpan.Context()}
+	} else {
+		parentSpanContext, err := c.extractRequest(r)
+		extractFailed = c.extractFailed(err, r)
+		parentRef = opentracing.ChildOf(parentSpanContext)
+	}
+
+	spanName := h.operationName(r)
+	start := time.Now()
+	span := NewSafeSpan(tracer.StartSpan(spanName, parentRef, ext.SpanKindRPCServer, componentTag, opentracing.StartTime(start)))
+	defer span.Finish()
+	ext.HTTPMethod.Set(span, r.Method)
+	ext.HTTPUrl.Set(span, c.urlTag(r.URL))
+	ext.PeerAddress.Set(span, r.RemoteAddr)
+	if extractFailed && c.extractFailedTag {
+		span.SetTag("trace.extract_failed", true)
+	}
+	for _, observe := range c.spanObservers {
+		observe(span, r)
+	}
+	c.logHeaders(span, "request headers", "http.request.header.", r.Header)
+	sr := &serverRequest{
+		config:        c,
+		span:          span,
+		rr:            responseRecorder{ResponseWriter: w, status: http.StatusOK},
+		pattern:       pattern,
+		route:         pattern,
+		operationName: spanName,
+		start:         start,
+	}
+	defer sr.finishSpan()
+	ctx := opentracing.ContextWithSpan(r.Context(), span)
+	r = r.WithContext(context.WithValue(ctx, serverRequestKey{}, sr))
+	sr.r = r
+	if bc := c.captureRequestBody(span, r); bc != nil {
+		defer bc.log()
+	}
+	c.writeTraceIDHeader(w, r)
+
+	if c.recoverPanics {
+		defer func() {
+			if p := recover(); p != nil {
+				c.recordPanic(sr, p)
+			}
+		}()
+	}
+	defer c.watchContext(r.Context(), sr)()
+	c.serve(sr, handler, sr.rr.writer())
+	c.finishServerSpan(sr)
+}
+
+// serverRequest is the state of a request traced by a Handler. The
+// response recorder is embedded to save an allocation.
+type serverRequest struct {
+	config        *handlerConfig
+	span          opentracing.Span
+	r             *http.Request
+	rr            responseRecorder
+	pattern       string
+	operationName string
+	start         time.Time
+
+	// route is the pattern the request was matched with, if known.
+	route string
+	// disconnected is when the client of a streaming response went away.
+	disconnected time.Time
+
+	panicked      bool
+	failed        bool
+}
+
+// serverRequestKey is the request context key of the serverRequest.
+type serverRequestKey struct{}
+
+// finishServerSpan records the response written by the handler on the span.
+func (c *handlerConfig) finishServerSpan(sr *serverRequest) {
+	c.tagRoute(sr)
+	span, rr := sr.span, &sr.rr
+	c.logHeaders(span, "response headers", "http.response.header.", rr.Header())
+	ext.HTTPStatusCode.Set(span, uint16(rr.status))
+	span.SetTag("http.response_size", rr.size)
+	sr.failed = c.tagError(span, rr.status, nil) || sr.panicked
+	c.decorate(span, sr.r, rr.status)
+	duration := time.Since(sr.start)
+	c.observeMetrics(RequestMetrics{
+		Operation:  sr.boundedOperationName(),
+		Kind:       "server",
+		Method:     sr.r.Method,
+		StatusCode: rr.status,
+		Error:      sr.failed,
+		Duration:   duration,
 	})
+	c.logAccess(sr, duration)
+	c.finished(span, sr.r, rr.status, duration)
 }
 
 // TraceRequest facilities the tracing of a http.Request by injecting the
@@ -49,57 +231,129 @@ func TraceHandler(pattern string, handler http.Handler) (string, http.Handler) {
 //	      span.SetTag("error", true)
 //    }
 //    span.Finish()
+//
+// Use WrapResponseBody to finish the span only once the response body has
+// been consumed. TransportOptions such as WithTracer and
+// WithOperationNameFunc are honored as well.
+//
+// Deprecated: TraceRequest copies the request by value. Use
+// TraceRequestContext, which takes a *http.Request.
+func TraceRequest(operationName string, ctx context.Context, r http.Request, opts ...TransportOption) (*http.Request, opentracing.Span) {
+	c := newTransportConfig(opts)
+	span := c.startSpan(ctx, &r, c.spanName(&r, operationName))
+	c.captureRequestBody(span, &r)
+	c.inject(span.Context(), r.Header)
 
-func TraceRequest(operationName string, ctx context.Context, r http.Request) (*http.Request, opentracing.Span) {
-	span, ctx := opentracing.StartSpanFromContext(ctx, operationName)
-	opentracing.GlobalTracer().Inject(
-		span.Context(),
-		opentracing.HTTPHeaders,
-		opentracing.HTTPHeadersCarrier(r.Header))
+	return r.WithContext(c.withClientTrace(r.Context(), span)), span
+}
 
-	trace := &httptrace.ClientTrace{
+// TraceRequestWithTracer is like TraceRequest but uses tracer instead of
+// the global tracer to start the span and inject its context.
+//
+// Deprecated: Use TraceRequestContext with the WithTracer option.
+func TraceRequestWithTracer(tracer opentracing.Tracer, operationName string, ctx context.Context, r http.Request, opts ...TransportOption) (*http.Request, opentracing.Span) {
+	return TraceRequest(operationName, ctx, r, append(opts[:len(opts):len(opts)], WithTracer(tracer))...)
+}
+
+// TraceRequestContext traces r like TraceRequest. The span is a child of
+// the span in ctx, and the returned request is a clone of r using ctx,
+// extended with the span, as its context; r itself is left unmodified.
+// For example:
+//
+//    req, _ := http.NewRequest("GET", "http://example.com/", nil)
+//    tracedReq, span := opentracing_helpers.TraceRequestContext(r.Context(), "GET example.com", req)
+//    resp, err := http.DefaultClient.Do(tracedReq)
+//    if err != nil {
+//        span.SetTag("error", true)
+//        span.Finish()
+//        return err
+//    }
+//    opentracing_helpers.WrapResponseBody(resp, span)
+//
+func TraceRequestContext(ctx context.Context, operationName string, r *http.Request, opts ...TransportOption) (*http.Request, opentracing.Span) {
+	c := newTransportConfig(opts)
+	span := c.startSpan(ctx, r, c.spanName(r, operationName))
+	ctx = c.withClientTrace(opentracing.ContextWithSpan(ctx, span), span)
+	r = r.Clone(ctx)
+	c.captureRequestBody(span, r)
+	c.inject(span.Context(), r.Header)
+	return r, span
+}
+
+// newClientTrace returns a ClientTrace that logs connection events on span.
+// The hooks may run on other goroutines than the one finishing the span,
+// so events are buffered and only added to the span when it finishes.
+// Event details are logged as typed fields, or as log.Object payloads if
+// the transport was configured WithObjectLogFields.
+func newClientTrace(span opentracing.Span, c *transportConfig) *httptrace.ClientTrace {
+	logFields := span.LogFields
+	if s, ok := span.(*SafeSpan); ok {
+		logFields = s.bufferLogFields
+	}
+	object := log.Object
+	if c.lazyLogFields {
+		object = lazyObject
+	}
+	return &httptrace.ClientTrace{
 		GetConn: func(hostPort string) {
-			span.LogFields(
+			logFields(
 				log.String("event", "Get Connection "),
 				log.String("host:port", hostPort),
 			)
 		},
 		GotConn: func(connInfo httptrace.GotConnInfo) {
-			span.LogFields(
-				log.String("event", "Got Connection"),
-				log.Object("connection info", connInfo),
-			)
+			tagConn(span, connInfo)
+			if c.objectLogFields {
+				logFields(log.String("event", "Got Connection"), object("connection info", connInfo))
+				return
+			}
+			logFields(append([]log.Field{log.String("event", "Got Connection")}, gotConnFields(connInfo)...)...)
 		},
 		DNSStart: func(dnsInfo httptrace.DNSStartInfo) {
-			span.LogFields(
-				log.String("event", "DNS Start"),
-				log.Object("dns start info", dnsInfo),
-			)
+			if c.objectLogFields {
+				logFields(log.String("event", "DNS Start"), object("dns start info", dnsInfo))
+				return
+			}
+			logFields(log.String("event", "DNS Start"), log.String("dns.host", dnsInfo.Host))
 		},
 		DNSDone: func(dnsInfo httptrace.DNSDoneInfo) {
-			span.LogFields(
-				log.String("event", "DNS Done"),
-				log.Object("dns done info", dnsInfo),
-			)
+			if c.objectLogFields {
+				logFields(log.String("event", "DNS Done"), object("dns done info", dnsInfo))
+				return
+			}
+			logFields(append([]log.Field{log.String("event", "DNS Done")}, dnsDoneFields(dnsInfo)...)...)
 		},
 		ConnectDone: func(network, addr string, err error) {
-			span.LogFields(
+			logFields(
 				log.String("event", "Connect Done"),
-				log.Object("network", network),
+				log.String("network", network),
 				log.String("address", addr),
 				log.Error(err),
 			)
 		},
+		TLSHandshakeStart: func() {
+			logFields(log.String("event", "TLS Handshake Start"))
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			fields := append([]log.Field{log.String("event", "TLS Handshake Done")}, tlsFields(state)...)
+			if err != nil {
+				fields = append(fields, log.Error(err))
+			}
+			logFields(fields...)
+		},
 		GotFirstResponseByte: func() {
-			span.LogFields(log.String("event", "Got First Response Byte"))
+			logFields(log.String("event", "Got First Response Byte"))
 		},
 		WroteRequest: func(info httptrace.WroteRequestInfo) {
-			span.LogFields(
-				log.String("event", "Wrote Request"),
-				log.Object("wrote request info", info),
-			)
+			if c.objectLogFields {
+				logFields(log.String("event", "Wrote Request"), object("wrote request info", info))
+				return
+			}
+			if info.Err != nil {
+				logFields(log.String("event", "Wrote Request"), log.Error(info.Err))
+				return
+			}
+			logFields(log.String("event", "Wrote Request"))
 		},
 	}
-
-	return r.WithContext(httptrace.WithClientTrace(r.Context(), trace)), span
 }
@@ -0,0 +1,376 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// finishedSpan returns the only span finished by tracer.
+func finishedSpan(t *testing.T, tracer *mocktracer.MockTracer) *mocktracer.MockSpan {
+	t.Helper()
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d finished spans, want 1", len(spans))
+	}
+	return spans[0]
+}
+
+// loggedFields returns the string values of the fields logged on span, by
+// key. Later values override earlier ones.
+func loggedFields(span *mocktracer.MockSpan) map[string]string {
+	fields := map[string]string{}
+	for _, l := range span.Logs() {
+		for _, f := range l.Fields {
+			fields[f.Key] = f.ValueString
+		}
+	}
+	return fields
+}
+
+// loggedEvents returns the values of the event fields logged on span.
+func loggedEvents(span *mocktracer.MockSpan) []string {
+	var events []string
+	for _, l := range span.Logs() {
+		for _, f := range l.Fields {
+			if f.Key == "event" {
+				events = append(events, f.ValueString)
+			}
+		}
+	}
+	return events
+}
+
+func TestTraceHandler(t *testing.T) {
+	tracer := mocktracer.New()
+	var inHandler opentracing.Span
+	pattern, h := TraceHandler("/items/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inHandler = opentracing.SpanFromContext(r.Context())
+	}), WithTracer(tracer))
+	if pattern != "/items/" {
+		t.Errorf("pattern = %q, want %q", pattern, "/items/")
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+	span := finishedSpan(t, tracer)
+	if span.OperationName != "GET /items/" {
+		t.Errorf("operation name = %q, want %q", span.OperationName, "GET /items/")
+	}
+	if inHandler == nil || inHandler.Context().(mocktracer.MockSpanContext).SpanID != span.SpanContext.SpanID {
+		t.Errorf("span in the handler context = %v, want the server span", inHandler)
+	}
+}
+
+func TestTraceHandlerContinuesIncomingTrace(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("client")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	tracer.Inject(parent.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+
+	_, h := TraceHandler("/", okHandler, WithTracer(tracer))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	span := finishedSpan(t, tracer)
+	want := parent.Context().(mocktracer.MockSpanContext)
+	if span.ParentID != want.SpanID || span.SpanContext.TraceID != want.TraceID {
+		t.Errorf("span has parent %d in trace %d, want %d in trace %d",
+			span.ParentID, span.SpanContext.TraceID, want.SpanID, want.TraceID)
+	}
+}
+
+func TestTraceHandlerOptions(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/items/", okHandler,
+		WithTracer(tracer),
+		WithOperationNameFormatter(func(pattern string, r *http.Request) string {
+			return "items " + r.URL.Path
+		}),
+		WithSpanObserver(func(span opentracing.Span, r *http.Request) {
+			span.SetTag("observed", r.Method)
+		}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items/42", nil))
+
+	span := finishedSpan(t, tracer)
+	if span.OperationName != "items /items/42" {
+		t.Errorf("operation name = %q, want %q", span.OperationName, "items /items/42")
+	}
+	if got := span.Tag("observed"); got != http.MethodPost {
+		t.Errorf("observed tag = %v, want %q", got, http.MethodPost)
+	}
+}
+
+func TestTraceHandlerUsesGlobalTracerAtRequestTime(t *testing.T) {
+	_, h := TraceHandler("/", okHandler)
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	finishedSpan(t, tracer)
+}
+
+func TestMiddleware(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	Middleware(okHandler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+	if span := finishedSpan(t, tracer); span.OperationName != "GET /items/42" {
+		t.Errorf("operation name = %q, want %q", span.OperationName, "GET /items/42")
+	}
+}
+
+func TestNewMiddleware(t *testing.T) {
+	tracer := mocktracer.New()
+	var pattern string
+	mw := NewMiddleware(WithTracer(tracer), WithOperationNameFormatter(func(p string, r *http.Request) string {
+		pattern = p
+		return "items"
+	}))
+	mw(okHandler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+	if span := finishedSpan(t, tracer); span.OperationName != "items" {
+		t.Errorf("operation name = %q, want %q", span.OperationName, "items")
+	}
+	if pattern != "" {
+		t.Errorf("pattern = %q, want it empty", pattern)
+	}
+}
+
+func TestTraceHandlerTagsResponse(t *testing.T) {
+	tests := []struct {
+		status    int
+		wantError bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNotFound, false},
+		{http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		tracer := mocktracer.New()
+		_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte("body"))
+		}), WithTracer(tracer))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		span := finishedSpan(t, tracer)
+		if got := span.Tag("http.status_code"); got != uint16(tt.status) {
+			t.Errorf("status %d: http.status_code = %v", tt.status, got)
+		}
+		if got := span.Tag("http.response_size"); got != int64(4) {
+			t.Errorf("status %d: http.response_size = %v, want 4", tt.status, got)
+		}
+		if got := span.Tag("error") == true; got != tt.wantError {
+			t.Errorf("status %d: error = %v, want %v", tt.status, got, tt.wantError)
+		}
+	}
+}
+
+func TestTraceHandlerKeepsFlusher(t *testing.T) {
+	_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("the handler's ResponseWriter is not an http.Flusher")
+		}
+	}), WithTracer(mocktracer.New()))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestTraceRequest(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	ctx := opentracing.ContextWithSpan(req.Context(), parent)
+
+	traced, span := TraceRequest("GET example.com", ctx, *req, WithTracer(tracer))
+	span.Finish()
+
+	got := finishedSpan(t, tracer)
+	if got.OperationName != "GET example.com" {
+		t.Errorf("operation name = %q, want %q", got.OperationName, "GET example.com")
+	}
+	if got.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the span is not a child of the context span")
+	}
+	sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(traced.Header))
+	if err != nil {
+		t.Fatalf("no span context in the request headers: %v", err)
+	}
+	if sc.(mocktracer.MockSpanContext).SpanID != got.SpanContext.SpanID {
+		t.Error("the injected span context is not the client span's")
+	}
+}
+
+func TestTraceHandlerSemanticTags(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/items/", okHandler, WithTracer(tracer))
+	r := httptest.NewRequest(http.MethodPut, "/items/42?draft=1", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	span := finishedSpan(t, tracer)
+	for key, want := range map[string]interface{}{
+		"span.kind":    ext.SpanKindRPCServerEnum,
+		"component":    "net/http",
+		"http.method":  http.MethodPut,
+		"http.url":     "/items/42",
+		"peer.address": "10.0.0.1:1234",
+	} {
+		if got := span.Tag(key); got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestTraceHandlerWithTracer(t *testing.T) {
+	tracer := mocktracer.New()
+	opts := make([]HandlerOption, 1, 2)
+	opts[0] = WithOperationNameFormatter(func(string, *http.Request) string { return "custom" })
+	_, h := TraceHandlerWithTracer(tracer, "/", okHandler, opts...)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if span := finishedSpan(t, tracer); span.OperationName != "custom" {
+		t.Errorf("operation name = %q, want the options to apply too", span.OperationName)
+	}
+	if opts[:2][1] != nil {
+		t.Error("the caller's options slice was modified")
+	}
+}
+
+func TestTraceRequestWithTracer(t *testing.T) {
+	tracer := mocktracer.New()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	traced, span := TraceRequestWithTracer(tracer, "GET example.com", req.Context(), *req)
+	span.Finish()
+
+	got := finishedSpan(t, tracer)
+	if _, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(traced.Header)); err != nil {
+		t.Errorf("no span context injected by tracer: %v", err)
+	}
+	if got.OperationName != "GET example.com" {
+		t.Errorf("operation name = %q", got.OperationName)
+	}
+}
+
+func TestTraceHandlerPrefersContextSpan(t *testing.T) {
+	tracer := mocktracer.New()
+	caller := tracer.StartSpan("caller")
+	upstream := tracer.StartSpan("upstream middleware")
+
+	for _, opts := range [][]HandlerOption{
+		{WithTracer(tracer)},
+		{WithTracer(tracer), WithContextSpanReference(opentracing.FollowsFromRef)},
+	} {
+		tracer.Reset()
+		_, h := TraceHandler("/", okHandler, opts...)
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		tracer.Inject(caller.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+		r = r.WithContext(opentracing.ContextWithSpan(r.Context(), upstream))
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		span := finishedSpan(t, tracer)
+		if span.ParentID != upstream.Context().(mocktracer.MockSpanContext).SpanID {
+			t.Errorf("parent = %d, want the span from the request context", span.ParentID)
+		}
+		if got := span.Tag(string(ext.SpanKind)); got != ext.SpanKindRPCServerEnum {
+			t.Errorf("span.kind = %v", got)
+		}
+	}
+}
+
+func TestTraceRequestContext(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+
+	traced, span := TraceRequestContext(ctx, "GET example.com", req, WithTracer(tracer))
+	span.Finish()
+
+	got := finishedSpan(t, tracer)
+	if got.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the span is not a child of the context span")
+	}
+	if opentracing.SpanFromContext(traced.Context()) != span {
+		t.Error("the returned request's context doesn't carry the span")
+	}
+	if len(req.Header) != 0 || opentracing.SpanFromContext(req.Context()) != nil {
+		t.Error("the original request was modified")
+	}
+	sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(traced.Header))
+	if err != nil || sc.(mocktracer.MockSpanContext).SpanID != got.SpanContext.SpanID {
+		t.Errorf("injected span context = %v, %v; want the client span's", sc, err)
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	tracer := mocktracer.New()
+	h := NewHandler("/items/", okHandler, WithTracer(tracer))
+	if h.names[http.MethodGet] != "GET /items/" {
+		t.Errorf("precomputed GET name = %q, want %q", h.names[http.MethodGet], "GET /items/")
+	}
+
+	for _, method := range []string{http.MethodGet, "PURGE"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/items/42", nil))
+		want := method + " /items/"
+		if span := finishedSpan(t, tracer); span.OperationName != want {
+			t.Errorf("operation name = %q, want %q", span.OperationName, want)
+		}
+		tracer.Reset()
+	}
+}
+
+func TestNewHandlerCustomOperationName(t *testing.T) {
+	tracer := mocktracer.New()
+	h := NewHandler("/items/", okHandler, WithTracer(tracer),
+		WithOperationNameFormatter(func(pattern string, r *http.Request) string {
+			return "items"
+		}))
+	if h.names != nil {
+		t.Errorf("names = %v, want none precomputed with a custom formatter", h.names)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+	if span := finishedSpan(t, tracer); span.OperationName != "items" {
+		t.Errorf("operation name = %q, want %q", span.OperationName, "items")
+	}
+}
+
+func benchmarkServe(b *testing.B, h http.Handler, tracer *mocktracer.MockTracer) {
+	r := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, r)
+		// Keep the mock tracer from growing with every iteration.
+		tracer.Reset()
+	}
+}
+
+func BenchmarkTraceHandler(b *testing.B) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/items/", okHandler, WithTracer(tracer))
+	benchmarkServe(b, h, tracer)
+}
+
+func BenchmarkHandler(b *testing.B) {
+	tracer := mocktracer.New()
+	h := NewHandler("/items/", okHandler, WithTracer(tracer))
+	benchmarkServe(b, h, tracer)
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	tracer := mocktracer.New()
+	h := NewMiddleware(WithTracer(tracer))(okHandler)
+	benchmarkServe(b, h, tracer)
+}
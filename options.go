@@ -0,0 +1,275 @@
+package opentracing_helpers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// HandlerOption customizes the behavior of TraceHandler and NewMiddleware.
+type HandlerOption interface {
+	applyHandler(*handlerConfig)
+}
+
+// TransportOption customizes the behavior of TracedTransport.
+type TransportOption interface {
+	applyTransport(*transportConfig)
+}
+
+// Option customizes both server and client tracing and can be passed
+// wherever a HandlerOption or TransportOption is accepted.
+type Option interface {
+	HandlerOption
+	TransportOption
+}
+
+// commonConfig holds the settings shared by server and client tracing.
+type commonConfig struct {
+	tracer opentracing.Tracer
+	filter func(r *http.Request) bool
+
+	capturedHeaders []string
+	headerRedactor  func(key, value string) string
+
+	bodyCaptureLimit int
+
+	spanDecorators []SpanDecorator
+
+	propagators []Propagator
+
+	metrics MetricsObserver
+
+	codec SpanContextCodec
+
+	disabled func() bool
+
+	errorClassifier ErrorClassifier
+
+	urlScrubber func(u *url.URL) string
+
+	finishHooks []FinishHook
+}
+
+// activeTracer returns the configured tracer, falling back to the global tracer.
+// The global tracer is resolved on every call so that handlers wrapped
+// before opentracing.SetGlobalTracer is called still pick it up.
+func (c *commonConfig) activeTracer() opentracing.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return opentracing.GlobalTracer()
+}
+
+// traced reports whether r should be traced.
+func (c *commonConfig) traced(r *http.Request) bool {
+	return c.filter == nil || c.filter(r)
+}
+
+type handlerConfig struct {
+	commonConfig
+	operationName func(pattern string, r *http.Request) string
+	spanObservers []func(span opentracing.Span, r *http.Request)
+	recoverPanics bool
+	repanic       bool
+
+	// customOperationName is set when operationName was replaced by an
+	// option, in which case names can't be precomputed.
+	customOperationName bool
+
+	contextSpanRef opentracing.SpanReferenceType
+	traceIDHeader  string
+	accessLog      *slog.Logger
+
+	queryParamContext []string
+	cookieContext     []string
+
+	streaming bool
+
+	extractErrorHandler func(err error, r *http.Request)
+	extractFailedTag    bool
+
+	profilerLabels      bool
+	executionTraceTasks bool
+}
+
+func newHandlerConfig(opts []HandlerOption) *handlerConfig {
+	c := &handlerConfig{
+		contextSpanRef: opentracing.ChildOfRef,
+		operationName: func(pattern string, r *http.Request) string {
+			if pattern == "" {
+				pattern = r.Pattern
+			}
+			if pattern == "" {
+				return r.Method + " " + r.URL.Path
+			}
+			return patternName(pattern, r.Method)
+		},
+	}
+	for _, opt := range opts {
+		opt.applyHandler(c)
+	}
+	return c
+}
+
+type transportConfig struct {
+	commonConfig
+	operationName     OperationNameFunc
+	clientTraceEvents ClientTraceEvents
+	phaseSpans        bool
+	objectLogFields   bool
+	lazyLogFields     bool
+	logBudget         int
+	urlTemplate       URLTemplateFunc
+}
+
+func newTransportConfig(opts []TransportOption) *transportConfig {
+	c := &transportConfig{clientTraceEvents: ClientTraceAllEvents}
+	for _, opt := range opts {
+		opt.applyTransport(c)
+	}
+	return c
+}
+
+// spanName returns the name of the client span for r, or fallback if
+// neither an OperationNameFunc nor a URLTemplateFunc was configured.
+func (c *transportConfig) spanName(r *http.Request, fallback string) string {
+	if c.operationName != nil {
+		return c.operationName(r)
+	}
+	if c.urlTemplate != nil {
+		return c.autoName(r)
+	}
+	return fallback
+}
+
+type commonOption func(*commonConfig)
+
+func (o commonOption) applyHandler(c *handlerConfig)     { o(&c.commonConfig) }
+func (o commonOption) applyTransport(c *transportConfig) { o(&c.commonConfig) }
+
+type handlerOption func(*handlerConfig)
+
+func (o handlerOption) applyHandler(c *handlerConfig) { o(c) }
+
+type transportOption func(*transportConfig)
+
+func (o transportOption) applyTransport(c *transportConfig) { o(c) }
+
+// WithOperationNameFormatter replaces the default "METHOD pattern" span
+// name. The formatter receives the pattern the handler was registered
+// with and the incoming request. The pattern is empty for handlers wrapped
+// with NewMiddleware.
+func WithOperationNameFormatter(f func(pattern string, r *http.Request) string) HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.operationName = f
+		c.customOperationName = true
+	})
+}
+
+// OperationNameFunc derives a span name from a request. It is useful to
+// name spans after route templates such as "GET /users/:id" rather than
+// raw paths, which keeps the number of distinct operations low.
+type OperationNameFunc func(r *http.Request) string
+
+type operationNameOption OperationNameFunc
+
+func (o operationNameOption) applyHandler(c *handlerConfig) {
+	c.operationName = func(_ string, r *http.Request) string { return o(r) }
+	c.customOperationName = true
+}
+
+func (o operationNameOption) applyTransport(c *transportConfig) {
+	c.operationName = OperationNameFunc(o)
+}
+
+// WithOperationNameFunc names server and client spans using f. On the
+// server it takes the place of the registration pattern, and for
+// TraceRequest it overrides the operationName argument.
+func WithOperationNameFunc(f OperationNameFunc) Option {
+	return operationNameOption(f)
+}
+
+// WithTracer uses tracer instead of opentracing.GlobalTracer().
+func WithTracer(tracer opentracing.Tracer) Option {
+	return commonOption(func(c *commonConfig) {
+		c.tracer = tracer
+	})
+}
+
+// WithContextSpanReference sets how the server span refers to a span
+// already present in the request context, for example one started by an
+// upstream middleware. The default is opentracing.ChildOfRef; use
+// opentracing.FollowsFromRef when the upstream span doesn't wait for the
+// handler. Spans in the context take precedence over span contexts
+// extracted from the request headers.
+func WithContextSpanReference(refType opentracing.SpanReferenceType) HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.contextSpanRef = refType
+	})
+}
+
+// WithFilter skips tracing of requests for which f returns false. This is
+// meant for requests like health checks, metrics scrapes or CORS preflights
+// that shouldn't produce spans at all. It applies to TraceHandler,
+// NewMiddleware and TracedTransport.
+func WithFilter(f func(r *http.Request) bool) Option {
+	return commonOption(func(c *commonConfig) {
+		c.filter = f
+	})
+}
+
+// SpanDecorator is called once a traced request has completed. status is
+// the response status code, or 0 if no response was received.
+type SpanDecorator func(span opentracing.Span, r *http.Request, status int)
+
+// WithSpanDecorator registers a function that is called after the handler
+// has run or TracedTransport has received the response, before the span is
+// finished. It allows attaching custom tags such as a tenant ID or cache
+// hit without reimplementing the middleware.
+func WithSpanDecorator(f SpanDecorator) Option {
+	return commonOption(func(c *commonConfig) {
+		c.spanDecorators = append(c.spanDecorators, f)
+	})
+}
+
+// decorate calls the configured span decorators.
+func (c *commonConfig) decorate(span opentracing.Span, r *http.Request, status int) {
+	for _, d := range c.spanDecorators {
+		d(span, r, status)
+	}
+}
+
+// FinishHook is called right before a span is finished. status is the
+// response status code, or 0 if no response was received, and duration
+// the time since the span was started.
+type FinishHook func(span opentracing.Span, r *http.Request, status int, duration time.Duration)
+
+// WithOnFinish registers a function that is called right before server
+// and client spans are finished, for slow request alerting, raising the
+// sampling priority of failed requests or custom metrics. Client spans of
+// TracedTransport are finished once the response body is consumed.
+func WithOnFinish(f FinishHook) Option {
+	return commonOption(func(c *commonConfig) {
+		c.finishHooks = append(c.finishHooks, f)
+	})
+}
+
+// finished calls the configured finish hooks.
+func (c *commonConfig) finished(span opentracing.Span, r *http.Request, status int, duration time.Duration) {
+	for _, f := range c.finishHooks {
+		f(span, r, status, duration)
+	}
+}
+
+// WithSpanObserver registers a function that is called with the server span
+// right after it is started, before the wrapped handler runs. It can be
+// used to set additional tags or baggage. Observers registered by multiple
+// options are called in order.
+func WithSpanObserver(f func(span opentracing.Span, r *http.Request)) HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.spanObservers = append(c.spanObservers, f)
+	})
+}
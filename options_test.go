@@ -0,0 +1,187 @@
+package opentracing_helpers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func routeName(r *http.Request) string { return r.Method + " /items/{id}" }
+
+func TestWithOperationNameFuncServer(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/items/", okHandler, WithTracer(tracer), WithOperationNameFunc(routeName))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+	if got := finishedSpan(t, tracer).OperationName; got != "GET /items/{id}" {
+		t.Errorf("operation name = %q, want %q", got, "GET /items/{id}")
+	}
+}
+
+func TestWithOperationNameFuncClient(t *testing.T) {
+	tracer := mocktracer.New()
+	transport := NewTracedTransport(respond(http.StatusOK, "", nil), WithTracer(tracer), WithOperationNameFunc(routeName))
+	resp, err := transport.RoundTrip(httptest.NewRequest(http.MethodDelete, "http://example.com/items/42", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := finishedSpan(t, tracer).OperationName; got != "DELETE /items/{id}" {
+		t.Errorf("operation name = %q, want %q", got, "DELETE /items/{id}")
+	}
+}
+
+func TestWithOperationNameFuncTraceRequest(t *testing.T) {
+	tracer := mocktracer.New()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/items/42", nil)
+	_, span := TraceRequest("ignored", req.Context(), *req, WithTracer(tracer), WithOperationNameFunc(routeName))
+	span.Finish()
+
+	if got := finishedSpan(t, tracer).OperationName; got != "GET /items/{id}" {
+		t.Errorf("operation name = %q, want %q", got, "GET /items/{id}")
+	}
+}
+
+func TestWithFilter(t *testing.T) {
+	skipHealth := WithFilter(func(r *http.Request) bool { return r.URL.Path != "/healthz" })
+
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", okHandler, WithTracer(tracer), skipHealth)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+	if got := finishedSpan(t, tracer).OperationName; got != "GET /" {
+		t.Errorf("traced %q, want only the /items request", got)
+	}
+
+	tracer = mocktracer.New()
+	var sent *http.Request
+	transport := NewTracedTransport(respond(http.StatusOK, "", &sent), WithTracer(tracer), skipHealth)
+	resp, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/healthz", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := len(tracer.FinishedSpans()); n != 0 {
+		t.Errorf("the filtered client request produced %d spans", n)
+	}
+	if len(sent.Header) != 0 {
+		t.Errorf("headers were injected into a filtered request: %v", sent.Header)
+	}
+}
+
+func TestWithSpanObserverCallsAllObservers(t *testing.T) {
+	tracer := mocktracer.New()
+	var calls []string
+	_, h := TraceHandler("/", okHandler, WithTracer(tracer),
+		WithSpanObserver(func(opentracing.Span, *http.Request) { calls = append(calls, "first") }),
+		WithSpanObserver(func(opentracing.Span, *http.Request) { calls = append(calls, "second") }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("observers called as %v, want [first second]", calls)
+	}
+}
+
+func TestWithSpanDecorator(t *testing.T) {
+	var statuses []int
+	decorate := WithSpanDecorator(func(span opentracing.Span, r *http.Request, status int) {
+		statuses = append(statuses, status)
+		span.SetTag("tenant", r.Header.Get("X-Tenant"))
+	})
+
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}), WithTracer(tracer), decorate)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Tenant", "acme")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got := finishedSpan(t, tracer).Tag("tenant"); got != "acme" {
+		t.Errorf("tenant tag = %v, want %q", got, "acme")
+	}
+
+	tracer = mocktracer.New()
+	transport := NewTracedTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}), WithTracer(tracer), decorate)
+	transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	finishedSpan(t, tracer)
+
+	if len(statuses) != 2 || statuses[0] != http.StatusTeapot || statuses[1] != 0 {
+		t.Errorf("decorators got statuses %v, want [418 0]", statuses)
+	}
+}
+
+func TestWithSpanDecoratorAfterPanic(t *testing.T) {
+	tracer := mocktracer.New()
+	var status int
+	_, h := TraceHandler("/", panicking, WithTracer(tracer), WithPanicRecovery(false),
+		WithSpanDecorator(func(_ opentracing.Span, _ *http.Request, s int) { status = s }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if status != http.StatusInternalServerError {
+		t.Errorf("decorator got status %d, want 500", status)
+	}
+}
+
+// finishRecorder is a FinishHook recording its calls.
+type finishRecorder struct {
+	tracer   *mocktracer.MockTracer
+	statuses []int
+	early    bool
+}
+
+func (f *finishRecorder) hook(span opentracing.Span, r *http.Request, status int, duration time.Duration) {
+	f.statuses = append(f.statuses, status)
+	f.early = len(f.tracer.FinishedSpans()) == 0 && duration > 0
+}
+
+func TestWithOnFinishServer(t *testing.T) {
+	tracer := mocktracer.New()
+	rec := &finishRecorder{tracer: tracer}
+	_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}), WithTracer(tracer), WithOnFinish(rec.hook))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	finishedSpan(t, tracer)
+	if !reflect.DeepEqual(rec.statuses, []int{http.StatusTeapot}) || !rec.early {
+		t.Errorf("hook called with %v, before finishing: %v; want 418, true", rec.statuses, rec.early)
+	}
+}
+
+func TestWithOnFinishClient(t *testing.T) {
+	tracer := mocktracer.New()
+	rec := &finishRecorder{tracer: tracer}
+	transport := NewTracedTransport(respond(http.StatusAccepted, "body", nil), WithTracer(tracer), WithOnFinish(rec.hook))
+	resp, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.statuses) != 0 {
+		t.Error("the hook was called before the body was consumed")
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if !reflect.DeepEqual(rec.statuses, []int{http.StatusAccepted}) || !rec.early {
+		t.Errorf("hook called with %v, before finishing: %v; want 202, true", rec.statuses, rec.early)
+	}
+
+	failing := NewTracedTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}), WithTracer(tracer), WithOnFinish(rec.hook))
+	tracer.Reset()
+	failing.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if !reflect.DeepEqual(rec.statuses, []int{http.StatusAccepted, 0}) || !rec.early {
+		t.Errorf("hook called with %v, before finishing: %v; want a 0 status for the failed round trip", rec.statuses, rec.early)
+	}
+}
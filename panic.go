@@ -0,0 +1,76 @@
+package opentracing_helpers
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// WithPanicRecovery recovers panics raised by the wrapped handler. The
+// span is tagged with error=true and the panic value and stack trace are
+// logged on it. A 500 response is written if the handler hadn't written a
+// status yet. When repanic is true the panic is propagated after it has
+// been recorded, leaving its handling to the caller or net/http.
+func WithPanicRecovery(repanic bool) HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.recoverPanics = true
+		c.repanic = repanic
+	})
+}
+
+// recordPanic records the recovered value p on the span and responds with
+// a 500 status.
+func (c *handlerConfig) recordPanic(sr *serverRequest, p interface{}) {
+	// http.ErrAbortHandler is used to abort a response on purpose.
+	if p == http.ErrAbortHandler {
+		panic(p)
+	}
+
+	logPanic(sr.span, p)
+	sr.panicked = true
+	if !sr.rr.wroteHeader {
+		sr.rr.WriteHeader(http.StatusInternalServerError)
+	}
+	c.finishServerSpan(sr)
+
+	if c.repanic {
+		panic(p)
+	}
+}
+
+// serveRecovered serves r without tracing it, still recovering panics and
+// responding with a 500 status as WithPanicRecovery promises.
+func (c *handlerConfig) serveRecovered(w http.ResponseWriter, r *http.Request, handler http.Handler) {
+	rr := responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		if p == http.ErrAbortHandler {
+			panic(p)
+		}
+		if !rr.wroteHeader {
+			rr.WriteHeader(http.StatusInternalServerError)
+		}
+		if c.repanic {
+			panic(p)
+		}
+	}()
+	handler.ServeHTTP(rr.writer(), r)
+}
+
+// logPanic tags span as failed and logs the panic value p along with the
+// stack trace of the current goroutine.
+func logPanic(span opentracing.Span, p interface{}) {
+	ext.Error.Set(span, true)
+	span.LogFields(
+		log.String("event", "panic"),
+		log.String("message", fmt.Sprint(p)),
+		log.String("stack", string(debug.Stack())),
+	)
+}
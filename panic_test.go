@@ -0,0 +1,103 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+var panicking = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+})
+
+func TestPanicRecovery(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", panicking, WithTracer(tracer), WithPanicRecovery(false))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	span := finishedSpan(t, tracer)
+	if span.Tag("error") != true {
+		t.Error("the span is not tagged as an error")
+	}
+	if got := span.Tag("http.status_code"); got != uint16(http.StatusInternalServerError) {
+		t.Errorf("http.status_code = %v, want 500", got)
+	}
+	var message string
+	for _, l := range span.Logs() {
+		for _, f := range l.Fields {
+			if f.Key == "message" {
+				message = f.ValueString
+			}
+		}
+	}
+	if message != "boom" {
+		t.Errorf("logged panic message %q, want %q", message, "boom")
+	}
+}
+
+func TestPanicRecoveryKeepsWrittenStatus(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("boom")
+	}), WithTracer(tracer), WithPanicRecovery(false))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want 202", w.Code)
+	}
+	if got := finishedSpan(t, tracer).Tag("http.status_code"); got != uint16(http.StatusAccepted) {
+		t.Errorf("http.status_code = %v, want 202", got)
+	}
+}
+
+func TestPanicRecoveryRepanics(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", panicking, WithTracer(tracer), WithPanicRecovery(true))
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want the handler's panic", p)
+		}
+		if finishedSpan(t, tracer).Tag("error") != true {
+			t.Error("the span is not tagged as an error")
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestPanicRecoveryAbortHandler(t *testing.T) {
+	_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), WithTracer(mocktracer.New()), WithPanicRecovery(false))
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", p)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestPanicRecoveryWhenNotTracing(t *testing.T) {
+	for name, opt := range map[string]HandlerOption{
+		"disabled": WithDisabled(func() bool { return true }),
+		"filtered": WithFilter(func(r *http.Request) bool { return false }),
+		"noop":     WithTracer(opentracing.NoopTracer{}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, h := TraceHandler("/", panicking, WithTracer(mocktracer.New()), opt, WithPanicRecovery(false))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want 500", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+)
+
+// defaultProbePaths are the paths excluded by ExcludeDefaultProbes.
+var defaultProbePaths = map[string]bool{
+	"/healthz":     true,
+	"/readyz":      true,
+	"/livez":       true,
+	"/metrics":     true,
+	"/favicon.ico": true,
+}
+
+// ExcludeDefaultProbes is a WithFilter preset that skips requests for
+// /healthz, /readyz, /livez, /metrics and /favicon.ico, so that Kubernetes
+// probes and metrics scrapes don't reach the tracer. Like any WithFilter
+// option it replaces previously given filters; use IsDefaultProbe to
+// combine it with other criteria.
+func ExcludeDefaultProbes() Option {
+	return WithFilter(func(r *http.Request) bool {
+		return !IsDefaultProbe(r)
+	})
+}
+
+// IsDefaultProbe reports whether r is for one of the paths excluded by
+// ExcludeDefaultProbes.
+func IsDefaultProbe(r *http.Request) bool {
+	return defaultProbePaths[r.URL.Path]
+}
@@ -0,0 +1,25 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestExcludeDefaultProbes(t *testing.T) {
+	tracer := mocktracer.New()
+	h := NewMiddleware(WithTracer(tracer), ExcludeDefaultProbes())(okHandler)
+	for _, path := range []string{"/healthz", "/readyz", "/livez", "/metrics", "/favicon.ico", "/healthz/deep", "/orders"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var traced []string
+	for _, span := range tracer.FinishedSpans() {
+		traced = append(traced, span.OperationName)
+	}
+	if len(traced) != 2 || traced[0] != "GET /healthz/deep" || traced[1] != "GET /orders" {
+		t.Errorf("traced %q, want only the non-probe requests", traced)
+	}
+}
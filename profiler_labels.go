@@ -0,0 +1,35 @@
+package opentracing_helpers
+
+import (
+	"runtime/pprof"
+)
+
+// WithProfilerLabels runs handlers with the pprof labels trace_id and
+// operation, so that CPU and goroutine profiles can be broken down by
+// endpoint and matched with traces. The operation is the name of the
+// server span for TraceHandler, derived from its registration pattern, as
+// in
+//
+//	go tool pprof -tagfocus operation="GET /users/{id}" cpu.pprof
+//
+// but only the request method for Middleware and NewMiddleware, since the
+// pattern matched by an http.ServeMux behind them is only known once the
+// handler returns, and raw paths would make for unbounded label values.
+//
+// The labels are also set on the request context and inherited by
+// goroutines started by the handler. The trace ID is omitted if it can't
+// be read, see WithSpanContextCodec.
+func WithProfilerLabels() HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.profilerLabels = true
+	})
+}
+
+// profilerLabelSet returns the pprof labels of sr.
+func (c *handlerConfig) profilerLabelSet(sr *serverRequest) pprof.LabelSet {
+	labels := []string{"operation", sr.boundedOperationName()}
+	if ids, ok := SpanIdentifiersFromContext(sr.r.Context(), c.codec); ok {
+		labels = append(labels, "trace_id", ids.TraceID)
+	}
+	return pprof.Labels(labels...)
+}
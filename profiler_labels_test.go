@@ -0,0 +1,74 @@
+package opentracing_helpers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// labelHandler stores the pprof labels of its request context.
+func labelHandler(labels map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pprof.ForLabels(r.Context(), func(key, value string) bool {
+			labels[key] = value
+			return true
+		})
+	})
+}
+
+func TestWithProfilerLabels(t *testing.T) {
+	tracer := mocktracer.New()
+	labels := map[string]string{}
+	_, h := TraceHandler("/users/", labelHandler(labels), WithTracer(tracer), WithSpanContextCodec(mockCodec{}), WithProfilerLabels())
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/", nil))
+
+	span := finishedSpan(t, tracer)
+	if labels["operation"] != span.OperationName {
+		t.Errorf("operation label %q, want %q", labels["operation"], span.OperationName)
+	}
+	if want := fmt.Sprintf("%016x", span.SpanContext.TraceID); labels["trace_id"] != want {
+		t.Errorf("trace_id label %q, want %q", labels["trace_id"], want)
+	}
+}
+
+func TestWithProfilerLabelsWithoutCodec(t *testing.T) {
+	labels := map[string]string{}
+	_, h := TraceHandler("/", labelHandler(labels), WithTracer(mocktracer.New()), WithProfilerLabels())
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if _, ok := labels["trace_id"]; ok || labels["operation"] == "" {
+		t.Errorf("labels %v, want only operation", labels)
+	}
+}
+
+func TestProfilerLabelsOffByDefault(t *testing.T) {
+	labels := map[string]string{}
+	_, h := TraceHandler("/", labelHandler(labels), WithTracer(mocktracer.New()))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if len(labels) != 0 {
+		t.Errorf("labels %v set without WithProfilerLabels", labels)
+	}
+}
+
+func TestProfilerLabelsWithoutRoute(t *testing.T) {
+	labels := map[string]string{}
+	h := NewMiddleware(WithTracer(mocktracer.New()), WithProfilerLabels())(labelHandler(labels))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+	if labels["operation"] != "GET" {
+		t.Errorf("operation label %q, want the method for a raw path", labels["operation"])
+	}
+}
+
+func TestRouteTaggedWithProfilerLabels(t *testing.T) {
+	tracer := mocktracer.New()
+	mux := http.NewServeMux()
+	mux.Handle("GET /items/{id}", okHandler)
+	NewMiddleware(WithTracer(tracer), WithProfilerLabels())(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/items/42", nil))
+
+	if got := finishedSpan(t, tracer).Tag("http.route"); got != "GET /items/{id}" {
+		t.Errorf("http.route = %v, want GET /items/{id}", got)
+	}
+}
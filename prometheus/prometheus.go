@@ -0,0 +1,113 @@
+// Package prometheus exports the RED metrics of traced requests to
+// Prometheus:
+//
+//	observer := otprom.NewObserver()
+//	prometheus.MustRegister(observer)
+//	handler := opentracing_helpers.TraceHandler("/users", users,
+//		opentracing_helpers.WithMetricsObserver(observer))
+package prometheus
+
+import (
+	"strconv"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var labels = []string{"operation", "kind", "method", "code"}
+
+// Option customizes the observer.
+type Option func(*config)
+
+type config struct {
+	namespace string
+	buckets   []float64
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{namespace: "http", buckets: prometheus.DefBuckets}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithNamespace sets the namespace of the metric names. The default is
+// "http".
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithBuckets sets the buckets of the latency histogram, in seconds. The
+// default is prometheus.DefBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(c *config) {
+		c.buckets = buckets
+	}
+}
+
+// Observer is an opentracing_helpers.MetricsObserver that records request
+// counts, error counts and latency histograms labelled by operation, span
+// kind, method and status code. It is a prometheus.Collector and must be
+// registered to be scraped. Server requests whose route isn't known are
+// labelled with their method as the operation, see
+// opentracing_helpers.RequestMetrics.
+type Observer struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+var (
+	_ opentracing_helpers.MetricsObserver = (*Observer)(nil)
+	_ prometheus.Collector                = (*Observer)(nil)
+)
+
+// NewObserver returns an unregistered Observer.
+func NewObserver(opts ...Option) *Observer {
+	c := newConfig(opts)
+	return &Observer{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Name:      "requests_total",
+			Help:      "Number of traced requests.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Name:      "errors_total",
+			Help:      "Number of traced requests whose span was tagged as an error.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of traced requests.",
+			Buckets:   c.buckets,
+		}, labels),
+	}
+}
+
+// ObserveRequest implements opentracing_helpers.MetricsObserver.
+func (o *Observer) ObserveRequest(m opentracing_helpers.RequestMetrics) {
+	values := []string{m.Operation, m.Kind, m.Method, strconv.Itoa(m.StatusCode)}
+	o.requests.WithLabelValues(values...).Inc()
+	if m.Error {
+		o.errors.WithLabelValues(values...).Inc()
+	}
+	o.duration.WithLabelValues(values...).Observe(m.Duration.Seconds())
+}
+
+// Describe implements prometheus.Collector.
+func (o *Observer) Describe(ch chan<- *prometheus.Desc) {
+	o.requests.Describe(ch)
+	o.errors.Describe(ch)
+	o.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (o *Observer) Collect(ch chan<- prometheus.Metric) {
+	o.requests.Collect(ch)
+	o.errors.Collect(ch)
+	o.duration.Collect(ch)
+}
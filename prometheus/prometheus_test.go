@@ -0,0 +1,71 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserver(t *testing.T) {
+	o := NewObserver(WithNamespace("api"), WithBuckets([]float64{0.1, 1}))
+	o.ObserveRequest(opentracing_helpers.RequestMetrics{
+		Operation: "GET /users/{id}", Kind: "server", Method: "GET", StatusCode: 200, Duration: 50 * time.Millisecond,
+	})
+	o.ObserveRequest(opentracing_helpers.RequestMetrics{
+		Operation: "GET /users/{id}", Kind: "server", Method: "GET", StatusCode: 500, Error: true, Duration: 2 * time.Second,
+	})
+
+	want := `
+# HELP api_errors_total Number of traced requests whose span was tagged as an error.
+# TYPE api_errors_total counter
+api_errors_total{code="500",kind="server",method="GET",operation="GET /users/{id}"} 1
+# HELP api_requests_total Number of traced requests.
+# TYPE api_requests_total counter
+api_requests_total{code="200",kind="server",method="GET",operation="GET /users/{id}"} 1
+api_requests_total{code="500",kind="server",method="GET",operation="GET /users/{id}"} 1
+`
+	if err := testutil.CollectAndCompare(o, strings.NewReader(want), "api_requests_total", "api_errors_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(o, "api_request_duration_seconds"); n != 2 {
+		t.Errorf("got %d latency histograms, want 2", n)
+	}
+}
+
+func TestObserverRegisters(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(NewObserver()); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(NewObserver(WithNamespace("grpc"))); err != nil {
+		t.Errorf("an observer with another namespace doesn't register: %v", err)
+	}
+}
+
+func TestObserverLabelsRawPathsWithMethod(t *testing.T) {
+	o := NewObserver()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	h := opentracing_helpers.NewMiddleware(opentracing_helpers.WithTracer(mocktracer.New()),
+		opentracing_helpers.WithMetricsObserver(o))(mux)
+	for _, path := range []string{"/users/1", "/users/2", "/a", "/b"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	want := `
+# HELP http_requests_total Number of traced requests.
+# TYPE http_requests_total counter
+http_requests_total{code="200",kind="server",method="GET",operation="GET /users/{id}"} 2
+http_requests_total{code="404",kind="server",method="GET",operation="GET"} 2
+`
+	if err := testutil.CollectAndCompare(o, strings.NewReader(want), "http_requests_total"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,302 @@
+package opentracing_helpers
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// Propagator injects span contexts into, and extracts them from, HTTP
+// headers using a particular wire format.
+type Propagator interface {
+	Inject(tracer opentracing.Tracer, sc opentracing.SpanContext, h http.Header) error
+	Extract(tracer opentracing.Tracer, h http.Header) (opentracing.SpanContext, error)
+}
+
+// WithPropagators selects the header formats used to propagate span
+// contexts. Extraction tries each propagator in order and uses the first
+// span context found, so a service can accept W3C, B3 and tracer-native
+// headers from its callers:
+//
+//	opentracing_helpers.WithPropagators(
+//	    opentracing_helpers.W3CPropagator{Codec: codec},
+//	    opentracing_helpers.B3Propagator{Codec: codec},
+//	    opentracing_helpers.NativePropagator{},
+//	)
+//
+// Injection only uses the first propagator; pass a PropagatorChain to
+// inject several formats. The default is NativePropagator.
+func WithPropagators(propagators ...Propagator) Option {
+	return commonOption(func(c *commonConfig) {
+		c.propagators = propagators
+	})
+}
+
+// extract returns the span context found in h by the configured
+// propagators, or nil.
+func (c *commonConfig) extract(h http.Header) (opentracing.SpanContext, error) {
+	var (
+		sc  opentracing.SpanContext
+		err error
+	)
+	if len(c.propagators) == 0 {
+		sc, err = NativePropagator{}.Extract(c.activeTracer(), h)
+	} else {
+		sc, err = PropagatorChain(c.propagators).Extract(c.activeTracer(), h)
+	}
+	countExtraction(err)
+	return sc, err
+}
+
+// inject injects sc into h using the first configured propagator.
+func (c *commonConfig) inject(sc opentracing.SpanContext, h http.Header) error {
+	if len(c.propagators) == 0 {
+		return NativePropagator{}.Inject(c.activeTracer(), sc, h)
+	}
+	return c.propagators[0].Inject(c.activeTracer(), sc, h)
+}
+
+// PropagatorChain combines propagators so that a service can speak several
+// formats at once, for example while migrating from Jaeger-native headers
+// to B3. Extraction tries each propagator in order and injection writes
+// the headers of all of them:
+//
+//	opentracing_helpers.WithPropagators(opentracing_helpers.PropagatorChain{
+//	    opentracing_helpers.NativePropagator{},
+//	    opentracing_helpers.B3Propagator{Codec: codec},
+//	})
+type PropagatorChain []Propagator
+
+// Inject implements Propagator. All propagators are attempted; the first
+// error is returned.
+func (pc PropagatorChain) Inject(tracer opentracing.Tracer, sc opentracing.SpanContext, h http.Header) error {
+	var firstErr error
+	for _, p := range pc {
+		if err := p.Inject(tracer, sc, h); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Extract implements Propagator, returning the first span context found.
+func (pc PropagatorChain) Extract(tracer opentracing.Tracer, h http.Header) (opentracing.SpanContext, error) {
+	err := opentracing.ErrSpanContextNotFound
+	for _, p := range pc {
+		sc, perr := p.Extract(tracer, h)
+		if perr == nil && sc != nil {
+			return sc, nil
+		}
+		// Report corrupted headers over missing ones.
+		if perr != nil && perr != opentracing.ErrSpanContextNotFound {
+			err = perr
+		}
+	}
+	return nil, err
+}
+
+// NativePropagator uses the tracer's own HTTP header format, via
+// opentracing.HTTPHeaders.
+type NativePropagator struct{}
+
+// Inject implements Propagator.
+func (NativePropagator) Inject(tracer opentracing.Tracer, sc opentracing.SpanContext, h http.Header) error {
+	return tracer.Inject(sc, opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h))
+}
+
+// Extract implements Propagator.
+func (NativePropagator) Extract(tracer opentracing.Tracer, h http.Header) (opentracing.SpanContext, error) {
+	return tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h))
+}
+
+// SpanIdentifiers are the tracer-independent parts of a span context, as
+// carried by the B3 and W3C formats. IDs are lower case hex strings.
+type SpanIdentifiers struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Sampled      bool
+	Debug        bool
+	// TraceState is the W3C tracestate header, passed through as is.
+	TraceState string
+}
+
+// SpanContextCodec converts between the span contexts of a particular
+// tracer and SpanIdentifiers. The OpenTracing API doesn't expose trace and
+// span IDs, so formats other than the tracer's own need one.
+type SpanContextCodec interface {
+	// Identifiers returns the IDs of sc, or false if sc wasn't created by
+	// the codec's tracer.
+	Identifiers(sc opentracing.SpanContext) (SpanIdentifiers, bool)
+	// SpanContext creates a span context of the codec's tracer.
+	SpanContext(ids SpanIdentifiers) (opentracing.SpanContext, error)
+}
+
+// B3 header names.
+const (
+	b3SingleHeader   = "b3"
+	b3TraceIDHeader  = "X-B3-Traceid"
+	b3SpanIDHeader   = "X-B3-Spanid"
+	b3ParentIDHeader = "X-B3-Parentspanid"
+	b3SampledHeader  = "X-B3-Sampled"
+	b3FlagsHeader    = "X-B3-Flags"
+)
+
+// B3Propagator uses the Zipkin B3 format, understood by Envoy and Istio.
+// Extraction accepts both the single b3 header and the multiple X-B3-*
+// headers; injection writes the single header when SingleHeader is true.
+type B3Propagator struct {
+	Codec        SpanContextCodec
+	SingleHeader bool
+}
+
+// Inject implements Propagator.
+func (p B3Propagator) Inject(_ opentracing.Tracer, sc opentracing.SpanContext, h http.Header) error {
+	ids, ok := p.Codec.Identifiers(sc)
+	if !ok {
+		return opentracing.ErrInvalidSpanContext
+	}
+	sampled := "0"
+	if ids.Debug {
+		sampled = "d"
+	} else if ids.Sampled {
+		sampled = "1"
+	}
+
+	if p.SingleHeader {
+		value := ids.TraceID + "-" + ids.SpanID + "-" + sampled
+		if ids.ParentSpanID != "" {
+			value += "-" + ids.ParentSpanID
+		}
+		h.Set(b3SingleHeader, value)
+		return nil
+	}
+
+	h.Set(b3TraceIDHeader, ids.TraceID)
+	h.Set(b3SpanIDHeader, ids.SpanID)
+	if ids.ParentSpanID != "" {
+		h.Set(b3ParentIDHeader, ids.ParentSpanID)
+	}
+	if ids.Debug {
+		h.Set(b3FlagsHeader, "1")
+	} else {
+		h.Set(b3SampledHeader, sampled)
+	}
+	return nil
+}
+
+// Extract implements Propagator.
+func (p B3Propagator) Extract(_ opentracing.Tracer, h http.Header) (opentracing.SpanContext, error) {
+	var ids SpanIdentifiers
+	if single := h.Get(b3SingleHeader); single != "" {
+		parts := strings.Split(single, "-")
+		// A lone sampling decision carries no IDs to continue from.
+		if len(parts) < 2 || len(parts) > 4 {
+			return nil, opentracing.ErrSpanContextNotFound
+		}
+		ids.TraceID, ids.SpanID = parts[0], parts[1]
+		if len(parts) > 2 {
+			ids.Sampled = parts[2] == "1" || parts[2] == "d"
+			ids.Debug = parts[2] == "d"
+		}
+		if len(parts) > 3 {
+			ids.ParentSpanID = parts[3]
+		}
+	} else {
+		ids.TraceID = h.Get(b3TraceIDHeader)
+		ids.SpanID = h.Get(b3SpanIDHeader)
+		if ids.TraceID == "" && ids.SpanID == "" {
+			return nil, opentracing.ErrSpanContextNotFound
+		}
+		ids.ParentSpanID = h.Get(b3ParentIDHeader)
+		sampled := h.Get(b3SampledHeader)
+		ids.Debug = h.Get(b3FlagsHeader) == "1"
+		ids.Sampled = ids.Debug || sampled == "1" || sampled == "true"
+	}
+
+	if !validID(ids.TraceID, 16, 32) || !validID(ids.SpanID, 16) ||
+		(ids.ParentSpanID != "" && !validID(ids.ParentSpanID, 16)) {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	return p.Codec.SpanContext(ids)
+}
+
+// W3C Trace Context header names.
+const (
+	traceparentHeader = "Traceparent"
+	tracestateHeader  = "Tracestate"
+)
+
+// W3CPropagator uses the W3C Trace Context traceparent and tracestate
+// headers, understood by OpenTelemetry.
+type W3CPropagator struct {
+	Codec SpanContextCodec
+}
+
+// Inject implements Propagator.
+func (p W3CPropagator) Inject(_ opentracing.Tracer, sc opentracing.SpanContext, h http.Header) error {
+	ids, ok := p.Codec.Identifiers(sc)
+	if !ok {
+		return opentracing.ErrInvalidSpanContext
+	}
+	flags := "00"
+	if ids.Sampled {
+		flags = "01"
+	}
+	// traceparent requires a 128-bit trace ID.
+	traceID := ids.TraceID
+	if len(traceID) < 32 {
+		traceID = strings.Repeat("0", 32-len(traceID)) + traceID
+	}
+	h.Set(traceparentHeader, fmt.Sprintf("00-%s-%s-%s", traceID, ids.SpanID, flags))
+	if ids.TraceState != "" {
+		h.Set(tracestateHeader, ids.TraceState)
+	}
+	return nil
+}
+
+// Extract implements Propagator.
+func (p W3CPropagator) Extract(_ opentracing.Tracer, h http.Header) (opentracing.SpanContext, error) {
+	traceparent := h.Get(traceparentHeader)
+	if traceparent == "" {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	parts := strings.Split(traceparent, "-")
+	// Future versions may append fields, version 00 has exactly four.
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		(parts[0] == "00" && len(parts) != 4) {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	if !validID(parts[1], 32) || !validID(parts[2], 16) || !validHex(parts[3], 2) {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	flags, _ := hex.DecodeString(parts[3])
+	return p.Codec.SpanContext(SpanIdentifiers{
+		TraceID:    parts[1],
+		SpanID:     parts[2],
+		Sampled:    flags[0]&1 == 1,
+		TraceState: h.Get(tracestateHeader),
+	})
+}
+
+// validID reports whether id is a non-zero hex ID of one of the given
+// lengths.
+func validID(id string, lengths ...int) bool {
+	for _, n := range lengths {
+		if validHex(id, n) {
+			return strings.Trim(id, "0") != ""
+		}
+	}
+	return false
+}
+
+func validHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,277 @@
+package opentracing_helpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// testSpanContext is the span context of testCodec, holding the IDs as is.
+type testSpanContext SpanIdentifiers
+
+func (testSpanContext) ForeachBaggageItem(func(k, v string) bool) {}
+
+type testCodec struct{}
+
+func (testCodec) Identifiers(sc opentracing.SpanContext) (SpanIdentifiers, bool) {
+	tsc, ok := sc.(testSpanContext)
+	return SpanIdentifiers(tsc), ok
+}
+
+func (testCodec) SpanContext(ids SpanIdentifiers) (opentracing.SpanContext, error) {
+	return testSpanContext(ids), nil
+}
+
+func TestPropagatorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		propagator Propagator
+		ids        SpanIdentifiers
+		want       SpanIdentifiers
+	}{
+		{
+			name:       "B3 multiple headers",
+			propagator: B3Propagator{Codec: testCodec{}},
+			ids:        SpanIdentifiers{TraceID: "463ac35c9f6413ad48485a3953bb6124", SpanID: "a2fb4a1d1a96d312", ParentSpanID: "0020000000000001", Sampled: true},
+		},
+		{
+			name:       "B3 single header",
+			propagator: B3Propagator{Codec: testCodec{}, SingleHeader: true},
+			ids:        SpanIdentifiers{TraceID: "463ac35c9f6413ad", SpanID: "a2fb4a1d1a96d312", ParentSpanID: "0020000000000001", Sampled: true},
+		},
+		{
+			name:       "B3 unsampled",
+			propagator: B3Propagator{Codec: testCodec{}},
+			ids:        SpanIdentifiers{TraceID: "463ac35c9f6413ad", SpanID: "a2fb4a1d1a96d312"},
+		},
+		{
+			name:       "B3 debug",
+			propagator: B3Propagator{Codec: testCodec{}, SingleHeader: true},
+			ids:        SpanIdentifiers{TraceID: "463ac35c9f6413ad", SpanID: "a2fb4a1d1a96d312", Sampled: true, Debug: true},
+		},
+		{
+			name:       "W3C",
+			propagator: W3CPropagator{Codec: testCodec{}},
+			ids:        SpanIdentifiers{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true, TraceState: "congo=t61rcWkgMzE"},
+		},
+		{
+			name:       "W3C 64-bit trace ID",
+			propagator: W3CPropagator{Codec: testCodec{}},
+			ids:        SpanIdentifiers{TraceID: "a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+			want:       SpanIdentifiers{TraceID: "0000000000000000a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want == (SpanIdentifiers{}) {
+				want = tt.ids
+			}
+			h := http.Header{}
+			if err := tt.propagator.Inject(nil, testSpanContext(tt.ids), h); err != nil {
+				t.Fatalf("Inject: %v", err)
+			}
+			sc, err := tt.propagator.Extract(nil, h)
+			if err != nil {
+				t.Fatalf("Extract(%v): %v", h, err)
+			}
+			if got := SpanIdentifiers(sc.(testSpanContext)); got != want {
+				t.Errorf("Extract(%v) = %+v, want %+v", h, got, want)
+			}
+		})
+	}
+}
+
+func TestPropagatorInjectForeignSpanContext(t *testing.T) {
+	for _, p := range []Propagator{B3Propagator{Codec: testCodec{}}, W3CPropagator{Codec: testCodec{}}} {
+		err := p.Inject(nil, opentracing.NoopTracer{}.StartSpan("op").Context(), http.Header{})
+		if !errors.Is(err, opentracing.ErrInvalidSpanContext) {
+			t.Errorf("%T.Inject of a foreign span context = %v, want %v", p, err, opentracing.ErrInvalidSpanContext)
+		}
+	}
+}
+
+func TestB3PropagatorExtractInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   error
+	}{
+		{"no headers", http.Header{}, opentracing.ErrSpanContextNotFound},
+		{"sampling decision only", http.Header{"B3": {"0"}}, opentracing.ErrSpanContextNotFound},
+		{"too many fields", http.Header{"B3": {"463ac35c9f6413ad-a2fb4a1d1a96d312-1-0020000000000001-x"}}, opentracing.ErrSpanContextNotFound},
+		{"non-hex trace ID", http.Header{"B3": {"463ac35c9f6413zz-a2fb4a1d1a96d312-1"}}, opentracing.ErrSpanContextCorrupted},
+		{"short span ID", http.Header{"B3": {"463ac35c9f6413ad-a2fb4a1d-1"}}, opentracing.ErrSpanContextCorrupted},
+		{"zero trace ID", http.Header{"B3": {"0000000000000000-a2fb4a1d1a96d312-1"}}, opentracing.ErrSpanContextCorrupted},
+		{"bad parent ID", http.Header{"B3": {"463ac35c9f6413ad-a2fb4a1d1a96d312-1-xyz"}}, opentracing.ErrSpanContextCorrupted},
+		{"missing span ID", http.Header{"X-B3-Traceid": {"463ac35c9f6413ad"}}, opentracing.ErrSpanContextCorrupted},
+		{"trace ID of 20 digits", http.Header{"X-B3-Traceid": {"463ac35c9f6413ad4848"}, "X-B3-Spanid": {"a2fb4a1d1a96d312"}}, opentracing.ErrSpanContextCorrupted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := B3Propagator{Codec: testCodec{}}.Extract(nil, tt.header)
+			if !errors.Is(err, tt.want) || sc != nil {
+				t.Errorf("Extract(%v) = %v, %v, want nil, %v", tt.header, sc, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestW3CPropagatorExtractInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		want        error
+	}{
+		{"missing", "", opentracing.ErrSpanContextNotFound},
+		{"too few fields", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", opentracing.ErrSpanContextCorrupted},
+		{"extra field in version 00", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-xx", opentracing.ErrSpanContextCorrupted},
+		{"forbidden version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", opentracing.ErrSpanContextCorrupted},
+		{"long version", "000-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", opentracing.ErrSpanContextCorrupted},
+		{"64-bit trace ID", "00-a3ce929d0e0e4736-00f067aa0ba902b7-01", opentracing.ErrSpanContextCorrupted},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", opentracing.ErrSpanContextCorrupted},
+		{"zero span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", opentracing.ErrSpanContextCorrupted},
+		{"non-hex span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902zz-01", opentracing.ErrSpanContextCorrupted},
+		{"non-hex flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0g", opentracing.ErrSpanContextCorrupted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.traceparent != "" {
+				h.Set("traceparent", tt.traceparent)
+			}
+			sc, err := W3CPropagator{Codec: testCodec{}}.Extract(nil, h)
+			if !errors.Is(err, tt.want) || sc != nil {
+				t.Errorf("Extract(%q) = %v, %v, want nil, %v", tt.traceparent, sc, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestW3CPropagatorExtractFutureVersion(t *testing.T) {
+	// Later versions may append fields, which must be ignored.
+	h := http.Header{}
+	h.Set("traceparent", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future")
+	sc, err := W3CPropagator{Codec: testCodec{}}.Extract(nil, h)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	want := SpanIdentifiers{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+	if got := SpanIdentifiers(sc.(testSpanContext)); got != want {
+		t.Errorf("Extract = %+v, want %+v", got, want)
+	}
+}
+
+// mockCodec maps the span contexts of mocktracer to 64-bit IDs.
+type mockCodec struct{}
+
+func (mockCodec) Identifiers(sc opentracing.SpanContext) (SpanIdentifiers, bool) {
+	msc, ok := sc.(mocktracer.MockSpanContext)
+	return SpanIdentifiers{
+		TraceID: fmt.Sprintf("%016x", msc.TraceID),
+		SpanID:  fmt.Sprintf("%016x", msc.SpanID),
+		Sampled: msc.Sampled,
+	}, ok
+}
+
+func (mockCodec) SpanContext(ids SpanIdentifiers) (opentracing.SpanContext, error) {
+	traceID, err := strconv.ParseUint(ids.TraceID, 16, 64)
+	if err != nil {
+		return nil, err
+	}
+	spanID, err := strconv.ParseUint(ids.SpanID, 16, 64)
+	if err != nil {
+		return nil, err
+	}
+	return mocktracer.MockSpanContext{TraceID: int(traceID), SpanID: int(spanID), Sampled: ids.Sampled}, nil
+}
+
+func TestWithPropagatorsServer(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", okHandler, WithTracer(tracer), WithPropagators(
+		W3CPropagator{Codec: mockCodec{}},
+		B3Propagator{Codec: mockCodec{}},
+	))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-B3-Traceid", "000000000000002a")
+	r.Header.Set("X-B3-Spanid", "0000000000000007")
+	r.Header.Set("X-B3-Sampled", "1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	span := finishedSpan(t, tracer)
+	if span.SpanContext.TraceID != 42 || span.ParentID != 7 {
+		t.Errorf("span trace %d, parent %d; want it to continue the B3 trace 42 from span 7", span.SpanContext.TraceID, span.ParentID)
+	}
+}
+
+func TestWithPropagatorsClient(t *testing.T) {
+	tracer := mocktracer.New()
+	var sent *http.Request
+	client := &http.Client{Transport: NewTracedTransport(respond(http.StatusOK, "", &sent),
+		WithTracer(tracer),
+		WithPropagators(W3CPropagator{Codec: mockCodec{}}, B3Propagator{Codec: mockCodec{}}),
+	)}
+	resp, err := client.Get("http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	span := finishedSpan(t, tracer)
+	want := fmt.Sprintf("00-%032x-%016x-01", span.SpanContext.TraceID, span.SpanContext.SpanID)
+	if got := sent.Header.Get("traceparent"); got != want {
+		t.Errorf("traceparent = %q, want %q", got, want)
+	}
+	if got := sent.Header.Get("X-B3-Traceid"); got != "" {
+		t.Errorf("X-B3-Traceid = %q, want only the first propagator to inject", got)
+	}
+}
+
+func TestPropagatorChainExtractsFirstFound(t *testing.T) {
+	ids := SpanIdentifiers{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+	h := http.Header{}
+	if err := (W3CPropagator{Codec: testCodec{}}).Inject(nil, testSpanContext(ids), h); err != nil {
+		t.Fatal(err)
+	}
+	chain := PropagatorChain{B3Propagator{Codec: testCodec{}}, W3CPropagator{Codec: testCodec{}}}
+	sc, err := chain.Extract(nil, h)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if got := SpanIdentifiers(sc.(testSpanContext)); got != ids {
+		t.Errorf("Extract = %+v, want %+v", got, ids)
+	}
+}
+
+func TestPropagatorChainExtractReportsCorruption(t *testing.T) {
+	h := http.Header{}
+	h.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902zz-01")
+	chain := PropagatorChain{B3Propagator{Codec: testCodec{}}, W3CPropagator{Codec: testCodec{}}}
+	if _, err := chain.Extract(nil, h); !errors.Is(err, opentracing.ErrSpanContextCorrupted) {
+		t.Errorf("Extract = %v, want %v", err, opentracing.ErrSpanContextCorrupted)
+	}
+}
+
+func TestPropagatorChainInjectsAll(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("op")
+	chain := PropagatorChain{
+		W3CPropagator{Codec: testCodec{}},
+		NativePropagator{},
+		B3Propagator{Codec: mockCodec{}, SingleHeader: true},
+	}
+	h := http.Header{}
+	err := chain.Inject(tracer, span.Context(), h)
+	if !errors.Is(err, opentracing.ErrInvalidSpanContext) {
+		t.Errorf("Inject = %v, want the W3C error for a foreign span context", err)
+	}
+	if h.Get("Mockpfx-Ids-Traceid") == "" || h.Get("b3") == "" {
+		t.Errorf("headers %v, want both native and B3 headers despite the W3C error", h)
+	}
+}
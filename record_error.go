@@ -0,0 +1,61 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// maxStackDepth bounds the number of frames logged by RecordError.
+const maxStackDepth = 32
+
+// RecordError marks the span in ctx as failed and logs err on it with the
+// error.kind, message and stack fields of the OpenTracing semantic
+// conventions, followed by fields. It returns err unchanged, so that it
+// can wrap return statements:
+//
+//	if err != nil {
+//		return opentracing_helpers.RecordError(ctx, err, log.String("user.id", id))
+//	}
+//
+// Nothing is recorded if err is nil or ctx carries no span.
+func RecordError(ctx context.Context, err error, fields ...log.Field) error {
+	if err == nil {
+		return nil
+	}
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return err
+	}
+	ext.Error.Set(span, true)
+	span.LogFields(append([]log.Field{
+		log.String("event", "error"),
+		log.Error(err),
+		log.String("error.kind", fmt.Sprintf("%T", err)),
+		log.String("message", err.Error()),
+		log.String("stack", callerStack(1)),
+	}, fields...)...)
+	return err
+}
+
+// callerStack formats the stack from the caller of callerStack, skipping
+// skip more frames, like runtime/debug.Stack without the goroutine header.
+func callerStack(skip int) string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
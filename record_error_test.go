@@ -0,0 +1,62 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestRecordError(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("load")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	err := &fs.PathError{Op: "open", Path: "/etc/app.conf", Err: fs.ErrNotExist}
+	if got := RecordError(ctx, err, log.String("user.id", "42")); got != err {
+		t.Errorf("RecordError returned %v, want err", got)
+	}
+	span.Finish()
+
+	finished := finishedSpan(t, tracer)
+	if finished.Tag("error") != true {
+		t.Error("span not marked as failed")
+	}
+	fields := loggedFields(finished)
+	for key, want := range map[string]string{
+		"event":      "error",
+		"error.kind": "*fs.PathError",
+		"message":    err.Error(),
+		"user.id":    "42",
+	} {
+		if fields[key] != want {
+			t.Errorf("%s = %q, want %q", key, fields[key], want)
+		}
+	}
+	if stack := fields["stack"]; !strings.HasPrefix(stack, "github.com/jfernandez/opentracing-helpers.TestRecordError\n") {
+		t.Errorf("stack doesn't start at the caller of RecordError:\n%s", stack)
+	}
+}
+
+func TestRecordErrorNothingToRecord(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("load")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	if err := RecordError(ctx, nil); err != nil {
+		t.Errorf("RecordError(nil) = %v", err)
+	}
+	span.Finish()
+	if finished := finishedSpan(t, tracer); finished.Tag("error") != nil || len(finished.Logs()) != 0 {
+		t.Error("a nil error was recorded")
+	}
+
+	err := errors.New("no span")
+	if got := RecordError(context.Background(), err); got != err {
+		t.Errorf("RecordError without a span returned %v, want err", got)
+	}
+}
@@ -0,0 +1,71 @@
+package opentracing_helpers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// maxRedirects is the limit of http.Client's default redirect policy.
+const maxRedirects = 10
+
+// TraceRedirects returns an http.Client CheckRedirect function that logs
+// every redirect followed by the client on the span of the request's
+// context, with the redirect status code and Location target, before
+// applying checkRedirect. A nil checkRedirect applies the client's default
+// policy of following at most 10 redirects:
+//
+//	client := &http.Client{
+//		Transport:     opentracing_helpers.NewTracedTransport(nil),
+//		CheckRedirect: opentracing_helpers.TraceRedirects(nil),
+//	}
+//
+// With TracedTransport each hop also gets its own client span, tagged with
+// http.redirect.hop, http.redirect.status_code and http.redirect.from.
+// Locations are scrubbed like the http.url tag, WithURLScrubber among opts
+// replaces the default policy.
+func TraceRedirects(checkRedirect func(req *http.Request, via []*http.Request) error, opts ...TransportOption) func(req *http.Request, via []*http.Request) error {
+	c := newTransportConfig(opts)
+	return func(req *http.Request, via []*http.Request) error {
+		var err error
+		if checkRedirect != nil {
+			err = checkRedirect(req, via)
+		} else if len(via) >= maxRedirects {
+			err = errors.New("stopped after 10 redirects")
+		}
+		span := opentracing.SpanFromContext(req.Context())
+		if span == nil || req.Response == nil {
+			return err
+		}
+		fields := []log.Field{
+			log.String("event", "redirect"),
+			log.Int("http.redirect.hop", len(via)),
+			log.Int("http.redirect.status_code", req.Response.StatusCode),
+			log.String("http.redirect.location", c.urlTag(req.URL)),
+		}
+		if err != nil {
+			fields = append(fields, log.Bool("http.redirect.followed", false), log.Error(err))
+		}
+		span.LogFields(fields...)
+		return err
+	}
+}
+
+// tagRedirect tags the span of a request sent because of a redirect, which
+// http.Client marks by setting req.Response to the redirect response.
+func (c *commonConfig) tagRedirect(span opentracing.Span, req *http.Request) {
+	if req.Response == nil {
+		return
+	}
+	hop := 0
+	for r := req; r != nil && r.Response != nil; r = r.Response.Request {
+		hop++
+	}
+	span.SetTag("http.redirect.hop", hop)
+	span.SetTag("http.redirect.status_code", req.Response.StatusCode)
+	if prev := req.Response.Request; prev != nil {
+		span.SetTag("http.redirect.from", c.urlTag(prev.URL))
+	}
+}
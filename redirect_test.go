@@ -0,0 +1,132 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func redirectServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/new", http.StatusFound))
+	mux.Handle("/new", okHandler)
+	return httptest.NewServer(mux)
+}
+
+func TestTracedClientRedirects(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/old", nil)
+	resp, err := TracedClient(nil, WithTracer(tracer)).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.Finish()
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 3 {
+		t.Fatalf("%d finished spans, want 3", len(spans))
+	}
+	if got := spans[0].Tag("http.redirect.hop"); got != nil {
+		t.Errorf("first request tagged with hop %v", got)
+	}
+	hop := spans[1]
+	if hop.Tag("http.redirect.hop") != 1 || hop.Tag("http.redirect.status_code") != http.StatusFound ||
+		hop.Tag("http.redirect.from") != server.URL+"/old" {
+		t.Errorf("redirected request tags = %v", hop.Tags())
+	}
+
+	fields := loggedFields(spans[2])
+	want := map[string]string{
+		"event":                     "redirect",
+		"http.redirect.hop":         "1",
+		"http.redirect.status_code": "302",
+		"http.redirect.location":    server.URL + "/new",
+	}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("parent logged %s=%q, want %q", k, fields[k], v)
+		}
+	}
+}
+
+func TestTraceRedirectsNotFollowed(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/old", nil)
+	client := &http.Client{CheckRedirect: TraceRedirects(func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	})}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.Finish()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	fields := loggedFields(finishedSpan(t, tracer))
+	if fields["http.redirect.followed"] != "false" || fields["error.object"] != http.ErrUseLastResponse.Error() {
+		t.Errorf("parent logged %v, want the redirect not followed", fields)
+	}
+}
+
+func TestTraceRedirectsScrubsURLs(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/login", http.RedirectHandler("/home?session=s3cr3t", http.StatusFound))
+	mux.Handle("/home", okHandler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	for name, test := range map[string]struct {
+		opts           []TransportOption
+		from, location string
+	}{
+		"default": {
+			from:     server.URL + "/login",
+			location: server.URL + "/home",
+		},
+		"custom": {
+			opts:     []TransportOption{WithURLScrubber(func(u *url.URL) string { return u.Path })},
+			from:     "/login",
+			location: "/home",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			parent := tracer.StartSpan("parent")
+			ctx := opentracing.ContextWithSpan(context.Background(), parent)
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/login?token=t0k3n", nil)
+			resp, err := TracedClient(nil, append(test.opts, WithTracer(tracer))...).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			parent.Finish()
+
+			spans := tracer.FinishedSpans()
+			if got := spans[1].Tag("http.redirect.from"); got != test.from {
+				t.Errorf("http.redirect.from = %v, want %q", got, test.from)
+			}
+			if got := loggedFields(spans[2])["http.redirect.location"]; got != test.location {
+				t.Errorf("http.redirect.location = %q, want %q", got, test.location)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"net"
+
+	"github.com/opentracing/opentracing-go"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Hook is a go-redis hook that traces commands and pipelines.
+type Hook struct {
+	config *config
+}
+
+var _ goredis.Hook = (*Hook)(nil)
+
+// NewHook returns a hook to register with AddHook.
+func NewHook(opts ...Option) *Hook {
+	return &Hook{config: newConfig(opts)}
+}
+
+// DialHook implements goredis.Hook. Dials are not traced.
+func (h *Hook) DialHook(next goredis.DialHook) goredis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook implements goredis.Hook.
+func (h *Hook) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		span := h.config.startSpan(ctx, cmd.Name(), len(cmd.Args())-1)
+		err := next(opentracing.ContextWithSpan(ctx, span), cmd)
+		finishSpan(span, ignoreNil(err))
+		return err
+	}
+}
+
+// ProcessPipelineHook implements goredis.Hook. A pipeline is traced as a
+// single span tagged with the number of commands it contains.
+func (h *Hook) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		span := h.config.startSpan(ctx, "pipeline", 0)
+		span.SetTag("redis.pipeline_length", len(cmds))
+		err := next(opentracing.ContextWithSpan(ctx, span), cmds)
+		finishSpan(span, ignoreNil(err))
+		return err
+	}
+}
+
+// ignoreNil filters out goredis.Nil, which signals a missing key rather
+// than a failure.
+func ignoreNil(err error) error {
+	if err == goredis.Nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Conn is a redigo connection whose commands are traced.
+type Conn struct {
+	redis.Conn
+	config *config
+}
+
+// WrapConn traces the commands sent on conn.
+func WrapConn(conn redis.Conn, opts ...Option) *Conn {
+	return &Conn{Conn: conn, config: newConfig(opts)}
+}
+
+// Do sends a command within a root span. Use DoContext to link it to the
+// current trace.
+func (c *Conn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	return c.DoContext(context.Background(), commandName, args...)
+}
+
+// DoContext sends a command within a child of the span in ctx.
+func (c *Conn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	// redigo uses an empty command to flush pending commands.
+	if commandName == "" {
+		return redis.DoContext(c.Conn, ctx, commandName, args...)
+	}
+	span := c.config.startSpan(ctx, commandName, len(args))
+	reply, err := redis.DoContext(c.Conn, ctx, commandName, args...)
+	if err == redis.ErrNil {
+		finishSpan(span, nil)
+	} else {
+		finishSpan(span, err)
+	}
+	return reply, err
+}
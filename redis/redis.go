@@ -0,0 +1,108 @@
+// Package redis traces Redis commands sent with redis/go-redis or
+// gomodule/redigo. Each command becomes a child of the span carried by the
+// command's context, for example the span started by TraceHandler:
+//
+//	rdb := goredis.NewClient(&goredis.Options{Addr: addr})
+//	rdb.AddHook(otredis.NewHook())
+//
+// or, with redigo:
+//
+//	conn := otredis.WrapConn(pool.Get())
+//	reply, err := conn.DoContext(r.Context(), "GET", "key")
+package redis
+
+import (
+	"context"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "redis"}
+
+// Option customizes the traced commands.
+type Option func(*config)
+
+type config struct {
+	tracer opentracing.Tracer
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) activeTracer() opentracing.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return opentracing.GlobalTracer()
+}
+
+// WithTracer uses tracer instead of opentracing.GlobalTracer().
+func WithTracer(tracer opentracing.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// startSpan starts a span for a command with nargs arguments as a child of
+// the span in ctx.
+func (c *config) startSpan(ctx context.Context, command string, nargs int) opentracing.Span {
+	var parent opentracing.SpanContext
+	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+		parent = parentSpan.Context()
+	}
+	command = strings.ToUpper(command)
+	span := c.activeTracer().StartSpan(
+		"redis "+command,
+		opentracing.ChildOf(parent),
+		ext.SpanKindRPCClient,
+		componentTag,
+	)
+	ext.DBType.Set(span, "redis")
+	span.SetTag("redis.command", command)
+	span.SetTag("redis.key_count", keyCount(command, nargs))
+	return span
+}
+
+func finishSpan(span opentracing.Span, err error) {
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+	span.Finish()
+}
+
+// multiKeyCommands take only keys as arguments.
+var multiKeyCommands = map[string]bool{
+	"DEL":     true,
+	"EXISTS":  true,
+	"MGET":    true,
+	"PFCOUNT": true,
+	"SDIFF":   true,
+	"SINTER":  true,
+	"SUNION":  true,
+	"TOUCH":   true,
+	"UNLINK":  true,
+	"WATCH":   true,
+}
+
+// keyCount estimates the number of keys a command with nargs arguments,
+// not counting the command name, operates on.
+func keyCount(command string, nargs int) int {
+	switch {
+	case nargs == 0:
+		return 0
+	case multiKeyCommands[command]:
+		return nargs
+	case command == "MSET" || command == "MSETNX":
+		return nargs / 2
+	}
+	return 1
+}
@@ -0,0 +1,154 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestHookProcess(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	hook := NewHook(WithTracer(tracer))
+
+	for _, tt := range []struct {
+		args    []interface{}
+		err     error
+		keys    int
+		wantErr bool
+	}{
+		{args: []interface{}{"get", "a"}, err: goredis.Nil, keys: 1},
+		{args: []interface{}{"del", "a", "b", "c"}, keys: 3},
+		{args: []interface{}{"mset", "a", "1", "b", "2"}, keys: 2},
+		{args: []interface{}{"ping"}, err: errors.New("connection refused"), keys: 0, wantErr: true},
+	} {
+		tracer.Reset()
+		cmd := goredis.NewCmd(ctx, tt.args...)
+		process := hook.ProcessHook(func(ctx context.Context, cmd goredis.Cmder) error {
+			if opentracing.SpanFromContext(ctx) == parent {
+				t.Error("next was called without the command span")
+			}
+			return tt.err
+		})
+		if err := process(ctx, cmd); err != tt.err {
+			t.Errorf("%v: err = %v, want %v", tt.args, err, tt.err)
+		}
+
+		spans := tracer.FinishedSpans()
+		if len(spans) != 1 {
+			t.Fatalf("%v: got %d spans, want 1", tt.args, len(spans))
+		}
+		span := spans[0]
+		name := goredis.NewCmd(ctx, tt.args...).Name()
+		if want := "redis " + strings.ToUpper(name); span.OperationName != want {
+			t.Errorf("operation name = %q, want %q", span.OperationName, want)
+		}
+		if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+			t.Errorf("%v: span is not a child of the context span", tt.args)
+		}
+		if got := span.Tag("redis.key_count"); got != tt.keys {
+			t.Errorf("%v: redis.key_count = %v, want %d", tt.args, got, tt.keys)
+		}
+		if got := span.Tag(string(ext.Error)) == true; got != tt.wantErr {
+			t.Errorf("%v: error tag = %v, want %v", tt.args, got, tt.wantErr)
+		}
+		if got := span.Tag(string(ext.SpanKind)); got != ext.SpanKindRPCClientEnum {
+			t.Errorf("span.kind = %v", got)
+		}
+	}
+}
+
+func TestHookPipeline(t *testing.T) {
+	tracer := mocktracer.New()
+	hook := NewHook(WithTracer(tracer))
+	ctx := context.Background()
+	cmds := []goredis.Cmder{goredis.NewCmd(ctx, "get", "a"), goredis.NewCmd(ctx, "get", "b")}
+
+	pipeline := hook.ProcessPipelineHook(func(context.Context, []goredis.Cmder) error { return nil })
+	if err := pipeline(ctx, cmds); err != nil {
+		t.Fatal(err)
+	}
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 || spans[0].OperationName != "redis PIPELINE" {
+		t.Fatalf("got spans %v, want a single redis PIPELINE", spans)
+	}
+	if got := spans[0].Tag("redis.pipeline_length"); got != 2 {
+		t.Errorf("redis.pipeline_length = %v, want 2", got)
+	}
+}
+
+// fakeConn is a redigo connection replying with the configured error.
+type fakeConn struct {
+	redis.Conn
+	err      error
+	commands []string
+}
+
+func (c *fakeConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	c.commands = append(c.commands, commandName)
+	return nil, c.err
+}
+
+func (c *fakeConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	return nil, c.err
+}
+
+func TestConnDo(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	fake := &fakeConn{err: redis.ErrNil}
+	conn := WrapConn(fake, WithTracer(tracer))
+
+	if _, err := conn.DoContext(ctx, "hget", "h", "f"); err != redis.ErrNil {
+		t.Fatalf("err = %v, want redis.ErrNil", err)
+	}
+	if _, err := conn.Do(""); err != redis.ErrNil {
+		t.Fatalf("err = %v, want redis.ErrNil", err)
+	}
+	if len(fake.commands) != 2 {
+		t.Errorf("commands sent = %q, want 2", fake.commands)
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1: an empty flush command is not traced", len(spans))
+	}
+	span := spans[0]
+	if span.OperationName != "redis HGET" || span.Tag("redis.command") != "HGET" {
+		t.Errorf("span = %q, redis.command %v", span.OperationName, span.Tag("redis.command"))
+	}
+	if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("span is not a child of the context span")
+	}
+	if span.Tag(string(ext.Error)) != nil {
+		t.Error("redis.ErrNil was recorded as an error")
+	}
+	if got := span.Tag(string(ext.DBType)); got != "redis" {
+		t.Errorf("db.type = %v", got)
+	}
+}
+
+func TestConnDoError(t *testing.T) {
+	tracer := mocktracer.New()
+	conn := WrapConn(&fakeConn{err: errors.New("READONLY")}, WithTracer(tracer))
+
+	if _, err := conn.Do("SET", "k", "v"); err == nil {
+		t.Fatal("expected an error")
+	}
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 || spans[0].Tag(string(ext.Error)) != true {
+		t.Fatalf("got %v, want a single span tagged as an error", spans)
+	}
+	if spans[0].ParentID != 0 {
+		t.Error("Do started a child span, want a root one")
+	}
+}
@@ -0,0 +1,55 @@
+package opentracing_helpers
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// StartSpanWithReferences starts a span that is a child of the span in ctx,
+// if any, and holds refs besides. It models fan-in work, such as a batch
+// of messages processed together, whose upstream spans don't fit a single
+// parent:
+//
+//	refs := opentracing_helpers.CollectReferences(messages, extractSpanContext)
+//	span, ctx := opentracing_helpers.StartSpanWithReferences(ctx, "process batch", refs...)
+//	defer span.Finish()
+//
+// Like opentracing.StartSpanFromContext it returns ctx extended with the
+// span.
+func StartSpanWithReferences(ctx context.Context, operationName string, refs ...opentracing.SpanReference) (opentracing.Span, context.Context) {
+	opts := make([]opentracing.StartSpanOption, 0, len(refs)+1)
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+	for _, ref := range refs {
+		if ref.ReferencedContext != nil {
+			opts = append(opts, ref)
+		}
+	}
+	span := opentracing.GlobalTracer().StartSpan(operationName, opts...)
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+// FollowsFromAll returns FollowsFrom references to the given span
+// contexts, skipping nil ones.
+func FollowsFromAll(contexts ...opentracing.SpanContext) []opentracing.SpanReference {
+	refs := make([]opentracing.SpanReference, 0, len(contexts))
+	for _, sc := range contexts {
+		if sc != nil {
+			refs = append(refs, opentracing.SpanReference{Type: opentracing.FollowsFromRef, ReferencedContext: sc})
+		}
+	}
+	return refs
+}
+
+// CollectReferences extracts the span contexts of msgs with extract and
+// returns FollowsFrom references to them, for StartSpanWithReferences.
+// Messages without a span context are skipped.
+func CollectReferences[T any](msgs []T, extract func(msg T) opentracing.SpanContext) []opentracing.SpanReference {
+	contexts := make([]opentracing.SpanContext, len(msgs))
+	for i, msg := range msgs {
+		contexts[i] = extract(msg)
+	}
+	return FollowsFromAll(contexts...)
+}
@@ -0,0 +1,74 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// refTracer records the references of the spans it starts, since the mock
+// tracer only keeps the first one as the parent.
+type refTracer struct {
+	*mocktracer.MockTracer
+	refs []opentracing.SpanReference
+}
+
+func (t *refTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, opt := range opts {
+		opt.Apply(&sso)
+	}
+	t.refs = sso.References
+	return t.MockTracer.StartSpan(operationName, opts...)
+}
+
+func TestStartSpanWithReferences(t *testing.T) {
+	tracer := &refTracer{MockTracer: mocktracer.New()}
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	parent := tracer.MockTracer.StartSpan("consume")
+	a, b := tracer.MockTracer.StartSpan("publish a"), tracer.MockTracer.StartSpan("publish b")
+	msgs := []opentracing.Span{a, nil, b}
+	refs := CollectReferences(msgs, func(s opentracing.Span) opentracing.SpanContext {
+		if s == nil {
+			return nil
+		}
+		return s.Context()
+	})
+
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	span, spanCtx := StartSpanWithReferences(ctx, "process batch", refs...)
+	span.Finish()
+
+	if opentracing.SpanFromContext(spanCtx) != span {
+		t.Error("the returned context doesn't carry the span")
+	}
+	want := []opentracing.SpanReference{
+		opentracing.ChildOf(parent.Context()),
+		{Type: opentracing.FollowsFromRef, ReferencedContext: a.Context()},
+		{Type: opentracing.FollowsFromRef, ReferencedContext: b.Context()},
+	}
+	if !reflect.DeepEqual(tracer.refs, want) {
+		t.Errorf("references = %v, want %v", tracer.refs, want)
+	}
+}
+
+func TestStartSpanWithReferencesWithoutParent(t *testing.T) {
+	tracer := &refTracer{MockTracer: mocktracer.New()}
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	upstream := tracer.MockTracer.StartSpan("publish")
+	span, _ := StartSpanWithReferences(context.Background(), "process batch",
+		opentracing.SpanReference{Type: opentracing.FollowsFromRef},
+		opentracing.FollowsFrom(upstream.Context()))
+	span.Finish()
+
+	if want := FollowsFromAll(upstream.Context()); !reflect.DeepEqual(tracer.refs, want) {
+		t.Errorf("references = %v, want %v", tracer.refs, want)
+	}
+}
@@ -0,0 +1,180 @@
+package opentracing_helpers
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// responseRecorder wraps an http.ResponseWriter to capture the status code
+// and the number of body bytes written by a handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+
+	// onFlush is called after every Flush with the bytes written so far.
+	onFlush func(size int64)
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	// Informational responses may be followed by the real status.
+	if !rr.wroteHeader && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		rr.status = status
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	n, err := rr.ResponseWriter.Write(b)
+	rr.size += int64(n)
+	return n, err
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// writer returns a ResponseWriter writing to rr that implements the same
+// optional interfaces among http.Flusher, http.Hijacker, io.ReaderFrom and
+// http.Pusher as the wrapped one, so that features like WebSocket upgrades
+// and sendfile keep working behind the middleware.
+func (rr *responseRecorder) writer() http.ResponseWriter {
+	_, f := rr.ResponseWriter.(http.Flusher)
+	_, h := rr.ResponseWriter.(http.Hijacker)
+	_, r := rr.ResponseWriter.(io.ReaderFrom)
+	_, p := rr.ResponseWriter.(http.Pusher)
+	switch {
+	case !f && !h && !r && !p:
+		return rr
+	case f && !h && !r && !p:
+		return struct {
+			*responseRecorder
+			http.Flusher
+		}{rr, flusher{rr}}
+	case !f && h && !r && !p:
+		return struct {
+			*responseRecorder
+			http.Hijacker
+		}{rr, hijacker{rr}}
+	case f && h && !r && !p:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Hijacker
+		}{rr, flusher{rr}, hijacker{rr}}
+	case !f && !h && r && !p:
+		return struct {
+			*responseRecorder
+			io.ReaderFrom
+		}{rr, readerFrom{rr}}
+	case f && !h && r && !p:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			io.ReaderFrom
+		}{rr, flusher{rr}, readerFrom{rr}}
+	case !f && h && r && !p:
+		return struct {
+			*responseRecorder
+			http.Hijacker
+			io.ReaderFrom
+		}{rr, hijacker{rr}, readerFrom{rr}}
+	case f && h && r && !p:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{rr, flusher{rr}, hijacker{rr}, readerFrom{rr}}
+	case !f && !h && !r && p:
+		return struct {
+			*responseRecorder
+			http.Pusher
+		}{rr, pusher{rr}}
+	case f && !h && !r && p:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Pusher
+		}{rr, flusher{rr}, pusher{rr}}
+	case !f && h && !r && p:
+		return struct {
+			*responseRecorder
+			http.Hijacker
+			http.Pusher
+		}{rr, hijacker{rr}, pusher{rr}}
+	case f && h && !r && p:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{rr, flusher{rr}, hijacker{rr}, pusher{rr}}
+	case !f && !h && r && p:
+		return struct {
+			*responseRecorder
+			io.ReaderFrom
+			http.Pusher
+		}{rr, readerFrom{rr}, pusher{rr}}
+	case f && !h && r && p:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			io.ReaderFrom
+			http.Pusher
+		}{rr, flusher{rr}, readerFrom{rr}, pusher{rr}}
+	case !f && h && r && p:
+		return struct {
+			*responseRecorder
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{rr, hijacker{rr}, readerFrom{rr}, pusher{rr}}
+	case f && h && r && p:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{rr, flusher{rr}, hijacker{rr}, readerFrom{rr}, pusher{rr}}
+	}
+	panic("unreachable")
+}
+
+type flusher struct{ rr *responseRecorder }
+
+func (f flusher) Flush() {
+	f.rr.wroteHeader = true
+	f.rr.ResponseWriter.(http.Flusher).Flush()
+	if f.rr.onFlush != nil {
+		f.rr.onFlush(f.rr.size)
+	}
+}
+
+type hijacker struct{ rr *responseRecorder }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.rr.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+type readerFrom struct{ rr *responseRecorder }
+
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	r.rr.wroteHeader = true
+	n, err := r.rr.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	r.rr.size += n
+	return n, err
+}
+
+type pusher struct{ rr *responseRecorder }
+
+func (p pusher) Push(target string, opts *http.PushOptions) error {
+	return p.rr.ResponseWriter.(http.Pusher).Push(target, opts)
+}
@@ -0,0 +1,178 @@
+package opentracing_helpers
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// baseWriter implements http.ResponseWriter only. The types embedding it
+// add optional interfaces, recording their calls.
+type baseWriter struct {
+	rec   *httptest.ResponseRecorder
+	calls []string
+}
+
+func newBaseWriter() *baseWriter {
+	return &baseWriter{rec: httptest.NewRecorder()}
+}
+
+func (w *baseWriter) Header() http.Header         { return w.rec.Header() }
+func (w *baseWriter) Write(b []byte) (int, error) { return w.rec.Write(b) }
+func (w *baseWriter) WriteHeader(status int)      { w.rec.WriteHeader(status) }
+
+func (w *baseWriter) flush() { w.calls = append(w.calls, "Flush") }
+
+func (w *baseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.calls = append(w.calls, "Hijack")
+	return nil, nil, nil
+}
+
+func (w *baseWriter) readFrom(src io.Reader) (int64, error) {
+	w.calls = append(w.calls, "ReadFrom")
+	return io.Copy(w.rec.Body, src)
+}
+
+func (w *baseWriter) push(string, *http.PushOptions) error {
+	w.calls = append(w.calls, "Push")
+	return nil
+}
+
+type plainWriter struct{ *baseWriter }
+
+type flushWriter struct{ *baseWriter }
+
+func (w flushWriter) Flush() { w.flush() }
+
+type hijackWriter struct{ *baseWriter }
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+// http1Writer has the optional interfaces of net/http's HTTP/1 writer.
+type http1Writer struct{ *baseWriter }
+
+func (w http1Writer) Flush()                                       { w.flush() }
+func (w http1Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+func (w http1Writer) ReadFrom(src io.Reader) (int64, error)        { return w.readFrom(src) }
+
+// http2Writer has the optional interfaces of net/http's HTTP/2 writer.
+type http2Writer struct{ *baseWriter }
+
+func (w http2Writer) Flush()                                           { w.flush() }
+func (w http2Writer) Push(target string, opts *http.PushOptions) error { return w.push(target, opts) }
+
+type fullWriter struct{ *baseWriter }
+
+func (w fullWriter) Flush()                                           { w.flush() }
+func (w fullWriter) Hijack() (net.Conn, *bufio.ReadWriter, error)     { return w.hijack() }
+func (w fullWriter) ReadFrom(src io.Reader) (int64, error)            { return w.readFrom(src) }
+func (w fullWriter) Push(target string, opts *http.PushOptions) error { return w.push(target, opts) }
+
+// optionalInterfaces lists the optional interfaces implemented by w.
+func optionalInterfaces(w http.ResponseWriter) []string {
+	var names []string
+	if _, ok := w.(http.Flusher); ok {
+		names = append(names, "Flusher")
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		names = append(names, "Hijacker")
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		names = append(names, "ReaderFrom")
+	}
+	if _, ok := w.(http.Pusher); ok {
+		names = append(names, "Pusher")
+	}
+	return names
+}
+
+func TestResponseRecorderWriterPreservesInterfaces(t *testing.T) {
+	base := newBaseWriter()
+	for _, w := range []http.ResponseWriter{
+		plainWriter{base},
+		flushWriter{base},
+		hijackWriter{base},
+		http1Writer{base},
+		http2Writer{base},
+		fullWriter{base},
+	} {
+		rr := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		got := strings.Join(optionalInterfaces(rr.writer()), ",")
+		want := strings.Join(optionalInterfaces(w), ",")
+		if got != want {
+			t.Errorf("writer() of %T implements [%s], want [%s]", w, got, want)
+		}
+	}
+}
+
+func TestResponseRecorderWriterDelegates(t *testing.T) {
+	base := newBaseWriter()
+	rr := &responseRecorder{ResponseWriter: fullWriter{base}, status: http.StatusOK}
+	var flushedSize int64 = -1
+	rr.onFlush = func(size int64) { flushedSize = size }
+	w := rr.writer()
+
+	w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello"))
+	w.(http.Flusher).Flush()
+	w.(http.Hijacker).Hijack()
+	w.(http.Pusher).Push("/style.css", nil)
+
+	if got, want := strings.Join(base.calls, ","), "ReadFrom,Flush,Hijack,Push"; got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+	if rr.size != 5 || flushedSize != 5 {
+		t.Errorf("size = %d and flushed size = %d, want 5", rr.size, flushedSize)
+	}
+	if !rr.wroteHeader || rr.status != http.StatusOK {
+		t.Errorf("wroteHeader = %v and status = %d, want true and 200", rr.wroteHeader, rr.status)
+	}
+	if got := base.rec.Body.String(); got != "hello" {
+		t.Errorf("body = %q, want %q", got, "hello")
+	}
+}
+
+func TestResponseRecorderFlushCommitsStatus(t *testing.T) {
+	rr := &responseRecorder{ResponseWriter: flushWriter{newBaseWriter()}, status: http.StatusOK}
+	rr.writer().(http.Flusher).Flush()
+	rr.WriteHeader(http.StatusInternalServerError)
+	if rr.status != http.StatusOK {
+		t.Errorf("status = %d, want 200 once flushed", rr.status)
+	}
+}
+
+func TestResponseRecorderStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		want     int
+	}{
+		{"implicit", nil, http.StatusOK},
+		{"explicit", []int{http.StatusNotFound}, http.StatusNotFound},
+		{"first wins", []int{http.StatusCreated, http.StatusInternalServerError}, http.StatusCreated},
+		{"informational first", []int{http.StatusEarlyHints, http.StatusAccepted}, http.StatusAccepted},
+		{"switching protocols", []int{http.StatusSwitchingProtocols}, http.StatusSwitchingProtocols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := &responseRecorder{ResponseWriter: httptest.NewRecorder(), status: http.StatusOK}
+			for _, status := range tt.statuses {
+				rr.WriteHeader(status)
+			}
+			if rr.status != tt.want {
+				t.Errorf("status = %d, want %d", rr.status, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseRecorderUnwrap(t *testing.T) {
+	inner := httptest.NewRecorder()
+	rr := &responseRecorder{ResponseWriter: inner, status: http.StatusOK}
+	if got := http.ResponseWriter(rr).(interface{ Unwrap() http.ResponseWriter }).Unwrap(); got != inner {
+		t.Errorf("Unwrap() = %v, want the wrapped writer", got)
+	}
+}
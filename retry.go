@@ -0,0 +1,176 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// RetryPolicy controls the retries performed by TraceRetries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// It defaults to 3.
+	MaxAttempts int
+	// Backoff returns how long to wait before the given attempt, counting
+	// from 2. It defaults to exponential backoff starting at 100ms.
+	Backoff func(attempt int) time.Duration
+	// ShouldRetry decides whether an attempt that returned resp or err must
+	// be retried, and why. It defaults to retrying transport errors and
+	// 429, 502, 503 and 504 responses.
+	ShouldRetry func(resp *http.Response, err error) (retry bool, reason string)
+}
+
+// TraceRetries returns a RoundTripper that retries requests according to
+// policy. The logical request gets a parent span, and every attempt a
+// child span tagged with its number, the backoff that preceded it and the
+// reason the previous attempt was retried:
+//
+//	client := &http.Client{Transport: opentracing_helpers.TraceRetries(nil,
+//	    opentracing_helpers.RetryPolicy{MaxAttempts: 5})}
+//
+// Requests with a body are only retried if their GetBody field is set, as
+// is the case for requests created by http.NewRequest from common body
+// types. Requests skipped by WithFilter, or sent while tracing is
+// disabled, are retried without spans. base defaults to
+// http.DefaultTransport.
+func TraceRetries(base http.RoundTripper, policy RetryPolicy, opts ...TransportOption) http.RoundTripper {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.Backoff == nil {
+		policy.Backoff = exponentialBackoff
+	}
+	if policy.ShouldRetry == nil {
+		policy.ShouldRetry = defaultShouldRetry
+	}
+	return &retryTransport{traced: NewTracedTransport(base, opts...), policy: policy}
+}
+
+type retryTransport struct {
+	traced *TracedTransport
+	policy RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.traced.config
+	// Requests that aren't traced are retried all the same, within a
+	// noop span and without attempt spans.
+	traced := !c.noop() && c.traced(req)
+	span := opentracing.NoopTracer{}.StartSpan("")
+	ctx := req.Context()
+	if traced {
+		span = NewSafeSpan(c.activeTracer().StartSpan(
+			c.spanName(req, "HTTP "+req.Method),
+			opentracing.ChildOf(parentContext(req.Context())),
+			ext.SpanKindRPCClient,
+			componentTag,
+		))
+		ext.HTTPMethod.Set(span, req.Method)
+		ext.HTTPUrl.Set(span, c.urlTag(req.URL))
+		ctx = opentracing.ContextWithSpan(ctx, span)
+	}
+
+	canRetry := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	var backoff time.Duration
+	var reason string
+	for attempt := 1; ; attempt++ {
+		attemptReq := req.WithContext(ctx)
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return t.fail(span, attempt-1, err)
+			}
+			attemptReq.Body = body
+		}
+
+		var resp *http.Response
+		var err error
+		if traced {
+			tags := []opentracing.Tag{{Key: "http.retry.attempt", Value: attempt}}
+			if attempt > 1 {
+				tags = append(tags,
+					opentracing.Tag{Key: "http.retry.backoff", Value: backoff.String()},
+					opentracing.Tag{Key: "http.retry.reason", Value: reason})
+			}
+			resp, err = t.traced.roundTrip(attemptReq, tags...)
+		} else {
+			resp, err = t.traced.base.RoundTrip(attemptReq)
+		}
+
+		var retry bool
+		retry, reason = t.policy.ShouldRetry(resp, err)
+		if !retry || !canRetry || attempt >= t.policy.MaxAttempts {
+			span.SetTag("http.retry.attempts", attempt)
+			if err != nil {
+				return t.fail(span, attempt, err)
+			}
+			if !traced {
+				return resp, nil
+			}
+			ext.HTTPStatusCode.Set(span, uint16(resp.StatusCode))
+			c.tagError(span, resp.StatusCode, nil)
+			resp.Body = newSpanBody(resp.Body, span, nil)
+			return resp, nil
+		}
+
+		// Release the connection of the discarded attempt, which also
+		// finishes its span.
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		backoff = t.policy.Backoff(attempt + 1)
+		span.LogFields(
+			log.String("event", "retry"),
+			log.String("reason", reason),
+			log.String("backoff", backoff.String()),
+		)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return t.fail(span, attempt, req.Context().Err())
+		}
+	}
+}
+
+// fail records err on the logical span and finishes it.
+func (t *retryTransport) fail(span opentracing.Span, attempts int, err error) (*http.Response, error) {
+	span.SetTag("http.retry.attempts", attempts)
+	t.traced.config.tagError(span, 0, err)
+	span.LogFields(log.String("event", "error"), log.Error(err))
+	span.Finish()
+	return nil, err
+}
+
+func exponentialBackoff(attempt int) time.Duration {
+	d := 100 * time.Millisecond << uint(attempt-2)
+	if max := 10 * time.Second; d > max || d <= 0 {
+		return max
+	}
+	return d
+}
+
+func defaultShouldRetry(resp *http.Response, err error) (bool, string) {
+	if err != nil {
+		// Don't retry requests the caller gave up on.
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false, ""
+		}
+		return true, err.Error()
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, "status " + strconv.Itoa(resp.StatusCode)
+	}
+	return false, ""
+}
@@ -0,0 +1,188 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// sequence returns a RoundTripper answering with the given statuses in
+// turn, a status of 0 standing for a transport error, and recording the
+// bodies it received.
+func sequence(bodies *[]string, statuses ...int) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			b, _ := io.ReadAll(req.Body)
+			*bodies = append(*bodies, string(b))
+		}
+		status := statuses[0]
+		statuses = statuses[1:]
+		if status == 0 {
+			return nil, errors.New("connection reset")
+		}
+		return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	})
+}
+
+var noBackoff = func(int) time.Duration { return 0 }
+
+// retrySpans splits the spans finished by tracer into the logical request
+// span and the attempt spans.
+func retrySpans(t *testing.T, tracer *mocktracer.MockTracer) (*mocktracer.MockSpan, []*mocktracer.MockSpan) {
+	t.Helper()
+	var logical *mocktracer.MockSpan
+	var attempts []*mocktracer.MockSpan
+	for _, span := range tracer.FinishedSpans() {
+		if span.ParentID == 0 {
+			logical = span
+		} else {
+			attempts = append(attempts, span)
+		}
+	}
+	if logical == nil {
+		t.Fatalf("no logical request span in %v", tracer.FinishedSpans())
+	}
+	return logical, attempts
+}
+
+func TestTraceRetries(t *testing.T) {
+	tracer := mocktracer.New()
+	var bodies []string
+	client := &http.Client{Transport: TraceRetries(sequence(&bodies, 503, 0, 200),
+		RetryPolicy{Backoff: noBackoff}, WithTracer(tracer))}
+
+	resp, err := client.Post("http://example.com/orders", "text/plain", strings.NewReader("order"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if want := []string{"order", "order", "order"}; !reflect.DeepEqual(bodies, want) {
+		t.Errorf("bodies sent %q, want %q", bodies, want)
+	}
+	logical, attempts := retrySpans(t, tracer)
+	if logical.Tag("span.kind") != ext.SpanKindRPCClientEnum {
+		t.Errorf("logical span.kind = %v, want client", logical.Tag("span.kind"))
+	}
+	if logical.Tag("http.retry.attempts") != 3 || logical.Tag("http.status_code") != uint16(200) {
+		t.Errorf("logical span tags %v", logical.Tags())
+	}
+	if got := loggedEvents(logical); !reflect.DeepEqual(got, []string{"retry", "retry"}) {
+		t.Errorf("logical span events %q, want two retries", got)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("got %d attempt spans, want 3", len(attempts))
+	}
+	reasons := []interface{}{nil, "status 503", "connection reset"}
+	for i, span := range attempts {
+		if span.ParentID != logical.SpanContext.SpanID {
+			t.Errorf("attempt %d is not a child of the logical span", i+1)
+		}
+		if got := span.Tag("http.retry.attempt"); got != i+1 {
+			t.Errorf("attempt %d: http.retry.attempt = %v", i+1, got)
+		}
+		if got := span.Tag("http.retry.reason"); got != reasons[i] {
+			t.Errorf("attempt %d: http.retry.reason = %v, want %v", i+1, got, reasons[i])
+		}
+	}
+}
+
+func TestTraceRetriesGivesUp(t *testing.T) {
+	tracer := mocktracer.New()
+	var bodies []string
+	client := &http.Client{Transport: TraceRetries(sequence(&bodies, 0, 0),
+		RetryPolicy{MaxAttempts: 2, Backoff: noBackoff}, WithTracer(tracer))}
+
+	if _, err := client.Get("http://example.com/"); err == nil {
+		t.Fatal("expected an error")
+	}
+	logical, attempts := retrySpans(t, tracer)
+	if len(attempts) != 2 || logical.Tag("http.retry.attempts") != 2 || logical.Tag("error") != true {
+		t.Errorf("got %d attempts, logical span tags %v", len(attempts), logical.Tags())
+	}
+}
+
+func TestTraceRetriesWithoutGetBody(t *testing.T) {
+	tracer := mocktracer.New()
+	var bodies []string
+	client := &http.Client{Transport: TraceRetries(sequence(&bodies, 503, 200),
+		RetryPolicy{Backoff: noBackoff}, WithTracer(tracer))}
+
+	req, _ := http.NewRequest(http.MethodPut, "http://example.com/", io.NopCloser(strings.NewReader("once")))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 503 || len(bodies) != 1 {
+		t.Errorf("status %d after %d attempts, want a single attempt", resp.StatusCode, len(bodies))
+	}
+}
+
+func TestTraceRetriesCanceledDuringBackoff(t *testing.T) {
+	tracer := mocktracer.New()
+	var bodies []string
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &http.Client{Transport: TraceRetries(sequence(&bodies, 503, 200), RetryPolicy{
+		Backoff: func(int) time.Duration {
+			cancel()
+			return time.Hour
+		},
+	}, WithTracer(tracer))}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+	if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	logical, attempts := retrySpans(t, tracer)
+	if len(attempts) != 1 || logical.Tag("error") != true {
+		t.Errorf("got %d attempts, logical span tags %v", len(attempts), logical.Tags())
+	}
+}
+
+func TestTraceRetriesUntraced(t *testing.T) {
+	for name, opt := range map[string]TransportOption{
+		"disabled": WithDisabled(func() bool { return true }),
+		"filtered": WithFilter(func(r *http.Request) bool { return false }),
+	} {
+		tracer := mocktracer.New()
+		var bodies []string
+		client := &http.Client{Transport: TraceRetries(sequence(&bodies, 503, 200),
+			RetryPolicy{Backoff: noBackoff}, WithTracer(tracer), opt)}
+
+		resp, err := client.Post("http://example.com/orders", "text/plain", strings.NewReader("order"))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 200 || len(bodies) != 2 {
+			t.Errorf("%s: got %d after %d attempts, want 200 after a retry", name, resp.StatusCode, len(bodies))
+		}
+		if spans := tracer.FinishedSpans(); len(spans) != 0 {
+			t.Errorf("%s: finished spans %v, want none", name, spans)
+		}
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{
+		2:  100 * time.Millisecond,
+		3:  200 * time.Millisecond,
+		5:  800 * time.Millisecond,
+		10: 10 * time.Second,
+		80: 10 * time.Second,
+	} {
+		if got := exponentialBackoff(attempt); got != want {
+			t.Errorf("exponentialBackoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
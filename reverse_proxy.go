@@ -0,0 +1,135 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// proxyRequest is the state of a request traced by TraceReverseProxy,
+// carried from the director to the response and error hooks in the
+// context of the request sent to the backend.
+type proxyRequest struct {
+	span          opentracing.Span
+	operationName string
+	start         time.Time
+}
+
+type proxyRequestKey struct{}
+
+// TraceReverseProxy instruments p, which is modified and returned, so that
+// every proxied request is sent within a client span that is a child of
+// the span in the incoming request's context, for example the server span
+// of TraceHandler. The span context is injected into the request sent to
+// the backend, and the span is tagged with the upstream target, the
+// backend status and proxy errors. It is finished once the response body
+// has been copied to the client:
+//
+//	proxy := opentracing_helpers.TraceReverseProxy(httputil.NewSingleHostReverseProxy(target))
+//	http.Handle(opentracing_helpers.TraceHandler("/", proxy))
+//
+// The Director or Rewrite, ModifyResponse and ErrorHandler functions of p
+// are wrapped, so they must be set beforehand. TraceReverseProxy panics if
+// p has neither a Director nor a Rewrite function, since it could then
+// neither trace nor route the requests.
+func TraceReverseProxy(p *httputil.ReverseProxy, opts ...TransportOption) *httputil.ReverseProxy {
+	if p.Rewrite == nil && p.Director == nil {
+		panic("opentracing_helpers: TraceReverseProxy called without a Director or Rewrite function")
+	}
+	c := newTransportConfig(opts)
+
+	if rewrite := p.Rewrite; rewrite != nil {
+		p.Rewrite = func(pr *httputil.ProxyRequest) {
+			rewrite(pr)
+			pr.Out = c.startProxySpan(pr.Out)
+		}
+	} else if director := p.Director; director != nil {
+		p.Director = func(req *http.Request) {
+			director(req)
+			*req = *c.startProxySpan(req)
+		}
+	}
+
+	modifyResponse := p.ModifyResponse
+	p.ModifyResponse = func(resp *http.Response) error {
+		if modifyResponse != nil {
+			if err := modifyResponse(resp); err != nil {
+				// The error handler finishes the span.
+				return err
+			}
+		}
+		pr, ok := resp.Request.Context().Value(proxyRequestKey{}).(*proxyRequest)
+		if !ok {
+			return nil
+		}
+		c.logHeaders(pr.span, "response headers", "http.response.header.", resp.Header)
+		pr.span.SetTag("http.status_class", statusClass(resp.StatusCode))
+		failed := c.tagError(pr.span, resp.StatusCode, nil)
+		c.decorate(pr.span, resp.Request, resp.StatusCode)
+		c.observeMetrics(RequestMetrics{
+			Operation:  pr.operationName,
+			Kind:       "client",
+			Method:     resp.Request.Method,
+			StatusCode: resp.StatusCode,
+			Error:      failed,
+			Duration:   time.Since(pr.start),
+		})
+		req := resp.Request
+		wrapResponseBody(resp, pr.span, func() {
+			c.finished(pr.span, req, resp.StatusCode, time.Since(pr.start))
+		})
+		return nil
+	}
+
+	errorHandler := p.ErrorHandler
+	p.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if pr, ok := r.Context().Value(proxyRequestKey{}).(*proxyRequest); ok {
+			failed := c.tagError(pr.span, 0, err)
+			pr.span.SetTag("proxy.error", true)
+			pr.span.LogFields(log.String("event", "error"), log.Error(err))
+			c.decorate(pr.span, r, 0)
+			c.observeMetrics(RequestMetrics{
+				Operation: pr.operationName,
+				Kind:      "client",
+				Method:    r.Method,
+				Error:     failed,
+				Duration:  time.Since(pr.start),
+			})
+			c.finished(pr.span, r, 0, time.Since(pr.start))
+			pr.span.Finish()
+		}
+		if errorHandler != nil {
+			errorHandler(w, r, err)
+			return
+		}
+		if p.ErrorLog != nil {
+			p.ErrorLog.Printf("http: proxy error: %v", err)
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	return p
+}
+
+// startProxySpan starts the span of the request to the backend, out, and
+// returns out with the span injected into its headers and context.
+func (c *transportConfig) startProxySpan(out *http.Request) *http.Request {
+	if c.noop() || !c.traced(out) {
+		return out
+	}
+	pr := &proxyRequest{
+		operationName: c.spanName(out, "proxy "+out.URL.Host),
+		start:         time.Now(),
+	}
+	pr.span = c.startSpan(out.Context(), out, pr.operationName)
+	pr.span.SetTag("proxy.upstream", out.URL.Scheme+"://"+out.URL.Host)
+
+	ctx := opentracing.ContextWithSpan(out.Context(), pr.span)
+	ctx = context.WithValue(ctx, proxyRequestKey{}, pr)
+	out = out.WithContext(c.withClientTrace(ctx, pr.span))
+	c.inject(pr.span.Context(), out.Header)
+	return out
+}
@@ -0,0 +1,122 @@
+package opentracing_helpers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// proxyThrough serves a GET through a traced proxy p and returns the
+// response recorder.
+func proxyThrough(t *testing.T, tracer *mocktracer.MockTracer, p *httputil.ReverseProxy) *httptest.ResponseRecorder {
+	t.Helper()
+	proxy := TraceReverseProxy(p, WithTracer(tracer))
+	_, h := TraceHandler("/", proxy, WithTracer(tracer))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items", nil))
+	return w
+}
+
+func TestTraceReverseProxy(t *testing.T) {
+	tracer := mocktracer.New()
+	var injected opentracing.SpanContext
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		injected, _ = tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+		io.WriteString(w, "items")
+	}))
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL)
+
+	for name, p := range map[string]*httputil.ReverseProxy{
+		"director": httputil.NewSingleHostReverseProxy(target),
+		"rewrite":  {Rewrite: func(pr *httputil.ProxyRequest) { pr.SetURL(target) }},
+	} {
+		tracer.Reset()
+		w := proxyThrough(t, tracer, p)
+		if w.Code != http.StatusOK || w.Body.String() != "items" {
+			t.Fatalf("%s: proxied response %d %q", name, w.Code, w.Body)
+		}
+
+		spans := tracer.FinishedSpans()
+		if len(spans) != 2 {
+			t.Fatalf("%s: %d finished spans, want the proxy and server spans", name, len(spans))
+		}
+		client, server := spans[0], spans[1]
+		if client.OperationName != "proxy "+target.Host || client.ParentID != server.SpanContext.SpanID {
+			t.Errorf("%s: client span %q with parent %d, want a child of the server span", name, client.OperationName, client.ParentID)
+		}
+		if client.Tag("proxy.upstream") != backend.URL || client.Tag("http.status_code") != uint16(http.StatusOK) {
+			t.Errorf("%s: client span tagged %v", name, client.Tags())
+		}
+		if sc, ok := injected.(mocktracer.MockSpanContext); !ok || sc.SpanID != client.SpanContext.SpanID {
+			t.Errorf("%s: the backend received span context %v, want the proxy span's", name, injected)
+		}
+	}
+}
+
+func TestTraceReverseProxyError(t *testing.T) {
+	tracer := mocktracer.New()
+	backend := httptest.NewServer(okHandler)
+	target, _ := url.Parse(backend.URL)
+	backend.Close()
+
+	w := proxyThrough(t, tracer, httputil.NewSingleHostReverseProxy(target))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadGateway)
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d finished spans, want the proxy and server spans", len(spans))
+	}
+	client := spans[0]
+	if client.Tag("proxy.error") != true || client.Tag("error") != true {
+		t.Errorf("client span tagged %v, want the proxy error", client.Tags())
+	}
+	if got := loggedFields(client)["error.object"]; got == "" {
+		t.Errorf("logged %v, want the proxy error", loggedFields(client))
+	}
+}
+
+func TestTraceReverseProxyOperationName(t *testing.T) {
+	tracer := mocktracer.New()
+	backend := httptest.NewServer(okHandler)
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL)
+
+	calls := 0
+	metrics := &metricsRecorder{}
+	proxy := TraceReverseProxy(httputil.NewSingleHostReverseProxy(target), WithTracer(tracer),
+		WithMetricsObserver(metrics),
+		WithOperationNameFunc(func(r *http.Request) string {
+			calls++
+			return "proxy items"
+		}))
+	proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+
+	if calls != 1 {
+		t.Errorf("OperationNameFunc called %d times, want once", calls)
+	}
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 || spans[0].OperationName != "proxy items" {
+		t.Fatalf("finished spans %v, want one proxy items", spans)
+	}
+	if m := metrics.only(t); m.Operation != "proxy items" {
+		t.Errorf("metrics operation = %q, want the span name", m.Operation)
+	}
+}
+
+func TestTraceReverseProxyWithoutDirector(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("TraceReverseProxy accepted a ReverseProxy without Director or Rewrite")
+		}
+	}()
+	TraceReverseProxy(&httputil.ReverseProxy{})
+}
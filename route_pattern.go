@@ -0,0 +1,93 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// httpRouteTag is the conventional tag for the matched route pattern.
+const httpRouteTag = "http.route"
+
+// patternName returns the span name for a Go 1.22 ServeMux pattern, which
+// may already start with a method as in "GET /items/{id}".
+func patternName(pattern string, method string) string {
+	if patternHasMethod(pattern) {
+		return pattern
+	}
+	return method + " " + pattern
+}
+
+// patternHasMethod reports whether pattern starts with a method.
+func patternHasMethod(pattern string) bool {
+	i := strings.IndexAny(pattern, " \t")
+	return i > 0 && !strings.Contains(pattern[:i], "/")
+}
+
+// patternWildcards returns the names of the wildcards of pattern, for
+// example "id" for "GET /items/{id}" and "path" for "/files/{path...}".
+func patternWildcards(pattern string) []string {
+	var names []string
+	for {
+		i := strings.IndexByte(pattern, '{')
+		if i < 0 {
+			return names
+		}
+		j := strings.IndexByte(pattern[i:], '}')
+		if j < 0 {
+			return names
+		}
+		name := strings.TrimSuffix(pattern[i+1:i+j], "...")
+		if name != "" && name != "$" {
+			names = append(names, name)
+		}
+		pattern = pattern[i+j+1:]
+	}
+}
+
+// tagRoute renames the span after the pattern matched by an http.ServeMux
+// behind the middleware, and tags the route and its path wildcards as
+// http.path_param.<name>.
+func (c *handlerConfig) tagRoute(sr *serverRequest) {
+	r := sr.r
+	if r.Pattern == "" {
+		return
+	}
+	if sr.pattern == "" {
+		if name := c.operationName("", r); name != sr.operationName {
+			sr.span.SetOperationName(name)
+			sr.operationName = name
+		}
+	}
+	sr.route = r.Pattern
+	sr.span.SetTag(httpRouteTag, r.Pattern)
+	for _, name := range patternWildcards(r.Pattern) {
+		sr.span.SetTag("http.path_param."+name, r.PathValue(name))
+	}
+}
+
+// SetRoute records route, the pattern a router other than http.ServeMux
+// matched r with, on the server span started for r by TraceHandler or
+// NewMiddleware. The span is tagged with route as http.route and, unless
+// it was registered with a pattern, renamed "METHOD route", or after the
+// name returned by the OperationNameFunc if one was set. The name is then
+// also the operation reported to MetricsObserver. SetRoute does nothing if
+// r isn't traced.
+func SetRoute(r *http.Request, route string) {
+	sr, _ := r.Context().Value(serverRequestKey{}).(*serverRequest)
+	if sr == nil || route == "" {
+		return
+	}
+	c := sr.config
+	if sr.pattern == "" {
+		name := patternName(route, r.Method)
+		if c.customOperationName {
+			name = c.operationName("", r)
+		}
+		if name != sr.operationName {
+			sr.span.SetOperationName(name)
+			sr.operationName = name
+		}
+	}
+	sr.route = route
+	sr.span.SetTag(httpRouteTag, route)
+}
@@ -0,0 +1,117 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestMiddlewareServeMuxPattern(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /items/{id}", okHandler)
+	mux.Handle("/files/{path...}", okHandler)
+
+	tests := []struct {
+		path   string
+		name   string
+		route  interface{}
+		params map[string]string
+	}{
+		{"/items/42", "GET /items/{id}", "GET /items/{id}", map[string]string{"id": "42"}},
+		{"/files/a/b.txt", "GET /files/{path...}", "/files/{path...}", map[string]string{"path": "a/b.txt"}},
+		{"/unknown", "GET /unknown", nil, nil},
+	}
+	for _, tt := range tests {
+		tracer := mocktracer.New()
+		NewMiddleware(WithTracer(tracer))(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		span := finishedSpan(t, tracer)
+		if span.OperationName != tt.name {
+			t.Errorf("%s: operation name = %q, want %q", tt.path, span.OperationName, tt.name)
+		}
+		if got := span.Tag(httpRouteTag); got != tt.route {
+			t.Errorf("%s: http.route = %v, want %v", tt.path, got, tt.route)
+		}
+		for name, want := range tt.params {
+			if got := span.Tag("http.path_param." + name); got != want {
+				t.Errorf("%s: http.path_param.%s = %v, want %q", tt.path, name, got, want)
+			}
+		}
+	}
+}
+
+func TestTraceHandlerKeepsRegistrationPattern(t *testing.T) {
+	tracer := mocktracer.New()
+	mux := http.NewServeMux()
+	mux.Handle(TraceHandler("GET /items/{id}", okHandler, WithTracer(tracer)))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/1", nil))
+
+	if span := finishedSpan(t, tracer); span.OperationName != "GET /items/{id}" {
+		t.Errorf("operation name = %q, want the pattern without a repeated method", span.OperationName)
+	}
+}
+
+func TestPatternName(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"/items/{id}":           "POST /items/{id}",
+		"GET /items/{id}":       "GET /items/{id}",
+		"example.com/items":     "POST example.com/items",
+		"DELETE\texample.com/a": "DELETE\texample.com/a",
+	} {
+		if got := patternName(pattern, http.MethodPost); got != want {
+			t.Errorf("patternName(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestPatternWildcards(t *testing.T) {
+	for pattern, want := range map[string][]string{
+		"/":                       nil,
+		"GET /items/{id}":         {"id"},
+		"/{org}/{repo}/{path...}": {"org", "repo", "path"},
+		"/exact/{$}":              nil,
+		"/broken/{id":             nil,
+	} {
+		if got := patternWildcards(pattern); !reflect.DeepEqual(got, want) {
+			t.Errorf("patternWildcards(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestSetRoute(t *testing.T) {
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoute(r, "/users/{id}")
+	})
+	tests := []struct {
+		name, pattern string
+		opts          []HandlerOption
+		want          string
+	}{
+		{"middleware", "", nil, "GET /users/{id}"},
+		{"operation name func", "", []HandlerOption{WithOperationNameFunc(func(r *http.Request) string { return "users" })}, "users"},
+		{"registration pattern", "/users/", nil, "GET /users/"},
+	}
+	for _, tt := range tests {
+		tracer := mocktracer.New()
+		metrics := &metricsRecorder{}
+		opts := append([]HandlerOption{WithTracer(tracer), WithMetricsObserver(metrics)}, tt.opts...)
+		var h http.Handler
+		if tt.pattern == "" {
+			h = NewMiddleware(opts...)(router)
+		} else {
+			_, h = TraceHandler(tt.pattern, router, opts...)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+		span := finishedSpan(t, tracer)
+		if span.OperationName != tt.want || span.Tag("http.route") != "/users/{id}" {
+			t.Errorf("%s: span %q with route %v, want %q", tt.name, span.OperationName, span.Tag("http.route"), tt.want)
+		}
+		if m := metrics.only(t); m.Operation != tt.want {
+			t.Errorf("%s: metrics operation %q, want %q", tt.name, m.Operation, tt.want)
+		}
+	}
+}
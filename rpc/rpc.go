@@ -0,0 +1,284 @@
+// Package rpc traces net/rpc calls with codec wrappers, which work with
+// the default gob codec as well as net/rpc/jsonrpc. Spans are named after
+// the called "Service.Method":
+//
+//	server.ServeCodec(otrpc.NewServerCodec(jsonrpc.NewServerCodec(conn)))
+//
+//	client := rpc.NewClientWithCodec(otrpc.NewClientCodec(jsonrpc.NewClientCodec(conn)))
+//	err := otrpc.Call(ctx, client, "Arith.Multiply", args, &reply)
+//
+// The codecs accept the root package's Options, of which WithTracer,
+// WithDisabled and WithPropagators apply.
+//
+// # Compatibility
+//
+// net/rpc requests have no room for metadata, so the span context is
+// propagated in the query of the service method name,
+// "Service.Method?key=value", which the server codec strips before the
+// call is dispatched. A server that doesn't use NewServerCodec fails such
+// calls with "rpc: can't find service". The query is therefore only added
+// to calls made with Call or Go from a context carrying a span: use them
+// only against servers known to use the wrappers. Calls made with
+// client.Call directly are sent unchanged and work with any server, but
+// start a new trace on the server side.
+package rpc
+
+import (
+	"context"
+	"net/rpc"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "net/rpc"}
+
+// Call invokes serviceMethod like client.Call. If ctx carries a span, the
+// client span of the call is its child and its context is sent to the
+// server, see Compatibility; net/rpc has no context of its own so calls
+// made with client.Call directly start new traces.
+func Call(ctx context.Context, client *rpc.Client, serviceMethod string, args, reply any) error {
+	return client.Call(contextServiceMethod(ctx, serviceMethod), args, reply)
+}
+
+// Go invokes serviceMethod asynchronously like client.Go. See Call.
+func Go(ctx context.Context, client *rpc.Client, serviceMethod string, args, reply any, done chan *rpc.Call) *rpc.Call {
+	return client.Go(contextServiceMethod(ctx, serviceMethod), args, reply, done)
+}
+
+// contextServiceMethod appends the context of the span in ctx to
+// serviceMethod, for the client codec to pick up as the parent.
+func contextServiceMethod(ctx context.Context, serviceMethod string) string {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return serviceMethod
+	}
+	carrier := opentracing.TextMapCarrier{}
+	if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+		return serviceMethod
+	}
+	return joinServiceMethod(serviceMethod, carrier)
+}
+
+// joinServiceMethod returns serviceMethod with carrier as its query.
+func joinServiceMethod(serviceMethod string, carrier opentracing.TextMapCarrier) string {
+	if len(carrier) == 0 {
+		return serviceMethod
+	}
+	query := url.Values{}
+	for k, v := range carrier {
+		query.Set(k, v)
+	}
+	return serviceMethod + "?" + query.Encode()
+}
+
+// splitServiceMethod returns the service method without its query, and
+// the query as a carrier, or nil if there is none.
+func splitServiceMethod(s string) (string, opentracing.TextMapCarrier) {
+	serviceMethod, rawQuery, ok := strings.Cut(s, "?")
+	if !ok {
+		return s, nil
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return serviceMethod, nil
+	}
+	carrier := opentracing.TextMapCarrier{}
+	for k := range query {
+		carrier[k] = query.Get(k)
+	}
+	return serviceMethod, carrier
+}
+
+// tagServiceMethod sets the rpc.service and rpc.method tags.
+func tagServiceMethod(span opentracing.Span, serviceMethod string) {
+	if service, method, ok := strings.Cut(serviceMethod, "."); ok {
+		span.SetTag("rpc.service", service)
+		span.SetTag("rpc.method", method)
+	}
+}
+
+// pendingSpans holds the spans of in-flight calls by sequence number.
+type pendingSpans struct {
+	mu    sync.Mutex
+	spans map[uint64]opentracing.Span
+}
+
+func (p *pendingSpans) put(seq uint64, span opentracing.Span) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.spans == nil {
+		p.spans = make(map[uint64]opentracing.Span)
+	}
+	p.spans[seq] = span
+}
+
+func (p *pendingSpans) take(seq uint64) opentracing.Span {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	span := p.spans[seq]
+	delete(p.spans, seq)
+	return span
+}
+
+// finishAll finishes the spans of calls that never completed.
+func (p *pendingSpans) finishAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for seq, span := range p.spans {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(rpc.ErrShutdown))
+		span.Finish()
+		delete(p.spans, seq)
+	}
+}
+
+type clientCodec struct {
+	rpc.ClientCodec
+	settings *opentracing_helpers.Settings
+	pending  pendingSpans
+
+	// reading is the span of the response being read, between
+	// ReadResponseHeader and ReadResponseBody.
+	reading opentracing.Span
+}
+
+// NewClientCodec wraps codec so that every call is sent within a client
+// span. The span context is propagated to the server for calls made with
+// Call or Go from a context carrying a span, see Compatibility.
+func NewClientCodec(codec rpc.ClientCodec, opts ...opentracing_helpers.Option) rpc.ClientCodec {
+	return &clientCodec{ClientCodec: codec, settings: opentracing_helpers.NewSettings(opts...)}
+}
+
+func (c *clientCodec) WriteRequest(r *rpc.Request, body any) error {
+	if c.settings.Noop() {
+		r.ServiceMethod, _ = splitServiceMethod(r.ServiceMethod)
+		return c.ClientCodec.WriteRequest(r, body)
+	}
+	tracer := c.settings.Tracer()
+	serviceMethod, parentCarrier := splitServiceMethod(r.ServiceMethod)
+	var parent opentracing.SpanContext
+	if parentCarrier != nil {
+		parent, _ = tracer.Extract(opentracing.TextMap, parentCarrier)
+	}
+	span := tracer.StartSpan(
+		serviceMethod,
+		opentracing.ChildOf(parent),
+		ext.SpanKindRPCClient,
+		componentTag,
+	)
+	tagServiceMethod(span, serviceMethod)
+	r.ServiceMethod = serviceMethod
+	if parent != nil {
+		carrier := opentracing.TextMapCarrier{}
+		if err := c.settings.InjectTextMap(span.Context(), carrier); err != nil {
+			span.LogFields(log.String("event", "inject failed"), log.Error(err))
+		}
+		r.ServiceMethod = joinServiceMethod(serviceMethod, carrier)
+	}
+
+	c.pending.put(r.Seq, span)
+	if err := c.ClientCodec.WriteRequest(r, body); err != nil {
+		c.pending.take(r.Seq)
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+		span.Finish()
+		return err
+	}
+	return nil
+}
+
+func (c *clientCodec) ReadResponseHeader(r *rpc.Response) error {
+	if err := c.ClientCodec.ReadResponseHeader(r); err != nil {
+		return err
+	}
+	c.reading = c.pending.take(r.Seq)
+	if c.reading != nil && r.Error != "" {
+		ext.Error.Set(c.reading, true)
+		c.reading.LogFields(log.String("event", "error"), log.String("message", r.Error))
+	}
+	return nil
+}
+
+func (c *clientCodec) ReadResponseBody(body any) error {
+	err := c.ClientCodec.ReadResponseBody(body)
+	if span := c.reading; span != nil {
+		c.reading = nil
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.LogFields(log.String("event", "error"), log.Error(err))
+		}
+		span.Finish()
+	}
+	return err
+}
+
+func (c *clientCodec) Close() error {
+	err := c.ClientCodec.Close()
+	c.pending.finishAll()
+	return err
+}
+
+type serverCodec struct {
+	rpc.ServerCodec
+	settings *opentracing_helpers.Settings
+	pending  pendingSpans
+}
+
+// NewServerCodec wraps codec so that every call is served within a server
+// span, a child of the span context propagated by the client codec.
+func NewServerCodec(codec rpc.ServerCodec, opts ...opentracing_helpers.Option) rpc.ServerCodec {
+	return &serverCodec{ServerCodec: codec, settings: opentracing_helpers.NewSettings(opts...)}
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	if err := c.ServerCodec.ReadRequestHeader(r); err != nil {
+		return err
+	}
+	serviceMethod, carrier := splitServiceMethod(r.ServiceMethod)
+	r.ServiceMethod = serviceMethod
+	if c.settings.Noop() {
+		return nil
+	}
+	var parent opentracing.SpanContext
+	if carrier != nil {
+		parent, _ = c.settings.ExtractTextMap(carrier)
+	}
+	span := c.settings.Tracer().StartSpan(
+		serviceMethod,
+		ext.RPCServerOption(parent),
+		componentTag,
+	)
+	tagServiceMethod(span, serviceMethod)
+	c.pending.put(r.Seq, span)
+	return nil
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, body any) error {
+	span := c.pending.take(r.Seq)
+	err := c.ServerCodec.WriteResponse(r, body)
+	if span == nil {
+		return err
+	}
+	if r.Error != "" {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.String("message", r.Error))
+	}
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+	span.Finish()
+	return err
+}
+
+func (c *serverCodec) Close() error {
+	err := c.ServerCodec.Close()
+	c.pending.finishAll()
+	return err
+}
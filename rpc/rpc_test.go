@@ -0,0 +1,221 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"testing"
+	"time"
+
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+type Arith struct{}
+
+func (Arith) Multiply(args [2]int, reply *int) error {
+	*reply = args[0] * args[1]
+	return nil
+}
+
+func (Arith) Divide(args [2]int, reply *int) error {
+	if args[1] == 0 {
+		return errors.New("divide by zero")
+	}
+	*reply = args[0] / args[1]
+	return nil
+}
+
+// dial serves Arith over a pipe and returns a client of it, both traced
+// by tracer.
+func dial(t *testing.T, tracer opentracing.Tracer) *rpc.Client {
+	server := rpc.NewServer()
+	if err := server.Register(Arith{}); err != nil {
+		t.Fatal(err)
+	}
+	cli, srv := net.Pipe()
+	go server.ServeCodec(NewServerCodec(jsonrpc.NewServerCodec(srv), opentracing_helpers.WithTracer(tracer)))
+	client := rpc.NewClientWithCodec(NewClientCodec(jsonrpc.NewClientCodec(cli), opentracing_helpers.WithTracer(tracer)))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// waitForSpans waits until tracer has finished n spans.
+func waitForSpans(t *testing.T, tracer *mocktracer.MockTracer, n int) []*mocktracer.MockSpan {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		spans := tracer.FinishedSpans()
+		if len(spans) >= n {
+			return spans
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d finished spans, want %d", len(spans), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// spansByKind returns the client and server spans of a call.
+func spansByKind(spans []*mocktracer.MockSpan) (client, server *mocktracer.MockSpan) {
+	for _, span := range spans {
+		switch span.Tag(string(ext.SpanKind)) {
+		case ext.SpanKindRPCClientEnum:
+			client = span
+		case ext.SpanKindRPCServerEnum:
+			server = span
+		}
+	}
+	return client, server
+}
+
+func TestCall(t *testing.T) {
+	tracer := mocktracer.New()
+	client := dial(t, tracer)
+	parent := tracer.StartSpan("request")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	var product int
+	if err := Call(ctx, client, "Arith.Multiply", [2]int{6, 7}, &product); err != nil {
+		t.Fatal(err)
+	}
+	if product != 42 {
+		t.Errorf("product = %d, want 42", product)
+	}
+
+	clientSpan, serverSpan := spansByKind(waitForSpans(t, tracer, 2))
+	if clientSpan == nil || serverSpan == nil {
+		t.Fatalf("missing client or server span in %v", tracer.FinishedSpans())
+	}
+	if clientSpan.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the client span isn't a child of the context span")
+	}
+	if serverSpan.ParentID != clientSpan.SpanContext.SpanID {
+		t.Error("the server span isn't a child of the client span")
+	}
+	for _, span := range []*mocktracer.MockSpan{clientSpan, serverSpan} {
+		if span.OperationName != "Arith.Multiply" {
+			t.Errorf("operation name %q, want Arith.Multiply", span.OperationName)
+		}
+		if span.Tag("rpc.service") != "Arith" || span.Tag("rpc.method") != "Multiply" || span.Tag("error") != nil {
+			t.Errorf("span tagged %v", span.Tags())
+		}
+	}
+}
+
+func TestCallWithoutContextSpan(t *testing.T) {
+	tracer := mocktracer.New()
+	client := dial(t, tracer)
+
+	var product int
+	if err := client.Call("Arith.Multiply", [2]int{2, 3}, &product); err != nil {
+		t.Fatal(err)
+	}
+	clientSpan, serverSpan := spansByKind(waitForSpans(t, tracer, 2))
+	if clientSpan.ParentID != 0 || serverSpan.ParentID != 0 || serverSpan.SpanContext.TraceID == clientSpan.SpanContext.TraceID {
+		t.Errorf("client parent %d and server parent %d, want two new traces", clientSpan.ParentID, serverSpan.ParentID)
+	}
+}
+
+func TestCallWithoutContextSpanToPlainServer(t *testing.T) {
+	tracer := mocktracer.New()
+	server := rpc.NewServer()
+	if err := server.Register(Arith{}); err != nil {
+		t.Fatal(err)
+	}
+	cli, srv := net.Pipe()
+	go server.ServeCodec(jsonrpc.NewServerCodec(srv))
+	client := rpc.NewClientWithCodec(NewClientCodec(jsonrpc.NewClientCodec(cli), opentracing_helpers.WithTracer(tracer)))
+	defer client.Close()
+
+	var product int
+	if err := Call(context.Background(), client, "Arith.Multiply", [2]int{2, 3}, &product); err != nil || product != 6 {
+		t.Fatalf("Call = %d, %v; want 6 from a server without the wrappers", product, err)
+	}
+	if span := waitForSpans(t, tracer, 1)[0]; span.OperationName != "Arith.Multiply" {
+		t.Errorf("client span named %q", span.OperationName)
+	}
+}
+
+func TestClientCodecNoop(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.Register(Arith{}); err != nil {
+		t.Fatal(err)
+	}
+	cli, srv := net.Pipe()
+	go server.ServeCodec(jsonrpc.NewServerCodec(srv))
+	client := rpc.NewClientWithCodec(NewClientCodec(jsonrpc.NewClientCodec(cli), opentracing_helpers.WithDisabled(func() bool { return true })))
+	defer client.Close()
+
+	tracer := mocktracer.New()
+	ctx := opentracing.ContextWithSpan(context.Background(), tracer.StartSpan("request"))
+	var product int
+	if err := Call(ctx, client, "Arith.Multiply", [2]int{2, 3}, &product); err != nil || product != 6 {
+		t.Fatalf("Call = %d, %v; want the query stripped when tracing is disabled", product, err)
+	}
+}
+
+func TestCallError(t *testing.T) {
+	tracer := mocktracer.New()
+	client := dial(t, tracer)
+
+	var quotient int
+	if err := Call(context.Background(), client, "Arith.Divide", [2]int{1, 0}, &quotient); err == nil {
+		t.Fatal("Call succeeded, want the service error")
+	}
+	clientSpan, serverSpan := spansByKind(waitForSpans(t, tracer, 2))
+	for _, span := range []*mocktracer.MockSpan{clientSpan, serverSpan} {
+		if span.Tag("error") != true {
+			t.Errorf("%s span not tagged as failed", span.Tag(string(ext.SpanKind)))
+		}
+		logs := span.Logs()
+		if len(logs) != 1 || logs[0].Fields[1].ValueString != "divide by zero" {
+			t.Errorf("%s span logged %v", span.Tag(string(ext.SpanKind)), logs)
+		}
+	}
+}
+
+func TestSplitServiceMethod(t *testing.T) {
+	joined := joinServiceMethod("Arith.Multiply", opentracing.TextMapCarrier{"trace": "1:2"})
+	if joined == "Arith.Multiply" {
+		t.Fatal("no carrier joined to the service method")
+	}
+
+	serviceMethod, carrier := splitServiceMethod(joined)
+	if serviceMethod != "Arith.Multiply" {
+		t.Errorf("service method %q, want Arith.Multiply", serviceMethod)
+	}
+	if carrier["trace"] != "1:2" {
+		t.Errorf("split carrier %v, want the joined one", carrier)
+	}
+	if serviceMethod, carrier := splitServiceMethod("Arith.Multiply"); serviceMethod != "Arith.Multiply" || carrier != nil {
+		t.Errorf("split plain service method into %q, %v", serviceMethod, carrier)
+	}
+	if joined := joinServiceMethod("Arith.Multiply", opentracing.TextMapCarrier{}); joined != "Arith.Multiply" {
+		t.Errorf("empty carrier joined as %q", joined)
+	}
+}
+
+func TestClientCodecCloseFinishesPending(t *testing.T) {
+	tracer := mocktracer.New()
+	cli, srv := net.Pipe()
+	defer srv.Close()
+	go func() {
+		// Read the request and never answer it.
+		buf := make([]byte, 1024)
+		srv.Read(buf)
+	}()
+	client := rpc.NewClientWithCodec(NewClientCodec(jsonrpc.NewClientCodec(cli), opentracing_helpers.WithTracer(tracer)))
+	call := client.Go("Arith.Multiply", [2]int{1, 2}, new(int), nil)
+	client.Close()
+	<-call.Done
+
+	spans := waitForSpans(t, tracer, 1)
+	if spans[0].Tag("error") != true {
+		t.Errorf("pending span tagged %v, want error", spans[0].Tags())
+	}
+}
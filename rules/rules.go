@@ -0,0 +1,237 @@
+// Package rules loads tracing rules from a JSON or YAML file, so that
+// instrumentation policy can be managed centrally rather than in code.
+// Rules match request paths with regular expressions and set the
+// operation name, the sampling decision and additional tags of spans:
+//
+//	rules:
+//	  - path: ^/users/([0-9]+)$
+//	    operation_name: GET /users/{id}
+//	    tags:
+//	      user.id: $1
+//	  - path: ^/internal/
+//	    sample: false
+//	  - path: ^/checkout
+//	    sample: true
+//	    tags:
+//	      team: payments
+//
+// The file can be watched, in which case changes apply to the following
+// requests without a restart:
+//
+//	r, err := rules.Load("/etc/tracing/rules.yaml")
+//	go r.Watch(ctx, 10*time.Second, func(err error) { log.Print(err) })
+//	handler := opentracing_helpers.NewMiddleware(r.ServerOption())(mux)
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the content of a rules file.
+type File struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule applies to the requests whose path matches the regular expression
+// Path and, if set, whose method is Method. OperationName and the tag
+// values may refer to the submatches of Path, as in regexp.Expand, for
+// example $1 or ${id}.
+type Rule struct {
+	Path   string `json:"path" yaml:"path"`
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+
+	OperationName string `json:"operation_name,omitempty" yaml:"operation_name,omitempty"`
+
+	// Sample forces the sampling decision of matching requests through
+	// the sampling.priority tag, if set.
+	Sample *bool `json:"sample,omitempty" yaml:"sample,omitempty"`
+
+	Tags map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+type compiledRule struct {
+	Rule
+	path *regexp.Regexp
+}
+
+// Rules is a set of rules, safe for concurrent use and reloadable at
+// runtime. Rules are evaluated in order: every matching rule sets its
+// tags, and the first matching rule with an operation name or a sampling
+// decision sets it.
+type Rules struct {
+	path    string
+	modTime time.Time
+	rules   atomic.Pointer[[]compiledRule]
+}
+
+// Load reads the rules file at path, in YAML if its extension is .yaml
+// or .yml and in JSON otherwise.
+func Load(path string) (*Rules, error) {
+	r := &Rules{path: path}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// New returns rules that aren't backed by a file.
+func New(f File) (*Rules, error) {
+	compiled, err := compile(f)
+	if err != nil {
+		return nil, err
+	}
+	r := &Rules{}
+	r.rules.Store(&compiled)
+	return r, nil
+}
+
+// Set replaces the rules with those of f, unless f is invalid.
+func (r *Rules) Set(f File) error {
+	compiled, err := compile(f)
+	if err != nil {
+		return err
+	}
+	r.rules.Store(&compiled)
+	return nil
+}
+
+func compile(f File) ([]compiledRule, error) {
+	compiled := make([]compiledRule, len(f.Rules))
+	for i, rule := range f.Rules {
+		re, err := regexp.Compile(rule.Path)
+		if err != nil {
+			return nil, fmt.Errorf("rules: rule %d: %w", i, err)
+		}
+		compiled[i] = compiledRule{Rule: rule, path: re}
+	}
+	return compiled, nil
+}
+
+// reload reads the file again.
+func (r *Rules) reload() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return fmt.Errorf("rules: %w", err)
+	}
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("rules: %w", err)
+	}
+	var f File
+	switch strings.ToLower(filepath.Ext(r.path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &f)
+	default:
+		err = json.Unmarshal(data, &f)
+	}
+	if err != nil {
+		return fmt.Errorf("rules: %s: %w", r.path, err)
+	}
+	if err := r.Set(f); err != nil {
+		return err
+	}
+	r.modTime = info.ModTime()
+	return nil
+}
+
+// Watch checks the rules file for changes every interval until ctx is
+// done, reloading it when its modification time changes. Invalid files
+// are reported to onError, if not nil, and the previous rules are kept.
+// Polling rather than file system notifications keeps working when the
+// file is replaced, as Kubernetes does with ConfigMap volumes.
+func (r *Rules) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	if r.path == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(r.path)
+		if err == nil && info.ModTime().Equal(r.modTime) {
+			continue
+		}
+		if err == nil {
+			err = r.reload()
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// ServerOption applies the rules to server spans when they are started,
+// so that sampling decisions are propagated to the spans of downstream
+// requests.
+func (r *Rules) ServerOption() opentracing_helpers.HandlerOption {
+	return opentracing_helpers.WithSpanObserver(func(span opentracing.Span, req *http.Request) {
+		r.Apply(span, req)
+	})
+}
+
+// ClientOption applies the rules to client spans. Since TracedTransport
+// starts spans before they can be decorated, the rules are applied once
+// the response is received, after the span context was injected.
+func (r *Rules) ClientOption() opentracing_helpers.Option {
+	return opentracing_helpers.WithSpanDecorator(func(span opentracing.Span, req *http.Request, _ int) {
+		r.Apply(span, req)
+	})
+}
+
+// Apply applies the rules matching req to span.
+func (r *Rules) Apply(span opentracing.Span, req *http.Request) {
+	rules := r.rules.Load()
+	if rules == nil {
+		return
+	}
+	named, sampled := false, false
+	for _, rule := range *rules {
+		if rule.Method != "" && !strings.EqualFold(rule.Method, req.Method) {
+			continue
+		}
+		match := rule.path.FindStringSubmatchIndex(req.URL.Path)
+		if match == nil {
+			continue
+		}
+		if rule.OperationName != "" && !named {
+			span.SetOperationName(rule.expand(rule.OperationName, req.URL.Path, match))
+			named = true
+		}
+		if rule.Sample != nil && !sampled {
+			if *rule.Sample {
+				ext.SamplingPriority.Set(span, 1)
+			} else {
+				ext.SamplingPriority.Set(span, 0)
+			}
+			sampled = true
+		}
+		for k, v := range rule.Tags {
+			span.SetTag(k, rule.expand(v, req.URL.Path, match))
+		}
+	}
+}
+
+func (rule *compiledRule) expand(template, path string, match []int) string {
+	if !strings.Contains(template, "$") {
+		return template
+	}
+	return string(rule.path.ExpandString(nil, template, path, match))
+}
@@ -0,0 +1,160 @@
+package rules
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+const yamlRules = `
+rules:
+  - path: ^/users/(?P<id>[0-9]+)$
+    operation_name: GET /users/{id}
+    tags:
+      user.id: ${id}
+  - path: ^/internal/
+    sample: false
+  - path: ^/
+    method: POST
+    tags:
+      write: "true"
+`
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestApply(t *testing.T) {
+	r, err := Load(writeFile(t, "rules.yaml", yamlRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := mocktracer.New()
+
+	span := tracer.StartSpan("GET /users/42")
+	r.Apply(span, httptest.NewRequest("GET", "/users/42", nil))
+	mock := span.(*mocktracer.MockSpan)
+	if mock.OperationName != "GET /users/{id}" || mock.Tag("user.id") != "42" || mock.Tag("write") != nil {
+		t.Errorf("span %q tagged %v", mock.OperationName, mock.Tags())
+	}
+
+	span = tracer.StartSpan("POST /internal/jobs")
+	r.Apply(span, httptest.NewRequest("POST", "/internal/jobs", nil))
+	mock = span.(*mocktracer.MockSpan)
+	if mock.SpanContext.Sampled || mock.Tag("write") != "true" || mock.OperationName != "POST /internal/jobs" {
+		t.Errorf("span %q sampled %t tagged %v", mock.OperationName, mock.SpanContext.Sampled, mock.Tags())
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	r, err := Load(writeFile(t, "rules.json", `{"rules": [{"path": "^/ping$", "operation_name": "ping"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := mocktracer.New().StartSpan("GET /ping")
+	r.Apply(span, httptest.NewRequest("GET", "/ping", nil))
+	if name := span.(*mocktracer.MockSpan).OperationName; name != "ping" {
+		t.Errorf("operation name %q, want ping", name)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"rules.yaml": "rules: [",
+		"rules.json": `{"rules": [{"path": "("}]}`,
+	} {
+		if _, err := Load(writeFile(t, name, content)); err == nil {
+			t.Errorf("%s %q loaded", name, content)
+		}
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("a missing file loaded")
+	}
+}
+
+func TestSetKeepsRulesOnError(t *testing.T) {
+	r, err := New(File{Rules: []Rule{{Path: "^/", OperationName: "all"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Set(File{Rules: []Rule{{Path: "["}}}); err == nil {
+		t.Fatal("invalid rules accepted")
+	}
+	span := mocktracer.New().StartSpan("GET /")
+	r.Apply(span, httptest.NewRequest("GET", "/", nil))
+	if name := span.(*mocktracer.MockSpan).OperationName; name != "all" {
+		t.Errorf("operation name %q, want the previous rule's", name)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := writeFile(t, "rules.yaml", "rules: [{path: ^/, operation_name: before}]")
+	r, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 10)
+	go r.Watch(ctx, time.Millisecond, func(err error) { errs <- err })
+
+	name := func() string {
+		span := mocktracer.New().StartSpan("GET /")
+		r.Apply(span, httptest.NewRequest("GET", "/", nil))
+		return span.(*mocktracer.MockSpan).OperationName
+	}
+	update := func(content string, mtime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	update("rules: [", time.Now().Add(time.Hour))
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("invalid file not reported")
+	}
+	if got := name(); got != "before" {
+		t.Errorf("operation name %q after an invalid update, want before", got)
+	}
+
+	update("rules: [{path: ^/, operation_name: after}]", time.Now().Add(2*time.Hour))
+	deadline := time.Now().Add(time.Second)
+	for name() != "after" {
+		if time.Now().After(deadline) {
+			t.Fatal("rules not reloaded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestServerOption(t *testing.T) {
+	r, err := New(File{Rules: []Rule{{Path: "^/orders/([0-9]+)$", OperationName: "order", Tags: map[string]string{"order.id": "$1"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := mocktracer.New()
+	h := opentracing_helpers.NewMiddleware(opentracing_helpers.WithTracer(tracer), r.ServerOption())(http.NotFoundHandler())
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders/7", nil))
+
+	span := tracer.FinishedSpans()[0]
+	if span.OperationName != "order" || span.Tag("order.id") != "7" {
+		t.Errorf("span %q tagged %v", span.OperationName, span.Tags())
+	}
+}
@@ -0,0 +1,221 @@
+package opentracing_helpers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// SafeSpan wraps a span so that it can be used from several goroutines,
+// for example by httptrace callbacks racing with the code reading the
+// response. Calls are serialized with a mutex, Finish is idempotent, and
+// calls made after the span was finished are ignored. The httptrace events
+// of client spans are buffered and handed to the tracer along with Finish.
+//
+// The spans started by TraceHandler, TracedTransport and TraceRequest are
+// SafeSpans.
+type SafeSpan struct {
+	mu       sync.Mutex
+	span     opentracing.Span
+	finished bool
+	failed   bool
+	buffered []opentracing.LogRecord
+
+	// beforeFinish is called before the wrapped span is finished.
+	beforeFinish []func()
+
+	// logBudget limits the number of log records, if positive.
+	logBudget int
+	logs      int
+	dropped   int
+}
+
+var _ opentracing.Span = (*SafeSpan)(nil)
+
+// NewSafeSpan wraps span. If span already is a SafeSpan it is returned as
+// is.
+func NewSafeSpan(span opentracing.Span) *SafeSpan {
+	if s, ok := span.(*SafeSpan); ok {
+		return s
+	}
+	stats.spansStarted.Add(1)
+	return &SafeSpan{span: span}
+}
+
+// Unwrap returns the wrapped span.
+func (s *SafeSpan) Unwrap() opentracing.Span {
+	return s.span
+}
+
+// Finish finishes the wrapped span the first time it is called.
+func (s *SafeSpan) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+// FinishWithOptions finishes the wrapped span the first time it is called.
+func (s *SafeSpan) FinishWithOptions(opts opentracing.FinishOptions) {
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		return
+	}
+	s.finished = true
+	hooks := s.beforeFinish
+	s.beforeFinish = nil
+	s.mu.Unlock()
+	// The hooks run without the mutex held since they may take locks of
+	// their own that are held while calling into s.
+	for _, f := range hooks {
+		f()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats.spansFinished.Add(1)
+	if s.dropped > 0 {
+		s.span.SetTag("log.dropped_records", s.dropped)
+		stats.droppedLogs.Add(int64(s.dropped))
+	}
+	if len(s.buffered) > 0 {
+		opts.LogRecords = append(s.buffered, opts.LogRecords...)
+		s.buffered = nil
+	}
+	s.span.FinishWithOptions(opts)
+}
+
+// whenFinishing registers f to be called once, before the wrapped span is
+// finished.
+func (s *SafeSpan) whenFinishing(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.beforeFinish = append(s.beforeFinish, f)
+}
+
+// bufferLogFields records fields with the current time, to be passed to the
+// tracer in a single FinishWithOptions call. Unlike LogFields it never
+// calls into the wrapped span, so it is safe for callbacks that may race
+// with Finish.
+func (s *SafeSpan) bufferLogFields(fields ...log.Field) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.allowLog() {
+		s.buffered = append(s.buffered, opentracing.LogRecord{Timestamp: time.Now(), Fields: fields})
+	}
+}
+
+// allowLog reports whether a log record may be added, counting it against
+// the log budget. s.mu must be held.
+func (s *SafeSpan) allowLog() bool {
+	if s.finished {
+		return false
+	}
+	if s.logBudget > 0 && s.logs >= s.logBudget {
+		s.dropped++
+		return false
+	}
+	s.logs++
+	return true
+}
+
+// Context implements opentracing.Span.
+func (s *SafeSpan) Context() opentracing.SpanContext {
+	return s.span.Context()
+}
+
+// SetOperationName implements opentracing.Span.
+func (s *SafeSpan) SetOperationName(operationName string) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.finished {
+		s.span.SetOperationName(operationName)
+	}
+	return s
+}
+
+// SetTag implements opentracing.Span.
+func (s *SafeSpan) SetTag(key string, value interface{}) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.finished {
+		s.span.SetTag(key, value)
+		if key == string(ext.Error) {
+			s.failed, _ = value.(bool)
+		}
+	}
+	return s
+}
+
+// Failed reports whether the span was tagged with error=true.
+func (s *SafeSpan) Failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failed
+}
+
+// LogFields implements opentracing.Span.
+func (s *SafeSpan) LogFields(fields ...log.Field) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.allowLog() {
+		s.span.LogFields(fields...)
+	}
+}
+
+// LogKV implements opentracing.Span.
+func (s *SafeSpan) LogKV(alternatingKeyValues ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.allowLog() {
+		s.span.LogKV(alternatingKeyValues...)
+	}
+}
+
+// SetBaggageItem implements opentracing.Span.
+func (s *SafeSpan) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.finished {
+		s.span.SetBaggageItem(restrictedKey, value)
+	}
+	return s
+}
+
+// BaggageItem implements opentracing.Span.
+func (s *SafeSpan) BaggageItem(restrictedKey string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.span.BaggageItem(restrictedKey)
+}
+
+// Tracer implements opentracing.Span.
+func (s *SafeSpan) Tracer() opentracing.Tracer {
+	return s.span.Tracer()
+}
+
+// LogEvent implements opentracing.Span.
+//
+// Deprecated: use LogFields or LogKV.
+func (s *SafeSpan) LogEvent(event string) {
+	s.LogFields(log.String("event", event))
+}
+
+// LogEventWithPayload implements opentracing.Span.
+//
+// Deprecated: use LogFields or LogKV.
+func (s *SafeSpan) LogEventWithPayload(event string, payload interface{}) {
+	s.LogFields(log.String("event", event), log.Object("payload", payload))
+}
+
+// Log implements opentracing.Span.
+//
+// Deprecated: use LogFields or LogKV.
+func (s *SafeSpan) Log(data opentracing.LogData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.allowLog() {
+		s.span.Log(data)
+	}
+}
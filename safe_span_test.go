@@ -0,0 +1,157 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestSafeSpanFinishIsIdempotent(t *testing.T) {
+	tracer := mocktracer.New()
+	span := NewSafeSpan(tracer.StartSpan("op"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			span.LogFields(log.String("event", "racing"))
+			span.Finish()
+		}()
+	}
+	wg.Wait()
+	span.Finish()
+
+	if n := len(tracer.FinishedSpans()); n != 1 {
+		t.Fatalf("span finished %d times, want 1", n)
+	}
+}
+
+func TestSafeSpanIgnoresCallsAfterFinish(t *testing.T) {
+	tracer := mocktracer.New()
+	span := NewSafeSpan(tracer.StartSpan("op"))
+	span.LogFields(log.String("event", "before"))
+	span.Finish()
+
+	span.SetTag("late", true)
+	span.SetOperationName("renamed")
+	span.LogFields(log.String("event", "after"))
+	span.LogKV("event", "after")
+	span.SetBaggageItem("late", "true")
+	span.bufferLogFields(log.String("event", "buffered after"))
+
+	finished := tracer.FinishedSpans()[0]
+	if _, ok := finished.Tags()["late"]; ok {
+		t.Error("tag set after Finish was recorded")
+	}
+	if finished.OperationName != "op" {
+		t.Errorf("operation name = %q, want %q", finished.OperationName, "op")
+	}
+	if n := len(finished.Logs()); n != 1 {
+		t.Errorf("span has %d log records, want 1", n)
+	}
+	if got := span.BaggageItem("late"); got != "" {
+		t.Errorf("baggage item set after Finish = %q", got)
+	}
+}
+
+func TestSafeSpanBufferedLogsAreFlushedOnFinish(t *testing.T) {
+	tracer := mocktracer.New()
+	span := NewSafeSpan(tracer.StartSpan("op"))
+	span.bufferLogFields(log.String("event", "DNS start"))
+	span.bufferLogFields(log.String("event", "DNS done"))
+	if n := len(span.Unwrap().(*mocktracer.MockSpan).Logs()); n != 0 {
+		t.Fatalf("%d buffered log records reached the span before Finish", n)
+	}
+	span.Finish()
+
+	if got, want := loggedEvents(tracer.FinishedSpans()[0]), []string{"DNS start", "DNS done"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logged events %q, want %q", got, want)
+	}
+}
+
+func TestSafeSpanLogBudget(t *testing.T) {
+	tracer := mocktracer.New()
+	span := NewSafeSpan(tracer.StartSpan("op"))
+	span.logBudget = 2
+	for i := 0; i < 5; i++ {
+		span.LogFields(log.Int("i", i))
+	}
+	span.Finish()
+
+	finished := tracer.FinishedSpans()[0]
+	if n := len(finished.Logs()); n != 2 {
+		t.Errorf("span has %d log records, want 2", n)
+	}
+	if got := finished.Tag("log.dropped_records"); got != 3 {
+		t.Errorf("log.dropped_records = %v, want 3", got)
+	}
+}
+
+func TestSafeSpanFailed(t *testing.T) {
+	span := NewSafeSpan(mocktracer.New().StartSpan("op"))
+	if span.Failed() {
+		t.Error("new span reports failure")
+	}
+	ext.Error.Set(span, true)
+	if !span.Failed() {
+		t.Error("span tagged with error=true doesn't report failure")
+	}
+}
+
+func TestNewSafeSpanDoesNotRewrap(t *testing.T) {
+	raw := mocktracer.New().StartSpan("op")
+	span := NewSafeSpan(raw)
+	if NewSafeSpan(span) != span {
+		t.Error("NewSafeSpan wrapped a SafeSpan again")
+	}
+	if span.Unwrap() != raw {
+		t.Error("Unwrap doesn't return the wrapped span")
+	}
+}
+
+func TestTraceHandlerUsesSafeSpan(t *testing.T) {
+	tracer := mocktracer.New()
+	var span opentracing.Span
+	_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span = opentracing.SpanFromContext(r.Context())
+	}), WithTracer(tracer))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if _, ok := span.(*SafeSpan); !ok {
+		t.Fatalf("the handler's span is a %T, want *SafeSpan", span)
+	}
+	// A handler finishing the span itself doesn't finish it twice.
+	span.Finish()
+	finishedSpan(t, tracer)
+}
+
+func TestSafeSpanWhenFinishing(t *testing.T) {
+	tracer := mocktracer.New()
+	span := NewSafeSpan(tracer.StartSpan("op"))
+	calls := 0
+	span.whenFinishing(func() {
+		calls++
+		// The hook may call into the span it is registered on.
+		span.LogFields(log.String("event", "finishing"))
+		if len(tracer.FinishedSpans()) != 0 {
+			t.Error("hook called after the span was finished")
+		}
+	})
+	span.Finish()
+	span.Finish()
+
+	if calls != 1 {
+		t.Errorf("hook called %d times, want 1", calls)
+	}
+	if got := loggedEvents(finishedSpan(t, tracer)); len(got) != 0 {
+		t.Errorf("logged %q after finishing started, want nothing", got)
+	}
+}
@@ -0,0 +1,43 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// WithErrorSamplingPriority sets sampling.priority=1 on server and client
+// spans that failed, answered with a 5xx status or took longer than
+// latency, so that their traces are kept even when head sampling is
+// aggressive. A span failed if it was tagged with error=true, for example
+// by WithErrorClassifier or a recovered panic. A latency of 0 disables the
+// latency criterion.
+func WithErrorSamplingPriority(latency time.Duration) Option {
+	return WithOnFinish(func(span opentracing.Span, _ *http.Request, status int, duration time.Duration) {
+		failed := status >= http.StatusInternalServerError || (latency > 0 && duration > latency)
+		if s, ok := span.(*SafeSpan); ok && s.Failed() {
+			failed = true
+		}
+		if failed {
+			ext.SamplingPriority.Set(span, 1)
+		}
+	})
+}
+
+// WithSlowThreshold tags server and client spans that took longer than d
+// with slow=true, and calls onSlow, if given, for each of them, for
+// example to log the request. It helps triaging latency outliers from
+// traces.
+func WithSlowThreshold(d time.Duration, onSlow ...func(span opentracing.Span, r *http.Request, duration time.Duration)) Option {
+	return WithOnFinish(func(span opentracing.Span, r *http.Request, _ int, duration time.Duration) {
+		if duration <= d {
+			return
+		}
+		span.SetTag("slow", true)
+		for _, f := range onSlow {
+			f(span, r, duration)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// unsampledRequest returns a request continuing a trace of tracer that
+// head sampling dropped.
+func unsampledRequest(tracer *mocktracer.MockTracer) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	parent := tracer.StartSpan("client")
+	ext.SamplingPriority.Set(parent, 0)
+	tracer.Inject(parent.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+	return r
+}
+
+func TestWithErrorSamplingPriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		latency time.Duration
+		opts    []HandlerOption
+		sampled bool
+	}{
+		{"ok", http.StatusOK, time.Hour, nil, false},
+		{"server error", http.StatusServiceUnavailable, 0, nil, true},
+		{"slow", http.StatusOK, time.Nanosecond, nil, true},
+		{"classified error", http.StatusTooManyRequests, 0, []HandlerOption{WithErrorClassifier(classifyRateLimits)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			_, h := TraceHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}), append(tt.opts, WithTracer(tracer), WithErrorSamplingPriority(tt.latency))...)
+			h.ServeHTTP(httptest.NewRecorder(), unsampledRequest(tracer))
+
+			// The mock tracer turns sampling.priority into the sampled flag.
+			if got := finishedSpan(t, tracer).SpanContext.Sampled; got != tt.sampled {
+				t.Errorf("sampled = %v, want %v", got, tt.sampled)
+			}
+		})
+	}
+}
+
+func TestWithErrorSamplingPriorityClient(t *testing.T) {
+	for _, tt := range []struct {
+		status  int
+		sampled bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusBadGateway, true},
+	} {
+		tracer := mocktracer.New()
+		parent := tracer.StartSpan("request")
+		ext.SamplingPriority.Set(parent, 0)
+		transport := NewTracedTransport(respond(tt.status, "", nil), WithTracer(tracer), WithErrorSamplingPriority(0))
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		req = req.WithContext(opentracing.ContextWithSpan(req.Context(), parent))
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if got := finishedSpan(t, tracer).SpanContext.Sampled; got != tt.sampled {
+			t.Errorf("status %d: sampled = %v, want %v", tt.status, got, tt.sampled)
+		}
+	}
+}
+
+func TestWithSlowThreshold(t *testing.T) {
+	for _, tt := range []struct {
+		threshold time.Duration
+		slow      bool
+	}{
+		{time.Hour, false},
+		{time.Nanosecond, true},
+	} {
+		tracer := mocktracer.New()
+		var slowPaths []string
+		onSlow := func(span opentracing.Span, r *http.Request, duration time.Duration) {
+			slowPaths = append(slowPaths, r.URL.Path)
+		}
+		_, h := TraceHandler("/", okHandler, WithTracer(tracer), WithSlowThreshold(tt.threshold, onSlow))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/report", nil))
+
+		span := finishedSpan(t, tracer)
+		if tt.slow {
+			if span.Tag("slow") != true || len(slowPaths) != 1 || slowPaths[0] != "/report" {
+				t.Errorf("threshold %v: slow = %v, onSlow called for %q", tt.threshold, span.Tag("slow"), slowPaths)
+			}
+			continue
+		}
+		if span.Tag("slow") != nil || len(slowPaths) != 0 {
+			t.Errorf("threshold %v: slow = %v, onSlow called for %q; want neither", tt.threshold, span.Tag("slow"), slowPaths)
+		}
+	}
+}
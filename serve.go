@@ -0,0 +1,29 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/http"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// serve calls the handler of sr, within an execution trace task and
+// pprof labels if configured. sr.r is replaced by the request passed to
+// the handler, on which an http.ServeMux behind the middleware records the
+// pattern and path values that finishServerSpan tags.
+func (c *handlerConfig) serve(sr *serverRequest, handler http.Handler, w http.ResponseWriter) {
+	if c.executionTraceTasks && trace.IsEnabled() {
+		ctx, task := c.startTask(sr)
+		defer task.End()
+		defer trace.StartRegion(ctx, "ServeHTTP").End()
+		sr.r = sr.r.WithContext(ctx)
+	}
+	if c.profilerLabels {
+		pprof.Do(sr.r.Context(), c.profilerLabelSet(sr), func(ctx context.Context) {
+			sr.r = sr.r.WithContext(ctx)
+			handler.ServeHTTP(w, sr.r)
+		})
+		return
+	}
+	handler.ServeHTTP(w, sr.r)
+}
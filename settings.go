@@ -0,0 +1,98 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// Settings is the configuration built from Options, for the packages of
+// this module that instrument libraries without net/http handlers or
+// transports, such as fasthttp and net/rpc. It lets them accept the same
+// Options as the rest of the module rather than defining their own.
+//
+// Settings honor WithTracer, WithDisabled and Toggle, WithFilter,
+// WithOperationNameFunc, WithURLScrubber, WithPropagators and
+// WithErrorClassifier. Packages working on other requests than
+// *http.Request pass an equivalent request to the methods taking one.
+//
+// The packages instrumenting message brokers, databases and gRPC keep an
+// Option type of their own, with little more than WithTracer: they
+// propagate span contexts in the tracer's TextMap format, which
+// Propagators, working on HTTP headers, would change on the wire.
+type Settings struct {
+	c *transportConfig
+}
+
+// NewSettings returns the Settings configured by opts.
+func NewSettings(opts ...Option) *Settings {
+	c := &transportConfig{}
+	for _, opt := range opts {
+		opt.applyTransport(c)
+	}
+	return &Settings{c: c}
+}
+
+// Tracer returns the configured tracer, falling back to the global tracer.
+func (s *Settings) Tracer() opentracing.Tracer {
+	return s.c.activeTracer()
+}
+
+// Noop reports whether tracing can be skipped altogether, because Toggle
+// is off, tracing is disabled or the tracer is a NoopTracer.
+func (s *Settings) Noop() bool {
+	return s.c.noop()
+}
+
+// Traced reports whether r passes the filter set with WithFilter.
+func (s *Settings) Traced(r *http.Request) bool {
+	return s.c.traced(r)
+}
+
+// OperationName returns the name of the span for r: the one returned by
+// the OperationNameFunc set with WithOperationNameFunc, or fallback.
+func (s *Settings) OperationName(r *http.Request, fallback string) string {
+	return s.c.spanName(r, fallback)
+}
+
+// URLTag returns the value of the http.url tag for u, scrubbed with
+// ScrubURL unless WithURLScrubber replaced it.
+func (s *Settings) URLTag(u *url.URL) string {
+	return s.c.urlTag(u)
+}
+
+// Inject injects sc into h with the configured propagators.
+func (s *Settings) Inject(sc opentracing.SpanContext, h http.Header) error {
+	return s.c.inject(sc, h)
+}
+
+// Extract returns the span context found in h by the configured
+// propagators.
+func (s *Settings) Extract(h http.Header) (opentracing.SpanContext, error) {
+	return s.c.extract(h)
+}
+
+// InjectTextMap is like Inject but writes the headers to carrier, for
+// protocols carrying metadata as plain key-value pairs.
+func (s *Settings) InjectTextMap(sc opentracing.SpanContext, carrier opentracing.TextMapWriter) error {
+	return s.c.injectCarrier(sc, carrier)
+}
+
+// ExtractTextMap is like Extract but reads the headers from carrier.
+func (s *Settings) ExtractTextMap(carrier opentracing.TextMapReader) (opentracing.SpanContext, error) {
+	return s.c.extractCarrier(carrier)
+}
+
+// TagError tags span as failed if the configured ErrorClassifier considers
+// the response status or err, either of which may be zero, an error. It
+// reports whether it did.
+func (s *Settings) TagError(span opentracing.Span, status int, err error) bool {
+	return s.c.tagError(span, status, err)
+}
+
+// LogPanic tags span as failed and logs the recovered panic value p along
+// with the stack trace, as WithPanicRecovery does.
+func (s *Settings) LogPanic(span opentracing.Span, p interface{}) {
+	logPanic(span, p)
+}
@@ -0,0 +1,49 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// codec reports fixed IDs for mocktracer span contexts.
+type codec struct{}
+
+func (codec) Identifiers(sc opentracing.SpanContext) (opentracing_helpers.SpanIdentifiers, bool) {
+	_, ok := sc.(mocktracer.MockSpanContext)
+	return opentracing_helpers.SpanIdentifiers{TraceID: "463ac35c9f6413ad", SpanID: "a2fb4a1d1a96d312", Sampled: true}, ok
+}
+
+func (codec) SpanContext(opentracing_helpers.SpanIdentifiers) (opentracing.SpanContext, error) {
+	return nil, opentracing.ErrUnsupportedFormat
+}
+
+func TestLoggerWithTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	span := mocktracer.New().StartSpan("op")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	LoggerWithTrace(ctx, logger, WithCodec(codec{})).Info("user created")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["trace_id"] != "463ac35c9f6413ad" || entry["span_id"] != "a2fb4a1d1a96d312" || entry["sampled"] != true {
+		t.Errorf("logged %v", entry)
+	}
+}
+
+func TestLoggerWithTraceWithoutSpan(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+	if got := LoggerWithTrace(context.Background(), logger); got != logger {
+		t.Error("the logger was not returned as is")
+	}
+}
@@ -0,0 +1,51 @@
+package opentracing_helpers
+
+import (
+	"bytes"
+	"encoding/base64"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// MarshalSpanContext serializes sc with the Binary format of the global
+// tracer, for applications that stash span contexts in job queues,
+// database rows or protobuf bytes fields. Tracers that don't support the
+// format fail with opentracing.ErrUnsupportedFormat.
+func MarshalSpanContext(sc opentracing.SpanContext) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := opentracing.GlobalTracer().Inject(sc, opentracing.Binary, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalSpanContext deserializes a span context serialized by
+// MarshalSpanContext, with the global tracer. It fails with
+// opentracing.ErrSpanContextNotFound if data is empty.
+func UnmarshalSpanContext(data []byte) (opentracing.SpanContext, error) {
+	if len(data) == 0 {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	return opentracing.GlobalTracer().Extract(opentracing.Binary, bytes.NewReader(data))
+}
+
+// MarshalSpanContextText is like MarshalSpanContext but returns unpadded
+// URL-safe base64, for text columns, JSON documents or query parameters.
+func MarshalSpanContextText(sc opentracing.SpanContext) (string, error) {
+	data, err := MarshalSpanContext(sc)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// UnmarshalSpanContextText deserializes a span context serialized by
+// MarshalSpanContextText. Malformed base64 fails with
+// opentracing.ErrSpanContextCorrupted.
+func UnmarshalSpanContextText(text string) (opentracing.SpanContext, error) {
+	data, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	return UnmarshalSpanContext(data)
+}
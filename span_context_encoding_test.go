@@ -0,0 +1,89 @@
+package opentracing_helpers
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// binaryTracer adds the Binary format to the mock tracer, serializing
+// span contexts as "traceID:spanID".
+type binaryTracer struct {
+	*mocktracer.MockTracer
+}
+
+func (t binaryTracer) Inject(sc opentracing.SpanContext, format, carrier interface{}) error {
+	if format != opentracing.Binary {
+		return t.MockTracer.Inject(sc, format, carrier)
+	}
+	msc := sc.(mocktracer.MockSpanContext)
+	_, err := fmt.Fprintf(carrier.(io.Writer), "%d:%d", msc.TraceID, msc.SpanID)
+	return err
+}
+
+func (t binaryTracer) Extract(format, carrier interface{}) (opentracing.SpanContext, error) {
+	if format != opentracing.Binary {
+		return t.MockTracer.Extract(format, carrier)
+	}
+	var msc mocktracer.MockSpanContext
+	if _, err := fmt.Fscanf(carrier.(io.Reader), "%d:%d", &msc.TraceID, &msc.SpanID); err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	return msc, nil
+}
+
+func TestMarshalSpanContext(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	tracer := binaryTracer{mocktracer.New()}
+	opentracing.SetGlobalTracer(tracer)
+	span := tracer.StartSpan("enqueue")
+	want := span.Context().(mocktracer.MockSpanContext)
+
+	data, err := MarshalSpanContext(span.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, err := UnmarshalSpanContext(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sc.(mocktracer.MockSpanContext); got.TraceID != want.TraceID || got.SpanID != want.SpanID {
+		t.Errorf("unmarshaled %+v, want %+v", got, want)
+	}
+
+	text, err := MarshalSpanContextText(span.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, err = UnmarshalSpanContextText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sc.(mocktracer.MockSpanContext); got.TraceID != want.TraceID || got.SpanID != want.SpanID {
+		t.Errorf("unmarshaled text %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshalSpanContextErrors(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(binaryTracer{mocktracer.New()})
+
+	if _, err := UnmarshalSpanContext(nil); err != opentracing.ErrSpanContextNotFound {
+		t.Errorf("empty data: %v, want ErrSpanContextNotFound", err)
+	}
+	if _, err := UnmarshalSpanContextText("not base64!"); err != opentracing.ErrSpanContextCorrupted {
+		t.Errorf("malformed text: %v, want ErrSpanContextCorrupted", err)
+	}
+}
+
+func TestMarshalSpanContextUnsupported(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	if _, err := MarshalSpanContext(tracer.StartSpan("op").Context()); err != opentracing.ErrUnsupportedFormat {
+		t.Errorf("MarshalSpanContext: %v, want ErrUnsupportedFormat", err)
+	}
+}
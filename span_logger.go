@@ -0,0 +1,121 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying logger, for
+// ContextWithSpanLogger.
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// SpanLogger writes every message both to a slog.Logger and to the logs
+// of a span, so that the two don't have to be written separately.
+type SpanLogger struct {
+	ctx    context.Context
+	logger *slog.Logger
+	span   opentracing.Span
+}
+
+// ContextWithSpanLogger returns a SpanLogger for the span in ctx and the
+// logger set with ContextWithLogger, or slog.Default(). Messages get the
+// trace_id and span_id attributes of the span, and are logged on the
+// span with their attributes and level, unless ctx carries no span:
+//
+//	l := opentracing_helpers.ContextWithSpanLogger(ctx)
+//	l.Info("cache miss", "key", key)
+//	l.Error("payment declined", "error", err)
+func ContextWithSpanLogger(ctx context.Context) *SpanLogger {
+	logger, ok := ctx.Value(loggerKey{}).(*slog.Logger)
+	if !ok {
+		logger = slog.Default()
+	}
+	l := &SpanLogger{ctx: ctx, logger: logger, span: opentracing.SpanFromContext(ctx)}
+	if ids, ok := SpanIdentifiersFromContext(ctx, nil); ok {
+		l.logger = logger.With(slog.String("trace_id", ids.TraceID), slog.String("span_id", ids.SpanID))
+	}
+	return l
+}
+
+// Logger returns the slog.Logger messages are written to.
+func (l *SpanLogger) Logger() *slog.Logger {
+	return l.logger
+}
+
+// Debug logs at slog.LevelDebug.
+func (l *SpanLogger) Debug(msg string, args ...any) {
+	l.log(slog.LevelDebug, msg, args)
+}
+
+// Info logs at slog.LevelInfo.
+func (l *SpanLogger) Info(msg string, args ...any) {
+	l.log(slog.LevelInfo, msg, args)
+}
+
+// Warn logs at slog.LevelWarn.
+func (l *SpanLogger) Warn(msg string, args ...any) {
+	l.log(slog.LevelWarn, msg, args)
+}
+
+// Error logs at slog.LevelError. It doesn't mark the span as failed, use
+// RecordError for that.
+func (l *SpanLogger) Error(msg string, args ...any) {
+	l.log(slog.LevelError, msg, args)
+}
+
+func (l *SpanLogger) log(level slog.Level, msg string, args []any) {
+	r := slog.NewRecord(time.Now(), level, msg, 0)
+	r.Add(args...)
+	if l.logger.Enabled(l.ctx, level) {
+		l.logger.Handler().Handle(l.ctx, r)
+	}
+	if l.span == nil {
+		return
+	}
+	fields := make([]log.Field, 0, 2+r.NumAttrs())
+	fields = append(fields, log.String("event", msg), log.String("level", level.String()))
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttrFields(fields, "", a)
+		return true
+	})
+	l.span.LogFields(fields...)
+}
+
+// appendAttrFields appends the span log fields of a, flattening groups
+// into dotted keys.
+func appendAttrFields(fields []log.Field, prefix string, a slog.Attr) []log.Field {
+	v := a.Value.Resolve()
+	key := prefix + a.Key
+	switch v.Kind() {
+	case slog.KindGroup:
+		if a.Key != "" {
+			prefix = key + "."
+		}
+		for _, ga := range v.Group() {
+			fields = appendAttrFields(fields, prefix, ga)
+		}
+		return fields
+	case slog.KindString:
+		return append(fields, log.String(key, v.String()))
+	case slog.KindInt64:
+		return append(fields, log.Int64(key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, log.Uint64(key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, log.Float64(key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, log.Bool(key, v.Bool()))
+	}
+	if err, ok := v.Any().(error); ok {
+		return append(fields, log.String(key, err.Error()))
+	}
+	return append(fields, log.String(key, v.String()))
+}
@@ -0,0 +1,85 @@
+package opentracing_helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// nativeSpan is a mock span whose tracer writes the given native headers,
+// so that its identifiers can be read without a codec.
+type nativeSpan struct {
+	*mocktracer.MockSpan
+	tracer headerTracer
+}
+
+func (s nativeSpan) Tracer() opentracing.Tracer { return s.tracer }
+
+func TestContextWithSpanLogger(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("checkout").(*mocktracer.MockSpan)
+	native := nativeSpan{span, headerTracer{headers: map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}}}
+	var buf bytes.Buffer
+	ctx := ContextWithLogger(context.Background(), slog.New(slog.NewJSONHandler(&buf, nil)))
+	ctx = opentracing.ContextWithSpan(ctx, native)
+
+	l := ContextWithSpanLogger(ctx)
+	l.Error("payment declined", "error", errors.New("card expired"), slog.Group("card", "last4", "4242", "retries", 2))
+	l.Debug("not logged to slog")
+
+	var line map[string]any
+	if err := json.Unmarshal(bytes.SplitN(buf.Bytes(), []byte("\n"), 2)[0], &line); err != nil {
+		t.Fatal(err)
+	}
+	if line["msg"] != "payment declined" || line["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || line["span_id"] != "00f067aa0ba902b7" {
+		t.Errorf("logged %v", line)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("not logged")) {
+		t.Error("a debug message was logged to slog at the info level")
+	}
+
+	logs := span.Logs()
+	if len(logs) != 2 {
+		t.Fatalf("%d span log records, want 2", len(logs))
+	}
+	fields := map[string]string{}
+	for _, f := range logs[0].Fields {
+		fields[f.Key] = f.ValueString
+	}
+	for key, want := range map[string]string{
+		"event":        "payment declined",
+		"level":        "ERROR",
+		"error":        "card expired",
+		"card.last4":   "4242",
+		"card.retries": "2",
+	} {
+		if fields[key] != want {
+			t.Errorf("span log field %s = %q, want %q", key, fields[key], want)
+		}
+	}
+}
+
+func TestContextWithSpanLoggerWithoutSpan(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := ContextWithLogger(context.Background(), slog.New(slog.NewTextHandler(&buf, nil)))
+	l := ContextWithSpanLogger(ctx)
+	l.Info("started", "port", 8080)
+	if got := buf.String(); !strings.Contains(got, "msg=started port=8080") || strings.Contains(got, "trace_id") {
+		t.Errorf("logged %q", got)
+	}
+}
+
+func TestContextWithSpanLoggerDefaultLogger(t *testing.T) {
+	if l := ContextWithSpanLogger(context.Background()); l.Logger() != slog.Default() {
+		t.Error("the logger isn't slog.Default() without ContextWithLogger")
+	}
+}
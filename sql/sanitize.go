@@ -0,0 +1,124 @@
+package sql
+
+import (
+	"strings"
+)
+
+// SanitizeSQL replaces the literal values of query, strings and numbers,
+// with ? placeholders so that db.statement tags don't leak personal data:
+//
+//	SELECT * FROM users WHERE email = 'jane@example.com' AND age > 30
+//
+// becomes
+//
+//	SELECT * FROM users WHERE email = ? AND age > ?
+//
+// Quoted identifiers, bind parameters such as $1 and comments are kept. It
+// is the default statement sanitizer of the traced driver.
+func SanitizeSQL(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'':
+			i = skipQuoted(query, i, '\'')
+			b.WriteByte('?')
+		case c == '"' || c == '`':
+			j := skipQuoted(query, i, c)
+			b.WriteString(query[i:j])
+			i = j
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+			b.WriteString(query[i : i+j])
+			i += j
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			j := strings.Index(query[i+2:], "*/")
+			if j < 0 {
+				j = len(query) - i - 4
+			}
+			b.WriteString(query[i : i+j+4])
+			i += j + 4
+		case c == '$':
+			// Bind parameters ($1) are kept, dollar-quoted strings
+			// ($$...$$ or $tag$...$tag$) are replaced.
+			if tag, ok := dollarTag(query[i:]); ok {
+				end := strings.Index(query[i+len(tag):], tag)
+				if end < 0 {
+					i = len(query)
+				} else {
+					i += len(tag) + end + len(tag)
+				}
+				b.WriteByte('?')
+				continue
+			}
+			j := i + 1
+			for j < len(query) && isIdentByte(query[j]) {
+				j++
+			}
+			b.WriteString(query[i:j])
+			i = j
+		case isDigit(c) && (i == 0 || !isIdentByte(query[i-1])):
+			j := i + 1
+			for j < len(query) && (isIdentByte(query[j]) || query[j] == '.') {
+				j++
+			}
+			b.WriteByte('?')
+			i = j
+		case isIdentByte(c):
+			j := i + 1
+			for j < len(query) && isIdentByte(query[j]) {
+				j++
+			}
+			b.WriteString(query[i:j])
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// skipQuoted returns the index following the string quoted by quote that
+// starts at i. Quotes are escaped by doubling them or with a backslash.
+func skipQuoted(query string, i int, quote byte) int {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			j++
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+// dollarTag returns the opening tag of a dollar-quoted string at the start
+// of s, such as "$$" or "$body$".
+func dollarTag(s string) (string, bool) {
+	for j := 1; j < len(s); j++ {
+		switch {
+		case s[j] == '$':
+			return s[:j+1], true
+		case isDigit(s[j]) && j == 1, !isIdentByte(s[j]):
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
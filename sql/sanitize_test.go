@@ -0,0 +1,82 @@
+package sql
+
+import "testing"
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		name, query, want string
+	}{
+		{
+			name:  "strings and numbers",
+			query: "SELECT * FROM users WHERE email = 'jane@example.com' AND age > 30",
+			want:  "SELECT * FROM users WHERE email = ? AND age > ?",
+		},
+		{
+			name:  "escaped quotes",
+			query: `UPDATE notes SET body = 'it''s' , title = 'a\'b' WHERE id = 7`,
+			want:  "UPDATE notes SET body = ? , title = ? WHERE id = ?",
+		},
+		{
+			name:  "decimal and negative numbers",
+			query: "SELECT price * 1.25 FROM items WHERE delta < -3",
+			want:  "SELECT price * ? FROM items WHERE delta < -?",
+		},
+		{
+			name:  "identifiers with digits",
+			query: "SELECT col1 FROM table2 WHERE x_3 = 4",
+			want:  "SELECT col1 FROM table2 WHERE x_3 = ?",
+		},
+		{
+			name:  "quoted identifiers",
+			query: "SELECT \"Last Name\", `order` FROM people WHERE id = 1",
+			want:  "SELECT \"Last Name\", `order` FROM people WHERE id = ?",
+		},
+		{
+			name:  "bind parameters",
+			query: "SELECT * FROM users WHERE id = $1 AND org = $2 AND name = ?",
+			want:  "SELECT * FROM users WHERE id = $1 AND org = $2 AND name = ?",
+		},
+		{
+			name:  "comments",
+			query: "SELECT 1 -- secret 'x'\nFROM t /* id = 5 */ WHERE a = 'b'",
+			want:  "SELECT ? -- secret 'x'\nFROM t /* id = 5 */ WHERE a = ?",
+		},
+		{
+			name:  "dollar-quoted strings",
+			query: "SELECT $$jane's$$, $body$a $$ b$body$, $1",
+			want:  "SELECT ?, ?, $1",
+		},
+		{
+			name:  "unterminated string",
+			query: "SELECT * FROM t WHERE a = 'oops",
+			want:  "SELECT * FROM t WHERE a = ?",
+		},
+		{
+			name:  "unterminated comment",
+			query: "SELECT 1 /* open",
+			want:  "SELECT ? /* open",
+		},
+		{
+			name:  "unterminated dollar quote",
+			query: "SELECT $x$ secret",
+			want:  "SELECT ?",
+		},
+		{
+			name:  "non-ASCII identifiers",
+			query: "SELECT prénom2 FROM personnes WHERE âge = 42",
+			want:  "SELECT prénom2 FROM personnes WHERE âge = ?",
+		},
+		{
+			name:  "empty",
+			query: "",
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeSQL(tt.query); got != tt.want {
+				t.Errorf("SanitizeSQL(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
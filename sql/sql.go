@@ -0,0 +1,181 @@
+// Package sql traces database/sql operations by wrapping a driver.Driver.
+// Spans are children of the span carried by the context passed to
+// QueryContext, ExecContext, PrepareContext and BeginTx:
+//
+//	sql.Register("traced-postgres", otsql.WrapDriver(&pq.Driver{},
+//	    otsql.WithDBType("postgresql"),
+//	    otsql.WithDBInstance("orders"),
+//	))
+//	db, err := sql.Open("traced-postgres", dsn)
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "database/sql"}
+
+// Option customizes the traced driver.
+type Option func(*config)
+
+type config struct {
+	tracer    opentracing.Tracer
+	dbType    string
+	instance  string
+	sanitizer func(query string) string
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{dbType: "sql", sanitizer: SanitizeSQL}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithTracer uses tracer instead of opentracing.GlobalTracer().
+func WithTracer(tracer opentracing.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// WithDBType sets the db.type tag, for example "postgresql". It defaults
+// to "sql".
+func WithDBType(dbType string) Option {
+	return func(c *config) {
+		c.dbType = dbType
+	}
+}
+
+// WithDBInstance sets the db.instance tag, usually the database name.
+func WithDBInstance(instance string) Option {
+	return func(c *config) {
+		c.instance = instance
+	}
+}
+
+// WithStatementSanitizer transforms queries before they are recorded in
+// the db.statement tag, so that literal values don't leak into traces. It
+// replaces SanitizeSQL, the default.
+func WithStatementSanitizer(f func(query string) string) Option {
+	return func(c *config) {
+		c.sanitizer = f
+	}
+}
+
+// WithRawStatements records queries in the db.statement tag as is,
+// literal values included. It is meant for debugging environments.
+func WithRawStatements() Option {
+	return WithStatementSanitizer(nil)
+}
+
+func (c *config) activeTracer() opentracing.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return opentracing.GlobalTracer()
+}
+
+// startSpan starts a span for operationName as a child of the span in ctx.
+// query may be empty for operations that don't run a statement.
+func (c *config) startSpan(ctx context.Context, operationName, query string) opentracing.Span {
+	var parent opentracing.SpanContext
+	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+		parent = parentSpan.Context()
+	}
+	span := c.activeTracer().StartSpan(
+		operationName,
+		opentracing.ChildOf(parent),
+		ext.SpanKindRPCClient,
+		componentTag,
+	)
+	ext.DBType.Set(span, c.dbType)
+	if c.instance != "" {
+		ext.DBInstance.Set(span, c.instance)
+	}
+	if query != "" {
+		if c.sanitizer != nil {
+			query = c.sanitizer(query)
+		}
+		ext.DBStatement.Set(span, query)
+	}
+	return span
+}
+
+// finishSpan records err, if any, and finishes span. driver.ErrSkip is not
+// an error: it asks database/sql to retry through a different code path.
+func finishSpan(span opentracing.Span, err error) {
+	if err != nil && err != driver.ErrSkip {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+	span.Finish()
+}
+
+// WrapDriver returns a driver that traces the operations of d. Register
+// it with sql.Register to use it.
+func WrapDriver(d driver.Driver, opts ...Option) driver.Driver {
+	return &tracedDriver{driver: d, config: newConfig(opts)}
+}
+
+type tracedDriver struct {
+	driver driver.Driver
+	config *config
+}
+
+func (d *tracedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, config: d.config}, nil
+}
+
+func (d *tracedDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &tracedConnector{connector: connector, driver: d}, nil
+	}
+	return &tracedConnector{connector: dsnConnector{name: name, driver: d.driver}, driver: d}, nil
+}
+
+type tracedConnector struct {
+	connector driver.Connector
+	driver    *tracedDriver
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, config: c.driver.config}, nil
+}
+
+func (c *tracedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// dsnConnector is a connector for drivers that don't implement
+// driver.DriverContext.
+type dsnConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
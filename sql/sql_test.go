@@ -0,0 +1,214 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+var errBadQuery = errors.New("syntax error")
+
+// fakeDriver is a database/sql driver without a database. Queries equal
+// to "bad" fail. Its connections only implement the context interfaces
+// when direct is true.
+type fakeDriver struct {
+	direct bool
+}
+
+func (d fakeDriver) Open(name string) (driver.Conn, error) {
+	if d.direct {
+		return directConn{}, nil
+	}
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if query == "bad" {
+		return nil, errBadQuery
+	}
+	return fakeStmt{}, nil
+}
+func (fakeConn) Close() error              { return nil }
+func (fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+// directConn runs statements without preparing them.
+type directConn struct{ fakeConn }
+
+func (directConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == "bad" {
+		return nil, errBadQuery
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (directConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if query == "bad" {
+		return nil, errBadQuery
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                                    { return nil }
+func (fakeStmt) NumInput() int                                   { return -1 }
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error)  { return &fakeRows{}, nil }
+
+// fakeRows returns a single row with a single column.
+type fakeRows struct{ done bool }
+
+func (*fakeRows) Columns() []string { return []string{"n"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func openDB(t *testing.T, d driver.Driver, opts ...Option) *sql.DB {
+	t.Helper()
+	connector, err := WrapDriver(d, opts...).(driver.DriverContext).OpenConnector("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// operations returns the operation names of the spans finished by tracer.
+func operations(tracer *mocktracer.MockTracer) []string {
+	var names []string
+	for _, span := range tracer.FinishedSpans() {
+		names = append(names, span.OperationName)
+	}
+	return names
+}
+
+func TestExecAndQuery(t *testing.T) {
+	for _, direct := range []bool{true, false} {
+		tracer := mocktracer.New()
+		db := openDB(t, fakeDriver{direct: direct}, WithTracer(tracer), WithDBType("postgresql"), WithDBInstance("orders"))
+		parent := tracer.StartSpan("parent")
+		ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+		if _, err := db.ExecContext(ctx, "DELETE FROM orders", 1); err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		if err := db.QueryRowContext(ctx, "SELECT 1").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+
+		var execs, queries int
+		for _, span := range tracer.FinishedSpans() {
+			switch span.OperationName {
+			case "sql.exec":
+				execs++
+				if got := span.Tag("db.statement"); got != "DELETE FROM orders" {
+					t.Errorf("direct=%v: db.statement = %v", direct, got)
+				}
+			case "sql.query":
+				queries++
+			case "sql.prepare":
+				if direct {
+					t.Error("a statement was prepared although the connection runs them directly")
+				}
+				continue
+			default:
+				t.Errorf("direct=%v: unexpected span %q", direct, span.OperationName)
+			}
+			if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+				t.Errorf("direct=%v: %s is not a child of the context span", direct, span.OperationName)
+			}
+			if span.Tag("db.type") != "postgresql" || span.Tag("db.instance") != "orders" {
+				t.Errorf("direct=%v: db tags = %v, %v", direct, span.Tag("db.type"), span.Tag("db.instance"))
+			}
+		}
+		if execs != 1 || queries != 1 {
+			t.Errorf("direct=%v: got spans %v, want one exec and one query", direct, operations(tracer))
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, direct := range []bool{true, false} {
+		tracer := mocktracer.New()
+		db := openDB(t, fakeDriver{direct: direct}, WithTracer(tracer))
+		if _, err := db.ExecContext(context.Background(), "bad"); !errors.Is(err, errBadQuery) {
+			t.Fatalf("direct=%v: err = %v, want %v", direct, err, errBadQuery)
+		}
+		spans := tracer.FinishedSpans()
+		if len(spans) != 1 {
+			t.Fatalf("direct=%v: got spans %v, want 1", direct, operations(tracer))
+		}
+		if spans[0].Tag("error") != true {
+			t.Errorf("direct=%v: %s is not tagged as an error", direct, spans[0].OperationName)
+		}
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	tracer := mocktracer.New()
+	db := openDB(t, fakeDriver{direct: true}, WithTracer(tracer))
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("UPDATE orders SET paid = true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"sql.begin", "sql.exec", "sql.commit"}
+	if got := operations(tracer); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("spans = %v, want %v", got, want)
+	}
+}
+
+func TestStatementSanitizer(t *testing.T) {
+	tracer := mocktracer.New()
+	db := openDB(t, fakeDriver{direct: true}, WithTracer(tracer), WithStatementSanitizer(func(string) string {
+		return "sanitized"
+	}))
+	db.Exec("SELECT * FROM users WHERE email = 'a@example.com'")
+	if got := tracer.FinishedSpans()[0].Tag("db.statement"); got != "sanitized" {
+		t.Errorf("db.statement = %v, want %q", got, "sanitized")
+	}
+}
+
+func TestDefaultStatementSanitizer(t *testing.T) {
+	query := "SELECT * FROM users WHERE email = 'a@example.com'"
+	for _, raw := range []bool{false, true} {
+		tracer := mocktracer.New()
+		opts := []Option{WithTracer(tracer)}
+		want := "SELECT * FROM users WHERE email = ?"
+		if raw {
+			opts = append(opts, WithRawStatements())
+			want = query
+		}
+		db := openDB(t, fakeDriver{direct: true}, opts...)
+		db.Exec(query)
+		if got := tracer.FinishedSpans()[0].Tag("db.statement"); got != want {
+			t.Errorf("raw=%v: db.statement = %v, want %q", raw, got, want)
+		}
+	}
+}
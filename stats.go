@@ -0,0 +1,70 @@
+package opentracing_helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// stats holds the counters reported by ReadStats.
+var stats struct {
+	spansStarted       atomic.Int64
+	spansFinished      atomic.Int64
+	extractionFailures atomic.Int64
+	droppedLogs        atomic.Int64
+}
+
+// Stats reports on the instrumentation itself, for example to find
+// spans that are never finished or requests with malformed span contexts.
+// Spans are counted when wrapped in a SafeSpan, which includes all server
+// and client spans of TraceHandler, TracedTransport and TraceRequest.
+type Stats struct {
+	SpansStarted  int64 `json:"spans_started"`
+	SpansFinished int64 `json:"spans_finished"`
+	ActiveSpans   int64 `json:"active_spans"`
+
+	// ExtractionFailures counts incoming span contexts that were present
+	// but couldn't be extracted, such as corrupted headers.
+	ExtractionFailures int64 `json:"extraction_failures"`
+
+	// DroppedLogs counts the log records dropped by WithLogBudget.
+	DroppedLogs int64 `json:"dropped_logs"`
+}
+
+// ReadStats returns the counters accumulated since the process started.
+// It can be published with expvar:
+//
+//	expvar.Publish("tracing", expvar.Func(func() any { return opentracing_helpers.ReadStats() }))
+func ReadStats() Stats {
+	// Read finished first so that active spans are never negative.
+	finished := stats.spansFinished.Load()
+	started := stats.spansStarted.Load()
+	return Stats{
+		SpansStarted:       started,
+		SpansFinished:      finished,
+		ActiveSpans:        started - finished,
+		ExtractionFailures: stats.extractionFailures.Load(),
+		DroppedLogs:        stats.droppedLogs.Load(),
+	}
+}
+
+// StatsHandler returns a handler serving ReadStats as JSON:
+//
+//	http.Handle("/debug/tracing", opentracing_helpers.StatsHandler())
+func StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ReadStats())
+	})
+}
+
+// countExtraction records a failure of tracer.Extract other than a missing
+// span context.
+func countExtraction(err error) {
+	if err != nil && !errors.Is(err, opentracing.ErrSpanContextNotFound) {
+		stats.extractionFailures.Add(1)
+	}
+}
@@ -0,0 +1,77 @@
+package opentracing_helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// corruptTracer fails to extract every span context as corrupted.
+type corruptTracer struct {
+	*mocktracer.MockTracer
+}
+
+func (corruptTracer) Extract(format, carrier interface{}) (opentracing.SpanContext, error) {
+	return nil, opentracing.ErrSpanContextCorrupted
+}
+
+func TestReadStats(t *testing.T) {
+	before := ReadStats()
+
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", okHandler, WithTracer(corruptTracer{tracer}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	_, h = TraceHandler("/", okHandler, WithTracer(tracer))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	active := NewSafeSpan(tracer.StartSpan("active"))
+	active.logBudget = 1
+	active.LogFields(log.Int("i", 0), log.Int("i", 1))
+	active.LogFields(log.Int("i", 2))
+	active.LogFields(log.Int("i", 3))
+
+	during := ReadStats()
+	if got := during.SpansStarted - before.SpansStarted; got != 3 {
+		t.Errorf("%d more spans started, want 3", got)
+	}
+	if got := during.ActiveSpans - before.ActiveSpans; got != 1 {
+		t.Errorf("%d more active spans, want 1", got)
+	}
+	if got := during.ExtractionFailures - before.ExtractionFailures; got != 1 {
+		t.Errorf("%d more extraction failures, want 1; a missing context isn't a failure", got)
+	}
+
+	active.Finish()
+	after := ReadStats()
+	if after.ActiveSpans != before.ActiveSpans {
+		t.Errorf("%d active spans after finishing, want %d", after.ActiveSpans, before.ActiveSpans)
+	}
+	if got := after.DroppedLogs - before.DroppedLogs; got != 2 {
+		t.Errorf("%d more dropped logs, want 2", got)
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	StatsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/tracing", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var got map[string]int64
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"spans_started", "spans_finished", "active_spans", "extraction_failures", "dropped_logs"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("no %q in %v", key, got)
+		}
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("served %d", w.Code)
+	}
+}
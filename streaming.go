@@ -0,0 +1,82 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// WithStreaming traces long-lived streaming responses such as Server-Sent
+// Events. Every http.Flusher flush is logged on the span along with the
+// number of bytes written so far, and if the client disconnects the span
+// ends at the time it did rather than when the handler notices and
+// returns. The span is still finished once the handler returns, with the
+// status and size of the response, unless the handler is still running
+// abandonedStreamTimeout after the client went away.
+func WithStreaming() HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.streaming = true
+	})
+}
+
+// abandonedStreamTimeout is how long a streaming handler may keep running
+// after the client disconnected before its span is finished without it.
+var abandonedStreamTimeout = 10 * time.Second
+
+// watchContext logs on sr's span when ctx, the request context, is done
+// while the handler is still running, which usually means the client went
+// away: the span is tagged with client.disconnected=true and the
+// cancellation cause and elapsed time are logged. In streaming mode it also
+// logs flushes and records the time ctx was done in sr.disconnected. The
+// returned function must be called when the handler returns.
+func (c *handlerConfig) watchContext(ctx context.Context, sr *serverRequest) (stop func()) {
+	span := sr.span
+	if c.streaming {
+		sr.rr.onFlush = func(size int64) {
+			span.LogFields(log.String("event", "flush"), log.Int64("http.response_size", size))
+		}
+	}
+	var abandoned *time.Timer
+	fired := make(chan struct{})
+	stopWatching := context.AfterFunc(ctx, func() {
+		defer close(fired)
+		event := "context done"
+		if errors.Is(ctx.Err(), context.Canceled) {
+			event = "client disconnected"
+			span.SetTag("client.disconnected", true)
+		}
+		span.LogFields(
+			log.String("event", event),
+			log.Error(context.Cause(ctx)),
+			log.String("elapsed", time.Since(sr.start).String()),
+		)
+		if c.streaming {
+			at := time.Now()
+			sr.disconnected = at
+			abandoned = time.AfterFunc(abandonedStreamTimeout, func() {
+				span.FinishWithOptions(opentracing.FinishOptions{FinishTime: at})
+			})
+		}
+	})
+	return func() {
+		if stopWatching() {
+			return
+		}
+		// Wait for the function to return before reading what it set.
+		<-fired
+		if abandoned != nil {
+			abandoned.Stop()
+		}
+	}
+}
+
+// finishSpan finishes the span of sr at the time the client disconnected
+// from a streaming response. Other spans are finished by ServeHTTP.
+func (sr *serverRequest) finishSpan() {
+	if !sr.disconnected.IsZero() {
+		sr.span.FinishWithOptions(opentracing.FinishOptions{FinishTime: sr.disconnected})
+	}
+}
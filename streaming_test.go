@@ -0,0 +1,173 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestWithStreaming(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/events", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 2; i++ {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}), WithTracer(tracer), WithStreaming())
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	span := finishedSpan(t, tracer)
+	var sizes []string
+	for _, l := range span.Logs() {
+		if l.Fields[0].ValueString == "flush" {
+			sizes = append(sizes, l.Fields[1].ValueString)
+		}
+	}
+	if want := []string{"9", "18"}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("flushes logged with sizes %q, want %q", sizes, want)
+	}
+	if got := span.Tag("client.disconnected"); got != nil {
+		t.Errorf("client.disconnected = %v on a completed stream", got)
+	}
+}
+
+func TestWithStreamingStatusAndMetrics(t *testing.T) {
+	tracer := mocktracer.New()
+	metrics := &metricsRecorder{}
+	_, h := TraceHandler("/events", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, "data: 0\n\n")
+		w.(http.Flusher).Flush()
+	}), WithTracer(tracer), WithStreaming(), WithMetricsObserver(metrics))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	span := finishedSpan(t, tracer)
+	if span.Tag("http.status_code") != uint16(http.StatusAccepted) || span.Tag("http.response_size") != int64(9) {
+		t.Errorf("flushed response span tagged %v", span.Tags())
+	}
+	if m := metrics.only(t); m.StatusCode != http.StatusAccepted || m.Error {
+		t.Errorf("observed %+v for the flushed response", m)
+	}
+}
+
+// disconnectingStream serves a streaming response that flushes, waits for
+// the client to disconnect and then for done to be closed. It returns the
+// channel closed once ServeHTTP returned.
+func disconnectingStream(t *testing.T, tracer *mocktracer.MockTracer, done chan struct{}) (disconnected time.Time, served chan struct{}) {
+	t.Helper()
+	flushed := make(chan struct{})
+	_, h := TraceHandler("/events", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		close(flushed)
+		<-r.Context().Done()
+		<-done
+	}), WithTracer(tracer), WithStreaming())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	served = make(chan struct{})
+	go func() {
+		defer close(served)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}()
+	<-flushed
+	disconnected = time.Now()
+	cancel()
+	return disconnected, served
+}
+
+func TestWithStreamingClientDisconnects(t *testing.T) {
+	tracer := mocktracer.New()
+	done := make(chan struct{})
+	disconnected, served := disconnectingStream(t, tracer, done)
+
+	time.Sleep(10 * time.Millisecond)
+	if n := len(tracer.FinishedSpans()); n != 0 {
+		t.Fatalf("%d spans finished before the handler returned", n)
+	}
+	returned := time.Now()
+	close(done)
+	<-served
+
+	span := finishedSpan(t, tracer)
+	if span.FinishTime.Before(disconnected) || !span.FinishTime.Before(returned) {
+		t.Errorf("span finished at %v, want when the client disconnected at %v", span.FinishTime, disconnected)
+	}
+	if span.Tag("client.disconnected") != true || span.Tag("http.status_code") != uint16(http.StatusOK) {
+		t.Errorf("span tagged %v", span.Tags())
+	}
+	if events := loggedEvents(span); !reflect.DeepEqual(events, []string{"flush", "client disconnected"}) {
+		t.Errorf("logged events %q", events)
+	}
+}
+
+func TestWithStreamingAbandoned(t *testing.T) {
+	defer func(d time.Duration) { abandonedStreamTimeout = d }(abandonedStreamTimeout)
+	abandonedStreamTimeout = time.Millisecond
+	tracer := mocktracer.New()
+	done := make(chan struct{})
+	_, served := disconnectingStream(t, tracer, done)
+
+	// The span finishes while the handler is still running.
+	span := waitForSpan(t, tracer)
+	close(done)
+	<-served
+	if span.Tag("client.disconnected") != true {
+		t.Errorf("client.disconnected = %v, want true", span.Tag("client.disconnected"))
+	}
+	if n := len(tracer.FinishedSpans()); n != 1 {
+		t.Errorf("span finished %d times, want 1", n)
+	}
+}
+
+func TestTraceHandlerLogsContextDone(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          func() (context.Context, context.CancelFunc)
+		event        string
+		disconnected interface{}
+	}{
+		{"canceled", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(time.Millisecond, cancel)
+			return ctx, cancel
+		}, "client disconnected", true},
+		{"deadline", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), time.Millisecond)
+		}, "context done", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			_, h := TraceHandler("/slow", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+				// Without streaming the span stays open until the
+				// handler returns.
+				time.Sleep(10 * time.Millisecond)
+				if n := len(tracer.FinishedSpans()); n != 0 {
+					t.Errorf("%d spans finished before the handler returned", n)
+				}
+			}), WithTracer(tracer))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil).WithContext(ctx))
+
+			span := finishedSpan(t, tracer)
+			if events := loggedEvents(span); !reflect.DeepEqual(events, []string{tt.event}) {
+				t.Errorf("logged events %q, want %q", events, tt.event)
+			}
+			if _, ok := loggedFields(span)["elapsed"]; !ok {
+				t.Error("the elapsed time is not logged")
+			}
+			if got := span.Tag("client.disconnected"); got != tt.disconnected {
+				t.Errorf("client.disconnected = %v, want %v", got, tt.disconnected)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package opentracing_helpers
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// Tag builds the tags of common application attributes, so that their
+// keys are the same across a codebase:
+//
+//	opentracing_helpers.SetTags(ctx,
+//		opentracing_helpers.Tag.User(user.ID),
+//		opentracing_helpers.Tag.Tenant(tenant),
+//		opentracing_helpers.Tag.Feature("new-checkout"),
+//	)
+var Tag TagBuilder
+
+// TagBuilder is the type of Tag.
+type TagBuilder struct{}
+
+// User returns the user.id tag.
+func (TagBuilder) User(id string) opentracing.Tag {
+	return opentracing.Tag{Key: "user.id", Value: id}
+}
+
+// Tenant returns the tenant.id tag.
+func (TagBuilder) Tenant(id string) opentracing.Tag {
+	return opentracing.Tag{Key: "tenant.id", Value: id}
+}
+
+// Feature returns the feature.<flag>=true tag, for a feature flag
+// enabled for the request. Every flag gets its own key, so that several
+// can be set and searched for.
+func (TagBuilder) Feature(flag string) opentracing.Tag {
+	return opentracing.Tag{Key: "feature." + flag, Value: true}
+}
+
+// SetTags sets tags on the span in ctx. It does nothing if ctx carries no
+// span.
+func SetTags(ctx context.Context, tags ...opentracing.Tag) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	for _, tag := range tags {
+		tag.Set(span)
+	}
+}
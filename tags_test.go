@@ -0,0 +1,31 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestSetTags(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("checkout")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	SetTags(ctx, Tag.User("u1"), Tag.Tenant("acme"), Tag.Feature("new-checkout"), Tag.Feature("dark-mode"))
+	span.Finish()
+
+	want := map[string]interface{}{
+		"user.id":              "u1",
+		"tenant.id":            "acme",
+		"feature.new-checkout": true,
+		"feature.dark-mode":    true,
+	}
+	if got := finishedSpan(t, tracer).Tags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("tags %v, want %v", got, want)
+	}
+
+	// Without a span, SetTags does nothing.
+	SetTags(context.Background(), Tag.User("u2"))
+}
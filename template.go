@@ -0,0 +1,48 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"html/template"
+	"io"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// TraceTemplate executes the template of t called name with data, writing
+// the output to w, within a child of the span in ctx. Server-side rendering
+// often dominates latency, and the span shows how much. An empty name
+// executes t itself. The span is tagged with template.name and the number
+// of bytes rendered, template.size:
+//
+//	err := opentracing_helpers.TraceTemplate(r.Context(), "order.html", tmpl, w, order)
+func TraceTemplate(ctx context.Context, name string, t *template.Template, w io.Writer, data any) error {
+	if name == "" {
+		name = t.Name()
+	}
+	span, _ := opentracing.StartSpanFromContext(ctx, "render "+name)
+	defer span.Finish()
+	span.SetTag("template.name", name)
+
+	cw := &countingWriter{w: w}
+	err := t.ExecuteTemplate(cw, name, data)
+	span.SetTag("template.size", cw.n)
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+	return err
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,56 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestTraceTemplate(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	tmpl := template.Must(template.New("page").Parse(`{{define "order.html"}}<p>{{.}}</p>{{end}}`))
+	parent := tracer.StartSpan("request")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	var out strings.Builder
+	if err := TraceTemplate(ctx, "order.html", tmpl, &out, "a&b"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "<p>a&amp;b</p>"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	span := finishedSpan(t, tracer)
+	if span.OperationName != "render order.html" || span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Errorf("span %q with parent %d, want render order.html under the request", span.OperationName, span.ParentID)
+	}
+	if span.Tag("template.name") != "order.html" || span.Tag("template.size") != int64(out.Len()) {
+		t.Errorf("tags = %v", span.Tags())
+	}
+}
+
+func TestTraceTemplateError(t *testing.T) {
+	tracer := mocktracer.New()
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(tracer)
+
+	tmpl := template.Must(template.New("page").Parse(`ok {{.Missing}}`))
+	if err := TraceTemplate(context.Background(), "", tmpl, &strings.Builder{}, 42); err == nil {
+		t.Fatal("TraceTemplate succeeded, want the execution error")
+	}
+
+	span := finishedSpan(t, tracer)
+	if span.OperationName != "render page" || span.Tag("error") != true {
+		t.Errorf("span %q with error = %v, want render page tagged as an error", span.OperationName, span.Tag("error"))
+	}
+	if got := span.Tag("template.size"); got != int64(3) {
+		t.Errorf("template.size = %v, want the 3 bytes rendered before the error", got)
+	}
+}
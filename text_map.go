@@ -0,0 +1,30 @@
+package opentracing_helpers
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// InjectIntoMap injects the context of the span in ctx into m with the
+// TextMap format, for custom message envelopes carrying a metadata map.
+// m is left untouched if ctx carries no span, and must not be nil:
+//
+//	msg.Headers = map[string]string{}
+//	err := opentracing_helpers.InjectIntoMap(ctx, msg.Headers)
+func InjectIntoMap(ctx context.Context, m map[string]string) error {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+	return span.Tracer().Inject(span.Context(), opentracing.TextMap, opentracing.TextMapCarrier(m))
+}
+
+// ExtractFromMap extracts a span context injected by InjectIntoMap, with
+// the global tracer:
+//
+//	sc, err := opentracing_helpers.ExtractFromMap(msg.Headers)
+//	span := opentracing.StartSpan("process", opentracing.FollowsFrom(sc))
+func ExtractFromMap(m map[string]string) (opentracing.SpanContext, error) {
+	return opentracing.GlobalTracer().Extract(opentracing.TextMap, opentracing.TextMapCarrier(m))
+}
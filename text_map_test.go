@@ -0,0 +1,39 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestInjectIntoMap(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	span := tracer.StartSpan("publish")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	headers := map[string]string{"content-type": "application/json"}
+	if err := InjectIntoMap(ctx, headers); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := ExtractFromMap(headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.(mocktracer.MockSpanContext).SpanID != span.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("extracted a different span context")
+	}
+	if headers["content-type"] != "application/json" {
+		t.Error("existing entries were changed")
+	}
+}
+
+func TestInjectIntoMapWithoutSpan(t *testing.T) {
+	headers := map[string]string{}
+	if err := InjectIntoMap(context.Background(), headers); err != nil || len(headers) != 0 {
+		t.Errorf("InjectIntoMap without a span = %v with %v, want nil and no entries", err, headers)
+	}
+}
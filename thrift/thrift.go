@@ -0,0 +1,76 @@
+// Package thrift propagates span contexts in the headers of Apache Thrift's
+// THeader protocol, which the generated clients and processors carry in
+// the call context:
+//
+//	// client
+//	ctx = otthrift.Inject(ctx, span)
+//	resp, err := client.Call(ctx, req)
+//
+//	// handler
+//	sc, _ := otthrift.Extract(ctx, opentracing.GlobalTracer())
+//	span := opentracing.StartSpan("Call", ext.RPCServerOption(sc))
+package thrift
+
+import (
+	"context"
+	"slices"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/opentracing/opentracing-go"
+)
+
+// HeaderCarrier satisfies both opentracing.TextMapWriter and
+// opentracing.TextMapReader for the THeader headers of a context. Set
+// adds write headers, sent with the next call made with Context, while
+// ForeachKey iterates over the headers read from the incoming call.
+type HeaderCarrier struct {
+	ctx context.Context
+}
+
+// NewHeaderCarrier returns a HeaderCarrier over the headers of ctx.
+func NewHeaderCarrier(ctx context.Context) *HeaderCarrier {
+	return &HeaderCarrier{ctx: ctx}
+}
+
+// Context returns the context with the headers added by Set.
+func (c *HeaderCarrier) Context() context.Context {
+	return c.ctx
+}
+
+// Set implements opentracing.TextMapWriter.
+func (c *HeaderCarrier) Set(key, val string) {
+	c.ctx = thrift.SetHeader(c.ctx, key, val)
+	if keys := thrift.GetWriteHeaderList(c.ctx); !slices.Contains(keys, key) {
+		// Clip so that the slice of a parent context is never appended to.
+		c.ctx = thrift.SetWriteHeaderList(c.ctx, append(slices.Clip(keys), key))
+	}
+}
+
+// ForeachKey implements opentracing.TextMapReader.
+func (c *HeaderCarrier) ForeachKey(handler func(key, val string) error) error {
+	for _, key := range thrift.GetReadHeaderList(c.ctx) {
+		val, ok := thrift.GetHeader(c.ctx, key)
+		if !ok {
+			continue
+		}
+		if err := handler(key, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Inject returns ctx with the context of span added to the THeader write
+// headers, or ctx unchanged if the tracer fails to inject it.
+func Inject(ctx context.Context, span opentracing.Span) context.Context {
+	carrier := NewHeaderCarrier(ctx)
+	if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+		return ctx
+	}
+	return carrier.Context()
+}
+
+// Extract returns the span context in the THeader read headers of ctx.
+func Extract(ctx context.Context, tracer opentracing.Tracer) (opentracing.SpanContext, error) {
+	return tracer.Extract(opentracing.TextMap, NewHeaderCarrier(ctx))
+}
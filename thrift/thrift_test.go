@@ -0,0 +1,68 @@
+package thrift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// received returns ctx as a THeader processor would see the write headers
+// of ctx sent by a client.
+func received(ctx context.Context) context.Context {
+	server := context.Background()
+	var keys []string
+	for _, key := range thrift.GetWriteHeaderList(ctx) {
+		if val, ok := thrift.GetHeader(ctx, key); ok {
+			server = thrift.SetHeader(server, key, val)
+			keys = append(keys, key)
+		}
+	}
+	return thrift.SetReadHeaderList(server, keys)
+}
+
+func TestInjectExtract(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("Call")
+	ctx := Inject(context.Background(), span)
+	if len(thrift.GetWriteHeaderList(ctx)) == 0 {
+		t.Fatal("no write headers added")
+	}
+
+	sc, err := Extract(received(ctx), tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.(mocktracer.MockSpanContext).SpanID != span.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("extracted a different span context")
+	}
+}
+
+func TestExtractWithoutHeaders(t *testing.T) {
+	tracer := mocktracer.New()
+	if _, err := Extract(context.Background(), tracer); err != opentracing.ErrSpanContextNotFound {
+		t.Errorf("Extract returned %v, want ErrSpanContextNotFound", err)
+	}
+}
+
+func TestHeaderCarrierKeepsParentList(t *testing.T) {
+	parent := thrift.SetWriteHeaderList(context.Background(), make([]string, 1, 4))
+	first := NewHeaderCarrier(parent)
+	first.Set("a", "1")
+	second := NewHeaderCarrier(parent)
+	second.Set("b", "2")
+
+	if got := thrift.GetWriteHeaderList(first.Context()); got[1] != "a" {
+		t.Errorf("first carrier's headers %q were overwritten", got)
+	}
+	if got := thrift.GetWriteHeaderList(parent); len(got) != 1 {
+		t.Errorf("parent headers changed to %q", got)
+	}
+	// Setting an existing key doesn't duplicate it.
+	first.Set("a", "3")
+	if got := thrift.GetWriteHeaderList(first.Context()); len(got) != 2 {
+		t.Errorf("headers %q, want a single a", got)
+	}
+}
@@ -0,0 +1,43 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// ContextWithTracedTimeout is like context.WithTimeout, and also records
+// the timeout and the resulting deadline, which may be earlier if ctx
+// already had one, on the span in ctx. If the deadline expires before
+// cancel is called, a deadline_exceeded event is logged on the span with
+// the elapsed time, which helps diagnose cascading timeouts:
+//
+//	ctx, cancel := opentracing_helpers.ContextWithTracedTimeout(ctx, 2*time.Second)
+//	defer cancel()
+func ContextWithTracedTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, d)
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return ctx, cancel
+	}
+
+	deadline, _ := ctx.Deadline()
+	span.SetTag("timeout", d.String())
+	span.SetTag("deadline", deadline.Format(time.RFC3339Nano))
+	stop := context.AfterFunc(ctx, func() {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			span.LogFields(
+				log.String("event", "deadline_exceeded"),
+				log.String("elapsed", time.Since(start).String()),
+			)
+		}
+	})
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,65 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestContextWithTracedTimeout(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("call")
+	ctx, cancel := ContextWithTracedTimeout(opentracing.ContextWithSpan(context.Background(), span), time.Millisecond)
+	<-ctx.Done()
+	// The event is logged by a goroutine once the deadline expires.
+	for deadline := time.Now().Add(5 * time.Second); len(span.(*mocktracer.MockSpan).Logs()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	span.Finish()
+
+	got := finishedSpan(t, tracer)
+	if got.Tag("timeout") != "1ms" {
+		t.Errorf("timeout = %v, want 1ms", got.Tag("timeout"))
+	}
+	deadline, _ := ctx.Deadline()
+	if got.Tag("deadline") != deadline.Format(time.RFC3339Nano) {
+		t.Errorf("deadline = %v, want %s", got.Tag("deadline"), deadline.Format(time.RFC3339Nano))
+	}
+	if events := loggedEvents(got); len(events) != 1 || events[0] != "deadline_exceeded" {
+		t.Errorf("logged events %q, want deadline_exceeded", events)
+	}
+}
+
+func TestContextWithTracedTimeoutCanceled(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("call")
+	parent, cancelParent := context.WithTimeout(opentracing.ContextWithSpan(context.Background(), span), time.Hour)
+	defer cancelParent()
+	ctx, cancel := ContextWithTracedTimeout(parent, 2*time.Hour)
+	cancel()
+	span.Finish()
+
+	got := finishedSpan(t, tracer)
+	parentDeadline, _ := parent.Deadline()
+	if got.Tag("deadline") != parentDeadline.Format(time.RFC3339Nano) {
+		t.Errorf("deadline = %v, want the earlier one of the parent context", got.Tag("deadline"))
+	}
+	if events := loggedEvents(got); len(events) != 0 {
+		t.Errorf("logged events %q after cancel, want none", events)
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("ctx.Err() = %v, want %v", ctx.Err(), context.Canceled)
+	}
+}
+
+func TestContextWithTracedTimeoutWithoutSpan(t *testing.T) {
+	ctx, cancel := ContextWithTracedTimeout(context.Background(), time.Hour)
+	defer cancel()
+	if _, ok := ctx.Deadline(); !ok {
+		t.Error("the context has no deadline")
+	}
+}
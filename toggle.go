@@ -0,0 +1,77 @@
+package opentracing_helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// Toggle turns the instrumentation of this package on and off at runtime.
+// While it is off, handlers and transports pass requests through without
+// starting spans, collecting metrics or logging, as cheaply as if they
+// weren't wrapped. It is on by default:
+//
+//	opentracing_helpers.Toggle.Disable()
+//
+// Unlike WithDisabled, it applies to everything at once.
+var Toggle Switch
+
+// Switch is an on/off switch safe for concurrent use. The zero value is
+// on.
+type Switch struct {
+	off atomic.Bool
+}
+
+// Enable turns s on.
+func (s *Switch) Enable() {
+	s.off.Store(false)
+}
+
+// Disable turns s off.
+func (s *Switch) Disable() {
+	s.off.Store(true)
+}
+
+// Set turns s on or off.
+func (s *Switch) Set(enabled bool) {
+	s.off.Store(!enabled)
+}
+
+// Enabled reports whether s is on.
+func (s *Switch) Enabled() bool {
+	return !s.off.Load()
+}
+
+// Handler returns an admin endpoint reporting the state of s as JSON, such
+// as {"enabled":true}, on GET, and changing it on POST or PUT with an
+// enabled form value:
+//
+//	http.Handle("/admin/tracing", opentracing_helpers.Toggle.Handler())
+//
+//	curl -X POST -d enabled=false localhost:8080/admin/tracing
+//
+// It performs no authentication, so it must only be served on an
+// internal or otherwise protected listener.
+func (s *Switch) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost, http.MethodPut:
+			enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+			if err != nil {
+				http.Error(w, "enabled must be true or false", http.StatusBadRequest)
+				return
+			}
+			s.Set(enabled)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Enabled bool `json:"enabled"`
+		}{s.Enabled()})
+	})
+}
@@ -0,0 +1,64 @@
+package opentracing_helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestToggle(t *testing.T) {
+	defer Toggle.Enable()
+	tracer := mocktracer.New()
+	handler := &countingHandler{}
+	_, h := TraceHandler("/", handler, WithTracer(tracer), WithMetricsObserver(&metricsRecorder{}))
+	client := &http.Client{Transport: NewTracedTransport(respond(http.StatusOK, "", nil), WithTracer(tracer))}
+
+	Toggle.Disable()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	resp, err := client.Get("http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := len(tracer.FinishedSpans()); n != 0 || handler.requests != 1 {
+		t.Fatalf("%d spans and %d requests while off, want 0 and 1", n, handler.requests)
+	}
+
+	Toggle.Enable()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	finishedSpan(t, tracer)
+}
+
+func TestSwitchHandler(t *testing.T) {
+	var s Switch
+	h := s.Handler()
+	for _, test := range []struct {
+		method, body string
+		status       int
+		response     string
+		enabled      bool
+	}{
+		{http.MethodGet, "", http.StatusOK, `{"enabled":true}`, true},
+		{http.MethodPost, "enabled=false", http.StatusOK, `{"enabled":false}`, false},
+		{http.MethodPut, "enabled=maybe", http.StatusBadRequest, "", false},
+		{http.MethodDelete, "", http.StatusMethodNotAllowed, "", false},
+		{http.MethodPut, "enabled=true", http.StatusOK, `{"enabled":true}`, true},
+	} {
+		r := httptest.NewRequest(test.method, "/admin/tracing", strings.NewReader(test.body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s %q: status %d, want %d", test.method, test.body, w.Code, test.status)
+		}
+		if test.response != "" && strings.TrimSpace(w.Body.String()) != test.response {
+			t.Errorf("%s %q: response %q, want %q", test.method, test.body, w.Body, test.response)
+		}
+		if s.Enabled() != test.enabled {
+			t.Errorf("%s %q: enabled = %t, want %t", test.method, test.body, s.Enabled(), test.enabled)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// SpanIdentifiersFromContext returns the identifiers of the span in ctx,
+// for example to correlate logs with traces. If codec is nil the span
+// context is injected with the tracer's native format and the IDs are read
+// from the Jaeger, B3, W3C or basictracer headers it produced. It returns
+// false if ctx carries no span or its IDs can't be determined.
+func SpanIdentifiersFromContext(ctx context.Context, codec SpanContextCodec) (SpanIdentifiers, bool) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return SpanIdentifiers{}, false
+	}
+	if codec != nil {
+		return codec.Identifiers(span.Context())
+	}
+	return nativeIdentifiers(span.Tracer(), span.Context())
+}
+
+// nativeIdentifiers extracts the IDs of sc from the headers written by
+// tracer for the well-known formats.
+func nativeIdentifiers(tracer opentracing.Tracer, sc opentracing.SpanContext) (SpanIdentifiers, bool) {
+	carrier := opentracing.TextMapCarrier{}
+	if err := tracer.Inject(sc, opentracing.TextMap, carrier); err != nil {
+		return SpanIdentifiers{}, false
+	}
+	h := make(map[string]string, len(carrier))
+	for k, v := range carrier {
+		h[strings.ToLower(k)] = v
+	}
+	b3 := func(key string) string { return h[strings.ToLower(key)] }
+
+	var ids SpanIdentifiers
+	switch {
+	case h["uber-trace-id"] != "":
+		parts := strings.Split(h["uber-trace-id"], ":")
+		if len(parts) != 4 {
+			return SpanIdentifiers{}, false
+		}
+		flags, _ := strconv.ParseUint(parts[3], 16, 8)
+		ids = SpanIdentifiers{
+			TraceID: padID(parts[0]),
+			SpanID:  padID(parts[1]),
+			Sampled: flags&1 != 0,
+			Debug:   flags&2 != 0,
+		}
+		if parts[2] != "0" {
+			ids.ParentSpanID = padID(parts[2])
+		}
+	case h["traceparent"] != "":
+		parts := strings.Split(h["traceparent"], "-")
+		if len(parts) < 4 {
+			return SpanIdentifiers{}, false
+		}
+		flags, _ := strconv.ParseUint(parts[3], 16, 8)
+		ids = SpanIdentifiers{
+			TraceID:    parts[1],
+			SpanID:     parts[2],
+			Sampled:    flags&1 != 0,
+			TraceState: h["tracestate"],
+		}
+	case b3(b3TraceIDHeader) != "":
+		ids = SpanIdentifiers{
+			TraceID:      b3(b3TraceIDHeader),
+			SpanID:       b3(b3SpanIDHeader),
+			ParentSpanID: b3(b3ParentIDHeader),
+			Sampled:      b3(b3SampledHeader) == "1" || b3(b3SampledHeader) == "true",
+			Debug:        b3(b3FlagsHeader) == "1",
+		}
+	case h["ot-tracer-traceid"] != "":
+		ids = SpanIdentifiers{
+			TraceID: padID(h["ot-tracer-traceid"]),
+			SpanID:  padID(h["ot-tracer-spanid"]),
+			Sampled: h["ot-tracer-sampled"] == "true",
+		}
+	default:
+		return SpanIdentifiers{}, false
+	}
+	if ids.TraceID == "" || ids.SpanID == "" {
+		return SpanIdentifiers{}, false
+	}
+	return ids, true
+}
+
+// padID left-pads a hex ID whose leading zeros were dropped to 16 or 32
+// characters.
+func padID(id string) string {
+	id = strings.ToLower(id)
+	switch {
+	case len(id) < 16:
+		return strings.Repeat("0", 16-len(id)) + id
+	case len(id) > 16 && len(id) < 32:
+		return strings.Repeat("0", 32-len(id)) + id
+	}
+	return id
+}
+
+// WithSpanContextCodec reads trace and span IDs with codec, for options
+// such as WithTraceIDResponseHeader. Without it the IDs are taken from the
+// headers written by the tracer's native propagation format, which works
+// for Jaeger, Zipkin (B3), W3C and basictracer-based tracers.
+func WithSpanContextCodec(codec SpanContextCodec) Option {
+	return commonOption(func(c *commonConfig) {
+		c.codec = codec
+	})
+}
+
+// WithTraceIDResponseHeader makes TraceHandler write the trace ID of the
+// server span to the named response header, for example "X-Trace-Id", so
+// that clients can quote it when reporting a problem.
+func WithTraceIDResponseHeader(header string) HandlerOption {
+	return handlerOption(func(c *handlerConfig) {
+		c.traceIDHeader = header
+	})
+}
+
+// writeTraceIDHeader sets the trace ID response header, if configured.
+// r must carry the server span.
+func (c *handlerConfig) writeTraceIDHeader(w http.ResponseWriter, r *http.Request) {
+	if c.traceIDHeader == "" {
+		return
+	}
+	if ids, ok := SpanIdentifiersFromContext(r.Context(), c.codec); ok {
+		w.Header().Set(c.traceIDHeader, ids.TraceID)
+	}
+}
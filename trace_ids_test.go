@@ -0,0 +1,118 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// headerTracer is a tracer whose native format is the given headers.
+type headerTracer struct {
+	opentracing.NoopTracer
+	headers map[string]string
+}
+
+func (t headerTracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	w := carrier.(opentracing.TextMapWriter)
+	for k, v := range t.headers {
+		w.Set(k, v)
+	}
+	return nil
+}
+
+func TestNativeIdentifiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    SpanIdentifiers
+		ok      bool
+	}{
+		{
+			name:    "Jaeger",
+			headers: map[string]string{"uber-trace-id": "a3ce929d0e0e4736:F067AA0BA902B7:a3ce929d0e0e4736:3"},
+			want:    SpanIdentifiers{TraceID: "a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", ParentSpanID: "a3ce929d0e0e4736", Sampled: true, Debug: true},
+			ok:      true,
+		},
+		{
+			name:    "Jaeger root span with a 128-bit trace ID",
+			headers: map[string]string{"uber-trace-id": "4bf92f3577b34da6a3ce929d0e0e4736:f067aa0ba902b7:0:0"},
+			want:    SpanIdentifiers{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+			ok:      true,
+		},
+		{
+			name:    "W3C",
+			headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "tracestate": "congo=t61rcWkgMzE"},
+			want:    SpanIdentifiers{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true, TraceState: "congo=t61rcWkgMzE"},
+			ok:      true,
+		},
+		{
+			name:    "B3",
+			headers: map[string]string{"X-B3-TraceId": "463ac35c9f6413ad", "X-B3-SpanId": "a2fb4a1d1a96d312", "X-B3-Sampled": "1"},
+			want:    SpanIdentifiers{TraceID: "463ac35c9f6413ad", SpanID: "a2fb4a1d1a96d312", Sampled: true},
+			ok:      true,
+		},
+		{
+			name:    "basictracer",
+			headers: map[string]string{"ot-tracer-traceid": "2a", "ot-tracer-spanid": "7", "ot-tracer-sampled": "true"},
+			want:    SpanIdentifiers{TraceID: "000000000000002a", SpanID: "0000000000000007", Sampled: true},
+			ok:      true,
+		},
+		{name: "malformed Jaeger", headers: map[string]string{"uber-trace-id": "a3ce929d0e0e4736"}},
+		{name: "B3 without span ID", headers: map[string]string{"X-B3-TraceId": "463ac35c9f6413ad"}},
+		{name: "unknown format", headers: map[string]string{"mockpfx-ids-traceid": "42"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := nativeIdentifiers(headerTracer{headers: tt.headers}, nil)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("nativeIdentifiers = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSpanIdentifiersFromContext(t *testing.T) {
+	if _, ok := SpanIdentifiersFromContext(context.Background(), nil); ok {
+		t.Error("found identifiers in a context without span")
+	}
+
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("op")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	if _, ok := SpanIdentifiersFromContext(ctx, nil); ok {
+		t.Error("found identifiers for mocktracer's unknown native format")
+	}
+	ids, ok := SpanIdentifiersFromContext(ctx, mockCodec{})
+	msc := span.Context().(mocktracer.MockSpanContext)
+	if want, _ := (mockCodec{}).Identifiers(msc); !ok || ids != want {
+		t.Errorf("SpanIdentifiersFromContext = %+v, %v; want the codec's %+v", ids, ok, want)
+	}
+}
+
+func TestWithTraceIDResponseHeader(t *testing.T) {
+	tracer := mocktracer.New()
+	_, h := TraceHandler("/", okHandler, WithTracer(tracer),
+		WithTraceIDResponseHeader("X-Trace-Id"), WithSpanContextCodec(mockCodec{}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	span := finishedSpan(t, tracer)
+	if got, want := w.Header().Get("X-Trace-Id"), fmt.Sprintf("%016x", span.SpanContext.TraceID); got != want {
+		t.Errorf("X-Trace-Id = %q, want %q", got, want)
+	}
+}
+
+func TestWithTraceIDResponseHeaderUnknownFormat(t *testing.T) {
+	_, h := TraceHandler("/", okHandler, WithTracer(mocktracer.New()), WithTraceIDResponseHeader("X-Trace-Id"))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if _, ok := w.Header()["X-Trace-Id"]; ok {
+		t.Error("the header was set although the trace ID is unknown")
+	}
+}
@@ -0,0 +1,79 @@
+package opentracing_helpers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// TracerBackendEnv is the environment variable read by InitTracer.
+const TracerBackendEnv = "TRACER_BACKEND"
+
+// TracerFactory creates a tracer for a vendor, configured from the
+// environment. The closer flushes buffered spans on shutdown.
+type TracerFactory func(serviceName string) (opentracing.Tracer, io.Closer, error)
+
+var (
+	tracerFactoriesMu sync.RWMutex
+	tracerFactories   = make(map[string]TracerFactory)
+)
+
+// RegisterTracerFactory makes a tracer backend available to InitTracer
+// under name. Backend packages such as lightstep and datadog register
+// themselves when imported, in the manner of database/sql drivers:
+//
+//	import _ "github.com/jfernandez/opentracing-helpers/datadog"
+//
+// It panics if name is registered twice.
+func RegisterTracerFactory(name string, f TracerFactory) {
+	tracerFactoriesMu.Lock()
+	defer tracerFactoriesMu.Unlock()
+	if _, dup := tracerFactories[name]; dup {
+		panic("opentracing_helpers: RegisterTracerFactory called twice for " + name)
+	}
+	tracerFactories[name] = f
+}
+
+// TracerBackends returns the sorted names of the registered backends.
+func TracerBackends() []string {
+	tracerFactoriesMu.RLock()
+	defer tracerFactoriesMu.RUnlock()
+	names := make([]string, 0, len(tracerFactories))
+	for name := range tracerFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InitTracer creates a tracer with the backend named by the
+// TRACER_BACKEND environment variable and sets it as the global tracer,
+// so that the same binary can report to different vendors. If the
+// variable is unset or empty, tracing stays disabled and a no-op tracer is
+// returned.
+func InitTracer(serviceName string) (opentracing.Tracer, io.Closer, error) {
+	name := os.Getenv(TracerBackendEnv)
+	if name == "" {
+		return opentracing.NoopTracer{}, nopCloser{}, nil
+	}
+	tracerFactoriesMu.RLock()
+	f, ok := tracerFactories[name]
+	tracerFactoriesMu.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("opentracing_helpers: unknown tracer backend %q (forgotten import?)", name)
+	}
+	tracer, closer, err := f(serviceName)
+	if err != nil {
+		return nil, nil, err
+	}
+	opentracing.SetGlobalTracer(tracer)
+	return tracer, closer, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,76 @@
+package opentracing_helpers
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func init() {
+	RegisterTracerFactory("mock", func(serviceName string) (opentracing.Tracer, io.Closer, error) {
+		return mocktracer.New(), nopCloser{}, nil
+	})
+	RegisterTracerFactory("broken", func(serviceName string) (opentracing.Tracer, io.Closer, error) {
+		return nil, nil, errors.New("no credentials")
+	})
+}
+
+func TestInitTracer(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	t.Setenv(TracerBackendEnv, "mock")
+
+	tracer, closer, err := InitTracer("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	if _, ok := tracer.(*mocktracer.MockTracer); !ok {
+		t.Errorf("tracer = %T, want the mock backend's", tracer)
+	}
+	if opentracing.GlobalTracer() != tracer {
+		t.Error("the tracer was not set as the global tracer")
+	}
+}
+
+func TestInitTracerDisabled(t *testing.T) {
+	t.Setenv(TracerBackendEnv, "")
+	tracer, closer, err := InitTracer("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tracer.(opentracing.NoopTracer); !ok || closer.Close() != nil {
+		t.Errorf("tracer = %T, want a no-op tracer and closer", tracer)
+	}
+}
+
+func TestInitTracerErrors(t *testing.T) {
+	global := opentracing.GlobalTracer()
+	for _, backend := range []string{"unknown", "broken"} {
+		t.Setenv(TracerBackendEnv, backend)
+		if _, _, err := InitTracer("users"); err == nil {
+			t.Errorf("%s: expected an error", backend)
+		}
+	}
+	if opentracing.GlobalTracer() != global {
+		t.Error("a failed InitTracer replaced the global tracer")
+	}
+}
+
+func TestRegisterTracerFactoryTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a backend twice didn't panic")
+		}
+	}()
+	RegisterTracerFactory("mock", nil)
+}
+
+func TestTracerBackends(t *testing.T) {
+	backends := TracerBackends()
+	if len(backends) != 2 || backends[0] != "broken" || backends[1] != "mock" {
+		t.Errorf("TracerBackends() = %q, want the sorted test backends", backends)
+	}
+}
@@ -0,0 +1,220 @@
+// Package tracetest helps testing instrumentation with the opentracing
+// mocktracer. Every test gets its own tracer, installed as the global
+// tracer for the duration of the test, whose finished spans can be
+// checked with chained assertions:
+//
+//	func TestHandler(t *testing.T) {
+//		srv, client := tracetest.NewServer(t, handler)
+//		resp, err := client.Get(srv.URL + "/users/1")
+//		...
+//		server := tracetest.AssertSpan(t, "GET /users/1").WithTag("http.status_code", 200)
+//		tracetest.AssertSpan(t, "db.query").WithParent(server)
+//	}
+//
+// Since the global tracer is shared, tests using this package must not
+// run in parallel.
+package tracetest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// spanWait bounds how long AssertSpan waits for a span to be finished,
+// since spans of HTTP servers may be finished after the client got its
+// response.
+const spanWait = time.Second
+
+var (
+	mu      sync.Mutex
+	tracers = make(map[testing.TB]*mocktracer.MockTracer)
+)
+
+// Tracer returns the tracer of t, creating it and installing it as the
+// global tracer on first use. The previous global tracer is restored
+// when t ends.
+func Tracer(t testing.TB) *mocktracer.MockTracer {
+	t.Helper()
+	mu.Lock()
+	defer mu.Unlock()
+	if tracer, ok := tracers[t]; ok {
+		return tracer
+	}
+	tracer := mocktracer.New()
+	tracers[t] = tracer
+	previous := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	t.Cleanup(func() {
+		opentracing.SetGlobalTracer(previous)
+		mu.Lock()
+		delete(tracers, t)
+		mu.Unlock()
+	})
+	return tracer
+}
+
+// RecordedSpans returns the spans finished so far by the tracer of t, in
+// the order they were finished.
+func RecordedSpans(t testing.TB) []*mocktracer.MockSpan {
+	t.Helper()
+	return Tracer(t).FinishedSpans()
+}
+
+// Reset forgets the spans recorded so far by the tracer of t.
+func Reset(t testing.TB) {
+	t.Helper()
+	Tracer(t).Reset()
+}
+
+// NewServer starts an httptest.Server serving handler behind the
+// opentracing_helpers middleware, and returns it with a client tracing
+// its requests, both using the tracer of t. The server is closed when t
+// ends.
+func NewServer(t testing.TB, handler http.Handler, opts ...opentracing_helpers.Option) (*httptest.Server, *http.Client) {
+	t.Helper()
+	tracer := Tracer(t)
+
+	handlerOpts := []opentracing_helpers.HandlerOption{opentracing_helpers.WithTracer(tracer)}
+	transportOpts := []opentracing_helpers.TransportOption{opentracing_helpers.WithTracer(tracer)}
+	for _, opt := range opts {
+		handlerOpts = append(handlerOpts, opt)
+		transportOpts = append(transportOpts, opt)
+	}
+
+	srv := httptest.NewServer(opentracing_helpers.NewMiddleware(handlerOpts...)(handler))
+	t.Cleanup(srv.Close)
+	return srv, opentracing_helpers.TracedClient(srv.Client(), transportOpts...)
+}
+
+// SpanAssertion checks a recorded span. Failed checks are reported with
+// t.Errorf, so all of them are reported at once.
+type SpanAssertion struct {
+	t    testing.TB
+	span *mocktracer.MockSpan
+}
+
+// AssertSpan returns an assertion on the first finished span of the
+// tracer of t named operationName, failing the test if there is none.
+func AssertSpan(t testing.TB, operationName string) *SpanAssertion {
+	t.Helper()
+	tracer := Tracer(t)
+	deadline := time.Now().Add(spanWait)
+	for {
+		for _, span := range tracer.FinishedSpans() {
+			if span.OperationName == operationName {
+				return &SpanAssertion{t: t, span: span}
+			}
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no finished span named %q, got %v", operationName, spanNames(tracer.FinishedSpans()))
+	return nil
+}
+
+// Span returns the checked span.
+func (a *SpanAssertion) Span() *mocktracer.MockSpan {
+	return a.span
+}
+
+// WithTag checks that the span has the tag key set to value. Numbers are
+// compared by value, so 200 matches a uint16 status code.
+func (a *SpanAssertion) WithTag(key string, value any) *SpanAssertion {
+	a.t.Helper()
+	got, ok := a.span.Tags()[key]
+	switch {
+	case !ok:
+		a.t.Errorf("span %q: no tag %q", a.span.OperationName, key)
+	case !tagEqual(got, value):
+		a.t.Errorf("span %q: tag %q = %v (%T), want %v (%T)", a.span.OperationName, key, got, got, value, value)
+	}
+	return a
+}
+
+// WithoutTag checks that the span doesn't have the tag key.
+func (a *SpanAssertion) WithoutTag(key string) *SpanAssertion {
+	a.t.Helper()
+	if got, ok := a.span.Tags()[key]; ok {
+		a.t.Errorf("span %q: unexpected tag %q = %v", a.span.OperationName, key, got)
+	}
+	return a
+}
+
+// WithParent checks that the span is a child of the span of parent, or
+// follows from it.
+func (a *SpanAssertion) WithParent(parent *SpanAssertion) *SpanAssertion {
+	a.t.Helper()
+	if a.span.ParentID != parent.span.SpanContext.SpanID {
+		a.t.Errorf("span %q: parent ID %d, want %d (%q)", a.span.OperationName,
+			a.span.ParentID, parent.span.SpanContext.SpanID, parent.span.OperationName)
+	}
+	return a
+}
+
+// WithoutParent checks that the span is the root of its trace.
+func (a *SpanAssertion) WithoutParent() *SpanAssertion {
+	a.t.Helper()
+	if a.span.ParentID != 0 {
+		a.t.Errorf("span %q: parent ID %d, want a root span", a.span.OperationName, a.span.ParentID)
+	}
+	return a
+}
+
+// WithLogEvent checks that the span has a log record with an "event"
+// field set to event.
+func (a *SpanAssertion) WithLogEvent(event string) *SpanAssertion {
+	a.t.Helper()
+	for _, record := range a.span.Logs() {
+		for _, field := range record.Fields {
+			if field.Key == "event" && field.ValueString == event {
+				return a
+			}
+		}
+	}
+	a.t.Errorf("span %q: no log with event %q", a.span.OperationName, event)
+	return a
+}
+
+// tagEqual reports whether the tag value got equals want, comparing
+// numbers of different types by value.
+func tagEqual(got, want any) bool {
+	if reflect.DeepEqual(got, want) {
+		return true
+	}
+	g, gok := number(got)
+	w, wok := number(want)
+	return gok && wok && g == w
+}
+
+// number returns v as a float64 if it is a number.
+func number(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func spanNames(spans []*mocktracer.MockSpan) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = fmt.Sprintf("%q", span.OperationName)
+	}
+	return names
+}
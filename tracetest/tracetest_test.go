@@ -0,0 +1,110 @@
+package tracetest
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// recordingT records the failures reported by assertions instead of
+// failing the test.
+type recordingT struct {
+	*testing.T
+	errors []string
+}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestNewServer(t *testing.T) {
+	srv, client := NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span, _ := opentracing.StartSpanFromContext(r.Context(), "db.query")
+		span.SetTag("db.type", "sql")
+		span.Finish()
+	}))
+	resp, err := client.Get(srv.URL + "/users/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	clientSpan := AssertSpan(t, "HTTP GET").WithoutParent()
+	server := AssertSpan(t, "GET /users/1").
+		WithParent(clientSpan).
+		WithTag("http.status_code", 200).
+		WithoutTag("error")
+	AssertSpan(t, "db.query").WithParent(server).WithTag("db.type", "sql")
+
+	if opentracing.GlobalTracer() != Tracer(t) {
+		t.Error("the tracer of t isn't the global tracer")
+	}
+}
+
+func TestTracerRestoresGlobalTracer(t *testing.T) {
+	previous := opentracing.GlobalTracer()
+	t.Run("child", func(t *testing.T) {
+		if Tracer(t) != Tracer(t) {
+			t.Error("Tracer returned a new tracer for the same test")
+		}
+	})
+	if opentracing.GlobalTracer() != previous {
+		t.Error("the global tracer wasn't restored")
+	}
+}
+
+func TestReset(t *testing.T) {
+	Tracer(t).StartSpan("forgotten").Finish()
+	Reset(t)
+	if spans := RecordedSpans(t); len(spans) != 0 {
+		t.Errorf("%d spans recorded after Reset", len(spans))
+	}
+}
+
+func TestAssertionFailures(t *testing.T) {
+	rt := &recordingT{T: t}
+	tracer := Tracer(rt)
+	parent := tracer.StartSpan("parent")
+	span := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	span.SetTag("http.status_code", uint16(500))
+	span.LogKV("event", "retry")
+	span.Finish()
+	parent.Finish()
+
+	AssertSpan(rt, "child").
+		WithTag("http.status_code", 500).
+		WithLogEvent("retry").
+		WithParent(AssertSpan(rt, "parent"))
+	if len(rt.errors) != 0 {
+		t.Fatalf("passing checks reported %q", rt.errors)
+	}
+
+	AssertSpan(rt, "child").
+		WithTag("http.status_code", 200).
+		WithTag("missing", 1).
+		WithoutTag("http.status_code").
+		WithoutParent().
+		WithLogEvent("error")
+	if len(rt.errors) != 5 {
+		t.Errorf("failing checks reported %q, want 5 errors", rt.errors)
+	}
+}
+
+func TestTagEqual(t *testing.T) {
+	for _, test := range []struct {
+		got, want any
+		equal     bool
+	}{
+		{uint16(200), 200, true},
+		{int64(3), 3.0, true},
+		{"200", 200, false},
+		{true, true, true},
+		{[]string{"a"}, []string{"a"}, true},
+	} {
+		if equal := tagEqual(test.got, test.want); equal != test.equal {
+			t.Errorf("tagEqual(%v (%T), %v (%T)) = %t", test.got, test.got, test.want, test.want, equal)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// TracedTransport is an http.RoundTripper that traces every request it
+// sends. A client span is started as a child of the span found in the
+// request's context, the span context is injected into the outgoing
+// headers, and connection events are logged using httptrace. The span is
+// finished when the response body is closed, so callers don't have to
+// manage its lifetime. For example:
+//
+//	client := &http.Client{Transport: opentracing_helpers.NewTracedTransport(nil)}
+//	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/", nil)
+//	resp, err := client.Do(req)
+//	if err == nil {
+//	    defer resp.Body.Close()
+//	}
+type TracedTransport struct {
+	base   http.RoundTripper
+	config *transportConfig
+}
+
+// NewTracedTransport wraps base, which defaults to http.DefaultTransport
+// when nil.
+func NewTracedTransport(base http.RoundTripper, opts ...TransportOption) *TracedTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &TracedTransport{base: base, config: newTransportConfig(opts)}
+}
+
+// TracedClient returns a copy of client whose transport is wrapped in a
+// TracedTransport. A nil client is treated as http.DefaultClient. Failed
+// round trips and 5xx responses are tagged with error=true, so callers
+// don't have to tag spans themselves, and redirects are traced with
+// TraceRedirects:
+//
+//	client := opentracing_helpers.TracedClient(nil)
+//	resp, err := client.Get("http://example.com/")
+func TracedClient(client *http.Client, opts ...TransportOption) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	traced := *client
+	traced.Transport = NewTracedTransport(client.Transport, opts...)
+	traced.CheckRedirect = TraceRedirects(client.CheckRedirect, opts...)
+	return &traced
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *TracedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.config.noop() || !t.config.traced(req) {
+		return t.base.RoundTrip(req)
+	}
+	return t.roundTrip(req)
+}
+
+// roundTrip sends req within a client span carrying the additional tags.
+func (t *TracedTransport) roundTrip(req *http.Request, tags ...opentracing.Tag) (*http.Response, error) {
+	start := time.Now()
+	operationName := t.config.spanName(req, "HTTP "+req.Method)
+	span := t.config.startSpan(req.Context(), req, operationName)
+	for _, tag := range tags {
+		tag.Set(span)
+	}
+
+	// A RoundTripper must not modify the request it was given.
+	ctx := opentracing.ContextWithSpan(req.Context(), span)
+	ctx = t.config.withClientTrace(ctx, span)
+	req = req.Clone(ctx)
+	t.config.captureRequestBody(span, req)
+	t.config.inject(span.Context(), req.Header)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		failed := t.config.tagError(span, 0, err)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+		t.config.decorate(span, req, 0)
+		t.config.observeMetrics(RequestMetrics{
+			Operation: operationName,
+			Kind:      "client",
+			Method:    req.Method,
+			Error:     failed,
+			Duration:  time.Since(start),
+		})
+		t.config.finished(span, req, 0, time.Since(start))
+		span.Finish()
+		return resp, err
+	}
+
+	t.config.logHeaders(span, "response headers", "http.response.header.", resp.Header)
+	span.SetTag("http.status_class", statusClass(resp.StatusCode))
+	failed := t.config.tagError(span, resp.StatusCode, nil)
+	t.config.decorate(span, req, resp.StatusCode)
+	t.config.observeMetrics(RequestMetrics{
+		Operation:  operationName,
+		Kind:       "client",
+		Method:     req.Method,
+		StatusCode: resp.StatusCode,
+		Error:      failed,
+		Duration:   time.Since(start),
+	})
+	wrapResponseBody(resp, span, func() {
+		t.config.finished(span, req, resp.StatusCode, time.Since(start))
+	})
+	return resp, nil
+}
+
+// startSpan starts a client span named operationName for r as a child of
+// the span in ctx. Callers resolve the name with spanName.
+func (c *transportConfig) startSpan(ctx context.Context, r *http.Request, operationName string) opentracing.Span {
+	span := NewSafeSpan(c.activeTracer().StartSpan(
+		operationName,
+		opentracing.ChildOf(parentContext(ctx)),
+		ext.SpanKindRPCClient,
+		componentTag,
+	))
+	span.logBudget = c.logBudget
+	ext.HTTPMethod.Set(span, r.Method)
+	ext.HTTPUrl.Set(span, c.urlTag(r.URL))
+	ext.PeerHostname.Set(span, r.URL.Hostname())
+	if port := peerPort(r.URL); port != 0 {
+		ext.PeerPort.Set(span, port)
+	}
+	c.tagRedirect(span, r)
+	c.logHeaders(span, "request headers", "http.request.header.", r.Header)
+	return span
+}
+
+// parentContext returns the context of the span in ctx, or nil.
+func parentContext(ctx context.Context) opentracing.SpanContext {
+	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+		return parentSpan.Context()
+	}
+	return nil
+}
+
+// peerPort returns the port u refers to, taking the scheme's default port
+// into account. It returns 0 if the port is unknown.
+func peerPort(u *url.URL) uint16 {
+	if p := u.Port(); p != "" {
+		port, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return 0
+		}
+		return uint16(port)
+	}
+	switch u.Scheme {
+	case "http":
+		return 80
+	case "https":
+		return 443
+	}
+	return 0
+}
+
+// statusClass returns the class of an HTTP status code, such as "2xx".
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
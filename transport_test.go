@@ -0,0 +1,201 @@
+package opentracing_helpers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// roundTripperFunc is an http.RoundTripper standing in for the network.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// respond returns a RoundTripper answering every request with status and
+// body, and storing the request it received in sent.
+func respond(status int, body string, sent **http.Request) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if sent != nil {
+			*sent = req
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+}
+
+func TestTracedTransport(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	var sent *http.Request
+	transport := NewTracedTransport(respond(http.StatusOK, "body", &sent), WithTracer(tracer))
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/items", nil)
+	req = req.WithContext(opentracing.ContextWithSpan(req.Context(), parent))
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Header) != 0 {
+		t.Errorf("the original request was modified: %v", req.Header)
+	}
+	if n := len(tracer.FinishedSpans()); n != 0 {
+		t.Errorf("%d spans finished before the body was closed", n)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	span := finishedSpan(t, tracer)
+	if span.OperationName != "HTTP GET" {
+		t.Errorf("operation name = %q, want %q", span.OperationName, "HTTP GET")
+	}
+	if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Errorf("span parent = %d, want the context span", span.ParentID)
+	}
+	if got := span.Tag("http.status_code"); got != uint16(http.StatusOK) {
+		t.Errorf("http.status_code = %v, want 200", got)
+	}
+	sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(sent.Header))
+	if err != nil {
+		t.Fatalf("no span context in the sent headers: %v", err)
+	}
+	if sc.(mocktracer.MockSpanContext).SpanID != span.SpanContext.SpanID {
+		t.Errorf("injected span %d, want the client span %d", sc.(mocktracer.MockSpanContext).SpanID, span.SpanContext.SpanID)
+	}
+}
+
+func TestTracedTransportError(t *testing.T) {
+	tracer := mocktracer.New()
+	errRefused := errors.New("connection refused")
+	transport := NewTracedTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errRefused
+	}), WithTracer(tracer))
+
+	if _, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/", nil)); err != errRefused {
+		t.Fatalf("err = %v, want %v", err, errRefused)
+	}
+	span := finishedSpan(t, tracer)
+	if span.Tag("error") != true {
+		t.Error("the span is not tagged as an error")
+	}
+	if logs := span.Logs(); len(logs) == 0 {
+		t.Error("the error was not logged")
+	}
+}
+
+func TestSpanBodyFinishesOnce(t *testing.T) {
+	tracer := mocktracer.New()
+	body := newSpanBody(io.NopCloser(strings.NewReader("")), tracer.StartSpan("client"), nil)
+	body.Close()
+	body.Close()
+	finishedSpan(t, tracer)
+}
+
+func TestTracedTransportSemanticTags(t *testing.T) {
+	tracer := mocktracer.New()
+	transport := NewTracedTransport(respond(http.StatusOK, "", nil), WithTracer(tracer))
+	resp, err := transport.RoundTrip(httptest.NewRequest(http.MethodPost, "https://api.example.com/v1/items", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	span := finishedSpan(t, tracer)
+	for key, want := range map[string]interface{}{
+		"span.kind":     ext.SpanKindRPCClientEnum,
+		"component":     "net/http",
+		"http.method":   http.MethodPost,
+		"http.url":      "https://api.example.com/v1/items",
+		"peer.hostname": "api.example.com",
+		"peer.port":     uint16(443),
+	} {
+		if got := span.Tag(key); got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestPeerPort(t *testing.T) {
+	tests := []struct {
+		url  string
+		want uint16
+	}{
+		{"http://example.com/", 80},
+		{"https://example.com/", 443},
+		{"http://example.com:8080/", 8080},
+		{"ftp://example.com/", 0},
+		{"http://example.com:99999/", 0},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := peerPort(u); got != tt.want {
+			t.Errorf("peerPort(%s) = %d, want %d", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestTracedClient(t *testing.T) {
+	tracer := mocktracer.New()
+	for _, tt := range []struct {
+		status    int
+		class     string
+		wantError bool
+	}{
+		{http.StatusOK, "2xx", false},
+		{http.StatusNotFound, "4xx", false},
+		{http.StatusServiceUnavailable, "5xx", true},
+	} {
+		tracer.Reset()
+		base := &http.Client{Transport: respond(tt.status, "", nil), Timeout: time.Second}
+		client := TracedClient(base, WithTracer(tracer))
+		if client == base || client.Timeout != time.Second || base.Transport == client.Transport {
+			t.Fatal("TracedClient didn't return a traced copy of the client")
+		}
+		resp, err := client.Get("http://example.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		span := finishedSpan(t, tracer)
+		if got := span.Tag("http.status_class"); got != tt.class {
+			t.Errorf("%d: http.status_class = %v, want %s", tt.status, got, tt.class)
+		}
+		if got := span.Tag("error") == true; got != tt.wantError {
+			t.Errorf("%d: error tag = %v, want %v", tt.status, got, tt.wantError)
+		}
+	}
+}
+
+func TestTracedClientDefault(t *testing.T) {
+	client := TracedClient(nil)
+	if client == http.DefaultClient || http.DefaultClient.Transport != nil {
+		t.Error("http.DefaultClient was modified")
+	}
+	if _, ok := client.Transport.(*TracedTransport); !ok {
+		t.Errorf("transport = %T, want *TracedTransport", client.Transport)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for status, want := range map[int]string{0: "unknown", 101: "1xx", 302: "3xx", 599: "5xx", 600: "unknown"} {
+		if got := statusClass(status); got != want {
+			t.Errorf("statusClass(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
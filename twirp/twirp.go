@@ -0,0 +1,233 @@
+// Package twirp traces Twirp services and clients. Spans are named
+// "package/Service/Method" and tagged with the Twirp error code of failed
+// calls:
+//
+//	server := example.NewHaberdasherServer(s, twirp.WithServerHooks(ottwirp.ServerHooks()))
+//	http.Handle(server.PathPrefix(), ottwirp.Handler(server))
+//
+//	client := example.NewHaberdasherProtobufClient(addr, &http.Client{
+//		Transport: ottwirp.NewTransport(nil),
+//	})
+package twirp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/twitchtv/twirp"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "twirp"}
+
+// maxErrorBody bounds how much of an error response NewTransport reads to
+// find its Twirp error code.
+const maxErrorBody = 64 << 10
+
+type parentKey struct{}
+
+// Handler extracts the span context propagated by the client from the
+// request headers, for the spans of ServerHooks. Twirp hooks don't see
+// the HTTP request, so services must be wrapped with Handler unless a
+// span is already in the request context, for example one started by
+// opentracing_helpers.NewMiddleware. Of opts, WithTracer and
+// WithPropagators apply, and WithFilter skips requests.
+func Handler(server http.Handler, opts ...opentracing_helpers.Option) http.Handler {
+	s := opentracing_helpers.NewSettings(opts...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Traced(r) {
+			server.ServeHTTP(w, r)
+			return
+		}
+		sc, err := s.Extract(r.Header)
+		if err == nil && sc != nil {
+			r = r.WithContext(context.WithValue(r.Context(), parentKey{}, sc))
+		}
+		server.ServeHTTP(w, r)
+	})
+}
+
+// ServerHooks returns hooks that serve every call within a server span,
+// stored in the context passed to the service methods. Use
+// twirp.ChainHooks to combine them with other hooks. Of opts, WithTracer
+// and WithDisabled apply.
+func ServerHooks(opts ...opentracing_helpers.Option) *twirp.ServerHooks {
+	s := opentracing_helpers.NewSettings(opts...)
+	return &twirp.ServerHooks{
+		RequestReceived: func(ctx context.Context) (context.Context, error) {
+			if s.Noop() {
+				return ctx, nil
+			}
+			var parent opentracing.SpanContext
+			if span := opentracing.SpanFromContext(ctx); span != nil {
+				parent = span.Context()
+			} else if sc, ok := ctx.Value(parentKey{}).(opentracing.SpanContext); ok {
+				parent = sc
+			}
+			pkg, _ := twirp.PackageName(ctx)
+			service, _ := twirp.ServiceName(ctx)
+			span := s.Tracer().StartSpan(
+				operationName(pkg, service, ""),
+				ext.RPCServerOption(parent),
+				componentTag,
+			)
+			if pkg != "" {
+				span.SetTag("twirp.package", pkg)
+			}
+			span.SetTag("rpc.service", service)
+			return opentracing.ContextWithSpan(ctx, span), nil
+		},
+		RequestRouted: func(ctx context.Context) (context.Context, error) {
+			span := opentracing.SpanFromContext(ctx)
+			if span == nil {
+				return ctx, nil
+			}
+			pkg, _ := twirp.PackageName(ctx)
+			service, _ := twirp.ServiceName(ctx)
+			method, _ := twirp.MethodName(ctx)
+			span.SetOperationName(operationName(pkg, service, method))
+			span.SetTag("rpc.method", method)
+			return ctx, nil
+		},
+		Error: func(ctx context.Context, err twirp.Error) context.Context {
+			if span := opentracing.SpanFromContext(ctx); span != nil {
+				tagError(span, err.Code(), err.Msg())
+			}
+			return ctx
+		},
+		ResponseSent: func(ctx context.Context) {
+			span := opentracing.SpanFromContext(ctx)
+			if span == nil {
+				return
+			}
+			if code, ok := twirp.StatusCode(ctx); ok {
+				if status, err := strconv.Atoi(code); err == nil {
+					ext.HTTPStatusCode.Set(span, uint16(status))
+				}
+			}
+			span.Finish()
+		},
+	}
+}
+
+// NewTransport returns a TracedTransport wrapping base, which defaults to
+// http.DefaultTransport, that names the spans of Twirp requests after the
+// called method and tags them with the Twirp error code of
+// failed calls. opts are applied after the Twirp options, so
+// WithOperationNameFunc can still override span names.
+func NewTransport(base http.RoundTripper, opts ...opentracing_helpers.TransportOption) *opentracing_helpers.TracedTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	twirpOpts := []opentracing_helpers.TransportOption{
+		opentracing_helpers.WithOperationNameFunc(clientOperationName),
+		opentracing_helpers.WithSpanDecorator(decorate),
+	}
+	return opentracing_helpers.NewTracedTransport(errorCodeTransport{base}, append(twirpOpts, opts...)...)
+}
+
+// parsePath splits a Twirp request path, "[prefix]/package.Service/Method".
+func parsePath(path string) (pkg, service, method string, ok bool) {
+	path = strings.TrimSuffix(path, "/")
+	i := strings.LastIndexByte(path, '/')
+	if i <= 0 {
+		return "", "", "", false
+	}
+	method = path[i+1:]
+	fullService := path[strings.LastIndexByte(path[:i], '/')+1 : i]
+	if fullService == "" || method == "" {
+		return "", "", "", false
+	}
+	if j := strings.LastIndexByte(fullService, '.'); j >= 0 {
+		return fullService[:j], fullService[j+1:], method, true
+	}
+	return "", fullService, method, true
+}
+
+func operationName(pkg, service, method string) string {
+	name := service
+	if pkg != "" {
+		name = pkg + "/" + name
+	}
+	if method != "" {
+		name += "/" + method
+	}
+	return name
+}
+
+func clientOperationName(r *http.Request) string {
+	if pkg, service, method, ok := parsePath(r.URL.Path); ok {
+		return operationName(pkg, service, method)
+	}
+	return "HTTP " + r.Method
+}
+
+func decorate(span opentracing.Span, r *http.Request, _ int) {
+	pkg, service, method, ok := parsePath(r.URL.Path)
+	if !ok {
+		return
+	}
+	if pkg != "" {
+		span.SetTag("twirp.package", pkg)
+	}
+	span.SetTag("rpc.service", service)
+	span.SetTag("rpc.method", method)
+}
+
+// errorCodeTransport reads the Twirp error code of error responses and
+// tags the client span, which TracedTransport stores in the request
+// context.
+type errorCodeTransport struct {
+	base http.RoundTripper
+}
+
+func (t errorCodeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode == http.StatusOK ||
+		!strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return resp, err
+	}
+	span := opentracing.SpanFromContext(req.Context())
+	if span == nil {
+		return resp, err
+	}
+
+	buf, readErr := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), resp.Body), resp.Body}
+	if readErr != nil {
+		return resp, nil
+	}
+	var twerr struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if json.Unmarshal(buf, &twerr) == nil && twerr.Code != "" {
+		tagError(span, twirp.ErrorCode(twerr.Code), twerr.Msg)
+	}
+	return resp, nil
+}
+
+// tagError tags span with a Twirp error, marking it as failed if the code
+// maps to a 5xx status like server errors of the parent package.
+func tagError(span opentracing.Span, code twirp.ErrorCode, msg string) {
+	span.SetTag("twirp.error_code", string(code))
+	if twirp.ServerHTTPStatusFromErrorCode(code) >= http.StatusInternalServerError {
+		ext.Error.Set(span, true)
+	}
+	span.LogFields(
+		log.String("event", "error"),
+		log.String("error.kind", string(code)),
+		log.String("message", msg),
+	)
+}
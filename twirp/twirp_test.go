@@ -0,0 +1,205 @@
+package twirp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/twitchtv/twirp"
+	"github.com/twitchtv/twirp/ctxsetters"
+)
+
+// serve runs hooks like a generated Twirp server routing a call to
+// example.Haberdasher/MakeHat, failing it with twerr if not nil.
+func serve(hooks *twirp.ServerHooks, twerr twirp.Error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := ctxsetters.WithPackageName(r.Context(), "example")
+		ctx = ctxsetters.WithServiceName(ctx, "Haberdasher")
+		ctx, _ = hooks.RequestReceived(ctx)
+		ctx = ctxsetters.WithMethodName(ctx, "MakeHat")
+		ctx, _ = hooks.RequestRouted(ctx)
+		status := http.StatusOK
+		if twerr != nil {
+			ctx = hooks.Error(ctx, twerr)
+			status = twirp.ServerHTTPStatusFromErrorCode(twerr.Code())
+		}
+		ctx = ctxsetters.WithStatusCode(ctx, status)
+		w.WriteHeader(status)
+		hooks.ResponseSent(ctx)
+	})
+}
+
+func TestServerHooks(t *testing.T) {
+	tracer := mocktracer.New()
+	client := tracer.StartSpan("client")
+	r := httptest.NewRequest("POST", "/twirp/example.Haberdasher/MakeHat", nil)
+	tracer.Inject(client.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+
+	h := Handler(serve(ServerHooks(opentracing_helpers.WithTracer(tracer)), nil), opentracing_helpers.WithTracer(tracer))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("%d finished spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.OperationName != "example/Haberdasher/MakeHat" {
+		t.Errorf("operation name %q, want example/Haberdasher/MakeHat", span.OperationName)
+	}
+	if span.ParentID != client.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the server span isn't a child of the propagated span")
+	}
+	for key, want := range map[string]interface{}{
+		"span.kind":        ext.SpanKindRPCServerEnum,
+		"component":        "twirp",
+		"twirp.package":    "example",
+		"rpc.service":      "Haberdasher",
+		"rpc.method":       "MakeHat",
+		"http.status_code": uint16(http.StatusOK),
+	} {
+		if got := span.Tag(key); got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestServerHooksUseContextSpan(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("http")
+	r := httptest.NewRequest("POST", "/twirp/example.Haberdasher/MakeHat", nil)
+	r = r.WithContext(opentracing.ContextWithSpan(r.Context(), parent))
+
+	serve(ServerHooks(opentracing_helpers.WithTracer(tracer)), nil).ServeHTTP(httptest.NewRecorder(), r)
+
+	span := tracer.FinishedSpans()[0]
+	if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("the server span isn't a child of the context span")
+	}
+}
+
+func TestServerHooksError(t *testing.T) {
+	for _, test := range []struct {
+		err    twirp.Error
+		failed bool
+	}{
+		{twirp.NotFoundError("no such hat"), false},
+		{twirp.InternalError("out of felt"), true},
+	} {
+		tracer := mocktracer.New()
+		r := httptest.NewRequest("POST", "/twirp/example.Haberdasher/MakeHat", nil)
+		serve(ServerHooks(opentracing_helpers.WithTracer(tracer)), test.err).ServeHTTP(httptest.NewRecorder(), r)
+
+		span := tracer.FinishedSpans()[0]
+		if got := span.Tag("twirp.error_code"); got != string(test.err.Code()) {
+			t.Errorf("%s: twirp.error_code = %v", test.err.Code(), got)
+		}
+		if failed := span.Tag("error") == true; failed != test.failed {
+			t.Errorf("%s: error = %t, want %t", test.err.Code(), failed, test.failed)
+		}
+		if logs := span.Logs(); len(logs) != 1 || logs[0].Fields[2].ValueString != test.err.Msg() {
+			t.Errorf("%s: logged %v", test.err.Code(), logs)
+		}
+	}
+}
+
+func TestHandlerFilter(t *testing.T) {
+	tracer := mocktracer.New()
+	client := tracer.StartSpan("client")
+	r := httptest.NewRequest("POST", "/twirp/example.Haberdasher/MakeHat", nil)
+	tracer.Inject(client.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+
+	h := Handler(serve(ServerHooks(opentracing_helpers.WithTracer(tracer)), nil), opentracing_helpers.WithTracer(tracer),
+		opentracing_helpers.WithFilter(func(r *http.Request) bool { return false }))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 || spans[0].ParentID != 0 {
+		t.Errorf("finished spans %v, want one root span for a filtered request", spans)
+	}
+}
+
+func TestServerHooksDisabled(t *testing.T) {
+	tracer := mocktracer.New()
+	r := httptest.NewRequest("POST", "/twirp/example.Haberdasher/MakeHat", nil)
+	hooks := ServerHooks(opentracing_helpers.WithTracer(tracer), opentracing_helpers.WithDisabled(func() bool { return true }))
+	serve(hooks, twirp.InternalError("boom")).ServeHTTP(httptest.NewRecorder(), r)
+
+	if spans := tracer.FinishedSpans(); len(spans) != 0 {
+		t.Errorf("finished spans %v, want none while disabled", spans)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	for _, test := range []struct {
+		path                 string
+		pkg, service, method string
+		ok                   bool
+	}{
+		{"/twirp/example.Haberdasher/MakeHat", "example", "Haberdasher", "MakeHat", true},
+		{"/twirp/acme.v1.Haberdasher/MakeHat", "acme.v1", "Haberdasher", "MakeHat", true},
+		{"/Haberdasher/MakeHat/", "", "Haberdasher", "MakeHat", true},
+		{"/MakeHat", "", "", "", false},
+		{"/twirp//MakeHat", "", "", "", false},
+	} {
+		pkg, service, method, ok := parsePath(test.path)
+		if pkg != test.pkg || service != test.service || method != test.method || ok != test.ok {
+			t.Errorf("parsePath(%q) = %q, %q, %q, %t", test.path, pkg, service, method, ok)
+		}
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestNewTransport(t *testing.T) {
+	tracer := mocktracer.New()
+	const body = `{"code":"internal","msg":"out of felt"}`
+	var injected opentracing.SpanContext
+	transport := NewTransport(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		injected, _ = tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    r,
+		}, nil
+	}), opentracing_helpers.WithTracer(tracer), opentracing_helpers.WithoutClientTrace())
+
+	req, _ := http.NewRequestWithContext(context.Background(), "POST", "http://hats.example.com/twirp/example.Haberdasher/MakeHat", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(got) != body {
+		t.Errorf("read body %q, want it intact", got)
+	}
+
+	span := tracer.FinishedSpans()[0]
+	if span.OperationName != "example/Haberdasher/MakeHat" {
+		t.Errorf("operation name %q, want example/Haberdasher/MakeHat", span.OperationName)
+	}
+	if sc, ok := injected.(mocktracer.MockSpanContext); !ok || sc.SpanID != span.SpanContext.SpanID {
+		t.Error("the client span context wasn't injected")
+	}
+	for key, want := range map[string]interface{}{
+		"twirp.package":    "example",
+		"rpc.service":      "Haberdasher",
+		"rpc.method":       "MakeHat",
+		"twirp.error_code": "internal",
+		"error":            true,
+	} {
+		if got := span.Tag(key); got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package opentracing_helpers
+
+import (
+	"net/url"
+	"strings"
+)
+
+// redactedSegment replaces sensitive path segments.
+const redactedSegment = "{redacted}"
+
+// WithURLScrubber replaces ScrubURL, the default policy for the http.url
+// tag, with f. Use it to redact application-specific path segments, or
+// pass func(u *url.URL) string { return u.String() } to tag raw URLs.
+func WithURLScrubber(f func(u *url.URL) string) Option {
+	return commonOption(func(c *commonConfig) {
+		c.urlScrubber = f
+	})
+}
+
+// urlTag returns the value of the http.url tag for u.
+func (c *commonConfig) urlTag(u *url.URL) string {
+	if c.urlScrubber != nil {
+		return c.urlScrubber(u)
+	}
+	return ScrubURL(u)
+}
+
+// ScrubURL returns u without the parts that commonly carry secrets or
+// personal data, and is the default policy for the http.url tag: the user
+// info, the query string and the fragment are dropped, and path segments
+// that look like email addresses or tokens are replaced by "{redacted}".
+func ScrubURL(u *url.URL) string {
+	scrubbed := url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   u.Path,
+	}
+	if u.Opaque != "" {
+		// Opaque URLs, such as the authority form of CONNECT requests,
+		// have no path to scrub.
+		scrubbed.Opaque = u.Opaque
+		return scrubbed.String()
+	}
+	if !sensitivePath(u.Path) {
+		return scrubbed.String()
+	}
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if sensitiveSegment(segment) {
+			segments[i] = redactedSegment
+		}
+	}
+	scrubbed.Path = strings.Join(segments, "/")
+	// url.URL escapes the braces of the placeholder.
+	return strings.ReplaceAll(scrubbed.String(), url.PathEscape(redactedSegment), redactedSegment)
+}
+
+// sensitivePath reports whether any segment of path is sensitive, without
+// allocating, since most paths have none.
+func sensitivePath(path string) bool {
+	for path != "" {
+		segment := path
+		if i := strings.IndexByte(path, '/'); i >= 0 {
+			segment, path = path[:i], path[i+1:]
+		} else {
+			path = ""
+		}
+		if sensitiveSegment(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitiveSegment reports whether a path segment looks like an email
+// address or a token: 32 or more characters of the hex or base64 alphabets
+// mixing letters and digits, as used by API keys, session IDs and JWTs.
+// UUIDs are kept, since they usually are plain resource identifiers.
+func sensitiveSegment(segment string) bool {
+	if strings.Contains(segment, "@") {
+		return true
+	}
+	if len(segment) < 32 || isUUID(segment) {
+		return false
+	}
+	var letters, digits bool
+	for _, r := range segment {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			letters = true
+		case r >= '0' && r <= '9':
+			digits = true
+		case r == '-', r == '_', r == '.', r == '=', r == '+', r == '~':
+		default:
+			return false
+		}
+	}
+	return letters && digits
+}
+
+// isUUID reports whether s is a UUID in its canonical form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
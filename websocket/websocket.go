@@ -0,0 +1,164 @@
+// Package websocket traces gorilla/websocket connections. Upgrading a
+// request starts a connection span that lasts until the connection is
+// closed, and every message read or written creates a child span tagged
+// with its direction, type and size:
+//
+//	func serveWS(w http.ResponseWriter, r *http.Request) {
+//	    conn, err := otws.Upgrade(&upgrader, w, r, nil)
+//	    if err != nil {
+//	        return
+//	    }
+//	    defer conn.Close()
+//	    for {
+//	        mt, msg, err := conn.ReadMessage()
+//	        ...
+//	    }
+//	}
+package websocket
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+var componentTag = opentracing.Tag{Key: string(ext.Component), Value: "websocket"}
+
+// Conn is a websocket.Conn whose ReadMessage and WriteMessage calls are
+// traced. Other methods of the embedded connection, including NextReader
+// and NextWriter, are not traced.
+type Conn struct {
+	*websocket.Conn
+	span   opentracing.Span
+	tracer opentracing.Tracer
+	once   sync.Once
+}
+
+// Upgrade upgrades the connection using u and starts the connection span.
+// The span is a child of the span in the request context, such as one
+// started by TraceHandler, or else of the span context sent by the client.
+// opts are the root package's Options, see opentracing_helpers.Settings
+// for the ones honored. Connections skipped because of them are traced
+// with a NoopTracer.
+func Upgrade(u *websocket.Upgrader, w http.ResponseWriter, r *http.Request, responseHeader http.Header, opts ...opentracing_helpers.Option) (*Conn, error) {
+	s := opentracing_helpers.NewSettings(opts...)
+	tracer := s.Tracer()
+	if s.Noop() || !s.Traced(r) {
+		tracer = opentracing.NoopTracer{}
+	}
+
+	var parent opentracing.SpanContext
+	if parentSpan := opentracing.SpanFromContext(r.Context()); parentSpan != nil {
+		parent = parentSpan.Context()
+	} else {
+		parent, _ = s.Extract(r.Header)
+	}
+	span := tracer.StartSpan(
+		s.OperationName(r, "WebSocket "+r.URL.Path),
+		ext.RPCServerOption(parent),
+		componentTag,
+	)
+	ext.HTTPUrl.Set(span, s.URLTag(r.URL))
+	ext.PeerAddress.Set(span, r.RemoteAddr)
+
+	conn, err := u.Upgrade(w, r, responseHeader)
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "upgrade failed"), log.Error(err))
+		span.Finish()
+		return nil, err
+	}
+	return &Conn{Conn: conn, span: span, tracer: tracer}, nil
+}
+
+// Span returns the connection span.
+func (c *Conn) Span() opentracing.Span {
+	return c.span
+}
+
+// ReadMessage reads a message and records it in a child span. A normal
+// close by the peer isn't a message: it is logged on the connection span,
+// which is then finished.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	messageType, p, err := c.Conn.ReadMessage()
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		c.closedByPeer(err.(*websocket.CloseError))
+		return messageType, p, err
+	}
+	c.messageSpan("receive", messageType, len(p), err)
+	return messageType, p, err
+}
+
+// WriteMessage writes a message within a child span.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	span := c.startMessageSpan("send", messageType, len(data))
+	err := c.Conn.WriteMessage(messageType, data)
+	finishMessageSpan(span, err)
+	return err
+}
+
+// Close closes the connection and finishes the connection span.
+func (c *Conn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.span.Finish)
+	return err
+}
+
+// closedByPeer logs the close frame sent by the peer and finishes the
+// connection span.
+func (c *Conn) closedByPeer(ce *websocket.CloseError) {
+	c.once.Do(func() {
+		c.span.LogFields(
+			log.String("event", "closed by peer"),
+			log.Int("close.code", ce.Code),
+			log.String("close.reason", ce.Text),
+		)
+		c.span.Finish()
+	})
+}
+
+func (c *Conn) startMessageSpan(direction string, messageType, size int) opentracing.Span {
+	return c.tracer.StartSpan(
+		"WebSocket "+direction,
+		opentracing.ChildOf(c.span.Context()),
+		componentTag,
+		opentracing.Tag{Key: "message.direction", Value: direction},
+		opentracing.Tag{Key: "message.type", Value: messageTypeName(messageType)},
+		opentracing.Tag{Key: "message.size", Value: size},
+	)
+}
+
+// messageSpan records a message that has already been received. The span
+// deliberately doesn't cover the time spent waiting for the message.
+func (c *Conn) messageSpan(direction string, messageType, size int, err error) {
+	finishMessageSpan(c.startMessageSpan(direction, messageType, size), err)
+}
+
+func finishMessageSpan(span opentracing.Span, err error) {
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.Error(err))
+	}
+	span.Finish()
+}
+
+func messageTypeName(messageType int) string {
+	switch messageType {
+	case websocket.TextMessage:
+		return "text"
+	case websocket.BinaryMessage:
+		return "binary"
+	case websocket.CloseMessage:
+		return "close"
+	case websocket.PingMessage:
+		return "ping"
+	case websocket.PongMessage:
+		return "pong"
+	}
+	return "unknown"
+}
@@ -0,0 +1,210 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// echoServer starts a server upgrading requests with Upgrade and echoing
+// one message back before closing the connection.
+func echoServer(t *testing.T, tracer *mocktracer.MockTracer) string {
+	t.Helper()
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(&upgrader, w, r, nil, opentracing_helpers.WithTracer(tracer))
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		mt, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.WriteMessage(mt, msg)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// waitForSpans waits until tracer has finished n spans.
+func waitForSpans(t *testing.T, tracer *mocktracer.MockTracer, n int) []*mocktracer.MockSpan {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		spans := tracer.FinishedSpans()
+		if len(spans) >= n {
+			return spans
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d finished spans, want %d", len(spans), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	tracer := mocktracer.New()
+	url := echoServer(t, tracer)
+
+	client, _, err := websocket.DefaultDialer.Dial(url+"/chat", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.WriteMessage(websocket.TextMessage, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := waitForSpans(t, tracer, 3)
+	byName := map[string]*mocktracer.MockSpan{}
+	for _, span := range spans {
+		byName[span.OperationName] = span
+	}
+	conn := byName["WebSocket /chat"]
+	if conn == nil {
+		t.Fatalf("no connection span among %d spans", len(spans))
+	}
+	for _, direction := range []string{"receive", "send"} {
+		span := byName["WebSocket "+direction]
+		if span == nil {
+			t.Errorf("no %s span", direction)
+			continue
+		}
+		if span.ParentID != conn.SpanContext.SpanID {
+			t.Errorf("the %s span is not a child of the connection span", direction)
+		}
+		for key, want := range map[string]interface{}{
+			"message.direction": direction,
+			"message.type":      "text",
+			"message.size":      5,
+		} {
+			if got := span.Tag(key); got != want {
+				t.Errorf("%s span: %s = %v, want %v", direction, key, got, want)
+			}
+		}
+	}
+}
+
+func TestUpgradeFailure(t *testing.T) {
+	tracer := mocktracer.New()
+	var upgrader websocket.Upgrader
+	w := httptest.NewRecorder()
+	// A plain GET request without the upgrade headers.
+	if _, err := Upgrade(&upgrader, w, httptest.NewRequest(http.MethodGet, "/chat", nil), nil, opentracing_helpers.WithTracer(tracer)); err == nil {
+		t.Fatal("Upgrade succeeded without a WebSocket handshake")
+	}
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 || spans[0].Tag("error") != true {
+		t.Fatalf("want one failed connection span, got %d spans", len(spans))
+	}
+}
+
+func TestMessageTypeName(t *testing.T) {
+	for mt, want := range map[int]string{
+		websocket.TextMessage:   "text",
+		websocket.BinaryMessage: "binary",
+		websocket.CloseMessage:  "close",
+		websocket.PingMessage:   "ping",
+		websocket.PongMessage:   "pong",
+		42:                      "unknown",
+	} {
+		if got := messageTypeName(mt); got != want {
+			t.Errorf("messageTypeName(%d) = %q, want %q", mt, got, want)
+		}
+	}
+}
+
+func TestPeerClose(t *testing.T) {
+	tracer := mocktracer.New()
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(&upgrader, w, r, nil, opentracing_helpers.WithTracer(tracer))
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/chat?token=secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye")
+	if err := client.WriteMessage(websocket.CloseMessage, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := waitForSpans(t, tracer, 1)
+	time.Sleep(10 * time.Millisecond)
+	if spans = tracer.FinishedSpans(); len(spans) != 1 {
+		t.Fatalf("got %d finished spans, want only the connection span", len(spans))
+	}
+	conn := spans[0]
+	if conn.Tag("error") != nil {
+		t.Error("a normal close is tagged as an error")
+	}
+	if got := conn.Tag("http.url"); got != "/chat" {
+		t.Errorf("http.url = %v, want the scrubbed /chat", got)
+	}
+	var closed map[string]string
+	for _, l := range conn.Logs() {
+		fields := map[string]string{}
+		for _, f := range l.Fields {
+			fields[f.Key] = f.ValueString
+		}
+		if fields["event"] == "closed by peer" {
+			closed = fields
+		}
+	}
+	if closed["close.code"] != "1000" || closed["close.reason"] != "bye" {
+		t.Errorf("close logged as %v, want code 1000 and reason bye", closed)
+	}
+}
+
+func TestWithURLScrubber(t *testing.T) {
+	tracer := mocktracer.New()
+	var upgrader websocket.Upgrader
+	r := httptest.NewRequest(http.MethodGet, "/chat?room=1", nil)
+	Upgrade(&upgrader, httptest.NewRecorder(), r, nil, opentracing_helpers.WithTracer(tracer), opentracing_helpers.WithURLScrubber(func(u *url.URL) string {
+		return "scrubbed " + u.Path
+	}))
+	if got := tracer.FinishedSpans()[0].Tag("http.url"); got != "scrubbed /chat" {
+		t.Errorf("http.url = %v, want the custom scrubber's", got)
+	}
+}
+
+func TestUpgradeOptions(t *testing.T) {
+	tracer := mocktracer.New()
+	var upgrader websocket.Upgrader
+	opts := []opentracing_helpers.Option{
+		opentracing_helpers.WithTracer(tracer),
+		opentracing_helpers.WithFilter(func(r *http.Request) bool { return r.URL.Path != "/internal" }),
+		opentracing_helpers.WithOperationNameFunc(func(r *http.Request) string { return "chat" }),
+	}
+	Upgrade(&upgrader, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/internal", nil), nil, opts...)
+	if spans := tracer.FinishedSpans(); len(spans) != 0 {
+		t.Fatalf("filtered connection traced as %v", spans)
+	}
+	Upgrade(&upgrader, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/chat", nil), nil, opts...)
+	if spans := tracer.FinishedSpans(); len(spans) != 1 || spans[0].OperationName != "chat" {
+		t.Errorf("finished spans %v, want one named chat", spans)
+	}
+}
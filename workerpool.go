@@ -0,0 +1,117 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// ErrWorkerPoolClosed is returned by WorkerPool.Submit after Close.
+var ErrWorkerPoolClosed = errors.New("opentracing_helpers: worker pool closed")
+
+// WorkerPool runs tasks on a fixed number of goroutines. Every submitted
+// task gets a span that follows from the span of the submitter and covers
+// both the time spent waiting in the queue and the execution, which are
+// logged separately so asynchronous work shows up correctly in traces:
+//
+//	pool := opentracing_helpers.NewWorkerPool(4, 100)
+//	defer pool.Close()
+//	err := pool.Submit(r.Context(), "resize image", func(ctx context.Context) {
+//	    resize(ctx, img)
+//	})
+type WorkerPool struct {
+	tasks  chan queuedTask
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+}
+
+type queuedTask struct {
+	ctx      context.Context
+	span     opentracing.Span
+	queuedAt time.Time
+	run      func(ctx context.Context)
+}
+
+// NewWorkerPool starts workers goroutines that process a queue holding up
+// to queueSize pending tasks.
+func NewWorkerPool(workers, queueSize int) *WorkerPool {
+	p := &WorkerPool{tasks: make(chan queuedTask, queueSize)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues task, blocking while the queue is full. It returns
+// ctx.Err() if ctx is done before the task could be queued. As with Go, the
+// context passed to task isn't canceled when ctx is.
+func (p *WorkerPool) Submit(ctx context.Context, operationName string, task func(ctx context.Context)) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrWorkerPoolClosed
+	}
+
+	span := startFollowsFromSpan(ctx, operationName)
+	span.LogFields(log.String("event", "queued"))
+	qt := queuedTask{
+		ctx:      opentracing.ContextWithSpan(context.WithoutCancel(ctx), span),
+		span:     span,
+		queuedAt: time.Now(),
+		run:      task,
+	}
+	select {
+	case p.tasks <- qt:
+		return nil
+	case <-ctx.Done():
+		span.LogFields(log.String("event", "not queued"), log.Error(ctx.Err()))
+		span.Finish()
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting tasks and waits for the queued ones to complete.
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+	for qt := range p.tasks {
+		qt.execute()
+	}
+}
+
+// execute runs the task and records how long it waited and ran. A panic in
+// the task is recorded on its span before it is propagated.
+func (qt queuedTask) execute() {
+	defer qt.span.Finish()
+	started := time.Now()
+	qt.span.LogFields(
+		log.String("event", "started"),
+		log.String("queue_wait", started.Sub(qt.queuedAt).String()),
+	)
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(qt.span, p)
+			panic(p)
+		}
+		qt.span.LogFields(
+			log.String("event", "finished"),
+			log.String("execution_time", time.Since(started).String()),
+		)
+	}()
+	qt.run(qt.ctx)
+}
@@ -0,0 +1,89 @@
+package opentracing_helpers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestWorkerPool(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("request")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	pool := NewWorkerPool(2, 10)
+	ran := make(chan opentracing.Span, 3)
+	for i := 0; i < 3; i++ {
+		err := pool.Submit(ctx, "resize image", func(ctx context.Context) {
+			ran <- opentracing.SpanFromContext(ctx)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pool.Close()
+
+	if len(ran) != 3 {
+		t.Fatalf("%d tasks ran before Close returned, want 3", len(ran))
+	}
+	spans := tracer.FinishedSpans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	for _, span := range spans {
+		if span.OperationName != "resize image" {
+			t.Errorf("operation name = %q", span.OperationName)
+		}
+		if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+			t.Error("the task span doesn't follow from the submitter's span")
+		}
+		if got, want := loggedEvents(span), []string{"queued", "started", "finished"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("logged events %q, want %q", got, want)
+		}
+	}
+	if span := <-ran; span == nil {
+		t.Error("the task context doesn't carry its span")
+	}
+}
+
+func TestWorkerPoolClosed(t *testing.T) {
+	pool := NewWorkerPool(1, 1)
+	pool.Close()
+	pool.Close()
+
+	err := pool.Submit(context.Background(), "task", func(context.Context) {
+		t.Error("a task ran after Close")
+	})
+	if err != ErrWorkerPoolClosed {
+		t.Errorf("err = %v, want ErrWorkerPoolClosed", err)
+	}
+}
+
+func TestWorkerPoolSubmitCanceled(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("request")
+	pool := NewWorkerPool(1, 0)
+	defer pool.Close()
+
+	release := make(chan struct{})
+	busy := make(chan struct{})
+	pool.Submit(context.Background(), "blocker", func(context.Context) {
+		close(busy)
+		<-release
+	})
+	<-busy
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(opentracing.ContextWithSpan(context.Background(), parent))
+	cancel()
+	if err := pool.Submit(ctx, "dropped", func(context.Context) { t.Error("the dropped task ran") }); err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	span := finishedSpan(t, tracer)
+	if got, want := loggedEvents(span), []string{"queued", "not queued"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logged events %q, want %q", got, want)
+	}
+}
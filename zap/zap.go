@@ -0,0 +1,51 @@
+// Package zap adds the trace and span IDs of the span in a context to zap
+// loggers, so that logs and traces can be joined:
+//
+//	otzap.LoggerWithTrace(r.Context(), logger).Info("user created")
+package zap
+
+import (
+	"context"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"go.uber.org/zap"
+)
+
+// Option customizes LoggerWithTrace.
+type Option func(*config)
+
+type config struct {
+	codec opentracing_helpers.SpanContextCodec
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithCodec reads the IDs with codec instead of from the tracer's native
+// headers. See opentracing_helpers.SpanIdentifiersFromContext.
+func WithCodec(codec opentracing_helpers.SpanContextCodec) Option {
+	return func(c *config) {
+		c.codec = codec
+	}
+}
+
+// LoggerWithTrace returns a child of logger with the trace_id, span_id and
+// sampled fields of the span in ctx. logger is returned as is if ctx
+// carries no span.
+func LoggerWithTrace(ctx context.Context, logger *zap.Logger, opts ...Option) *zap.Logger {
+	c := newConfig(opts)
+	ids, ok := opentracing_helpers.SpanIdentifiersFromContext(ctx, c.codec)
+	if !ok {
+		return logger
+	}
+	return logger.With(
+		zap.String("trace_id", ids.TraceID),
+		zap.String("span_id", ids.SpanID),
+		zap.Bool("sampled", ids.Sampled),
+	)
+}
@@ -0,0 +1,48 @@
+package zap
+
+import (
+	"context"
+	"testing"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// codec reports fixed IDs for mocktracer span contexts.
+type codec struct{}
+
+func (codec) Identifiers(sc opentracing.SpanContext) (opentracing_helpers.SpanIdentifiers, bool) {
+	_, ok := sc.(mocktracer.MockSpanContext)
+	return opentracing_helpers.SpanIdentifiers{TraceID: "463ac35c9f6413ad", SpanID: "a2fb4a1d1a96d312", Sampled: true}, ok
+}
+
+func (codec) SpanContext(opentracing_helpers.SpanIdentifiers) (opentracing.SpanContext, error) {
+	return nil, opentracing.ErrUnsupportedFormat
+}
+
+func TestLoggerWithTrace(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	span := mocktracer.New().StartSpan("op")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	LoggerWithTrace(ctx, zap.New(core), WithCodec(codec{})).Info("user created")
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["trace_id"] != "463ac35c9f6413ad" || fields["span_id"] != "a2fb4a1d1a96d312" || fields["sampled"] != true {
+		t.Errorf("logged %v", fields)
+	}
+}
+
+func TestLoggerWithTraceWithoutSpan(t *testing.T) {
+	logger := zap.NewNop()
+	if got := LoggerWithTrace(context.Background(), logger); got != logger {
+		t.Error("the logger was not returned as is")
+	}
+}
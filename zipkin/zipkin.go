@@ -0,0 +1,72 @@
+// Package zipkin bootstraps a Zipkin tracer for services instrumented with
+// opentracing_helpers:
+//
+//	tracer, closer, err := otzipkin.InitZipkin("users", "http://zipkin:9411/api/v2/spans")
+//	if err != nil {
+//		return err
+//	}
+//	defer closer.Close()
+//
+// Importing the package also registers the "zipkin" backend for
+// opentracing_helpers.InitTracer, which reports to the collector named by
+// the ZIPKIN_COLLECTOR_URL environment variable.
+package zipkin
+
+import (
+	"io"
+	"os"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+	zipkinot "github.com/openzipkin-contrib/zipkin-go-opentracing"
+	"github.com/openzipkin/zipkin-go"
+	zipkinhttp "github.com/openzipkin/zipkin-go/reporter/http"
+)
+
+// DefaultCollectorURL is the collector used by the "zipkin" backend when
+// ZIPKIN_COLLECTOR_URL is unset.
+const DefaultCollectorURL = "http://localhost:9411/api/v2/spans"
+
+func init() {
+	opentracing_helpers.RegisterTracerFactory("zipkin", NewTracer)
+}
+
+// NewTracer is an opentracing_helpers.TracerFactory creating a Zipkin
+// tracer like InitZipkin, reporting to ZIPKIN_COLLECTOR_URL, without
+// setting the global tracer.
+func NewTracer(serviceName string) (opentracing.Tracer, io.Closer, error) {
+	collectorURL := os.Getenv("ZIPKIN_COLLECTOR_URL")
+	if collectorURL == "" {
+		collectorURL = DefaultCollectorURL
+	}
+	return newTracer(serviceName, collectorURL)
+}
+
+// InitZipkin creates a Zipkin tracer named serviceName that reports spans
+// to the HTTP collector at collectorURL, and sets it as the global tracer.
+// Span contexts are propagated with B3 headers. All spans are sampled
+// unless opts, which are passed to zipkin.NewTracer, set a sampler. The
+// returned closer flushes buffered spans and must be closed on shutdown.
+func InitZipkin(serviceName, collectorURL string, opts ...zipkin.TracerOption) (opentracing.Tracer, io.Closer, error) {
+	tracer, closer, err := newTracer(serviceName, collectorURL, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	opentracing.SetGlobalTracer(tracer)
+	return tracer, closer, nil
+}
+
+func newTracer(serviceName, collectorURL string, opts ...zipkin.TracerOption) (opentracing.Tracer, io.Closer, error) {
+	endpoint, err := zipkin.NewEndpoint(serviceName, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	reporter := zipkinhttp.NewReporter(collectorURL)
+	opts = append([]zipkin.TracerOption{zipkin.WithLocalEndpoint(endpoint)}, opts...)
+	native, err := zipkin.NewTracer(reporter, opts...)
+	if err != nil {
+		reporter.Close()
+		return nil, nil, err
+	}
+	return zipkinot.Wrap(native, zipkinot.WithB3InjectOption(zipkinot.B3InjectStandard)), reporter, nil
+}
@@ -0,0 +1,106 @@
+package zipkin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	opentracing_helpers "github.com/jfernandez/opentracing-helpers"
+	"github.com/opentracing/opentracing-go"
+)
+
+// collector is a Zipkin HTTP collector keeping the spans it received.
+type collector struct {
+	*httptest.Server
+	mu       sync.Mutex
+	reported []map[string]interface{}
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var spans []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&spans); err != nil {
+			t.Error(err)
+		}
+		c.mu.Lock()
+		c.reported = append(c.reported, spans...)
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func (c *collector) spans() []map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reported
+}
+
+func TestInitZipkin(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.GlobalTracer())
+	collector := newCollector(t)
+
+	tracer, closer, err := InitZipkin("users", collector.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opentracing.GlobalTracer() != tracer {
+		t.Error("the tracer was not set as the global tracer")
+	}
+
+	span := tracer.StartSpan("op")
+	h := http.Header{}
+	if err := tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h)); err != nil {
+		t.Fatal(err)
+	}
+	if h.Get("X-B3-Traceid") == "" || h.Get("X-B3-Sampled") != "1" {
+		t.Errorf("injected headers %v, want sampled B3 headers", h)
+	}
+	span.Finish()
+	closer.Close()
+
+	reported := collector.spans()
+	if len(reported) != 1 {
+		t.Fatalf("reported %d spans, want 1", len(reported))
+	}
+	endpoint, _ := reported[0]["localEndpoint"].(map[string]interface{})
+	if reported[0]["name"] != "op" || endpoint["serviceName"] != "users" {
+		t.Errorf("reported %v", reported[0])
+	}
+}
+
+func TestNewTracer(t *testing.T) {
+	global := opentracing.GlobalTracer()
+	collector := newCollector(t)
+	t.Setenv("ZIPKIN_COLLECTOR_URL", collector.URL)
+
+	tracer, closer, err := NewTracer("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer.StartSpan("op").Finish()
+	closer.Close()
+
+	if opentracing.GlobalTracer() != global {
+		t.Error("NewTracer replaced the global tracer")
+	}
+	if n := len(collector.spans()); n != 1 {
+		t.Errorf("ZIPKIN_COLLECTOR_URL received %d spans, want 1", n)
+	}
+	if !contains(opentracing_helpers.TracerBackends(), "zipkin") {
+		t.Error("the zipkin backend is not registered")
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}